@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var console bool
+
+	var attachCmd = &cobra.Command{
+		Use:   "attach [container] [command...]",
+		Short: "Open an interactive session in a running container",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			// Create container manager
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+
+			return manager.Attach(args[0], container.AttachOptions{
+				Command: args[1:],
+				Console: console,
+				Stdin:   os.Stdin,
+				Stdout:  os.Stdout,
+				Stderr:  os.Stderr,
+			})
+		},
+	}
+
+	attachCmd.Flags().SetInterspersed(false)
+	attachCmd.Flags().BoolVar(&console, "console", false, "Attach to the container console instead of a shell")
+
+	rootCmd.AddCommand(attachCmd)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/oci"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var commitCmd = &cobra.Command{
+		Use:         "commit [container] [repository:tag]",
+		Short:       "Create an image from a container's root filesystem",
+		Annotations: changesContainers,
+		Long: `Capture a container's root filesystem as an image in the local image
+cache, keeping its environment, entrypoint, command and labels. A running
+container is frozen while its filesystem is read.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			name := args[0]
+			ref, err := oci.ParseImageReference(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid image reference: %w", err)
+			}
+
+			// Create container manager
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+			if _, err := useImages(manager, string(oci.PullNever)); err != nil {
+				return err
+			}
+
+			fmt.Printf("Committing container '%s' to '%s'...\n", name, ref)
+			if err := manager.Commit(name, ref); err != nil {
+				return fmt.Errorf("failed to commit container '%s': %w", name, err)
+			}
+			return nil
+		},
+	}
+
+	rootCmd.AddCommand(commitCmd)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	var projectFile string
+	var services, volumes bool
+
+	var configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Validate and print the compose file",
+		Long: `Print the compose file as up sees it, with networks resolved to bridges.
+With --services, print only the service names, one per line. With --volumes,
+print the host paths services mount through storage.mounts, one per line;
+lxc-compose has no named volumes, so these are the volumes a project uses.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if services {
+				names, err := projectServices(projectFile)
+				if err != nil {
+					return err
+				}
+				for _, name := range names {
+					fmt.Println(name)
+				}
+				return nil
+			}
+
+			project, err := loadProject(projectFile)
+			if err != nil {
+				return err
+			}
+
+			if volumes {
+				for _, source := range config.VolumeSources(project.services) {
+					fmt.Println(source)
+				}
+				return nil
+			}
+
+			enc := yaml.NewEncoder(os.Stdout)
+			enc.SetIndent(2)
+			if err := enc.Encode(&common.ComposeConfig{Services: project.services, Networks: project.networks}); err != nil {
+				return fmt.Errorf("failed to encode config: %w", err)
+			}
+			return enc.Close()
+		},
+	}
+
+	configCmd.Flags().StringVarP(&projectFile, "file", "f", "lxc-compose.yml", "Compose file to read")
+	configCmd.Flags().BoolVar(&services, "services", false, "Print the service names, one per line")
+	configCmd.Flags().BoolVar(&volumes, "volumes", false, "Print the host paths mounted by services, one per line")
+	configCmd.MarkFlagsMutuallyExclusive("services", "volumes")
+	rootCmd.AddCommand(configCmd)
+}
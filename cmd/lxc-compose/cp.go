@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/oci"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var noProgress bool
+
+	var cpCmd = &cobra.Command{
+		Use:   "cp CONTAINER:SRC DEST | SRC CONTAINER:DEST",
+		Short: "Copy files or directories between a container and the host",
+		Long: `Copy files or directories between a container and the host.
+Directories are copied recursively, preserving structure, modes and symlinks.
+If DEST is an existing directory, SRC is copied inside it.
+
+The source can also be a stored template or image, given as
+template:NAME:SRC or image:REF:SRC, to read files without creating a
+container. Images are pulled if missing and only the copied files are read from them.`,
+		Example: `  lxc-compose cp web:/etc/nginx/nginx.conf .
+  lxc-compose cp template:base:/etc/os-release ./os-release
+  lxc-compose cp image:alpine:3.19:/etc/apk/repositories .`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if kind, _, _, ok := parseStoredArg(args[1]); ok {
+				return fmt.Errorf("cannot copy into %s, only from it", kind)
+			}
+			kind, stored, storedPath, fromStored := parseStoredArg(args[0])
+
+			srcContainer, srcPath := parseCopyArg(args[0])
+			dstContainer, dstPath := parseCopyArg(args[1])
+			if fromStored && dstContainer != "" {
+				return fmt.Errorf("can only copy from a %s to the host", kind)
+			}
+			if !fromStored && (srcContainer == "") == (dstContainer == "") {
+				return fmt.Errorf("exactly one of source or destination must be CONTAINER:PATH")
+			}
+			if readOnlyState && dstContainer != "" {
+				return readOnlyStateError("copying into a container")
+			}
+
+			// Create container manager
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+
+			var progress container.CopyProgress
+			if !noProgress {
+				progress = newProgressBar(os.Stderr)
+				defer fmt.Fprintln(os.Stderr)
+			}
+
+			switch {
+			case fromStored && kind == "template":
+				if err := manager.CopyFromTemplate(stored, storedPath, args[1], progress); err != nil {
+					return fmt.Errorf("failed to copy from template '%s': %w", stored, err)
+				}
+				return nil
+			case fromStored:
+				if _, err := useImages(manager, string(oci.PullMissing)); err != nil {
+					return err
+				}
+				if err := manager.CopyFromImage(context.Background(), stored, storedPath, args[1], progress); err != nil {
+					return fmt.Errorf("failed to copy from image '%s': %w", stored, err)
+				}
+				return nil
+			case srcContainer != "":
+				if err := manager.CopyFromContainer(srcContainer, srcPath, dstPath, progress); err != nil {
+					return fmt.Errorf("failed to copy from container '%s': %w", srcContainer, err)
+				}
+				return nil
+			}
+
+			if err := manager.CopyToContainer(dstContainer, srcPath, dstPath, progress); err != nil {
+				return fmt.Errorf("failed to copy to container '%s': %w", dstContainer, err)
+			}
+			return nil
+		},
+	}
+
+	cpCmd.Flags().BoolVar(&noProgress, "no-progress", false, "Suppress the progress bar")
+
+	rootCmd.AddCommand(cpCmd)
+}
+
+// parseCopyArg splits a CONTAINER:PATH argument. Plain host paths, including
+// relative ones like ./a:b, return an empty container name.
+func parseCopyArg(arg string) (string, string) {
+	if strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, ".") {
+		return "", arg
+	}
+	name, path, ok := strings.Cut(arg, ":")
+	if !ok || name == "" {
+		return "", arg
+	}
+	return name, path
+}
+
+// parseStoredArg splits a template:NAME:PATH or image:REF:PATH argument.
+// Image references may contain ':' themselves, so the path starts after the
+// last one.
+func parseStoredArg(arg string) (kind, name, path string, ok bool) {
+	kind, rest, found := strings.Cut(arg, ":")
+	if !found || (kind != "template" && kind != "image") {
+		return "", "", "", false
+	}
+
+	i := strings.LastIndex(rest, ":")
+	if i <= 0 {
+		return "", "", "", false
+	}
+	return kind, rest[:i], rest[i+1:], true
+}
+
+// newProgressBar returns a CopyProgress that redraws a single-line bar on w,
+// only when the displayed percentage changes
+func newProgressBar(w io.Writer) container.CopyProgress {
+	const width = 30
+	last := -1
+
+	return func(copied, total int64) {
+		percent := 100
+		if total > 0 {
+			percent = int(copied * 100 / total)
+		}
+		if percent == last {
+			return
+		}
+		last = percent
+
+		filled := width * percent / 100
+		fmt.Fprintf(w, "\r[%s%s] %3d%% %s/%s",
+			strings.Repeat("=", filled), strings.Repeat(" ", width-filled),
+			percent, humanSize(copied), humanSize(total))
+	}
+}
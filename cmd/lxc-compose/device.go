@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var deviceName, deviceType string
+	var options []string
+
+	var deviceCmd = &cobra.Command{
+		Use:   "device",
+		Short: "Attach and detach container devices",
+	}
+
+	var deviceAddCmd = &cobra.Command{
+		Use:         "add [container] [source] [destination]",
+		Short:       "Attach a device to a container",
+		Annotations: changesContainers,
+		Long: `Attach a host device to a container. A running container gets the device
+right away through lxc-device. The device is also added to the container's
+config as a bind mount, so it is still there after a restart. The destination
+defaults to the source path, the name to the source's base name and the type
+to unix-block, unix-char or disk depending on the source.`,
+		Example: `  lxc-compose device add db /dev/sdb
+  lxc-compose device add db /dev/sdb /dev/data --name data --option ro`,
+		Args: cobra.RangeArgs(2, 3),
+		RunE: func(_ *cobra.Command, args []string) error {
+			dev := common.DeviceConfig{
+				Name:    deviceName,
+				Type:    deviceType,
+				Source:  args[1],
+				Options: options,
+			}
+			if len(args) == 3 {
+				dev.Destination = args[2]
+			}
+			if dev.Name == "" {
+				dev.Name = filepath.Base(dev.Source)
+			}
+			if dev.Type == "" {
+				dev.Type = deviceTypeOf(dev.Source)
+			}
+
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+
+			if err := manager.AttachDevice(args[0], dev); err != nil {
+				return err
+			}
+			fmt.Printf("Attached device '%s' to container '%s'\n", dev.Name, args[0])
+			return nil
+		},
+	}
+
+	var deviceRemoveCmd = &cobra.Command{
+		Use:         "remove [container] [device]",
+		Aliases:     []string{"rm"},
+		Short:       "Detach a device from a container",
+		Annotations: changesContainers,
+		Args:        cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+
+			if err := manager.DetachDevice(args[0], args[1]); err != nil {
+				return err
+			}
+			fmt.Printf("Detached device '%s' from container '%s'\n", args[1], args[0])
+			return nil
+		},
+	}
+
+	deviceAddCmd.Flags().StringVar(&deviceName, "name", "", "Device name (default: base name of the source)")
+	deviceAddCmd.Flags().StringVar(&deviceType, "type", "", "Device type (default: detected from the source)")
+	deviceAddCmd.Flags().StringSliceVar(&options, "option", nil, "Device option such as ro or optional, can be repeated")
+
+	deviceCmd.AddCommand(deviceAddCmd, deviceRemoveCmd)
+	rootCmd.AddCommand(deviceCmd)
+}
+
+// deviceTypeOf guesses a device type from what its source path is
+func deviceTypeOf(source string) string {
+	info, err := os.Stat(source)
+	if err != nil {
+		return "disk"
+	}
+	mode := info.Mode()
+	switch {
+	case mode&os.ModeCharDevice != 0:
+		return "unix-char"
+	case mode&os.ModeDevice != 0:
+		return "unix-block"
+	default:
+		return "disk"
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var bridge string
+
+	var doctorCmd = &cobra.Command{
+		Use:     "doctor",
+		Aliases: []string{"diagnose"},
+		Short:   "Check the host environment for common problems",
+		Long: `Check the host for what lxc-compose needs: the LXC tools, the cgroup
+version, the default bridge, /etc/subuid and /etc/subgid entries for
+unprivileged containers, write access to the config directory and docker for
+image operations. Failed checks print a hint on how to fix them. The command
+exits with status 1 when a required check fails; optional checks only affect
+some features.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			dir, err := dataDir()
+			if err != nil {
+				return err
+			}
+
+			checks := container.Diagnose(container.DoctorOptions{
+				ConfigDir: filepath.Join(dir, "containers"),
+				Bridge:    bridge,
+			})
+
+			failed := 0
+			for _, check := range checks {
+				status := "PASS"
+				switch {
+				case !check.OK && check.Optional:
+					status = "WARN"
+				case !check.OK:
+					status = "FAIL"
+					failed++
+				}
+				fmt.Printf("[%s] %s: %s\n", status, check.Name, check.Detail)
+				if check.Hint != "" {
+					fmt.Printf("       hint: %s\n", check.Hint)
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d check(s) failed", failed)
+			}
+			return nil
+		},
+	}
+
+	doctorCmd.Flags().StringVar(&bridge, "bridge", container.DefaultBridge, "Bridge containers are expected to attach to")
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -2,75 +2,175 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"time"
 
-	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
 
 	"github.com/spf13/cobra"
 )
 
-var removeContainers bool
+var (
+	removeContainers bool
+	downOrphans      bool
+	downAssumeYes    bool
+	downRemoveImages string
+	downTimeout      int
+	downRemoveBridge string
+)
 
 func init() {
 	var downCmd = &cobra.Command{
-		Use:   "down [service...]",
-		Short: "Stop and optionally remove containers",
+		Use:         "down [service...]",
+		Short:       "Stop and optionally remove containers",
+		Annotations: changesContainers,
 		Long: `Stop containers defined in the lxc-compose.yml file.
 If service names are provided, only those services will be stopped.
-Use --rm to also remove the containers.`,
+Use --rm to also remove the containers, and --remove-orphans to remove
+containers for services no longer in the compose file. --rmi, with --rm,
+removes the images used by the services, skipping images other containers
+still use.
+Each container gets its service's stop_grace_period to shut down cleanly
+before it is killed; --timeout overrides it for all of them. --remove-bridge
+tears down a bridge set up by up --ensure-bridge once the containers are down,
+bridges it didn't create are left alone.`,
 		RunE: downCmdRunE,
 	}
 
 	downCmd.Flags().StringVarP(&configFile, "file", "f", "", "Specify an alternate compose file (default: lxc-compose.yml)")
 	downCmd.Flags().BoolVar(&removeContainers, "rm", false, "Remove containers after stopping")
+	downCmd.Flags().BoolVar(&downOrphans, "remove-orphans", false, "Remove containers for services no longer in the compose file")
+	downCmd.Flags().BoolVarP(&downAssumeYes, "yes", "y", false, "Don't ask for confirmation before removing orphans")
+	downCmd.Flags().StringVar(&downRemoveImages, "rmi", "", "Remove images used by services, requires --rm: 'local' for images in the lxc-compose cache, 'all' for any image")
+	downCmd.Flags().IntVarP(&downTimeout, "timeout", "t", 0, "Seconds to wait for containers to stop before killing them (default: each service's stop_grace_period)")
+	downCmd.Flags().StringVar(&downRemoveBridge, "remove-bridge", "", "Remove a bridge set up by up --ensure-bridge, with its NAT rule and dnsmasq")
 	rootCmd.AddCommand(downCmd)
 }
 
-func downCmdRunE(_ *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := common.Load(configFile)
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+func downCmdRunE(cmd *cobra.Command, args []string) error {
+	switch downRemoveImages {
+	case "", rmiLocal, rmiAll:
+	default:
+		return fmt.Errorf("invalid --rmi value %q (expected local or all)", downRemoveImages)
+	}
+	// Stopped containers still use their images
+	if downRemoveImages != "" && !removeContainers {
+		return fmt.Errorf("--rmi requires --rm, the stopped containers would still use their images")
 	}
 
-	// Convert to compose config type
-	var compose common.ComposeConfig
-	compose.Services = make(map[string]common.Container)
-	if cfg != nil {
-		compose.Services["default"] = cfg.Services["default"]
+	var stopOpts container.StopOptions
+	if cmd.Flags().Changed("timeout") {
+		if downTimeout < 0 {
+			return fmt.Errorf("invalid --timeout value %d (must not be negative)", downTimeout)
+		}
+		timeout := time.Duration(downTimeout) * time.Second
+		stopOpts.Timeout = &timeout
+	}
+
+	// Load configuration
+	cfg, err := loadCompose(configFile)
+	if err != nil {
+		return err
 	}
 
 	// Create container manager
-	manager, err := container.NewLXCManager("/var/lib/lxc")
+	manager, err := newManager()
 	if err != nil {
 		return fmt.Errorf("failed to create container manager: %w", err)
 	}
 
-	// Stop all or specified services
-	services := args
-	if len(services) == 0 {
-		for name := range compose.Services {
-			services = append(services, name)
+	if downOrphans {
+		if err := removeOrphans(manager, container.ProjectName(configFile), cfg.Services, downAssumeYes); err != nil {
+			return err
 		}
 	}
 
+	// Stop all or specified services, dependents before their dependencies
+	services, err := config.ResolveStopOrder(cfg.Services, args)
+	if err != nil {
+		return err
+	}
+
+	results, downed := downServices(manager, services, stopOpts)
+	if err := reportResults(os.Stdout, results); err != nil {
+		return err
+	}
+
+	if downRemoveImages != "" {
+		images := make([]string, 0, len(services))
+		for _, name := range services {
+			images = append(images, cfg.Services[name].Image)
+		}
+		if err := removeServiceImages(cmd.Context(), manager, images, downed, downRemoveImages); err != nil {
+			return err
+		}
+	}
+
+	if downRemoveBridge != "" {
+		runDir, err := bridgeRunDir()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removing bridge '%s'...\n", downRemoveBridge)
+		if err := container.RemoveNATBridge(downRemoveBridge, runDir); err != nil {
+			return fmt.Errorf("failed to remove bridge %s: %w", downRemoveBridge, err)
+		}
+	}
+
+	return nil
+}
+
+// downServices stops, and with --rm removes, the containers of services in
+// order, carrying on past failures. It also returns the containers it took
+// down.
+func downServices(manager *container.LXCManager, services []string, opts container.StopOptions) ([]OperationResult, map[string]bool) {
+	action := "stop"
+	if removeContainers {
+		action = "remove"
+	}
+
+	var results []OperationResult
+	downed := make(map[string]bool)
 	for _, name := range services {
-		if _, ok := compose.Services[name]; !ok {
-			return fmt.Errorf("service '%s' not found in config", name)
+		// Scaled services run as replicas instead of a single container
+		replicas, err := manager.Replicas(name)
+		if err != nil {
+			results = append(results, OperationResult{
+				Service: name,
+				Action:  action,
+				Err:     fmt.Errorf("failed to list replicas of service '%s': %w", name, err),
+			})
+			continue
+		}
+		if len(replicas) == 0 || manager.ContainerExists(name) {
+			err := stopContainer(manager, name, true, opts)
+			results = append(results, OperationResult{Service: name, Action: action, Err: err})
+			downed[name] = err == nil
+		}
+		for _, replica := range replicas {
+			err := stopContainer(manager, replica.Name, replica.State != "STOPPED", opts)
+			results = append(results, OperationResult{Service: replica.Name, Action: action, Err: err})
+			downed[replica.Name] = err == nil
 		}
+	}
+	return results, downed
+}
 
+// stopContainer stops a container if requested and removes it when --rm is set
+func stopContainer(manager *container.LXCManager, name string, stop bool, opts container.StopOptions) error {
+	if stop {
 		fmt.Printf("Stopping container '%s'...\n", name)
-		if err := manager.Stop(name); err != nil {
+		if err := manager.StopWithOptions(name, opts); err != nil {
 			return fmt.Errorf("failed to stop container '%s': %w", name, err)
 		}
+	}
 
-		if removeContainers {
-			fmt.Printf("Removing container '%s'...\n", name)
-			if err := manager.Remove(name); err != nil {
-				return fmt.Errorf("failed to remove container '%s': %w", name, err)
-			}
+	if removeContainers {
+		fmt.Printf("Removing container '%s'...\n", name)
+		if err := manager.Remove(name); err != nil {
+			return fmt.Errorf("failed to remove container '%s': %w", name, err)
 		}
 	}
-
 	return nil
 }
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var duCmd = &cobra.Command{
+		Use:   "du [container...]",
+		Short: "Show disk usage per container",
+		RunE: func(_ *cobra.Command, args []string) error {
+			// Create container manager
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+
+			// Default to all containers
+			names := args
+			if len(names) == 0 {
+				containers, err := manager.List()
+				if err != nil {
+					return fmt.Errorf("failed to list containers: %w", err)
+				}
+				for _, c := range containers {
+					names = append(names, c.Name)
+				}
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "NAME\tROOTFS\tLOGS\tCONFIG\tTOTAL")
+
+			var total int64
+			for _, name := range names {
+				usage, err := manager.DiskUsage(name)
+				if err != nil {
+					return fmt.Errorf("failed to get disk usage for container '%s': %w", name, err)
+				}
+
+				totalStr := humanSize(usage.Total)
+				if usage.Partial {
+					totalStr += " (partial)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name,
+					humanSize(usage.Rootfs), humanSize(usage.Logs), humanSize(usage.Config), totalStr)
+				total += usage.Total
+			}
+
+			fmt.Fprintf(w, "TOTAL\t\t\t\t%s\n", humanSize(total))
+			w.Flush()
+
+			return nil
+		},
+	}
+
+	rootCmd.AddCommand(duCmd)
+}
+
+// humanSize formats a byte count using binary units
+func humanSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
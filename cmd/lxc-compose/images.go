@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"text/template"
 
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/errors"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/oci"
@@ -18,6 +23,12 @@ func init() {
 	imagesCmd.AddCommand(pushCmd)
 	imagesCmd.AddCommand(listCmd)
 	imagesCmd.AddCommand(removeCmd)
+
+	listCmd.Flags().String("format", "", "Format output using a Go template, or 'json'")
+	pullCmd.Flags().String("platform", "", "Platform to pull in os/arch[/variant] form (defaults to the host platform)")
+	pullCmd.Flags().Bool("quiet-pull", false, "Don't show layer download progress")
+	pullCmd.Flags().Duration("timeout", 0, "Give up on the pull after this long, e.g. 10m (default: no timeout)")
+	pushCmd.Flags().Duration("timeout", 0, "Give up on the push after this long, e.g. 10m (default: no timeout)")
 }
 
 var imagesCmd = &cobra.Command{
@@ -36,6 +47,13 @@ var pullCmd = &cobra.Command{
 			return errors.Wrap(err, errors.ErrValidation, "invalid image reference")
 		}
 
+		if platform, _ := cmd.Flags().GetString("platform"); platform != "" {
+			ref.Platform, err = oci.ParsePlatform(platform)
+			if err != nil {
+				return errors.Wrap(err, errors.ErrValidation, "invalid platform")
+			}
+		}
+
 		logging.Info("Starting image pull",
 			"image", args[0],
 			"ref", ref)
@@ -45,7 +63,16 @@ var pullCmd = &cobra.Command{
 			return errors.Wrap(err, errors.ErrSystem, "failed to initialize registry manager")
 		}
 
-		if err := manager.Pull(cmd.Context(), ref); err != nil {
+		var progress oci.PullProgress
+		if quiet, _ := cmd.Flags().GetBool("quiet-pull"); !quiet {
+			progress = newPullProgress(os.Stderr)
+			defer fmt.Fprintln(os.Stderr)
+		}
+
+		ctx, cancel := timeoutContext(cmd)
+		defer cancel()
+
+		if err := manager.PullWithProgress(ctx, ref, progress); err != nil {
 			if errors.IsType(err, errors.ErrRegistry) {
 				logging.Error("Failed to pull image",
 					"image", args[0],
@@ -77,7 +104,10 @@ var pushCmd = &cobra.Command{
 			return errors.Wrap(err, errors.ErrSystem, "failed to initialize registry manager")
 		}
 
-		if err := manager.Push(cmd.Context(), ref); err != nil {
+		ctx, cancel := timeoutContext(cmd)
+		defer cancel()
+
+		if err := manager.Push(ctx, ref); err != nil {
 			if errors.IsType(err, errors.ErrRegistry) {
 				logging.Error("Failed to push image",
 					"image", args[0],
@@ -90,6 +120,15 @@ var pushCmd = &cobra.Command{
 	},
 }
 
+// timeoutContext returns the command's context, with a deadline if its
+// --timeout flag is set
+func timeoutContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	if timeout, _ := cmd.Flags().GetDuration("timeout"); timeout > 0 {
+		return context.WithTimeout(cmd.Context(), timeout)
+	}
+	return context.WithCancel(cmd.Context())
+}
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List locally stored images",
@@ -99,6 +138,16 @@ var listCmd = &cobra.Command{
 			return errors.Wrap(err, errors.ErrSystem, "failed to initialize registry manager")
 		}
 
+		format, _ := cmd.Flags().GetString("format")
+		if format != "" {
+			images, err := manager.ListInfo(cmd.Context())
+			if err != nil {
+				logging.Error("Failed to list images", "error", err)
+				return err
+			}
+			return formatImages(os.Stdout, images, format)
+		}
+
 		images, err := manager.List(cmd.Context())
 		if err != nil {
 			logging.Error("Failed to list images", "error", err)
@@ -123,6 +172,31 @@ var listCmd = &cobra.Command{
 	},
 }
 
+// formatImages writes images as JSON or evaluates a Go template once per image
+func formatImages(w io.Writer, images []oci.ImageInfo, format string) error {
+	if format == "json" {
+		if images == nil {
+			images = []oci.ImageInfo{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(images)
+	}
+
+	tmpl, err := template.New("format").Parse(format)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrValidation, "invalid format template")
+	}
+
+	for _, img := range images {
+		if err := tmpl.Execute(w, img); err != nil {
+			return errors.Wrap(err, errors.ErrValidation, "failed to execute format template")
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
 var removeCmd = &cobra.Command{
 	Use:   "remove [registry/repository:tag]",
 	Short: "Remove an image from local storage",
@@ -153,13 +227,59 @@ var removeCmd = &cobra.Command{
 	},
 }
 
+// newPullProgress returns a PullProgress that redraws a single summary line
+// on w with completed layers and, when docker reports them, bytes downloaded
+func newPullProgress(w io.Writer) oci.PullProgress {
+	type layer struct {
+		current, total int64
+		done           bool
+	}
+	layers := make(map[string]*layer)
+	var order []string
+
+	return func(p oci.LayerProgress) {
+		l, ok := layers[p.Layer]
+		if !ok {
+			l = &layer{}
+			layers[p.Layer] = l
+			order = append(order, p.Layer)
+		}
+
+		switch p.Status {
+		case oci.LayerDownloading:
+			l.current, l.total = p.Current, p.Total
+		case oci.LayerDownloadComplete:
+			l.current = l.total
+		case oci.LayerPullComplete, oci.LayerAlreadyExists:
+			l.current = l.total
+			l.done = true
+		}
+
+		var done int
+		var current, total int64
+		for _, id := range order {
+			if layers[id].done {
+				done++
+			}
+			current += layers[id].current
+			total += layers[id].total
+		}
+
+		line := fmt.Sprintf("Pulling: %d/%d layers complete", done, len(order))
+		if total > 0 {
+			line += fmt.Sprintf(", %s/%s", humanSize(current), humanSize(total))
+		}
+		fmt.Fprintf(w, "\r\033[K%s", line)
+	}
+}
+
 func getRegistryManager() (*oci.RegistryManager, error) {
-	homeDir, err := os.UserHomeDir()
+	dir, err := dataDir()
 	if err != nil {
-		return nil, errors.Wrap(err, errors.ErrSystem, "failed to get home directory")
+		return nil, errors.Wrap(err, errors.ErrSystem, "failed to get data directory")
 	}
 
-	storageDir := filepath.Join(homeDir, ".lxc-compose", "images")
+	storageDir := filepath.Join(dir, "images")
 	manager, err := oci.NewRegistryManager(storageDir)
 	if err != nil {
 		return nil, errors.Wrap(err, errors.ErrSystem, "failed to create registry manager")
@@ -167,3 +287,21 @@ func getRegistryManager() (*oci.RegistryManager, error) {
 
 	return manager, nil
 }
+
+// useImages makes manager unpack service images into new containers, pulling
+// them according to the given --pull policy, and returns the fetcher so
+// images can be pulled ahead of time
+func useImages(manager *container.LXCManager, pull string) (*oci.ImageFetcher, error) {
+	policy, err := oci.ParsePullPolicy(pull)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrValidation, "invalid --pull value")
+	}
+
+	registry, err := getRegistryManager()
+	if err != nil {
+		return nil, err
+	}
+	fetcher := oci.NewImageFetcher(registry, policy)
+	manager.SetImageStore(fetcher)
+	return fetcher, nil
+}
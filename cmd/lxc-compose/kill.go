@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var signal string
+
+	var killCmd = &cobra.Command{
+		Use:         "kill [container...]",
+		Short:       "Send a signal to one or more running containers",
+		Annotations: changesContainers,
+		Args:        cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			// Create container manager
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+
+			// Signal each container
+			for _, name := range args {
+				fmt.Printf("Sending %s to container '%s'...\n", signal, name)
+				if err := manager.Kill(name, signal); err != nil {
+					return fmt.Errorf("failed to kill container '%s': %w", name, err)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	killCmd.Flags().StringVarP(&signal, "signal", "s", "SIGKILL", "Signal to send to the container")
+
+	rootCmd.AddCommand(killCmd)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var projectFile string
+
+	var lintCmd = &cobra.Command{
+		Use:   "lint",
+		Short: "Check a compose file for common mistakes",
+		Long: `Check a compose file for common mistakes without creating anything.
+Warnings point at settings that are probably not what was meant; errors are
+problems that make up fail. The command exits with status 1 only when errors
+are found.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			issues, err := config.LintFile(projectFile)
+			if err != nil {
+				return err
+			}
+
+			errCount := 0
+			for _, issue := range issues {
+				fmt.Println(issue)
+				if issue.Severity == config.LintError {
+					errCount++
+				}
+			}
+
+			if errCount > 0 {
+				return fmt.Errorf("%s: %d error(s), %d warning(s)", projectFile, errCount, len(issues)-errCount)
+			}
+			if len(issues) == 0 {
+				fmt.Printf("%s: no issues found\n", projectFile)
+			}
+			return nil
+		},
+	}
+
+	lintCmd.Flags().StringVarP(&projectFile, "file", "f", "lxc-compose.yml", "Compose file to check")
+	rootCmd.AddCommand(lintCmd)
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
@@ -11,61 +16,150 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// logColors are the ANSI colors cycled through for service prefixes
+var logColors = []string{"36", "33", "32", "35", "34", "31", "96", "93", "92", "95", "94", "91"}
+
 func init() {
 	var follow bool
 	var tail int
 	var since string
+	var until string
+	var timestampFormat string
 	var timestamp bool
+	var noColor bool
+	var projectFile string
 
 	var logsCmd = &cobra.Command{
-		Use:   "logs [container]",
+		Use:   "logs [container...]",
 		Short: "View container logs",
-		Args:  cobra.ExactArgs(1),
+		Long: `View container logs.
+With a single container, its log is printed as-is. With several containers,
+or none to select every service in the project file, lines are prefixed with
+the service name and color-coded.`,
 		RunE: func(_ *cobra.Command, args []string) error {
-			name := args[0]
-
 			// Create container manager
-			manager, err := container.NewLXCManager("/var/lib/lxc")
+			manager, err := newManager()
 			if err != nil {
 				return fmt.Errorf("failed to create container manager: %w", err)
 			}
 
-			// Parse since time if provided
-			var sinceTime time.Time
-			if since != "" {
-				if since == "1h" || since == "24h" {
-					duration, _ := time.ParseDuration(since)
-					sinceTime = time.Now().Add(-duration)
-				} else {
-					sinceTime, err = time.Parse(time.RFC3339, since)
-					if err != nil {
-						return fmt.Errorf("invalid time format for --since: %w", err)
-					}
+			sinceTime, err := parseLogTime(since)
+			if err != nil {
+				return fmt.Errorf("invalid time format for --since: %w", err)
+			}
+			untilTime, err := parseLogTime(until)
+			if err != nil {
+				return fmt.Errorf("invalid time format for --until: %w", err)
+			}
+
+			opts := container.LogOptions{
+				Follow:          follow,
+				Since:           sinceTime,
+				Until:           untilTime,
+				Tail:            tail,
+				Timestamp:       timestamp,
+				TimestampFormat: timestampFormat,
+			}
+
+			if len(args) == 1 {
+				// Get logs
+				logs, err := manager.GetLogs(args[0], opts)
+				if err != nil {
+					return fmt.Errorf("failed to get logs: %w", err)
 				}
+				defer logs.Close()
+
+				// Copy logs to stdout
+				_, err = io.Copy(os.Stdout, logs)
+				return err
 			}
 
-			// Get logs
-			logs, err := manager.GetLogs(name, container.LogOptions{
-				Follow:    follow,
-				Since:     sinceTime,
-				Tail:      tail,
-				Timestamp: timestamp,
-			})
-			if err != nil {
-				return fmt.Errorf("failed to get logs: %w", err)
+			names := args
+			if len(names) == 0 {
+				names, err = projectServices(projectFile)
+				if err != nil {
+					return err
+				}
 			}
-			defer logs.Close()
 
-			// Copy logs to stdout
-			_, err = io.Copy(os.Stdout, logs)
-			return err
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			color := !noColor && isTerminalFile(os.Stdout)
+			handle := newLogPrinter(os.Stdout, names, color)
+			if err := manager.MultiplexLogs(ctx, names, opts, handle); err != nil {
+				return fmt.Errorf("failed to get logs: %w", err)
+			}
+			return nil
 		},
 	}
 
 	logsCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow log output")
 	logsCmd.Flags().IntVarP(&tail, "tail", "n", 0, "Number of lines to show from the end of the logs")
 	logsCmd.Flags().StringVar(&since, "since", "", "Show logs since timestamp (RFC3339) or relative (e.g., 1h, 24h)")
+	logsCmd.Flags().StringVar(&until, "until", "", "Show logs before timestamp (RFC3339) or relative (e.g., 30m)")
+	logsCmd.Flags().StringVar(&timestampFormat, "timestamp-format", time.RFC3339, "Go time layout of the timestamp log lines start with, used by --since and --until")
 	logsCmd.Flags().BoolVarP(&timestamp, "timestamps", "t", false, "Show timestamps")
+	logsCmd.Flags().BoolVar(&noColor, "no-color", false, "Don't color-code service prefixes")
+	logsCmd.Flags().StringVar(&projectFile, "file", "lxc-compose.yml", "Compose file listing the project's services")
 
 	rootCmd.AddCommand(logsCmd)
 }
+
+// parseLogTime parses a --since or --until value, either an RFC3339 timestamp
+// or a duration before now. An empty value returns the zero time.
+func parseLogTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// projectServices returns the sorted service names defined in a compose file
+func projectServices(path string) ([]string, error) {
+	cfg, err := loadCompose(path)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(cfg.Services))
+	for name := range cfg.Services {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no services defined in %s", path)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// newLogPrinter returns a handler that writes each line prefixed with its
+// padded service name, optionally colored per service
+func newLogPrinter(w io.Writer, names []string, color bool) func(container.LogLine) {
+	width := 0
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+
+	prefixes := make(map[string]string, len(names))
+	for i, name := range names {
+		prefix := fmt.Sprintf("%-*s |", width, name)
+		if color {
+			prefix = fmt.Sprintf("\033[%sm%s\033[0m", logColors[i%len(logColors)], prefix)
+		}
+		prefixes[name] = prefix
+	}
+
+	return func(line container.LogLine) {
+		fmt.Fprintf(w, "%s %s\n", prefixes[line.Container], strings.TrimRight(line.Text, "\r"))
+	}
+}
+
+// isTerminalFile reports whether f is a character device such as a terminal
+func isTerminalFile(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
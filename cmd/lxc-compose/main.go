@@ -1,44 +1,47 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile     string
-	debugMode   bool
-	development bool
+	cfgFile       string
+	configDir     string
+	projectDir    string
+	debugMode     bool
+	verbosity     int
+	quietness     int
+	readOnlyState bool
+	development   bool
+	logFormat     string
 )
 
 func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.lxc-compose.yaml)")
+	rootCmd.PersistentFlags().StringVar(&configDir, "config-dir", "", "directory holding container state and images (default is $HOME/.lxc-compose, env LXC_COMPOSE_DIR)")
+	rootCmd.PersistentFlags().StringVar(&projectDir, "project-directory", "", "directory relative paths in the compose file resolve against (default is the compose file's directory)")
 	rootCmd.PersistentFlags().BoolVar(&debugMode, "debug", false, "enable debug logging")
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "log more: -v for debug, -vv for trace")
+	rootCmd.PersistentFlags().CountVarP(&quietness, "quiet", "q", "log less: -q for warnings and errors, -qq for errors only")
+	rootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
+	rootCmd.PersistentFlags().BoolVar(&readOnlyState, "read-only-state", false, "load container state without writing it, for inspecting containers when the config dir is read-only; commands that change containers are rejected")
 	rootCmd.PersistentFlags().BoolVar(&development, "dev", false, "enable development mode")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "log output format: json or console (default console with --dev, json otherwise)")
 }
 
 func initConfig() {
-	// Initialize logging first
-	logLevel := "info"
-	if debugMode {
-		logLevel = "debug"
-	}
-
-	if err := logging.Init(logging.Config{
-		Level:       logLevel,
-		Development: development,
-	}); err != nil {
-		fmt.Printf("Error initializing logger: %v\n", err)
-		os.Exit(1)
-	}
-
 	// Load configuration file if specified
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
@@ -51,9 +54,81 @@ func initConfig() {
 		viper.SetConfigName(".lxc-compose")
 	}
 
-	if err := viper.ReadInConfig(); err == nil {
+	configErr := viper.ReadInConfig()
+
+	// Initialize logging before anything logs, with the format from the flag
+	// or log_format in the config file
+	cobra.CheckErr(viper.BindPFlag("log_format", rootCmd.PersistentFlags().Lookup("log-format")))
+	if err := logging.Init(logging.Config{
+		Level:       logLevel(),
+		Development: development,
+		Format:      viper.GetString("log_format"),
+	}); err != nil {
+		fmt.Printf("Error initializing logger: %v\n", err)
+		os.Exit(1)
+	}
+
+	if configErr == nil {
 		logging.Info("Using config file", "path", viper.ConfigFileUsed())
 	}
+
+	// The flag wins over LXC_COMPOSE_DIR, which wins over config_dir in the config file
+	cobra.CheckErr(viper.BindPFlag("config_dir", rootCmd.PersistentFlags().Lookup("config-dir")))
+	cobra.CheckErr(viper.BindEnv("config_dir", "LXC_COMPOSE_DIR"))
+}
+
+// logLevel picks the log level from --debug, -v and -q: info by default,
+// -v or --debug for debug, -vv for trace, -q for warn and -qq for error
+func logLevel() string {
+	switch {
+	case verbosity >= 2:
+		return "trace"
+	case verbosity == 1 || debugMode:
+		return "debug"
+	case quietness >= 2:
+		return "error"
+	case quietness == 1:
+		return "warn"
+	}
+	return "info"
+}
+
+// dataDir returns the root directory shared by the container and image stores
+func dataDir() (string, error) {
+	if dir := viper.GetString("config_dir"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".lxc-compose"), nil
+}
+
+// newManager creates a container manager storing its state under dataDir,
+// or only reading it with --read-only-state
+func newManager() (*container.LXCManager, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return nil, err
+	}
+	if readOnlyState {
+		return container.NewReadOnlyLXCManager(filepath.Join(dir, "containers"))
+	}
+	return container.NewLXCManager(filepath.Join(dir, "containers"))
+}
+
+// changesContainersKey annotates commands that create, change or remove
+// containers. They are rejected up front with --read-only-state, as they
+// would change LXC and then fail to save the container state.
+const changesContainersKey = "changes-containers"
+
+// changesContainers is the Annotations value for such commands
+var changesContainers = map[string]string{changesContainersKey: "true"}
+
+// readOnlyStateError reports that what cannot run with --read-only-state
+func readOnlyStateError(what string) error {
+	return fmt.Errorf("%s changes containers and cannot run with --read-only-state", what)
 }
 
 var rootCmd = &cobra.Command{
@@ -62,11 +137,32 @@ var rootCmd = &cobra.Command{
 	Long: `lxc-compose is a CLI tool that allows you to manage LXC containers 
 using a docker-compose like syntax. It supports creating, starting, stopping, 
 and managing containers defined in a YAML configuration file.`,
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		if readOnlyState && cmd.Annotations[changesContainersKey] != "" {
+			return readOnlyStateError(cmd.CommandPath())
+		}
+		return nil
+	},
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		printError(err)
 		os.Exit(1)
 	}
 }
+
+// printError prints validation failures one per line so each config path can
+// be copied straight into an editor search
+func printError(err error) {
+	var errs validation.ValidationErrors
+	if !errors.As(err, &errs) {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("invalid configuration:")
+	for _, e := range errs {
+		fmt.Printf("  %s\n", e)
+	}
+}
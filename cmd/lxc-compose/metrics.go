@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/metrics"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var addr string
+
+	var metricsCmd = &cobra.Command{
+		Use:   "metrics",
+		Short: "Export container metrics",
+	}
+
+	var serveCmd = &cobra.Command{
+		Use:   "serve",
+		Short: "Serve container metrics for Prometheus",
+		Long: `Serve container CPU, memory and state metrics in the Prometheus
+text format on the /metrics endpoint.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			// Create container manager
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.NewExporter(manager))
+
+			logging.Info("Serving metrics", "addr", addr)
+			if err := http.ListenAndServe(addr, mux); err != nil {
+				return fmt.Errorf("failed to serve metrics: %w", err)
+			}
+			return nil
+		},
+	}
+
+	serveCmd.Flags().StringVar(&addr, "addr", ":9101", "Address to listen on")
+	metricsCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(metricsCmd)
+}
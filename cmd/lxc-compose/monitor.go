@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var interval time.Duration
+
+	var monitorCmd = &cobra.Command{
+		Use:         "monitor",
+		Short:       "Restart stopped containers and probe the health of running ones",
+		Annotations: changesContainers,
+		Long: `Watch containers in the foreground and start the ones that stopped,
+according to their restart policy. Containers with "restart: unless-stopped"
+are restarted after exiting or crashing, but not after lxc-compose stop, down
+or kill. Containers with "restart: always" are also restarted after a user
+stop, but only when the monitor starts. Running containers with a health check
+are probed on every pass and their on_healthy and on_unhealthy hooks run when
+their health changes. Console logs over their logging max_size are rotated
+on every pass too. Run it from a service manager such as systemd to
+restart containers after a host reboot.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if interval <= 0 {
+				return fmt.Errorf("interval must be positive")
+			}
+
+			// Create container manager
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			logging.Info("Monitoring containers", "interval", interval)
+			if err := manager.Monitor(ctx, interval); err != nil && !errors.Is(err, context.Canceled) {
+				return fmt.Errorf("failed to monitor containers: %w", err)
+			}
+			return nil
+		},
+	}
+
+	monitorCmd.Flags().DurationVar(&interval, "interval", 10*time.Second, "How often to check for stopped containers, probe health and rotate logs")
+	rootCmd.AddCommand(monitorCmd)
+}
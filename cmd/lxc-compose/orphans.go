@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+)
+
+// removeOrphans stops and removes project containers whose service is no
+// longer defined, asking first unless assumeYes is set
+func removeOrphans(manager *container.LXCManager, project string, services map[string]common.Container, assumeYes bool) error {
+	orphans, err := manager.Orphans(project, services)
+	if err != nil {
+		return fmt.Errorf("failed to find orphan containers: %w", err)
+	}
+	if len(orphans) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(orphans))
+	for i, c := range orphans {
+		names[i] = c.Name
+	}
+
+	if !assumeYes {
+		if !isTerminalFile(os.Stdin) {
+			return fmt.Errorf("refusing to remove orphan containers (%s) without confirmation; use --yes", strings.Join(names, ", "))
+		}
+		ok, err := confirm(os.Stdin, os.Stdout, fmt.Sprintf("Remove %d orphan container(s): %s?", len(names), strings.Join(names, ", ")))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Keeping orphan containers")
+			return nil
+		}
+	}
+
+	for _, c := range orphans {
+		if c.State != "STOPPED" {
+			fmt.Printf("Stopping orphan container '%s'...\n", c.Name)
+			if err := manager.Stop(c.Name); err != nil {
+				return fmt.Errorf("failed to stop container '%s': %w", c.Name, err)
+			}
+		}
+		fmt.Printf("Removing orphan container '%s'...\n", c.Name)
+		if err := manager.Remove(c.Name); err != nil {
+			return fmt.Errorf("failed to remove container '%s': %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// confirm asks a yes/no question, defaulting to no
+func confirm(in io.Reader, out io.Writer, question string) (bool, error) {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read answer: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var portCmd = &cobra.Command{
+		Use:   "port CONTAINER [GUEST_PORT[/PROTOCOL]]",
+		Short: "List port mappings or look up the host port for a guest port",
+		Long: `List port mappings or look up the host port for a guest port.
+With only a container, every mapping is listed as GUEST/PROTOCOL -> HOST.
+With a guest port, the host ports forwarded to it are printed. The protocol
+defaults to tcp.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			name := args[0]
+
+			var guest int
+			protocol := "tcp"
+			if len(args) == 2 {
+				var err error
+				guest, protocol, err = parsePortArg(args[1])
+				if err != nil {
+					return err
+				}
+			}
+
+			// Create container manager
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+
+			if !manager.ContainerExists(name) {
+				return fmt.Errorf("container '%s' does not exist", name)
+			}
+
+			networkCfg, err := manager.GetNetworkConfig(name)
+			if err != nil {
+				return fmt.Errorf("failed to get network config: %w", err)
+			}
+			if networkCfg == nil || len(networkCfg.PortForwards) == 0 {
+				return fmt.Errorf("container '%s' has no port forwards", name)
+			}
+
+			found := false
+			for _, pf := range networkCfg.PortForwards {
+				if guest == 0 {
+					fmt.Printf("%d/%s -> %d\n", pf.Guest, pf.Protocol, pf.Host)
+					continue
+				}
+				if pf.Guest == guest && strings.EqualFold(pf.Protocol, protocol) {
+					fmt.Println(pf.Host)
+					found = true
+				}
+			}
+			if guest != 0 && !found {
+				return fmt.Errorf("no host port mapped to %d/%s in container '%s'", guest, protocol, name)
+			}
+			return nil
+		},
+	}
+
+	rootCmd.AddCommand(portCmd)
+}
+
+// parsePortArg splits a GUEST_PORT[/PROTOCOL] argument
+func parsePortArg(arg string) (int, string, error) {
+	value, protocol, ok := strings.Cut(arg, "/")
+	if !ok {
+		protocol = "tcp"
+	}
+	protocol = strings.ToLower(protocol)
+	if protocol != "tcp" && protocol != "udp" {
+		return 0, "", fmt.Errorf("invalid protocol %q (expected tcp or udp)", protocol)
+	}
+
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, "", fmt.Errorf("invalid guest port %q", value)
+	}
+	return port, protocol, nil
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
 
@@ -11,27 +13,54 @@ import (
 )
 
 func init() {
+	var filters []string
+	var noRefresh bool
+
 	var psCmd = &cobra.Command{
 		Use:   "ps",
 		Short: "List containers",
 		RunE: func(_ *cobra.Command, _ []string) error {
+			match, err := parsePsFilters(filters)
+			if err != nil {
+				return err
+			}
+
 			// Create container manager
-			manager, err := container.NewLXCManager("/var/lib/lxc")
+			manager, err := newManager()
 			if err != nil {
 				return fmt.Errorf("failed to create container manager: %w", err)
 			}
 
 			// Get list of containers
-			containers, err := manager.List()
+			containers, err := manager.ListWithOptions(container.ListOptions{NoRefresh: noRefresh})
 			if err != nil {
 				return fmt.Errorf("failed to list containers: %w", err)
 			}
 
 			// Create tabwriter for formatted output
 			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-			fmt.Fprintln(w, "NAME\tSTATE")
+			fmt.Fprintln(w, "NAME\tSERVICE\tSTATE\tHEALTH\tIP")
 			for _, c := range containers {
-				fmt.Fprintf(w, "%s\t%s\n", c.Name, c.State)
+				if !match(c) {
+					continue
+				}
+				health := c.Health
+				if health == "" {
+					health = "-"
+				}
+				service := container.ServiceOf(c)
+				if service == "" {
+					service = "-"
+				}
+				ip := "-"
+				if len(c.IPs) > 0 {
+					ip = strings.Join(c.IPs, ",")
+				}
+				state := c.State
+				if c.FrozenAt != nil {
+					state = fmt.Sprintf("%s (%s)", state, time.Since(*c.FrozenAt).Round(time.Second))
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Name, service, state, health, ip)
 			}
 			w.Flush()
 
@@ -39,5 +68,42 @@ func init() {
 		},
 	}
 
+	psCmd.Flags().StringArrayVar(&filters, "filter", nil, "Filter output by key=value (health, service, state)")
+	psCmd.Flags().BoolVar(&noRefresh, "no-refresh", false, "Show the last recorded state instead of querying LXC")
+
 	rootCmd.AddCommand(psCmd)
 }
+
+// parsePsFilters builds a predicate from key=value filters, all of which must match
+func parsePsFilters(filters []string) (func(container.Container) bool, error) {
+	wanted := make(map[string]string, len(filters))
+	for _, f := range filters {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid filter %q (expected key=value)", f)
+		}
+		switch key {
+		case "health":
+			value = strings.ToLower(value)
+		case "state":
+			value = strings.ToUpper(value)
+		case "service":
+		default:
+			return nil, fmt.Errorf("unsupported filter key %q (supported: health, service, state)", key)
+		}
+		wanted[key] = value
+	}
+
+	return func(c container.Container) bool {
+		if health, ok := wanted["health"]; ok && c.Health != health {
+			return false
+		}
+		if state, ok := wanted["state"]; ok && c.State != state {
+			return false
+		}
+		if service, ok := wanted["service"]; ok && container.ServiceOf(c) != service {
+			return false
+		}
+		return true
+	}, nil
+}
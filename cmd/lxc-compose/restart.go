@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var (
+		rolling        bool
+		parallelism    int
+		maxUnavailable int
+		preserveState  bool
+	)
+
+	var restartCmd = &cobra.Command{
+		Use:         "restart [container...]",
+		Short:       "Restart one or more containers",
+		Annotations: changesContainers,
+		Long: `Restart one or more containers.
+With --rolling, the arguments are scaled services whose replicas are restarted
+in batches of --parallelism, waiting for each batch to pass its health check
+before moving on. Replicas that are already down or unhealthy are restarted
+first. With --preserve-state, frozen containers are resumed instead
+of restarted and stopped containers are left stopped.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Create container manager
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+
+			if !rolling {
+				for _, name := range args {
+					fmt.Printf("Restarting container '%s'...\n", name)
+					if err := manager.RestartWithOptions(name, container.RestartOptions{PreserveState: preserveState}); err != nil {
+						return fmt.Errorf("failed to restart container '%s': %w", name, err)
+					}
+				}
+				return nil
+			}
+
+			// Replicas in a batch report concurrently
+			var mu sync.Mutex
+			opts := container.RollingRestartOptions{
+				Parallelism:    parallelism,
+				MaxUnavailable: maxUnavailable,
+				Progress: func(replica, status string) {
+					mu.Lock()
+					defer mu.Unlock()
+					fmt.Printf("Replica '%s': %s\n", replica, status)
+				},
+			}
+			for _, service := range args {
+				fmt.Printf("Rolling restart of service '%s'...\n", service)
+				if err := manager.RollingRestart(cmd.Context(), service, opts); err != nil {
+					return fmt.Errorf("rolling restart of service '%s' failed: %w", service, err)
+				}
+			}
+			return nil
+		},
+	}
+
+	restartCmd.Flags().BoolVar(&rolling, "rolling", false, "Restart the replicas of scaled services one batch at a time")
+	restartCmd.Flags().IntVar(&parallelism, "parallelism", 1, "Replicas to restart at once with --rolling")
+	restartCmd.Flags().IntVar(&maxUnavailable, "max-unavailable", 0, "Replicas that may be unavailable at once with --rolling (default: --parallelism)")
+	restartCmd.Flags().BoolVar(&preserveState, "preserve-state", false, "Resume frozen containers and leave stopped ones stopped instead of restarting them")
+	restartCmd.MarkFlagsMutuallyExclusive("rolling", "preserve-state")
+
+	rootCmd.AddCommand(restartCmd)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// OperationResult is the outcome of one action of a batch command such as up
+// or down on one service or replica
+type OperationResult struct {
+	Service string
+	Action  string
+	Err     error
+}
+
+// reportResults prints a summary table of a batch command's results to w,
+// returning an error naming how many failed if any did
+func reportResults(w io.Writer, results []OperationResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	failed := 0
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tACTION\tSTATUS\tERROR")
+	for _, r := range results {
+		status, msg := "OK", "-"
+		if r.Err != nil {
+			failed++
+			status, msg = "FAILED", r.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Service, r.Action, status, msg)
+	}
+	tw.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d services failed", failed, len(results))
+	}
+	return nil
+}
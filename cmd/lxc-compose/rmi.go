@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/oci"
+)
+
+// Values accepted by down --rmi
+const (
+	rmiLocal = "local" // Only images in the lxc-compose image cache
+	rmiAll   = "all"   // Any image referenced by a service
+)
+
+// removeServiceImages removes the given service images after down, skipping
+// images still used by containers other than the ones in downed
+func removeServiceImages(ctx context.Context, manager *container.LXCManager, images []string, downed map[string]bool, mode string) error {
+	containers, err := manager.List()
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+	inUse := make(map[string]string)
+	for _, c := range containers {
+		if downed[c.Name] || c.Config == nil || c.Config.Image == "" {
+			continue
+		}
+		if ref, err := oci.ParseImageReference(c.Config.Image); err == nil {
+			inUse[ref.String()] = c.Name
+		}
+	}
+
+	registry, err := getRegistryManager()
+	if err != nil {
+		return fmt.Errorf("failed to initialize registry manager: %w", err)
+	}
+
+	cached := make(map[string]bool)
+	if mode == rmiLocal {
+		refs, err := registry.List(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list cached images: %w", err)
+		}
+		for _, ref := range refs {
+			cached[ref.String()] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, image := range images {
+		if image == "" {
+			continue
+		}
+		ref, err := oci.ParseImageReference(image)
+		if err != nil {
+			return fmt.Errorf("invalid image reference '%s': %w", image, err)
+		}
+		key := ref.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if owner, ok := inUse[key]; ok {
+			fmt.Printf("Keeping image '%s', still used by container '%s'\n", image, owner)
+			continue
+		}
+		if mode == rmiLocal && !cached[key] {
+			continue
+		}
+
+		fmt.Printf("Removing image '%s'...\n", image)
+		if err := registry.Delete(ctx, ref); err != nil {
+			return fmt.Errorf("failed to remove image '%s': %w", image, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/oci"
+
+	"github.com/spf13/cobra"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+func init() {
+	var remove bool
+	var interactive bool
+	var tty bool
+	var name string
+	var env []string
+	var pull string
+
+	var runCmd = &cobra.Command{
+		Use:         "run [image] [command...]",
+		Short:       "Run a command in a new one-off container",
+		Annotations: changesContainers,
+		Long: `Create and start a throwaway container from an image, run a command in it,
+then stop and remove the container once the command exits. Interrupts and
+SIGTERM are passed on to the command. With -t the terminal is put into raw
+mode and the command gets a terminal of its own.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			image, command := args[0], args[1:]
+
+			if tty {
+				if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+					return fmt.Errorf("the input device is not a TTY")
+				}
+			}
+
+			environment, err := parseEnvVars(env)
+			if err != nil {
+				return err
+			}
+
+			if name == "" {
+				name, err = generateRunName(image)
+				if err != nil {
+					return fmt.Errorf("failed to generate container name: %w", err)
+				}
+			}
+
+			// Create container manager
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+			if _, err := useImages(manager, pull); err != nil {
+				return err
+			}
+
+			if err := manager.Create(name, &common.Container{Image: image}); err != nil {
+				return fmt.Errorf("failed to create container '%s': %w", name, err)
+			}
+
+			// Pass interrupts on to the command in the container, so cleanup still runs
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			defer signal.Stop(signals)
+
+			if remove {
+				defer cleanupRunContainer(manager, name)
+			}
+
+			if err := manager.Start(name); err != nil {
+				return fmt.Errorf("failed to start container '%s': %w", name, err)
+			}
+
+			opts := container.ExecOptions{
+				Command: command,
+				Env:     environment,
+				Stdout:  os.Stdout,
+				Stderr:  os.Stderr,
+				TTY:     tty,
+				Signals: signals,
+			}
+			// lxc-attach only allocates a terminal for a terminal stdin
+			if interactive || tty {
+				opts.Stdin = os.Stdin
+			}
+
+			return manager.Exec(name, opts)
+		},
+	}
+
+	runCmd.Flags().SetInterspersed(false)
+	runCmd.Flags().BoolVar(&remove, "rm", true, "Remove the container when the command exits")
+	runCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Keep stdin attached")
+	runCmd.Flags().BoolVarP(&tty, "tty", "t", false, "Allocate a terminal for the session, stdin must be one")
+	runCmd.Flags().StringVar(&name, "name", "", "Name for the container (default: generated)")
+	runCmd.Flags().StringArrayVarP(&env, "env", "e", nil, "Set environment variables (KEY=VALUE)")
+	runCmd.Flags().StringVar(&pull, "pull", string(oci.PullMissing), "Pull the image before creating the container: always, missing or never")
+
+	rootCmd.AddCommand(runCmd)
+}
+
+// cleanupRunContainer stops and removes a one-off container
+func cleanupRunContainer(manager *container.LXCManager, name string) {
+	if c, err := manager.Get(name); err == nil && (c.State == "RUNNING" || c.State == "FROZEN") {
+		if err := manager.Stop(name); err != nil {
+			logging.Warn("Failed to stop container", "name", name, "error", err)
+		}
+	}
+	if err := manager.Remove(name); err != nil {
+		logging.Warn("Failed to remove container", "name", name, "error", err)
+	}
+}
+
+// generateRunName builds a unique container name from an image reference
+func generateRunName(image string) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+
+	// Use the last path component of the image without its tag or digest
+	base := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.IndexAny(base, ":@"); i >= 0 {
+		base = base[:i]
+	}
+	base = strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(base), "-"), "-")
+	if base == "" {
+		base = "container"
+	}
+
+	return fmt.Sprintf("run-%s-%s", base, hex.EncodeToString(suffix)), nil
+}
+
+// parseEnvVars converts KEY=VALUE pairs into a map
+func parseEnvVars(vars []string) (map[string]string, error) {
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	env := make(map[string]string, len(vars))
+	for _, v := range vars {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid environment variable %q (expected KEY=VALUE)", v)
+		}
+		env[key] = value
+	}
+	return env, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/oci"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var pull string
+	var parallelPull int
+
+	var scaleCmd = &cobra.Command{
+		Use:         "scale SERVICE=REPLICAS...",
+		Short:       "Set the number of containers running for a service",
+		Annotations: changesContainers,
+		Long: `Set the number of containers running for a service.
+Replicas are named SERVICE_1 to SERVICE_N and each gets its own hostname and
+MAC address. Missing replicas are created and started, and surplus replicas
+are stopped and removed.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			targets := make([]string, 0, len(args))
+			counts := make(map[string]int, len(args))
+			for _, arg := range args {
+				service, count, err := parseScaleArg(arg)
+				if err != nil {
+					return err
+				}
+				targets = append(targets, service)
+				counts[service] = count
+			}
+
+			cfg, err := loadCompose(configFile)
+			if err != nil {
+				return err
+			}
+			services, err := config.ResolveNetworks(cfg.Networks, cfg.Services)
+			if err != nil {
+				return fmt.Errorf("invalid network configuration: %w", err)
+			}
+
+			// Create container manager
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+			fetcher, err := useImages(manager, pull)
+			if err != nil {
+				return err
+			}
+
+			// Pull the images of the scaled services up front, each once
+			images := make([]string, 0, len(targets))
+			for _, service := range targets {
+				base, ok := services[service]
+				if !ok {
+					return fmt.Errorf("service '%s' not found in config", service)
+				}
+				if base.Image != "" {
+					images = append(images, base.Image)
+				}
+			}
+			if err := fetcher.Prefetch(cmd.Context(), images, parallelPull); err != nil {
+				return fmt.Errorf("failed to pull images: %w", err)
+			}
+
+			project := container.ProjectName(configFile)
+			for _, service := range targets {
+				svc := container.WithProjectLabels(services[service], project, service)
+
+				fmt.Printf("Scaling service '%s' to %d replicas...\n", service, counts[service])
+				if err := manager.Scale(service, &svc, counts[service]); err != nil {
+					return fmt.Errorf("failed to scale service '%s': %w", service, err)
+				}
+			}
+			return nil
+		},
+	}
+
+	scaleCmd.Flags().StringVarP(&configFile, "file", "f", "", "Specify an alternate compose file (default: lxc-compose.yml)")
+	scaleCmd.Flags().StringVar(&pull, "pull", string(oci.PullMissing), "Pull images before creating replicas: always, missing or never")
+	scaleCmd.Flags().IntVar(&parallelPull, "parallel-pull", 4, "Images to pull at once")
+
+	rootCmd.AddCommand(scaleCmd)
+}
+
+// parseScaleArg splits a SERVICE=REPLICAS argument
+func parseScaleArg(arg string) (string, int, error) {
+	service, value, ok := strings.Cut(arg, "=")
+	if !ok || service == "" {
+		return "", 0, fmt.Errorf("invalid scale argument %q (expected SERVICE=REPLICAS)", arg)
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return "", 0, fmt.Errorf("invalid replica count %q for service '%s'", value, service)
+	}
+	return service, count, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var snapshotCmd = &cobra.Command{
+		Use:   "snapshot",
+		Short: "Manage storage snapshots of containers",
+		Long: `Manage snapshots of container root filesystems at the storage layer.
+Containers on zfs use zfs snapshots and containers on btrfs use read-only
+subvolumes. Containers on the dir backend are copied to a tarball instead.
+The lvm backend doesn't support snapshots.`,
+	}
+
+	var snapshotCreateCmd = &cobra.Command{
+		Use:         "create [container] [snapshot]",
+		Short:       "Snapshot a container's root filesystem",
+		Annotations: changesContainers,
+		Args:        cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+
+			if err := manager.SnapshotStorage(args[0], args[1]); err != nil {
+				return err
+			}
+			fmt.Printf("Created snapshot '%s' of container '%s'\n", args[1], args[0])
+			return nil
+		},
+	}
+
+	var snapshotListCmd = &cobra.Command{
+		Use:   "list [container]",
+		Short: "List the snapshots of a container, oldest first",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+
+			snapshots, err := manager.ListStorageSnapshots(args[0])
+			if err != nil {
+				return err
+			}
+			for _, snap := range snapshots {
+				fmt.Println(snap)
+			}
+			return nil
+		},
+	}
+
+	var snapshotRollbackCmd = &cobra.Command{
+		Use:         "rollback [container] [snapshot]",
+		Short:       "Restore a stopped container's root filesystem from a snapshot",
+		Annotations: changesContainers,
+		Long: `Restore a stopped container's root filesystem from a snapshot.
+On zfs, snapshots taken after the one rolled back to are destroyed.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			manager, err := newManager()
+			if err != nil {
+				return fmt.Errorf("failed to create container manager: %w", err)
+			}
+
+			if err := manager.RollbackStorage(args[0], args[1]); err != nil {
+				return err
+			}
+			fmt.Printf("Rolled back container '%s' to snapshot '%s'\n", args[0], args[1])
+			return nil
+		},
+	}
+
+	snapshotCmd.AddCommand(snapshotCreateCmd, snapshotListCmd, snapshotRollbackCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
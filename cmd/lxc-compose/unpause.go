@@ -3,19 +3,18 @@ package main
 import (
 	"fmt"
 
-	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
-
 	"github.com/spf13/cobra"
 )
 
 func init() {
 	var unpauseCmd = &cobra.Command{
-		Use:   "unpause [container...]",
-		Short: "Unpause one or more containers",
-		Args:  cobra.MinimumNArgs(1),
+		Use:         "unpause [container...]",
+		Short:       "Unpause one or more containers",
+		Annotations: changesContainers,
+		Args:        cobra.MinimumNArgs(1),
 		RunE: func(_ *cobra.Command, args []string) error {
 			// Create container manager
-			manager, err := container.NewLXCManager("/var/lib/lxc")
+			manager, err := newManager()
 			if err != nil {
 				return fmt.Errorf("failed to create container manager: %w", err)
 			}
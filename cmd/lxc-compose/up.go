@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/oci"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
 
 	"github.com/spf13/cobra"
 )
@@ -13,61 +21,402 @@ var configFile string
 
 func init() {
 	var upCmd = &cobra.Command{
-		Use:   "up [service...]",
-		Short: "Create and start containers",
+		Use:         "up [service...]",
+		Short:       "Create and start containers",
+		Annotations: changesContainers,
 		Long: `Create and start containers defined in the lxc-compose.yml file.
-If service names are provided, only those services will be started.`,
+If service names are provided, only those services and their dependencies will be started.
+Existing containers are recreated when their configuration changed and only
+started otherwise; --force-recreate always recreates them and --no-recreate
+never does. Data in storage mounts lives on the host and survives recreation.
+--ensure-bridge sets up a missing bridge like LXC's lxcbr0, with an address,
+NAT and a dnsmasq for DHCP, for hosts without one; an existing bridge is left
+as is. down --remove-bridge tears down a bridge it set up again. With --watch, up keeps running in the foreground and re-applies services whose
+configuration changed whenever the compose file is written or SIGHUP is received.`,
 		RunE: upCmdRunE,
 	}
 
 	upCmd.Flags().StringVarP(&configFile, "file", "f", "", "Specify an alternate compose file (default: lxc-compose.yml)")
+	upCmd.Flags().Bool("no-deps", false, "Don't start services listed in depends_on")
+	upCmd.Flags().Bool("remove-orphans", false, "Remove containers for services no longer in the compose file")
+	upCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before removing orphans")
+	upCmd.Flags().Bool("create-networks", false, "Create missing bridges for networks defined in the compose file")
+	upCmd.Flags().String("ensure-bridge", "", "Set up this bridge with NAT and DHCP like LXC's lxcbr0 if missing, e.g. lxcbr0")
+	upCmd.Flags().String("bridge-subnet", container.DefaultBridgeSubnet, "Subnet of the bridge set up by --ensure-bridge, the bridge takes its first address")
+	upCmd.Flags().String("pull", string(oci.PullMissing), "Pull images before creating containers: always, missing or never")
+	upCmd.Flags().Int("parallel-pull", 4, "Images to pull at once; services sharing an image pull it once")
+	upCmd.Flags().Bool("watch", false, "Keep running and re-apply changed services when the compose file changes or on SIGHUP")
+	upCmd.Flags().Bool("force-recreate", false, "Recreate containers even if their configuration is unchanged")
+	upCmd.Flags().Bool("no-recreate", false, "Don't recreate existing containers, only create missing ones")
+	upCmd.MarkFlagsMutuallyExclusive("force-recreate", "no-recreate")
+	upCmd.Flags().Bool("use-host-timezone", false, "Bind-mount the host's /etc/localtime read-only into services without a timezone")
+	upCmd.Flags().Bool("compatibility", false, "Translate docker-compose deploy.resources limits and reservations into CPU and memory settings")
 	rootCmd.AddCommand(upCmd)
 }
 
-func upCmdRunE(_ *cobra.Command, args []string) error {
-	// Load configuration
-	cfg, err := common.Load(configFile)
+func upCmdRunE(cmd *cobra.Command, args []string) error {
+	noDeps, _ := cmd.Flags().GetBool("no-deps")
+	orphans, _ := cmd.Flags().GetBool("remove-orphans")
+	assumeYes, _ := cmd.Flags().GetBool("yes")
+	createNetworks, _ := cmd.Flags().GetBool("create-networks")
+	ensureBridge, _ := cmd.Flags().GetString("ensure-bridge")
+	bridgeSubnet, _ := cmd.Flags().GetString("bridge-subnet")
+	pull, _ := cmd.Flags().GetString("pull")
+	parallelPull, _ := cmd.Flags().GetInt("parallel-pull")
+	watch, _ := cmd.Flags().GetBool("watch")
+	forceRecreate, _ := cmd.Flags().GetBool("force-recreate")
+	noRecreate, _ := cmd.Flags().GetBool("no-recreate")
+	hostTimezone, _ := cmd.Flags().GetBool("use-host-timezone")
+	compatibility, _ := cmd.Flags().GetBool("compatibility")
+
+	project, err := loadProject(configFile)
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
+	}
+	if hostTimezone {
+		project.useHostTimezone()
+	}
+	if compatibility {
+		if err := project.applyCompatibility(); err != nil {
+			return err
+		}
 	}
 
-	// Convert to compose config type
-	var compose common.ComposeConfig
-	compose.Services = make(map[string]common.Container)
-	if cfg != nil {
-		compose.Services["default"] = cfg.Services["default"]
+	// Start all or specified services, dependencies first
+	services, err := config.ResolveServiceOrder(project.services, args, !noDeps)
+	if err != nil {
+		return fmt.Errorf("failed to resolve service dependencies: %w", err)
 	}
 
 	// Create container manager
-	manager, err := container.NewLXCManager("/var/lib/lxc")
+	manager, err := newManager()
 	if err != nil {
 		return fmt.Errorf("failed to create container manager: %w", err)
 	}
+	fetcher, err := useImages(manager, pull)
+	if err != nil {
+		return err
+	}
 
-	// Start all or specified services
-	services := args
-	if len(services) == 0 {
-		for name := range compose.Services {
-			services = append(services, name)
+	projectName := container.ProjectName(configFile)
+	if orphans {
+		if err := removeOrphans(manager, projectName, project.services, assumeYes); err != nil {
+			return err
 		}
 	}
 
+	if ensureBridge != "" {
+		runDir, err := bridgeRunDir()
+		if err != nil {
+			return err
+		}
+		if err := container.EnsureNATBridge(ensureBridge, bridgeSubnet, runDir); err != nil {
+			return fmt.Errorf("failed to set up bridge %s: %w", ensureBridge, err)
+		}
+	}
+	if err := ensureNetworks(project.networks, project.services, services, createNetworks); err != nil {
+		return err
+	}
+
+	if noDeps {
+		warnUnstartedDependencies(manager, project.services, services)
+	}
+
+	// Pull the images of containers about to be created up front, each once
+	svcCfgs := make(map[string]common.Container, len(services))
+	var images []string
 	for _, name := range services {
-		svcCfg, ok := compose.Services[name]
-		if !ok {
-			return fmt.Errorf("service '%s' not found in config", name)
+		svcCfg := container.WithProjectLabels(project.services[name], projectName, name)
+		svcCfgs[name] = svcCfg
+
+		create, err := serviceNeedsCreate(manager, name, &svcCfg, forceRecreate, noRecreate)
+		if err != nil {
+			return err
 		}
+		if create && svcCfg.Image != "" {
+			images = append(images, svcCfg.Image)
+		}
+	}
+	if err := fetcher.Prefetch(context.Background(), images, parallelPull); err != nil {
+		return fmt.Errorf("failed to pull images: %w", err)
+	}
 
-		fmt.Printf("Creating container '%s'...\n", name)
-		if err := manager.Create(name, &svcCfg); err != nil {
-			return fmt.Errorf("failed to create container '%s': %w", name, err)
+	// Carry on past failed services and report them all at the end
+	results := upServices(manager, project.services, services, svcCfgs, forceRecreate, noRecreate)
+	if err := reportResults(os.Stdout, results); err != nil {
+		return err
+	}
+
+	if !watch {
+		return nil
+	}
+	return watchProject(manager, project, upOptions{
+		services:       args,
+		noDeps:         noDeps,
+		createNetworks: createNetworks,
+		hostTimezone:   hostTimezone,
+		compatibility:  compatibility,
+		fetcher:        fetcher,
+	})
+}
+
+// upServices creates and starts services in order, carrying on past failures.
+// Services whose dependencies failed are skipped and reported as failed too.
+func upServices(manager *container.LXCManager, all map[string]common.Container, order []string, cfgs map[string]common.Container, force, keep bool) []OperationResult {
+	results := make([]OperationResult, 0, len(order))
+	failed := make(map[string]bool)
+	for _, name := range order {
+		result := OperationResult{Service: name, Action: "skip"}
+		if dep := failedDependency(all, name, failed); dep != "" {
+			result.Err = fmt.Errorf("dependency '%s' failed", dep)
+		} else {
+			cfg := cfgs[name]
+			result.Action, result.Err = upService(manager, name, &cfg, force, keep)
+		}
+		if result.Err != nil {
+			failed[name] = true
 		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// failedDependency returns the first dependency of a service that failed, or
+// "" if none did
+func failedDependency(services map[string]common.Container, name string, failed map[string]bool) string {
+	for _, dep := range config.ServiceDependencies(services, name) {
+		if failed[dep] {
+			return dep
+		}
+	}
+	return ""
+}
+
+// upService creates a service's container if needed and starts it, returning
+// what it did: create, recreate, start or none
+func upService(manager *container.LXCManager, name string, cfg *common.Container, force, keep bool) (string, error) {
+	action, err := createService(manager, name, cfg, force, keep)
+	if err != nil {
+		return action, err
+	}
+
+	if c, err := manager.Get(name); err == nil && c.State != "STOPPED" {
+		return action, nil
+	}
+	if action == "none" {
+		action = "start"
+	}
+	fmt.Printf("Starting container '%s'...\n", name)
+	if err := manager.Start(name); err != nil {
+		return action, fmt.Errorf("failed to start container '%s': %w", name, err)
+	}
+	return action, nil
+}
+
+// createService creates the container of a service, or recreates an existing
+// one whose configuration changed. force recreates it regardless and keep
+// never does. It returns what it did: create, recreate or none.
+func createService(manager *container.LXCManager, name string, cfg *common.Container, force, keep bool) (string, error) {
+	action := "create"
+	if manager.ContainerExists(name) {
+		action = "recreate"
+		recreate, err := serviceNeedsCreate(manager, name, cfg, force, keep)
+		if err != nil {
+			return action, err
+		}
+		if !recreate {
+			fmt.Printf("Container '%s' is up to date\n", name)
+			return "none", nil
+		}
+
+		fmt.Printf("Recreating container '%s'...\n", name)
+		if c, err := manager.Get(name); err == nil && c.State != "STOPPED" {
+			if err := manager.Stop(name); err != nil {
+				return action, fmt.Errorf("failed to stop container '%s': %w", name, err)
+			}
+		}
+		if err := manager.Remove(name); err != nil {
+			return action, fmt.Errorf("failed to remove container '%s': %w", name, err)
+		}
+	} else {
+		fmt.Printf("Creating container '%s'...\n", name)
+	}
+
+	if err := manager.Create(name, cfg); err != nil {
+		return action, fmt.Errorf("failed to create container '%s': %w", name, err)
+	}
+	return action, nil
+}
+
+// serviceNeedsCreate reports whether createService would create or recreate
+// the container of a service
+func serviceNeedsCreate(manager *container.LXCManager, name string, cfg *common.Container, force, keep bool) (bool, error) {
+	if !manager.ContainerExists(name) || force {
+		return true, nil
+	}
+	if keep {
+		return false, nil
+	}
+	changed, err := manager.ConfigChanged(name, cfg)
+	if err != nil {
+		return false, fmt.Errorf("failed to compare configuration of container '%s': %w", name, err)
+	}
+	return changed, nil
+}
+
+// composeProject is a loaded compose file with networks resolved
+type composeProject struct {
+	services map[string]common.Container
+	networks map[string]common.NetworkDefinition
+}
+
+// loadProject reads a compose file and points services at the bridges of the
+// networks they reference
+func loadProject(path string) (*composeProject, error) {
+	// Load configuration
+	cfg, err := loadCompose(path)
+	if err != nil {
+		return nil, err
+	}
 
-		fmt.Printf("Starting container '%s'...\n", name)
-		if err := manager.Start(name); err != nil {
-			return fmt.Errorf("failed to start container '%s': %w", name, err)
+	services := make(map[string]common.Container)
+	if cfg.Services != nil {
+		services = cfg.Services
+	}
+
+	// Point services at the bridges of the networks they reference
+	if err := config.ValidateNetworks(cfg.Networks); err != nil {
+		return nil, fmt.Errorf("invalid network configuration: %w", err)
+	}
+	services, err = config.ResolveNetworks(cfg.Networks, services)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network configuration: %w", err)
+	}
+
+	// Two services forwarding the same host port would write conflicting DNAT rules
+	if err := config.CheckPortConflicts(services); err != nil {
+		return nil, fmt.Errorf("invalid port configuration: %w", err)
+	}
+	if err := validateServices(services); err != nil {
+		return nil, err
+	}
+
+	return &composeProject{services: services, networks: cfg.Networks}, nil
+}
+
+// validateServices checks every service before anything is created or
+// pulled, reporting all failures located under services.<name>
+func validateServices(services map[string]common.Container) error {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs validation.ValidationErrors
+	for _, name := range names {
+		svc := services[name]
+		path := "services." + name
+		if svc.Image != "" {
+			if _, err := oci.ParseImageReference(svc.Image); err != nil {
+				errs.Add(validation.JoinPath(path, "image"), fmt.Errorf("invalid image reference %q: %w", svc.Image, err))
+			}
 		}
+		errs.Add(path, container.ValidateConfig(&svc))
 	}
+	return errs.ErrorOrNil()
+}
 
+// loadCompose reads a compose file with its relative paths resolved against
+// --project-directory, or the compose file's own directory, not the working
+// directory
+func loadCompose(path string) (*common.ComposeConfig, error) {
+	dir := projectDir
+	if dir == "" {
+		dir = filepath.Dir(path)
+	} else if info, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("invalid project directory: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("invalid project directory: %s is not a directory", dir)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project directory: %w", err)
+	}
+
+	cfg, err := common.LoadFromDir(path, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg, nil
+}
+
+// useHostTimezone makes services without a timezone follow the host's
+func (p *composeProject) useHostTimezone() {
+	for name, svc := range p.services {
+		p.services[name] = container.WithHostTimezone(svc)
+	}
+}
+
+// applyCompatibility translates the services' docker-compose deploy.resources
+// into CPU and memory settings
+func (p *composeProject) applyCompatibility() error {
+	services, err := config.ApplyCompatibility(p.services)
+	if err != nil {
+		return fmt.Errorf("invalid deploy configuration: %w", err)
+	}
+	p.services = services
+	return nil
+}
+
+// ensureNetworks makes sure the bridges of the networks used by targets exist
+func ensureNetworks(networks map[string]common.NetworkDefinition, services map[string]common.Container, targets []string, create bool) error {
+	seen := make(map[string]bool)
+	for _, name := range targets {
+		for _, network := range config.ServiceNetworks(services[name]) {
+			if seen[network] {
+				continue
+			}
+			seen[network] = true
+
+			bridge := config.BridgeName(network, networks[network])
+			if err := container.EnsureBridge(bridge, networks[network], create); err != nil {
+				return fmt.Errorf("network '%s': %w", network, err)
+			}
+		}
+	}
 	return nil
 }
+
+// bridgeRunDir returns where bridges set up by --ensure-bridge are recorded
+// and their dnsmasq keeps its pid and lease files
+func bridgeRunDir() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bridges"), nil
+}
+
+// warnUnstartedDependencies warns about dependencies skipped by --no-deps that aren't running
+func warnUnstartedDependencies(manager *container.LXCManager, services map[string]common.Container, targets []string) {
+	selected := make(map[string]bool, len(targets))
+	for _, name := range targets {
+		selected[name] = true
+	}
+
+	for _, name := range targets {
+		for _, dep := range config.ServiceDependencies(services, name) {
+			if selected[dep] {
+				continue
+			}
+			if c, err := manager.Get(dep); err == nil && c.State == "RUNNING" {
+				continue
+			}
+			logging.Warn("Service depends on a service that is not running",
+				"service", name,
+				"dependency", dep,
+			)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/oci"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce is how long up --watch waits after the last file change or
+// SIGHUP before reloading, so an editor's burst of writes causes one reload
+const reloadDebounce = 500 * time.Millisecond
+
+// upOptions are the up flags a reload applies again
+type upOptions struct {
+	services       []string
+	noDeps         bool
+	createNetworks bool
+	hostTimezone   bool
+	compatibility  bool
+	// fetcher pulls the images of services a reload creates
+	fetcher *oci.ImageFetcher
+}
+
+// watchProject re-applies the compose file whenever it is written or SIGHUP
+// is received, until interrupted. Both triggers share one debounce timer.
+func watchProject(manager *container.LXCManager, project *composeProject, opts upOptions) error {
+	path, err := filepath.Abs(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve compose file path: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch compose file: %w", err)
+	}
+	defer watcher.Close()
+	// Editors often replace the file instead of writing it, so watch its directory
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to watch compose file: %w", err)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	projectName := container.ProjectName(configFile)
+	applied := make(map[string]common.Container, len(project.services))
+	for name, svc := range project.services {
+		applied[name] = container.WithProjectLabels(svc, projectName, name)
+	}
+
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+	trigger := ""
+
+	logging.Info("Watching compose file for changes", "path", path)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != path || !(event.Has(fsnotify.Write) || event.Has(fsnotify.Create)) {
+				continue
+			}
+			trigger = "file change"
+			debounce.Reset(reloadDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logging.Warn("Error watching compose file", "path", path, "error", err)
+		case <-hup:
+			trigger = "SIGHUP"
+			debounce.Reset(reloadDebounce)
+		case <-debounce.C:
+			logging.Info("Reloading compose file", "path", path, "trigger", trigger)
+			if err := reloadProject(manager, projectName, applied, opts); err != nil {
+				logging.Error("Failed to reload compose file", "path", path, "error", err)
+			}
+		}
+	}
+}
+
+// reloadProject reads the compose file again and creates new services and
+// updates changed ones. applied holds the configuration each service was last
+// applied with and is updated as services are applied. Running containers are
+// restarted after an update so every changed setting takes effect. Each
+// reload is a new pull pass, so images are pulled again as --pull requires.
+func reloadProject(manager *container.LXCManager, projectName string, applied map[string]common.Container, opts upOptions) error {
+	opts.fetcher.Forget()
+
+	project, err := loadProject(configFile)
+	if err != nil {
+		return err
+	}
+	if opts.hostTimezone {
+		project.useHostTimezone()
+	}
+	if opts.compatibility {
+		if err := project.applyCompatibility(); err != nil {
+			return err
+		}
+	}
+
+	services, err := config.ResolveServiceOrder(project.services, opts.services, !opts.noDeps)
+	if err != nil {
+		return fmt.Errorf("failed to resolve service dependencies: %w", err)
+	}
+
+	if err := ensureNetworks(project.networks, project.services, services, opts.createNetworks); err != nil {
+		return err
+	}
+
+	for name := range applied {
+		if _, ok := project.services[name]; !ok {
+			logging.Warn("Service removed from compose file, its container is left in place", "service", name)
+			delete(applied, name)
+		}
+	}
+
+	for _, name := range services {
+		svcCfg := container.WithProjectLabels(project.services[name], projectName, name)
+
+		if !manager.ContainerExists(name) {
+			logging.Info("Creating service", "service", name)
+			if err := manager.Create(name, &svcCfg); err != nil {
+				return fmt.Errorf("failed to create container '%s': %w", name, err)
+			}
+			if err := manager.Start(name); err != nil {
+				return fmt.Errorf("failed to start container '%s': %w", name, err)
+			}
+			applied[name] = svcCfg
+			continue
+		}
+
+		if previous, ok := applied[name]; ok && reflect.DeepEqual(previous, svcCfg) {
+			continue
+		}
+
+		logging.Info("Updating changed service", "service", name)
+		if err := manager.Update(name, &svcCfg); err != nil {
+			return fmt.Errorf("failed to update container '%s': %w", name, err)
+		}
+		if c, err := manager.Get(name); err == nil && c.State == "RUNNING" {
+			if err := manager.Restart(name); err != nil {
+				return fmt.Errorf("failed to restart container '%s': %w", name, err)
+			}
+		}
+		applied[name] = svcCfg
+	}
+
+	return nil
+}
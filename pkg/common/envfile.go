@@ -0,0 +1,102 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadEnvFile parses a docker-compose style env file of KEY=VALUE lines.
+// Blank lines and lines starting with # are skipped, and values wrapped in
+// matching single or double quotes are unquoted. A KEY without a value is
+// taken from the environment and left out when unset there.
+func ReadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("env file %s does not exist", path)
+		}
+		return nil, fmt.Errorf("failed to read env file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: invalid variable name %q", path, lineNum, key)
+		}
+		if !ok {
+			if v, set := os.LookupEnv(key); set {
+				env[key] = v
+			}
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file %s: %w", path, err)
+	}
+	return env, nil
+}
+
+// MergeEnvFiles reads env files, relative paths resolved against dir, and
+// merges them under env: later files override earlier ones and env overrides
+// them all. It returns the files as absolute paths and the merged
+// environment.
+func MergeEnvFiles(dir string, files []string, env map[string]string) ([]string, map[string]string, error) {
+	if len(files) == 0 {
+		return files, env, nil
+	}
+
+	resolved := make([]string, len(files))
+	merged := make(map[string]string)
+	for i, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve env file %s: %w", file, err)
+		}
+		resolved[i] = abs
+
+		vars, err := ReadEnvFile(abs)
+		if err != nil {
+			return nil, nil, err
+		}
+		for k, v := range vars {
+			merged[k] = v
+		}
+	}
+	for k, v := range env {
+		merged[k] = v
+	}
+	return resolved, merged, nil
+}
+
+// ResolveEnvFiles merges the service's env_file entries, relative to dir,
+// into its environment, see MergeEnvFiles
+func (c *Container) ResolveEnvFiles(dir string) error {
+	files, env, err := MergeEnvFiles(dir, c.EnvFile, c.Environment)
+	if err != nil {
+		return err
+	}
+	c.EnvFile, c.Environment = files, env
+	return nil
+}
@@ -0,0 +1,114 @@
+package common_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+)
+
+func TestReadEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.env")
+	content := `# database settings
+DB_HOST=db
+DB_PASS="s3cret=1"
+export MODE='production'
+
+EMPTY=
+FROM_HOST
+UNSET_VAR
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FROM_HOST", "host-value")
+
+	env, err := common.ReadEnvFile(path)
+	if err != nil {
+		t.Fatalf("ReadEnvFile() error = %v", err)
+	}
+	want := map[string]string{
+		"DB_HOST":   "db",
+		"DB_PASS":   "s3cret=1",
+		"MODE":      "production",
+		"EMPTY":     "",
+		"FROM_HOST": "host-value",
+	}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("ReadEnvFile() = %v, want %v", env, want)
+	}
+
+	if err := os.WriteFile(path, []byte("BAD KEY=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := common.ReadEnvFile(path); err == nil || !strings.Contains(err.Error(), "app.env:1") {
+		t.Errorf("ReadEnvFile() error = %v, want invalid variable name at line 1", err)
+	}
+
+	if _, err := common.ReadEnvFile(filepath.Join(dir, "missing.env")); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("ReadEnvFile() error = %v, want does not exist", err)
+	}
+}
+
+func TestLoadEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"common.env":              "LEVEL=info\nREGION=eu\n",
+		"overrides/web.env":       "LEVEL=debug\n",
+		"lxc-compose.yml":         "services:\n  web:\n    image: nginx:1\n    env_file:\n      - common.env\n      - overrides/web.env\n    environment:\n      REGION: us\n",
+		"missing/lxc-compose.yml": "services:\n  web:\n    image: nginx:1\n    env_file: [nope.env]\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := common.Load(filepath.Join(dir, "lxc-compose.yml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	web := cfg.Services["web"]
+	want := map[string]string{"LEVEL": "debug", "REGION": "us"}
+	if !reflect.DeepEqual(web.Environment, want) {
+		t.Errorf("environment = %v, want %v", web.Environment, want)
+	}
+	if web.EnvFile[1] != filepath.Join(dir, "overrides", "web.env") {
+		t.Errorf("env_file not resolved against the compose file: %v", web.EnvFile)
+	}
+
+	_, err = common.Load(filepath.Join(dir, "missing", "lxc-compose.yml"))
+	if err == nil || !strings.Contains(err.Error(), "service 'web'") || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Load() error = %v, want missing env file for service 'web'", err)
+	}
+
+	// A project directory moves the base env files are resolved against
+	if err := os.WriteFile(filepath.Join(dir, "missing", "common.env"), []byte("LEVEL=warn\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = common.LoadFromDir(filepath.Join(dir, "lxc-compose.yml"), filepath.Join(dir, "missing"))
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(dir, "missing", "overrides", "web.env")) {
+		t.Errorf("LoadFromDir() error = %v, want overrides/web.env missing from the project directory", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "missing", "overrides"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "missing", "overrides", "web.env"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = common.LoadFromDir(filepath.Join(dir, "lxc-compose.yml"), filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatalf("LoadFromDir() error = %v", err)
+	}
+	if level := cfg.Services["web"].Environment["LEVEL"]; level != "warn" {
+		t.Errorf("LEVEL = %q, want warn from the project directory's common.env", level)
+	}
+}
@@ -0,0 +1,180 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExtendsResolver merges services with the base services they extend,
+// reading each compose file once
+type ExtendsResolver struct {
+	files map[string]map[string]map[string]interface{}
+}
+
+// NewExtendsResolver creates an ExtendsResolver
+func NewExtendsResolver() *ExtendsResolver {
+	return &ExtendsResolver{
+		files: make(map[string]map[string]map[string]interface{}),
+	}
+}
+
+// services returns the raw services of a compose file
+func (r *ExtendsResolver) services(path string) (map[string]map[string]interface{}, error) {
+	if services, ok := r.files[path]; ok {
+		return services, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw struct {
+		Services map[string]map[string]interface{} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	r.files[path] = raw.Services
+	return raw.Services, nil
+}
+
+// Resolve returns the raw named service of a compose file with its extends
+// chain merged in
+func (r *ExtendsResolver) Resolve(path, name string) (map[string]interface{}, error) {
+	return r.resolve(path, name, nil)
+}
+
+// ResolveMap merges a raw service definition read from path over the base
+// service it extends, if any
+func (r *ExtendsResolver) ResolveMap(path string, svc map[string]interface{}) (map[string]interface{}, error) {
+	return r.resolveMap(path, svc, nil)
+}
+
+// resolveExtends replaces the services of the compose file at path that use
+// extends with their definitions merged over their base services
+func (c *ComposeConfig) resolveExtends(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	r := NewExtendsResolver()
+	services, err := r.services(path)
+	if err != nil {
+		return err
+	}
+	for name, svc := range services {
+		if _, ok := svc["extends"]; !ok {
+			continue
+		}
+		resolved, err := r.resolve(path, name, nil)
+		if err != nil {
+			return fmt.Errorf("service '%s': %w", name, err)
+		}
+		data, err := yaml.Marshal(resolved)
+		if err != nil {
+			return fmt.Errorf("service '%s': %w", name, err)
+		}
+		var container Container
+		if err := yaml.Unmarshal(data, &container); err != nil {
+			return fmt.Errorf("service '%s': %w", name, err)
+		}
+		c.Services[name] = container
+	}
+	return nil
+}
+
+// resolve returns the named service of a file with its extends chain merged in
+func (r *ExtendsResolver) resolve(path, name string, visiting []string) (map[string]interface{}, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := path + "#" + name
+	for _, v := range visiting {
+		if v == key {
+			return nil, fmt.Errorf("extends cycle detected: %s", strings.Join(append(visiting, key), " -> "))
+		}
+	}
+
+	services, err := r.services(path)
+	if err != nil {
+		return nil, err
+	}
+
+	svc, ok := services[name]
+	if !ok {
+		return nil, fmt.Errorf("service '%s' not found in %s", name, path)
+	}
+
+	return r.resolveMap(path, svc, append(visiting, key))
+}
+
+// resolveMap merges a raw service definition over the base service it extends
+func (r *ExtendsResolver) resolveMap(path string, svc map[string]interface{}, visiting []string) (map[string]interface{}, error) {
+	ext, ok := svc["extends"]
+	if !ok {
+		return svc, nil
+	}
+
+	extMap, ok := ext.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("extends must be a mapping with a service and optional file")
+	}
+
+	baseName, _ := extMap["service"].(string)
+	if baseName == "" {
+		return nil, fmt.Errorf("extends requires a service")
+	}
+
+	basePath := path
+	if file, _ := extMap["file"].(string); file != "" {
+		if filepath.IsAbs(file) {
+			basePath = file
+		} else {
+			basePath = filepath.Join(filepath.Dir(path), file)
+		}
+	}
+
+	base, err := r.resolve(basePath, baseName, visiting)
+	if err != nil {
+		return nil, err
+	}
+
+	override := make(map[string]interface{}, len(svc))
+	for k, v := range svc {
+		if k != "extends" {
+			override[k] = v
+		}
+	}
+
+	return mergeMaps(base, override), nil
+}
+
+// mergeMaps deep-merges override into a copy of base. Nested mappings are merged,
+// scalars and lists from override replace those in base.
+func mergeMaps(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+
+	for k, v := range override {
+		baseMap, baseIsMap := merged[k].(map[string]interface{})
+		overrideMap, overrideIsMap := v.(map[string]interface{})
+		if baseIsMap && overrideIsMap {
+			merged[k] = mergeMaps(baseMap, overrideMap)
+			continue
+		}
+		merged[k] = v
+	}
+
+	return merged
+}
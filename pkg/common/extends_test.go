@@ -0,0 +1,66 @@
+package common_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+)
+
+func TestLoadExtends(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"common.yml": "services:\n  hardened:\n    image: debian:12\n    security:\n      isolation: strict\n",
+		"lxc-compose.yml": `services:
+  base:
+    image: ubuntu:20.04
+    environment:
+      LOG_LEVEL: info
+  app:
+    extends:
+      service: base
+    image: ubuntu:22.04
+    environment:
+      PORT: "8080"
+  worker:
+    extends:
+      file: common.yml
+      service: hardened
+`,
+		"cycle/lxc-compose.yml": "services:\n  app:\n    extends:\n      service: other\n  other:\n    extends:\n      service: app\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := common.Load(filepath.Join(dir, "lxc-compose.yml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	app := cfg.Services["app"]
+	if app.Image != "ubuntu:22.04" {
+		t.Errorf("app image = %q, want ubuntu:22.04", app.Image)
+	}
+	if app.Environment["LOG_LEVEL"] != "info" || app.Environment["PORT"] != "8080" {
+		t.Errorf("app environment = %v, want LOG_LEVEL from base and PORT", app.Environment)
+	}
+
+	worker := cfg.Services["worker"]
+	if worker.Image != "debian:12" || worker.Security == nil || worker.Security.Isolation != "strict" {
+		t.Errorf("worker = %+v, want the hardened service from common.yml", worker)
+	}
+
+	_, err = common.Load(filepath.Join(dir, "cycle", "lxc-compose.yml"))
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Load() error = %v, want an extends cycle", err)
+	}
+}
@@ -0,0 +1,53 @@
+package common_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+)
+
+func TestLoadExtensions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lxc-compose.yml")
+	content := `version: "1.0"
+x-deploy:
+  region: eu-west
+services:
+  app:
+    image: ubuntu:20.04
+    x-owner: platform
+    unknown: ignored
+  db:
+    image: postgres:15
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := common.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	top := cfg.Extensions("")
+	deploy, ok := top["x-deploy"].(map[string]interface{})
+	if len(top) != 1 || !ok || deploy["region"] != "eu-west" {
+		t.Errorf("top-level extensions = %v, want only x-deploy", top)
+	}
+
+	app := cfg.Extensions("app")
+	if len(app) != 1 || app["x-owner"] != "platform" {
+		t.Errorf("app extensions = %v, want only x-owner", app)
+	}
+	if cfg.Services["app"].Image != "ubuntu:20.04" {
+		t.Errorf("app image = %q, want ubuntu:20.04", cfg.Services["app"].Image)
+	}
+
+	if ext := cfg.Extensions("db"); ext != nil {
+		t.Errorf("db extensions = %v, want none", ext)
+	}
+	if ext := cfg.Extensions("missing"); ext != nil {
+		t.Errorf("missing service extensions = %v, want none", ext)
+	}
+}
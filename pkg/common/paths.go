@@ -0,0 +1,65 @@
+package common
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// ResolvePaths resolves the service's relative host paths against dir, like
+// docker-compose does: bind mount sources, include_configs, the VPN config
+// and env files, which are merged into its environment, see
+// ResolveEnvFiles. Absolute paths are left as they are. Mounts and the
+// network are copied before they are changed, services using extends may
+// share them with their base.
+func (c *Container) ResolvePaths(dir string) error {
+	if c.Storage != nil && len(c.Storage.Mounts) > 0 {
+		storage := *c.Storage
+		storage.Mounts = make([]Mount, len(c.Storage.Mounts))
+		for i, mount := range c.Storage.Mounts {
+			if IsBindMount(mount.Type) {
+				mount.Source = ResolvePath(dir, mount.Source)
+			}
+			storage.Mounts[i] = mount
+		}
+		c.Storage = &storage
+	}
+	if c.Network != nil && c.Network.VPN != nil {
+		network, vpn := *c.Network, *c.Network.VPN
+		vpn.Config = ResolvePath(dir, vpn.Config)
+		network.VPN = &vpn
+		c.Network = &network
+	}
+	c.IncludeConfigs = ResolvePathList(dir, c.IncludeConfigs)
+	return c.ResolveEnvFiles(dir)
+}
+
+// IsBindMount reports whether a mount's source is a host path, as opposed to
+// a filesystem like tmpfs or proc
+func IsBindMount(mountType string) bool {
+	switch strings.ToLower(mountType) {
+	case "", "bind", "none":
+		return true
+	}
+	return false
+}
+
+// ResolvePath makes a relative path absolute against dir. Empty and absolute
+// paths are returned unchanged.
+func ResolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// ResolvePathList applies ResolvePath to each path
+func ResolvePathList(dir string, paths []string) []string {
+	if len(paths) == 0 {
+		return paths
+	}
+	resolved := make([]string, len(paths))
+	for i, path := range paths {
+		resolved[i] = ResolvePath(dir, path)
+	}
+	return resolved
+}
@@ -0,0 +1,91 @@
+package common_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+)
+
+func TestResolvePaths(t *testing.T) {
+	dir := t.TempDir()
+	project := filepath.Join(dir, "project")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+	shared := filepath.Join(dir, "shared.env")
+	for path, content := range map[string]string{
+		filepath.Join(project, "web.env"): "MODE=prod\n",
+		shared:                            "REGION=eu\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(project, "lxc-compose.yml")
+	content := `version: "1.0"
+services:
+  web:
+    image: ubuntu:20.04
+    env_file: [web.env, ` + shared + `]
+    include_configs: [lxc/common.conf, /usr/share/lxc/config/common.conf]
+    storage:
+      mounts:
+        - {source: ../static, target: /srv, type: bind}
+        - {source: /var/log/web, target: /logs}
+        - {source: proc, target: /host/proc, type: proc}
+    network:
+      vpn:
+        remote: vpn.example.com
+        port: 1194
+        protocol: udp
+        config: vpn/client.ovpn
+  worker:
+    extends: {service: web}
+  db:
+    image: postgres:16
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := common.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	web := cfg.Services["web"]
+	wantMounts := []string{filepath.Join(dir, "static"), "/var/log/web", "proc"}
+	for i, want := range wantMounts {
+		if got := web.Storage.Mounts[i].Source; got != want {
+			t.Errorf("mount %d source = %s, want %s", i, got, want)
+		}
+	}
+	wantEnvFiles := []string{filepath.Join(project, "web.env"), shared}
+	if !reflect.DeepEqual(web.EnvFile, wantEnvFiles) {
+		t.Errorf("env_file = %v, want %v", web.EnvFile, wantEnvFiles)
+	}
+	wantIncludes := []string{filepath.Join(project, "lxc", "common.conf"), "/usr/share/lxc/config/common.conf"}
+	if !reflect.DeepEqual(web.IncludeConfigs, wantIncludes) {
+		t.Errorf("include_configs = %v, want %v", web.IncludeConfigs, wantIncludes)
+	}
+	if got, want := web.Network.VPN.Config, filepath.Join(project, "vpn", "client.ovpn"); got != want {
+		t.Errorf("vpn config = %s, want %s", got, want)
+	}
+
+	// Paths inherited through extends are resolved once
+	worker := cfg.Services["worker"]
+	if got, want := worker.Storage.Mounts[0].Source, filepath.Join(dir, "static"); got != want {
+		t.Errorf("worker mount source = %s, want %s", got, want)
+	}
+	if got, want := worker.Network.VPN.Config, filepath.Join(project, "vpn", "client.ovpn"); got != want {
+		t.Errorf("worker vpn config = %s, want %s", got, want)
+	}
+
+	if cfg.Services["db"].Storage != nil {
+		t.Errorf("db storage = %+v, want none", cfg.Services["db"].Storage)
+	}
+}
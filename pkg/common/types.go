@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -26,17 +27,23 @@ type VPNConfig struct {
 // NetworkConfig represents network configuration for a container
 type NetworkConfig struct {
 	Type         string             `yaml:"type" json:"type"`
+	Network      string             `yaml:"network,omitempty" json:"network,omitempty"` // Name of a top-level network to attach to
 	Bridge       string             `yaml:"bridge,omitempty" json:"bridge,omitempty"`
 	Interface    string             `yaml:"interface,omitempty" json:"interface,omitempty"`
 	IP           string             `yaml:"ip,omitempty" json:"ip,omitempty"`
 	Gateway      string             `yaml:"gateway,omitempty" json:"gateway,omitempty"`
 	DNS          []string           `yaml:"dns,omitempty" json:"dns,omitempty"`
+	DNSOptions   []string           `yaml:"dns_options,omitempty" json:"dns_options,omitempty"` // resolv.conf options, e.g. ndots:2
 	DHCP         bool               `yaml:"dhcp,omitempty" json:"dhcp,omitempty"`
 	Hostname     string             `yaml:"hostname,omitempty" json:"hostname,omitempty"`
 	MTU          int                `yaml:"mtu,omitempty" json:"mtu,omitempty"`
 	MAC          string             `yaml:"mac,omitempty" json:"mac,omitempty"`
 	Interfaces   []NetworkInterface `yaml:"interfaces,omitempty" json:"interfaces,omitempty"`
 	PortForwards []PortForward      `yaml:"port_forwards,omitempty" json:"port_forwards,omitempty"`
+	VPN          *VPNConfig         `yaml:"vpn,omitempty" json:"vpn,omitempty"`
+
+	// DisableAutoMAC leaves MAC assignment to LXC instead of deriving one from the container name
+	DisableAutoMAC bool `yaml:"disable_auto_mac,omitempty" json:"disable_auto_mac,omitempty"`
 }
 
 // BandwidthLimit defines bandwidth rate limiting configuration
@@ -50,6 +57,7 @@ type BandwidthLimit struct {
 // NetworkInterface represents a network interface configuration
 type NetworkInterface struct {
 	Type      string          `yaml:"type" json:"type"`
+	Network   string          `yaml:"network,omitempty" json:"network,omitempty"` // Name of a top-level network to attach to
 	Bridge    string          `yaml:"bridge,omitempty" json:"bridge,omitempty"`
 	Interface string          `yaml:"interface,omitempty" json:"interface,omitempty"`
 	IP        string          `yaml:"ip,omitempty" json:"ip,omitempty"`
@@ -62,6 +70,48 @@ type NetworkInterface struct {
 	Bandwidth *BandwidthLimit `yaml:"bandwidth,omitempty" json:"bandwidth,omitempty"`
 }
 
+// LoggingConfig represents console log rotation settings
+type LoggingConfig struct {
+	MaxSize  string `yaml:"max_size,omitempty" json:"max_size,omitempty"`
+	MaxFiles int    `yaml:"max_files,omitempty" json:"max_files,omitempty"`
+}
+
+// Ulimit represents a process resource limit, applied as lxc.prlimit
+type Ulimit struct {
+	Soft int64 `yaml:"soft" json:"soft"`
+	Hard int64 `yaml:"hard" json:"hard"`
+}
+
+// HealthCheck represents a probe deciding whether a container is healthy
+type HealthCheck struct {
+	Type     string   `yaml:"type,omitempty" json:"type,omitempty"`         // command (default), tcp or http
+	Command  []string `yaml:"command,omitempty" json:"command,omitempty"`   // command: run in the container, healthy on exit 0
+	Port     int      `yaml:"port,omitempty" json:"port,omitempty"`         // tcp, http: port on the container IP
+	Path     string   `yaml:"path,omitempty" json:"path,omitempty"`         // http: path to GET, healthy on 2xx
+	Interval string   `yaml:"interval,omitempty" json:"interval,omitempty"` // Time between probes, e.g. 5s
+	Timeout  string   `yaml:"timeout,omitempty" json:"timeout,omitempty"`   // Time a single probe may take
+	Retries  int      `yaml:"retries,omitempty" json:"retries,omitempty"`   // Consecutive failures before unhealthy
+
+	// OnHealthy and OnUnhealthy are run once each time the container becomes
+	// healthy or unhealthy
+	OnHealthy   *HealthHook `yaml:"on_healthy,omitempty" json:"on_healthy,omitempty"`
+	OnUnhealthy *HealthHook `yaml:"on_unhealthy,omitempty" json:"on_unhealthy,omitempty"`
+}
+
+// HealthHook is a notification of a health status change: a command run on
+// the host or a webhook URL, exactly one of which must be set
+type HealthHook struct {
+	Command []string `yaml:"command,omitempty" json:"command,omitempty"` // Run with LXC_COMPOSE_CONTAINER and LXC_COMPOSE_HEALTH set
+	URL     string   `yaml:"url,omitempty" json:"url,omitempty"`         // POSTed a JSON body with the container and health
+}
+
+// Health check probe types supported by HealthCheck.Type
+const (
+	HealthCheckCommand = "command"
+	HealthCheckTCP     = "tcp"
+	HealthCheckHTTP    = "http"
+)
+
 // PortForward represents a port forwarding configuration
 type PortForward struct {
 	Protocol string `yaml:"protocol" json:"protocol"`
@@ -71,16 +121,21 @@ type PortForward struct {
 
 // CPUConfig represents CPU resource limits
 type CPUConfig struct {
-	Shares *int64 `yaml:"shares,omitempty" json:"shares,omitempty"`
-	Quota  *int64 `yaml:"quota,omitempty" json:"quota,omitempty"`
-	Period *int64 `yaml:"period,omitempty" json:"period,omitempty"`
-	Cores  *int   `yaml:"cores,omitempty" json:"cores,omitempty"`
+	Shares      *int64 `yaml:"shares,omitempty" json:"shares,omitempty"`
+	Quota       *int64 `yaml:"quota,omitempty" json:"quota,omitempty"`
+	Period      *int64 `yaml:"period,omitempty" json:"period,omitempty"`
+	Cores       *int   `yaml:"cores,omitempty" json:"cores,omitempty"`
+	CPUSet      string `yaml:"cpuset,omitempty" json:"cpuset,omitempty"`             // CPUs to pin to, e.g. "0-3,6"
+	MemoryNodes string `yaml:"memory_nodes,omitempty" json:"memory_nodes,omitempty"` // NUMA nodes to allocate from, same syntax
 }
 
 // MemoryConfig represents memory resource limits
 type MemoryConfig struct {
-	Limit string `yaml:"limit,omitempty" json:"limit,omitempty"`
-	Swap  string `yaml:"swap,omitempty" json:"swap,omitempty"`
+	Limit          string `yaml:"limit,omitempty" json:"limit,omitempty"`
+	Swap           string `yaml:"swap,omitempty" json:"swap,omitempty"`
+	Reserve        string `yaml:"reserve,omitempty" json:"reserve,omitempty"`                   // Soft limit reclaimed under memory pressure
+	Swappiness     *int   `yaml:"swappiness,omitempty" json:"swappiness,omitempty"`             // 0-100, nil keeps the host default
+	OOMKillDisable bool   `yaml:"oom_kill_disable,omitempty" json:"oom_kill_disable,omitempty"` // Pause instead of OOM-killing tasks
 }
 
 // StorageConfig represents storage configuration
@@ -90,6 +145,15 @@ type StorageConfig struct {
 	Pool      string  `yaml:"pool,omitempty" json:"pool,omitempty"`
 	AutoMount bool    `yaml:"auto_mount,omitempty" json:"auto_mount,omitempty"`
 	Mounts    []Mount `yaml:"mounts,omitempty" json:"mounts,omitempty"`
+	// TmpfsMounts are in-memory filesystems discarded when the container stops
+	TmpfsMounts []TmpfsMount `yaml:"tmpfs,omitempty" json:"tmpfs,omitempty"`
+}
+
+// TmpfsMount represents a tmpfs mount inside the container
+type TmpfsMount struct {
+	Target string `yaml:"target" json:"target"`
+	Size   string `yaml:"size,omitempty" json:"size,omitempty"` // e.g. 64M, defaults to half of RAM
+	Mode   string `yaml:"mode,omitempty" json:"mode,omitempty"` // Octal permissions, e.g. 1777
 }
 
 // Mount represents a mount point configuration
@@ -132,17 +196,124 @@ type Container struct {
 	Env         map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
 	Command     []string          `yaml:"command,omitempty" json:"command,omitempty"`
 	Entrypoint  []string          `yaml:"entrypoint,omitempty" json:"entrypoint,omitempty"`
+	Init        string            `yaml:"init,omitempty" json:"init,omitempty"`       // none (default), systemd or sysvinit
+	AutoDev     *bool             `yaml:"autodev,omitempty" json:"autodev,omitempty"` // Populate a minimal /dev, on by default with systemd
+	TTYs        *int              `yaml:"ttys,omitempty" json:"ttys,omitempty"`       // Number of ttys, lxc.tty.max
+	Restart     string            `yaml:"restart,omitempty" json:"restart,omitempty"` // no (default), always or unless-stopped
 	Environment map[string]string `yaml:"environment,omitempty" json:"environment,omitempty"`
 	Devices     []DeviceConfig    `yaml:"devices,omitempty" json:"devices,omitempty"`
+	DependsOn   []string          `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	Logging     *LoggingConfig    `yaml:"logging,omitempty" json:"logging,omitempty"`
+	HealthCheck *HealthCheck      `yaml:"healthcheck,omitempty" json:"healthcheck,omitempty"`
+	Ulimits     map[string]Ulimit `yaml:"ulimits,omitempty" json:"ulimits,omitempty"` // Keyed by limit name, e.g. nofile
+	Sysctls     map[string]string `yaml:"sysctls,omitempty" json:"sysctls,omitempty"` // Kernel parameters, e.g. net.core.somaxconn
+	StopSignal  string            `yaml:"stop_signal,omitempty" json:"stop_signal,omitempty"`
+	Timezone    string            `yaml:"timezone,omitempty" json:"timezone,omitempty"` // IANA name, e.g. Europe/Berlin
+	AutoStart   bool              `yaml:"autostart,omitempty" json:"autostart,omitempty"`
+	StartOrder  int               `yaml:"start_order,omitempty" json:"start_order,omitempty"` // Boot order, lower starts first
+	StartDelay  int               `yaml:"start_delay,omitempty" json:"start_delay,omitempty"` // Seconds to wait after starting
+	Labels      map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+
+	// IncludeConfigs are LXC config files included before the generated keys
+	IncludeConfigs []string `yaml:"include_configs,omitempty" json:"include_configs,omitempty"`
+	// Deploy is docker-compose's deploy block, only read in compatibility mode
+	Deploy *DeployConfig `yaml:"deploy,omitempty" json:"deploy,omitempty"`
+
+	// Privileged is docker-compose's top-level privileged flag, see ResolvedSecurity
+	Privileged *bool `yaml:"privileged,omitempty" json:"privileged,omitempty"`
+	// StopGracePeriod is how long stopping waits before killing, e.g. 30s
+	StopGracePeriod string `yaml:"stop_grace_period,omitempty" json:"stop_grace_period,omitempty"`
+	// NetworkMode container:<name> joins another container's network namespace
+	NetworkMode string `yaml:"network_mode,omitempty" json:"network_mode,omitempty"`
+	// PidMode and IpcMode are host or container:<name> to share that
+	// namespace instead of creating one
+	PidMode string `yaml:"pid,omitempty" json:"pid,omitempty"`
+	IpcMode string `yaml:"ipc,omitempty" json:"ipc,omitempty"`
+	// EnvFile lists env files merged into Environment when the compose file is
+	// loaded, relative to the compose file's directory
+	EnvFile []string `yaml:"env_file,omitempty" json:"env_file,omitempty"`
+	// Extensions holds the service's x- keys for custom tooling, see
+	// ComposeConfig.Extensions
+	Extensions map[string]interface{} `yaml:",inline" json:"extensions,omitempty"`
+}
+
+// ResolvedSecurity returns the security settings with the top-level
+// Privileged shortcut applied: true sets security.privileged and
+// isolation: privileged, false clears security.privileged. A shortcut that
+// contradicts the nested security block is rejected by validation, so the
+// shortcut only fills in what the block leaves unset.
+func (c *Container) ResolvedSecurity() *SecurityConfig {
+	if c.Privileged == nil {
+		return c.Security
+	}
+
+	sec := SecurityConfig{}
+	if c.Security != nil {
+		sec = *c.Security
+	}
+	sec.Privileged = *c.Privileged
+	if sec.Privileged {
+		sec.Isolation = "privileged"
+	}
+	return &sec
+}
+
+// DeployConfig is the part of docker-compose's deploy block that has an LXC
+// equivalent, see config.ApplyCompatibility
+type DeployConfig struct {
+	Resources *DeployResources `yaml:"resources,omitempty" json:"resources,omitempty"`
+}
+
+// DeployResources holds docker-compose resource limits and reservations
+type DeployResources struct {
+	Limits       *ResourceSpec `yaml:"limits,omitempty" json:"limits,omitempty"`
+	Reservations *ResourceSpec `yaml:"reservations,omitempty" json:"reservations,omitempty"`
+}
+
+// ResourceSpec is an amount of CPU and memory in docker-compose notation
+type ResourceSpec struct {
+	CPUs   string `yaml:"cpus,omitempty" json:"cpus,omitempty"`     // Number of CPUs, e.g. "1.5"
+	Memory string `yaml:"memory,omitempty" json:"memory,omitempty"` // e.g. 512M
+}
+
+// Init systems supported by Container.Init
+const (
+	InitNone     = "none"     // Run entrypoint/command as PID 1
+	InitSystemd  = "systemd"  // Boot /sbin/init as systemd
+	InitSysVInit = "sysvinit" // Boot /sbin/init as SysV init
+)
+
+// Restart policies supported by Container.Restart
+const (
+	RestartNo            = "no"             // Never restart automatically
+	RestartAlways        = "always"         // Restart whenever the container stops
+	RestartUnlessStopped = "unless-stopped" // Restart unless it was stopped by the user
+)
+
+// NetworkDefinition represents a host bridge shared by services
+type NetworkDefinition struct {
+	Bridge  string `yaml:"bridge,omitempty" json:"bridge,omitempty"`   // Defaults to the network name
+	Subnet  string `yaml:"subnet,omitempty" json:"subnet,omitempty"`   // CIDR, e.g. 10.0.3.0/24
+	Gateway string `yaml:"gateway,omitempty" json:"gateway,omitempty"` // Address of the bridge within Subnet
 }
 
 // ComposeConfig represents a docker-compose like configuration
 type ComposeConfig struct {
-	Services map[string]Container `yaml:"services" json:"services"`
+	Services map[string]Container         `yaml:"services" json:"services"`
+	Networks map[string]NetworkDefinition `yaml:"networks,omitempty" json:"networks,omitempty"`
+	// TopLevelExtensions holds the file's x- keys, see Extensions
+	TopLevelExtensions map[string]interface{} `yaml:",inline" json:"-"`
 }
 
-// Load loads the configuration from a file
+// Load loads the configuration from a file, resolving relative paths against
+// the file's directory
 func Load(configFile string) (*ComposeConfig, error) {
+	return LoadFromDir(configFile, filepath.Dir(configFile))
+}
+
+// LoadFromDir loads the configuration from a file like Load, resolving
+// relative paths against projectDir instead
+func LoadFromDir(configFile, projectDir string) (*ComposeConfig, error) {
 	data, err := os.ReadFile(configFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
@@ -153,6 +324,23 @@ func Load(configFile string) (*ComposeConfig, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	// Merge in the base services of services using extends
+	if err := config.resolveExtends(configFile); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// Resolve relative paths, merging env files into each service's
+	// environment, and keep only the x- keys of the unknown keys the file was
+	// decoded with
+	config.TopLevelExtensions = extensions(config.TopLevelExtensions)
+	for name, svc := range config.Services {
+		if err := svc.ResolvePaths(projectDir); err != nil {
+			return nil, fmt.Errorf("service '%s': %w", name, err)
+		}
+		svc.Extensions = extensions(svc.Extensions)
+		config.Services[name] = svc
+	}
+
 	return &config, nil
 }
 
@@ -168,6 +356,12 @@ func ValidateNetworkConfig(cfg *NetworkConfig) error {
 			return fmt.Errorf("invalid network type: %s", cfg.Type)
 		}
 	}
+	if cfg.DHCP && cfg.IP != "" {
+		return fmt.Errorf("cannot specify static IP when DHCP is enabled")
+	}
+	if cfg.DHCP && cfg.Gateway != "" {
+		return fmt.Errorf("cannot specify gateway when DHCP is enabled")
+	}
 
 	// Validate interfaces
 	for i, iface := range cfg.Interfaces {
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
+)
+
+const (
+	// cfsPeriod is the CFS period, in microseconds, that CPU limits are
+	// expressed against
+	cfsPeriod = 100000
+	// sharesPerCPU is the cpu.shares weight of one reserved CPU
+	sharesPerCPU = 1024
+)
+
+// ApplyCompatibility returns services with docker-compose's
+// deploy.resources translated into CPU and memory settings:
+//
+//   - limits.cpus becomes a CFS quota of cpus * 100000 over a period of 100000
+//   - reservations.cpus becomes cpu.shares of cpus * 1024
+//   - limits.memory becomes the memory limit
+//   - reservations.memory becomes the memory soft limit
+//
+// Settings a service already makes in its cpu and memory sections win over
+// the translated ones.
+func ApplyCompatibility(services map[string]common.Container) (map[string]common.Container, error) {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs validation.ValidationErrors
+	translated := make(map[string]common.Container, len(services))
+	for _, name := range names {
+		svc := services[name]
+		if svc.Deploy != nil && svc.Deploy.Resources != nil {
+			err := translateResources(&svc, svc.Deploy.Resources)
+			errs.Add("services."+name+".deploy.resources", err)
+		}
+		translated[name] = svc
+	}
+	if err := errs.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	return translated, nil
+}
+
+// translateResources fills in svc's CPU and memory settings from resources,
+// copying the sections it changes
+func translateResources(svc *common.Container, resources *common.DeployResources) error {
+	var errs validation.ValidationErrors
+	cpu := common.CPUConfig{}
+	if svc.CPU != nil {
+		cpu = *svc.CPU
+	}
+	memory := common.MemoryConfig{}
+	if svc.Memory != nil {
+		memory = *svc.Memory
+	}
+
+	if limits := resources.Limits; limits != nil {
+		if limits.CPUs != "" {
+			cpus, err := validation.ValidateCPUs(limits.CPUs)
+			errs.Add("limits.cpus", err)
+			if err == nil && cpu.Quota == nil && cpu.Period == nil {
+				quota, period := int64(math.Round(cpus*cfsPeriod)), int64(cfsPeriod)
+				cpu.Quota, cpu.Period = &quota, &period
+			}
+		}
+		if limits.Memory != "" {
+			value, err := memoryValue(limits.Memory)
+			errs.Add("limits.memory", err)
+			if err == nil && memory.Limit == "" {
+				memory.Limit = value
+			}
+		}
+	}
+
+	if reservations := resources.Reservations; reservations != nil {
+		if reservations.CPUs != "" {
+			cpus, err := validation.ValidateCPUs(reservations.CPUs)
+			errs.Add("reservations.cpus", err)
+			if err == nil && cpu.Shares == nil {
+				// cpu.shares has a minimum of 2
+				shares := int64(math.Max(2, math.Round(cpus*sharesPerCPU)))
+				cpu.Shares = &shares
+			}
+		}
+		if reservations.Memory != "" {
+			value, err := memoryValue(reservations.Memory)
+			errs.Add("reservations.memory", err)
+			if err == nil && memory.Reserve == "" {
+				memory.Reserve = value
+			}
+		}
+	}
+
+	if err := errs.ErrorOrNil(); err != nil {
+		return err
+	}
+	if cpu != (common.CPUConfig{}) {
+		svc.CPU = &cpu
+	}
+	if memory != (common.MemoryConfig{}) {
+		svc.Memory = &memory
+	}
+	return nil
+}
+
+// memoryValue converts a docker-compose memory amount such as "512m" into the
+// notation the memory cgroup accepts, falling back to bytes for fractions
+func memoryValue(memory string) (string, error) {
+	bytes, err := validation.ValidateStorageSize(memory)
+	if err != nil {
+		return "", err
+	}
+	if formatted := validation.FormatBytes(bytes); !strings.Contains(formatted, ".") {
+		return formatted, nil
+	}
+	return strconv.FormatInt(bytes, 10), nil
+}
@@ -0,0 +1,85 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestApplyCompatibility(t *testing.T) {
+	t.Run("translates limits and reservations", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "lxc-compose.yml")
+		testing_internal.AssertNoError(t, os.WriteFile(path, []byte(`services:
+  web:
+    image: nginx
+    deploy:
+      resources:
+        limits:
+          cpus: 1.5
+          memory: 512m
+        reservations:
+          cpus: "0.5"
+          memory: 1.5G
+`), 0644))
+		cfg, err := common.Load(path)
+		testing_internal.AssertNoError(t, err)
+
+		services, err := config.ApplyCompatibility(cfg.Services)
+		testing_internal.AssertNoError(t, err)
+
+		web := services["web"]
+		testing_internal.AssertEqual(t, int64(150000), *web.CPU.Quota)
+		testing_internal.AssertEqual(t, int64(100000), *web.CPU.Period)
+		testing_internal.AssertEqual(t, int64(512), *web.CPU.Shares)
+		testing_internal.AssertEqual(t, "512M", web.Memory.Limit)
+		testing_internal.AssertEqual(t, "1610612736", web.Memory.Reserve)
+	})
+
+	t.Run("explicit settings win", func(t *testing.T) {
+		quota, shares := int64(50000), int64(2048)
+		cpu := &common.CPUConfig{Quota: &quota, Shares: &shares}
+		services, err := config.ApplyCompatibility(map[string]common.Container{
+			"web": {
+				CPU:    cpu,
+				Memory: &common.MemoryConfig{Limit: "1G"},
+				Deploy: &common.DeployConfig{Resources: &common.DeployResources{
+					Limits:       &common.ResourceSpec{CPUs: "2", Memory: "256M"},
+					Reservations: &common.ResourceSpec{CPUs: "1", Memory: "128M"},
+				}},
+			},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		web := services["web"]
+		testing_internal.AssertEqual(t, int64(50000), *web.CPU.Quota)
+		testing_internal.AssertEqual(t, int64(2048), *web.CPU.Shares)
+		testing_internal.AssertEqual(t, "1G", web.Memory.Limit)
+		testing_internal.AssertEqual(t, "128M", web.Memory.Reserve)
+		// The service's own sections are copied, not modified
+		testing_internal.AssertEqual(t, (*int64)(nil), cpu.Period)
+	})
+
+	t.Run("without deploy", func(t *testing.T) {
+		services, err := config.ApplyCompatibility(map[string]common.Container{"web": {Image: "nginx"}})
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, (*common.CPUConfig)(nil), services["web"].CPU)
+		testing_internal.AssertEqual(t, (*common.MemoryConfig)(nil), services["web"].Memory)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		_, err := config.ApplyCompatibility(map[string]common.Container{
+			"web": {Deploy: &common.DeployConfig{Resources: &common.DeployResources{
+				Limits:       &common.ResourceSpec{CPUs: "0", Memory: "lots"},
+				Reservations: &common.ResourceSpec{CPUs: "half"},
+			}}},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "services.web.deploy.resources.limits.cpus")
+		testing_internal.AssertContains(t, err.Error(), "services.web.deploy.resources.limits.memory")
+		testing_internal.AssertContains(t, err.Error(), "services.web.deploy.resources.reservations.cpus")
+	})
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
 	"strconv"
 	"strings"
 )
@@ -31,6 +32,11 @@ func ValidateContainer(container *common.Container) error {
 		if err := validateSecurityConfig(container.Security); err != nil {
 			return err
 		}
+		if container.Privileged != nil {
+			if err := validation.ValidatePrivilegedShortcut(*container.Privileged, container.Security.Isolation, container.Security.Privileged); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -120,7 +126,7 @@ func validateSecurityConfig(config *common.SecurityConfig) error {
 		}
 	}
 
-	return nil
+	return validation.ValidateCapabilityConflicts(config.Isolation, config.Privileged, config.Capabilities)
 }
 
 // Convert legacy CPU/Memory configs to ResourceConfig
@@ -135,23 +141,52 @@ func (c *Container) ToCommonContainer() *common.Container {
 	c.migrateToResourceConfig()
 
 	return &common.Container{
-		Image:      c.Image,
-		Storage:    c.Storage.ToCommonStorageConfig(),
-		Network:    c.Network.ToCommonNetworkConfig(),
-		Security:   c.Security.ToCommonSecurityConfig(),
-		Command:    c.Command,
-		Entrypoint: c.Entrypoint,
-		Devices:    ToCommonDeviceConfigs(c.Devices),
+		Image:       c.Image,
+		Storage:     c.Storage.ToCommonStorageConfig(),
+		Network:     c.Network.ToCommonNetworkConfig(),
+		Security:    c.Security.ToCommonSecurityConfig(),
+		Command:     c.Command,
+		Entrypoint:  c.Entrypoint,
+		Init:        c.Init,
+		AutoDev:     c.AutoDev,
+		TTYs:        c.TTYs,
+		Restart:     c.Restart,
+		Devices:     ToCommonDeviceConfigs(c.Devices),
+		Ports:       ToCommonPortForwards(c.Ports),
+		DependsOn:   c.DependsOn,
+		Logging:     c.Logging.ToCommonLoggingConfig(),
+		HealthCheck: c.HealthCheck.ToCommonHealthCheck(),
+		Ulimits:     ToCommonUlimits(c.Ulimits),
+		Sysctls:     c.Sysctls,
+		StopSignal:  c.StopSignal,
+		Timezone:    c.Timezone,
+		AutoStart:   c.AutoStart,
+		StartOrder:  c.StartOrder,
+		StartDelay:  c.StartDelay,
+		Labels:      c.Labels,
 		CPU: &common.CPUConfig{
-			Cores:  &c.Resources.Cores,
-			Shares: &c.Resources.CPUShares,
-			Quota:  &c.Resources.CPUQuota,
-			Period: &c.Resources.CPUPeriod,
+			Cores:       &c.Resources.Cores,
+			Shares:      &c.Resources.CPUShares,
+			Quota:       &c.Resources.CPUQuota,
+			Period:      &c.Resources.CPUPeriod,
+			CPUSet:      c.Resources.CPUSet,
+			MemoryNodes: c.Resources.MemoryNodes,
 		},
 		Memory: &common.MemoryConfig{
-			Limit: c.Resources.Memory,
-			Swap:  c.Resources.MemorySwap,
+			Limit:          c.Resources.Memory,
+			Swap:           c.Resources.MemorySwap,
+			Reserve:        c.Resources.MemoryReserve,
+			Swappiness:     c.Resources.MemorySwappiness,
+			OOMKillDisable: c.Resources.OOMKillDisable,
 		},
+		IncludeConfigs:  c.IncludeConfigs,
+		Privileged:      c.Privileged,
+		StopGracePeriod: c.StopGracePeriod,
+		NetworkMode:     c.NetworkMode,
+		PidMode:         c.PidMode,
+		IpcMode:         c.IpcMode,
+		EnvFile:         c.EnvFile,
+		Extensions:      c.Extensions,
 	}
 }
 
@@ -167,9 +202,34 @@ func FromCommonContainer(c *common.Container) *Container {
 		Security:    FromCommonSecurityConfig(c.Security),
 		Resources:   FromCommonResources(c.CPU, c.Memory),
 		Devices:     FromCommonDeviceConfigs(c.Devices),
+		Ports:       FromCommonPortForwards(c.Ports),
+		DependsOn:   c.DependsOn,
+		Logging:     FromCommonLoggingConfig(c.Logging),
+		HealthCheck: FromCommonHealthCheck(c.HealthCheck),
+		Ulimits:     FromCommonUlimits(c.Ulimits),
+		Sysctls:     c.Sysctls,
 		Command:     c.Command,
 		Entrypoint:  c.Entrypoint,
+		Init:        c.Init,
+		AutoDev:     c.AutoDev,
+		TTYs:        c.TTYs,
+		Restart:     c.Restart,
 		Environment: c.Environment,
+		StopSignal:  c.StopSignal,
+		Timezone:    c.Timezone,
+		AutoStart:   c.AutoStart,
+		StartOrder:  c.StartOrder,
+		StartDelay:  c.StartDelay,
+		Labels:      c.Labels,
+
+		IncludeConfigs:  c.IncludeConfigs,
+		Privileged:      c.Privileged,
+		StopGracePeriod: c.StopGracePeriod,
+		NetworkMode:     c.NetworkMode,
+		PidMode:         c.PidMode,
+		IpcMode:         c.IpcMode,
+		EnvFile:         c.EnvFile,
+		Extensions:      c.Extensions,
 	}
 }
 
@@ -185,12 +245,16 @@ func (c *NetworkConfig) ToCommonNetworkConfig() *common.NetworkConfig {
 		IP:           c.IP,
 		Gateway:      c.Gateway,
 		DNS:          c.DNS,
+		DNSOptions:   c.DNSOptions,
 		DHCP:         c.DHCP,
 		Hostname:     c.Hostname,
 		MTU:          c.MTU,
 		MAC:          c.MAC,
 		Interfaces:   make([]common.NetworkInterface, len(c.Interfaces)),
 		PortForwards: make([]common.PortForward, len(c.PortForwards)),
+		VPN:          (*common.VPNConfig)(c.VPN),
+
+		DisableAutoMAC: c.DisableAutoMAC,
 	}
 
 	for i, iface := range c.Interfaces {
@@ -231,12 +295,16 @@ func FromCommonNetworkConfig(c *common.NetworkConfig) *NetworkConfig {
 		IP:           c.IP,
 		Gateway:      c.Gateway,
 		DNS:          c.DNS,
+		DNSOptions:   c.DNSOptions,
 		DHCP:         c.DHCP,
 		Hostname:     c.Hostname,
 		MTU:          c.MTU,
 		MAC:          c.MAC,
 		Interfaces:   make([]NetworkInterface, len(c.Interfaces)),
 		PortForwards: make([]PortForward, len(c.PortForwards)),
+		VPN:          (*VPNConfig)(c.VPN),
+
+		DisableAutoMAC: c.DisableAutoMAC,
 	}
 
 	for i, iface := range c.Interfaces {
@@ -271,10 +339,11 @@ func (c *StorageConfig) ToCommonStorageConfig() *common.StorageConfig {
 		return nil
 	}
 	return &common.StorageConfig{
-		Root:      c.Root,
-		Backend:   c.Backend,
-		Pool:      c.Pool,
-		AutoMount: c.AutoMount,
+		Root:        c.Root,
+		Backend:     c.Backend,
+		Pool:        c.Pool,
+		AutoMount:   c.AutoMount,
+		TmpfsMounts: ToCommonTmpfsMounts(c.TmpfsMounts),
 	}
 }
 
@@ -284,11 +353,36 @@ func FromCommonStorageConfig(c *common.StorageConfig) *StorageConfig {
 		return nil
 	}
 	return &StorageConfig{
-		Root:      c.Root,
-		Backend:   c.Backend,
-		Pool:      c.Pool,
-		AutoMount: c.AutoMount,
+		Root:        c.Root,
+		Backend:     c.Backend,
+		Pool:        c.Pool,
+		AutoMount:   c.AutoMount,
+		TmpfsMounts: FromCommonTmpfsMounts(c.TmpfsMounts),
+	}
+}
+
+// ToCommonTmpfsMounts converts []config.TmpfsMount to []common.TmpfsMount
+func ToCommonTmpfsMounts(mounts []TmpfsMount) []common.TmpfsMount {
+	if mounts == nil {
+		return nil
+	}
+	result := make([]common.TmpfsMount, len(mounts))
+	for i, m := range mounts {
+		result[i] = common.TmpfsMount{Target: m.Target, Size: m.Size, Mode: m.Mode}
+	}
+	return result
+}
+
+// FromCommonTmpfsMounts converts []common.TmpfsMount to []config.TmpfsMount
+func FromCommonTmpfsMounts(mounts []common.TmpfsMount) []TmpfsMount {
+	if mounts == nil {
+		return nil
+	}
+	result := make([]TmpfsMount, len(mounts))
+	for i, m := range mounts {
+		result[i] = TmpfsMount{Target: m.Target, Size: m.Size, Mode: m.Mode}
 	}
+	return result
 }
 
 // ToCommonCPUConfig converts config.CPUConfig to common.CPUConfig
@@ -297,10 +391,12 @@ func (c *CPUConfig) ToCommonCPUConfig() *common.CPUConfig {
 		return nil
 	}
 	return &common.CPUConfig{
-		Cores:  c.Cores,
-		Shares: c.Shares,
-		Quota:  c.Quota,
-		Period: c.Period,
+		Cores:       c.Cores,
+		Shares:      c.Shares,
+		Quota:       c.Quota,
+		Period:      c.Period,
+		CPUSet:      c.CPUSet,
+		MemoryNodes: c.MemoryNodes,
 	}
 }
 
@@ -310,7 +406,10 @@ func FromCommonResources(c *common.CPUConfig, m *common.MemoryConfig) *ResourceC
 		return nil
 	}
 	var shares, quota, period int64
-	var memory, memorySwap string
+	var cpuSet, memoryNodes string
+	var memory, memorySwap, memoryReserve string
+	var swappiness *int
+	var oomKillDisable bool
 	if c != nil {
 		if c.Shares != nil {
 			shares = *c.Shares
@@ -321,17 +420,27 @@ func FromCommonResources(c *common.CPUConfig, m *common.MemoryConfig) *ResourceC
 		if c.Period != nil {
 			period = *c.Period
 		}
+		cpuSet = c.CPUSet
+		memoryNodes = c.MemoryNodes
 	}
 	if m != nil {
 		memory = m.Limit
 		memorySwap = m.Swap
+		memoryReserve = m.Reserve
+		swappiness = m.Swappiness
+		oomKillDisable = m.OOMKillDisable
 	}
 	return &ResourceConfig{
-		CPUShares:  shares,
-		CPUQuota:   quota,
-		CPUPeriod:  period,
-		Memory:     memory,
-		MemorySwap: memorySwap,
+		CPUShares:        shares,
+		CPUQuota:         quota,
+		CPUPeriod:        period,
+		CPUSet:           cpuSet,
+		MemoryNodes:      memoryNodes,
+		Memory:           memory,
+		MemorySwap:       memorySwap,
+		MemoryReserve:    memoryReserve,
+		MemorySwappiness: swappiness,
+		OOMKillDisable:   oomKillDisable,
 	}
 }
 
@@ -341,10 +450,12 @@ func FromCommonCPUConfig(c *common.CPUConfig) *CPUConfig {
 		return nil
 	}
 	return &CPUConfig{
-		Cores:  c.Cores,
-		Shares: c.Shares,
-		Quota:  c.Quota,
-		Period: c.Period,
+		Cores:       c.Cores,
+		Shares:      c.Shares,
+		Quota:       c.Quota,
+		Period:      c.Period,
+		CPUSet:      c.CPUSet,
+		MemoryNodes: c.MemoryNodes,
 	}
 }
 
@@ -354,8 +465,11 @@ func (c *MemoryConfig) ToCommonMemoryConfig() *common.MemoryConfig {
 		return nil
 	}
 	return &common.MemoryConfig{
-		Limit: c.Limit,
-		Swap:  c.Swap,
+		Limit:          c.Limit,
+		Swap:           c.Swap,
+		Reserve:        c.Reserve,
+		Swappiness:     c.Swappiness,
+		OOMKillDisable: c.OOMKillDisable,
 	}
 }
 
@@ -365,8 +479,11 @@ func FromCommonMemoryConfig(c *common.MemoryConfig) *MemoryConfig {
 		return nil
 	}
 	return &MemoryConfig{
-		Limit: c.Limit,
-		Swap:  c.Swap,
+		Limit:          c.Limit,
+		Swap:           c.Swap,
+		Reserve:        c.Reserve,
+		Swappiness:     c.Swappiness,
+		OOMKillDisable: c.OOMKillDisable,
 	}
 }
 
@@ -406,6 +523,138 @@ func FromCommonDeviceConfigs(devices []common.DeviceConfig) []DeviceConfig {
 	return configDevices
 }
 
+// ToCommonPortForwards converts []PortForward to []common.PortForward
+func ToCommonPortForwards(ports []PortForward) []common.PortForward {
+	if ports == nil {
+		return nil
+	}
+	commonPorts := make([]common.PortForward, len(ports))
+	for i, pf := range ports {
+		commonPorts[i] = common.PortForward{
+			Protocol: pf.Protocol,
+			Host:     pf.Host,
+			Guest:    pf.Guest,
+		}
+	}
+	return commonPorts
+}
+
+// FromCommonPortForwards converts []common.PortForward to []PortForward
+func FromCommonPortForwards(ports []common.PortForward) []PortForward {
+	if ports == nil {
+		return nil
+	}
+	configPorts := make([]PortForward, len(ports))
+	for i, pf := range ports {
+		configPorts[i] = PortForward{
+			Protocol: pf.Protocol,
+			Host:     pf.Host,
+			Guest:    pf.Guest,
+		}
+	}
+	return configPorts
+}
+
+// ToCommonLoggingConfig converts LoggingConfig to common.LoggingConfig
+func (c *LoggingConfig) ToCommonLoggingConfig() *common.LoggingConfig {
+	if c == nil {
+		return nil
+	}
+	return &common.LoggingConfig{
+		MaxSize:  c.MaxSize,
+		MaxFiles: c.MaxFiles,
+	}
+}
+
+// FromCommonLoggingConfig converts common.LoggingConfig to LoggingConfig
+func FromCommonLoggingConfig(c *common.LoggingConfig) *LoggingConfig {
+	if c == nil {
+		return nil
+	}
+	return &LoggingConfig{
+		MaxSize:  c.MaxSize,
+		MaxFiles: c.MaxFiles,
+	}
+}
+
+// ToCommonUlimits converts Ulimits to common.Ulimits
+func ToCommonUlimits(ulimits map[string]Ulimit) map[string]common.Ulimit {
+	if ulimits == nil {
+		return nil
+	}
+	out := make(map[string]common.Ulimit, len(ulimits))
+	for name, u := range ulimits {
+		out[name] = common.Ulimit{Soft: u.Soft, Hard: u.Hard}
+	}
+	return out
+}
+
+// FromCommonUlimits converts common.Ulimits to Ulimits
+func FromCommonUlimits(ulimits map[string]common.Ulimit) map[string]Ulimit {
+	if ulimits == nil {
+		return nil
+	}
+	out := make(map[string]Ulimit, len(ulimits))
+	for name, u := range ulimits {
+		out[name] = Ulimit{Soft: u.Soft, Hard: u.Hard}
+	}
+	return out
+}
+
+// ToCommonHealthCheck converts HealthCheck to common.HealthCheck
+func (c *HealthCheck) ToCommonHealthCheck() *common.HealthCheck {
+	if c == nil {
+		return nil
+	}
+	return &common.HealthCheck{
+		Type:     c.Type,
+		Command:  c.Command,
+		Port:     c.Port,
+		Path:     c.Path,
+		Interval: c.Interval,
+		Timeout:  c.Timeout,
+		Retries:  c.Retries,
+
+		OnHealthy:   c.OnHealthy.ToCommonHealthHook(),
+		OnUnhealthy: c.OnUnhealthy.ToCommonHealthHook(),
+	}
+}
+
+// ToCommonHealthHook converts HealthHook to common.HealthHook
+func (h *HealthHook) ToCommonHealthHook() *common.HealthHook {
+	if h == nil {
+		return nil
+	}
+	return &common.HealthHook{Command: h.Command, URL: h.URL}
+}
+
+// FromCommonHealthCheck converts common.HealthCheck to HealthCheck
+func FromCommonHealthCheck(c *common.HealthCheck) *HealthCheck {
+	if c == nil {
+		return nil
+	}
+	return &HealthCheck{
+		Type:     c.Type,
+		Command:  c.Command,
+		Port:     c.Port,
+		Path:     c.Path,
+		Interval: c.Interval,
+		Timeout:  c.Timeout,
+		Retries:  c.Retries,
+
+		OnHealthy:   FromCommonHealthHook(c.OnHealthy),
+		OnUnhealthy: FromCommonHealthHook(c.OnUnhealthy),
+	}
+}
+
+// FromCommonHealthHook converts common.HealthHook to HealthHook
+func FromCommonHealthHook(h *common.HealthHook) *HealthHook {
+	if h == nil {
+		return nil
+	}
+	return &HealthHook{Command: h.Command, URL: h.URL}
+}
+
 func (c *SecurityConfig) ToCommonSecurityConfig() *common.SecurityConfig {
 	if c == nil {
 		return nil
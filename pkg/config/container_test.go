@@ -122,6 +122,25 @@ func TestSecurityConfig(t *testing.T) {
 			wantErr:     true,
 			errContains: "cannot use privileged mode with strict isolation",
 		},
+		{
+			name: "capability dropped by strict isolation",
+			config: &common.SecurityConfig{
+				Isolation:    "strict",
+				Capabilities: []string{"SYS_ADMIN"},
+			},
+			wantErr:     true,
+			errContains: "SYS_ADMIN is dropped by strict isolation",
+		},
+		{
+			name: "privileged with capability keep-list",
+			config: &common.SecurityConfig{
+				Isolation:    "privileged",
+				Privileged:   true,
+				Capabilities: []string{"NET_ADMIN"},
+			},
+			wantErr:     true,
+			errContains: "keep-list has no effect",
+		},
 		{
 			name: "invalid capability",
 			config: &common.SecurityConfig{
@@ -183,6 +202,20 @@ func TestContainerConfig(t *testing.T) {
 	}
 }
 
+func TestResourcesRoundTrip(t *testing.T) {
+	shares, swappiness := int64(512), 10
+	cpu := &common.CPUConfig{Shares: &shares, CPUSet: "0-1"}
+	memory := &common.MemoryConfig{Limit: "1G", Swap: "2G", Reserve: "256M", Swappiness: &swappiness}
+
+	c := &config.Container{Resources: config.FromCommonResources(cpu, memory)}
+	got := c.ToCommonContainer()
+	testing_internal.AssertEqual(t, "256M", got.Memory.Reserve)
+	testing_internal.AssertEqual(t, "0-1", got.CPU.CPUSet)
+	testing_internal.AssertEqual(t, 10, *got.Memory.Swappiness)
+
+	testing_internal.AssertEqual(t, "256M", config.FromCommonMemoryConfig(memory).ToCommonMemoryConfig().Reserve)
+}
+
 func TestContainerValidation(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
+)
+
+// ServiceDependencies returns the services a service needs running first: its
+// depends_on entries and the services whose namespaces it joins with
+// network_mode, pid or ipc, when those are services of the project
+func ServiceDependencies(services map[string]common.Container, name string) []string {
+	svc := services[name]
+	deps := append([]string(nil), svc.DependsOn...)
+	seen := make(map[string]bool, len(deps))
+	for _, dep := range deps {
+		seen[dep] = true
+	}
+	for _, ns := range validation.SharedNamespaces(svc.NetworkMode, svc.PidMode, svc.IpcMode) {
+		if _, ok := services[ns.Container]; !ok || seen[ns.Container] {
+			continue
+		}
+		seen[ns.Container] = true
+		deps = append(deps, ns.Container)
+	}
+	return deps
+}
+
+// ResolveServiceOrder returns the services to operate on in dependency order,
+// dependencies first. When targets is empty all services are returned. When
+// includeDeps is false only the targets are returned, still in dependency order
+// relative to each other.
+func ResolveServiceOrder(services map[string]common.Container, targets []string, includeDeps bool) ([]string, error) {
+	// Sorted as a copy, targets is often the caller's command line arguments
+	if len(targets) == 0 {
+		for name := range services {
+			targets = append(targets, name)
+		}
+	} else {
+		targets = append([]string(nil), targets...)
+	}
+	sort.Strings(targets)
+
+	selected := make(map[string]bool, len(targets))
+	for _, name := range targets {
+		if _, ok := services[name]; !ok {
+			return nil, fmt.Errorf("service '%s' not found in config", name)
+		}
+		selected[name] = true
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make(map[string]int)
+	var order []string
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch marks[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle detected: %v", append(path, name))
+		}
+		marks[name] = visiting
+
+		deps := ServiceDependencies(services, name)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if _, ok := services[dep]; !ok {
+				return fmt.Errorf("service '%s' depends on unknown service '%s'", name, dep)
+			}
+			if !includeDeps && !selected[dep] {
+				continue
+			}
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+
+		marks[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range targets {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// ResolveStopOrder returns the services to stop, dependents before their
+// dependencies: the reverse of ResolveServiceOrder. When targets is empty all
+// services are returned, otherwise only the targets, still ordered by
+// transitive dependencies through services that aren't targeted.
+func ResolveStopOrder(services map[string]common.Container, targets []string) ([]string, error) {
+	order, err := ResolveServiceOrder(services, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool, len(targets))
+	for _, name := range targets {
+		if _, ok := services[name]; !ok {
+			return nil, fmt.Errorf("service '%s' not found in config", name)
+		}
+		selected[name] = true
+	}
+
+	stop := make([]string, 0, len(order))
+	for i := len(order) - 1; i >= 0; i-- {
+		if len(targets) == 0 || selected[order[i]] {
+			stop = append(stop, order[i])
+		}
+	}
+	return stop, nil
+}
@@ -0,0 +1,129 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestResolveServiceOrder(t *testing.T) {
+	services := map[string]common.Container{
+		"db":    {},
+		"cache": {},
+		"api":   {DependsOn: []string{"db", "cache"}},
+		"web":   {DependsOn: []string{"api"}},
+	}
+
+	tests := []struct {
+		name        string
+		services    map[string]common.Container
+		targets     []string
+		includeDeps bool
+		want        []string
+		errContains string
+	}{
+		{
+			name:        "all services",
+			services:    services,
+			includeDeps: true,
+			want:        []string{"cache", "db", "api", "web"},
+		},
+		{
+			name:        "target with transitive deps",
+			services:    services,
+			targets:     []string{"web"},
+			includeDeps: true,
+			want:        []string{"cache", "db", "api", "web"},
+		},
+		{
+			name:     "no deps",
+			services: services,
+			targets:  []string{"web"},
+			want:     []string{"web"},
+		},
+		{
+			name:     "no deps keeps order among targets",
+			services: services,
+			targets:  []string{"web", "db", "api"},
+			want:     []string{"db", "api", "web"},
+		},
+		{
+			name:        "unknown target",
+			services:    services,
+			targets:     []string{"missing"},
+			includeDeps: true,
+			errContains: "not found",
+		},
+		{
+			name: "unknown dependency",
+			services: map[string]common.Container{
+				"web": {DependsOn: []string{"missing"}},
+			},
+			includeDeps: true,
+			errContains: "unknown service 'missing'",
+		},
+		{
+			name: "network mode target first",
+			services: map[string]common.Container{
+				"app":     {NetworkMode: "container:proxy"},
+				"proxy":   {},
+				"sidecar": {NetworkMode: "container:external"},
+			},
+			includeDeps: true,
+			want:        []string{"proxy", "app", "sidecar"},
+		},
+		{
+			name: "cycle",
+			services: map[string]common.Container{
+				"a": {DependsOn: []string{"b"}},
+				"b": {DependsOn: []string{"a"}},
+			},
+			includeDeps: true,
+			errContains: "cycle",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets := strings.Join(tt.targets, ",")
+			got, err := config.ResolveServiceOrder(tt.services, tt.targets, tt.includeDeps)
+			// The caller's targets are left in their order
+			testing_internal.AssertEqual(t, targets, strings.Join(tt.targets, ","))
+			if tt.errContains != "" {
+				testing_internal.AssertError(t, err)
+				testing_internal.AssertContains(t, err.Error(), tt.errContains)
+				return
+			}
+			testing_internal.AssertNoError(t, err)
+			testing_internal.AssertEqual(t, strings.Join(tt.want, ","), strings.Join(got, ","))
+		})
+	}
+}
+
+func TestResolveStopOrder(t *testing.T) {
+	services := map[string]common.Container{
+		"app": {DependsOn: []string{"api"}},
+		"api": {DependsOn: []string{"db"}},
+		"db":  {},
+	}
+
+	start, err := config.ResolveServiceOrder(services, nil, true)
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertEqual(t, "db,api,app", strings.Join(start, ","))
+
+	stop, err := config.ResolveStopOrder(services, nil)
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertEqual(t, "app,api,db", strings.Join(stop, ","))
+
+	// Targets are ordered through services that aren't targeted
+	stop, err = config.ResolveStopOrder(services, []string{"db", "app"})
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertEqual(t, "app,db", strings.Join(stop, ","))
+
+	_, err = config.ResolveStopOrder(services, []string{"missing"})
+	testing_internal.AssertError(t, err)
+	testing_internal.AssertContains(t, err.Error(), "service 'missing' not found")
+}
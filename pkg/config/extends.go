@@ -0,0 +1,21 @@
+package config
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// decodeContainer converts a resolved raw service into a Container
+func decodeContainer(raw map[string]interface{}) (*Container, error) {
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var container Container
+	if err := yaml.Unmarshal(data, &container); err != nil {
+		return nil, err
+	}
+	container.Extends = nil
+	container.Extensions = extensions(container.Extensions)
+	return &container, nil
+}
@@ -0,0 +1,117 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadExtends(t *testing.T) {
+	t.Run("same_file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeConfigFile(t, dir, "lxc-compose.yml", `
+version: "1.0"
+services:
+  base:
+    image: ubuntu:20.04
+    security:
+      isolation: strict
+      capabilities:
+        - NET_ADMIN
+    environment:
+      LOG_LEVEL: info
+  app:
+    extends:
+      service: base
+    image: ubuntu:22.04
+    security:
+      capabilities:
+        - SYS_TIME
+    environment:
+      PORT: "8080"
+`)
+
+		cfg, err := config.Load(path)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "ubuntu:22.04", cfg.Image)
+		testing_internal.AssertEqual(t, "strict", cfg.Security.Isolation)
+		testing_internal.AssertEqual(t, 1, len(cfg.Security.Capabilities))
+		testing_internal.AssertEqual(t, "SYS_TIME", cfg.Security.Capabilities[0])
+		testing_internal.AssertEqual(t, "info", cfg.Environment["LOG_LEVEL"])
+		testing_internal.AssertEqual(t, "8080", cfg.Environment["PORT"])
+		if cfg.Extends != nil {
+			t.Error("expected extends to be cleared after resolution")
+		}
+	})
+
+	t.Run("other_file", func(t *testing.T) {
+		dir := t.TempDir()
+		writeConfigFile(t, dir, "common.yml", `
+services:
+  hardened:
+    image: debian:12
+    security:
+      isolation: strict
+`)
+		path := writeConfigFile(t, dir, "lxc-compose.yml", `
+version: "1.0"
+services:
+  app:
+    extends:
+      file: common.yml
+      service: hardened
+`)
+
+		cfg, err := config.Load(path)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "debian:12", cfg.Image)
+		testing_internal.AssertEqual(t, "strict", cfg.Security.Isolation)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeConfigFile(t, dir, "lxc-compose.yml", `
+version: "1.0"
+services:
+  app:
+    image: ubuntu:20.04
+    extends:
+      service: other
+  other:
+    extends:
+      service: app
+`)
+
+		_, err := config.Load(path)
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "cycle")
+	})
+
+	t.Run("unknown_service", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeConfigFile(t, dir, "lxc-compose.yml", `
+version: "1.0"
+services:
+  app:
+    image: ubuntu:20.04
+    extends:
+      service: missing
+`)
+
+		_, err := config.Load(path)
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "not found")
+	})
+}
@@ -0,0 +1,36 @@
+package config
+
+import "strings"
+
+// extensionPrefix marks keys reserved for custom tooling, as in docker-compose
+const extensionPrefix = "x-"
+
+// Extensions returns the x- keys of the named service, or of the top level of
+// the file when name is empty. It returns nil for services that don't exist or
+// have no extensions.
+func (c *ComposeConfig) Extensions(name string) map[string]interface{} {
+	if name == "" {
+		return extensions(c.TopLevelExtensions)
+	}
+	service, ok := c.Services[name]
+	if !ok {
+		return nil
+	}
+	return extensions(service.Extensions)
+}
+
+// extensions returns the x- keys of the unknown keys a type was decoded with,
+// others are ignored as they always have been
+func extensions(fields map[string]interface{}) map[string]interface{} {
+	var ext map[string]interface{}
+	for key, value := range fields {
+		if !strings.HasPrefix(key, extensionPrefix) {
+			continue
+		}
+		if ext == nil {
+			ext = make(map[string]interface{})
+		}
+		ext[key] = value
+	}
+	return ext
+}
@@ -0,0 +1,67 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const extensionsConfig = `
+version: "1.0"
+x-deploy:
+  region: eu-west
+services:
+  app:
+    image: ubuntu:20.04
+    x-owner: platform
+    x-alerts:
+      - pager
+    unknown: ignored
+  db:
+    image: postgres:15
+`
+
+func TestExtensions(t *testing.T) {
+	t.Run("compose", func(t *testing.T) {
+		var cfg config.ComposeConfig
+		testing_internal.AssertNoError(t, yaml.Unmarshal([]byte(extensionsConfig), &cfg))
+
+		top := cfg.Extensions("")
+		testing_internal.AssertEqual(t, 1, len(top))
+		deploy, ok := top["x-deploy"].(map[string]interface{})
+		testing_internal.AssertEqual(t, true, ok)
+		testing_internal.AssertEqual(t, "eu-west", deploy["region"])
+
+		app := cfg.Extensions("app")
+		testing_internal.AssertEqual(t, 2, len(app))
+		testing_internal.AssertEqual(t, "platform", app["x-owner"])
+		testing_internal.AssertEqual(t, "ubuntu:20.04", cfg.Services["app"].Image)
+
+		testing_internal.AssertEqual(t, 0, len(cfg.Extensions("db")))
+		testing_internal.AssertEqual(t, 0, len(cfg.Extensions("missing")))
+	})
+
+	t.Run("load", func(t *testing.T) {
+		path := writeConfigFile(t, t.TempDir(), "lxc-compose.yml", extensionsConfig)
+		cfg, err := config.Load(path)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 2, len(cfg.Extensions))
+		testing_internal.AssertEqual(t, "platform", cfg.Extensions["x-owner"])
+	})
+
+	t.Run("known_fields_still_checked", func(t *testing.T) {
+		path := writeConfigFile(t, t.TempDir(), "lxc-compose.yml", `
+services:
+  app:
+    image: ubuntu:20.04
+    x-owner: platform
+    autostart: sometimes
+`)
+		_, err := config.Load(path)
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "failed to parse config file")
+	})
+}
@@ -0,0 +1,301 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LintSeverity tells whether a lint issue breaks the configuration
+type LintSeverity string
+
+// Lint severities
+const (
+	// LintWarning marks likely mistakes that still load and run
+	LintWarning LintSeverity = "warning"
+	// LintError marks problems that make up, create or start fail
+	LintError LintSeverity = "error"
+)
+
+// LintIssue is a problem found by Lint
+type LintIssue struct {
+	Severity LintSeverity
+	Path     string // Location in the compose file, e.g. services.web.depends_on
+	Message  string
+}
+
+func (i LintIssue) String() string {
+	if i.Path == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Path, i.Message)
+}
+
+// LintFile reads a compose file and reports likely mistakes in it, see Lint.
+// It also reports anchors that are never aliased and services defined more
+// than once, which YAML parsing alone silently accepts.
+func LintFile(path string) ([]LintIssue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return []LintIssue{{Severity: LintError, Message: fmt.Sprintf("failed to parse config file: %v", err)}}, nil
+	}
+	issues := lintNodes(&root)
+	for _, issue := range issues {
+		// Duplicate services also fail decoding, with a less helpful message
+		if issue.Severity == LintError {
+			return issues, nil
+		}
+	}
+
+	var cfg common.ComposeConfig
+	if err := root.Decode(&cfg); err != nil {
+		return append(issues, LintIssue{Severity: LintError, Message: fmt.Sprintf("failed to parse config file: %v", err)}), nil
+	}
+	return append(issues, Lint(&cfg)...), nil
+}
+
+// lintNodes checks the YAML structure for unused anchors and duplicate services
+func lintNodes(root *yaml.Node) []LintIssue {
+	var issues []LintIssue
+
+	anchors := make(map[string]*yaml.Node)
+	aliased := make(map[string]bool)
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Anchor != "" {
+			anchors[n.Anchor] = n
+		}
+		if n.Kind == yaml.AliasNode && n.Alias != nil {
+			aliased[n.Alias.Anchor] = true
+		}
+		for _, child := range n.Content {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	names := make([]string, 0, len(anchors))
+	for name := range anchors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !aliased[name] {
+			issues = append(issues, LintIssue{
+				Severity: LintWarning,
+				Path:     fmt.Sprintf("line %d", anchors[name].Line),
+				Message:  fmt.Sprintf("anchor &%s is never used", name),
+			})
+		}
+	}
+
+	if services := mappingValue(root, "services"); services != nil && services.Kind == yaml.MappingNode {
+		seen := make(map[string]int)
+		for i := 0; i+1 < len(services.Content); i += 2 {
+			key := services.Content[i]
+			if line, ok := seen[key.Value]; ok {
+				issues = append(issues, LintIssue{
+					Severity: LintError,
+					Path:     "services." + key.Value,
+					Message:  fmt.Sprintf("service defined again at line %d, replacing the definition at line %d", key.Line, line),
+				})
+				continue
+			}
+			seen[key.Value] = key.Line
+		}
+	}
+
+	return issues
+}
+
+// mappingValue returns the value of key in the top-level mapping of a document
+func mappingValue(root *yaml.Node, key string) *yaml.Node {
+	n := root
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// Lint reports likely mistakes in a compose configuration: invalid service
+// settings, host ports forwarded by several services, unknown or cyclic
+// depends_on entries, network_mode containers outside the file, untagged images, privileged services with strict
+// isolation, and settings that never take effect. Issues are ordered by
+// service name.
+func Lint(cfg *common.ComposeConfig) []LintIssue {
+	var issues []LintIssue
+	add := func(severity LintSeverity, path, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{Severity: severity, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(cfg.Services) == 0 {
+		add(LintError, "services", "no services defined")
+		return issues
+	}
+
+	names := make([]string, 0, len(cfg.Services))
+	for name := range cfg.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	usedNetworks := make(map[string]bool)
+	knownDeps := true
+	ports := make(map[string]string)
+	for _, name := range names {
+		svc := cfg.Services[name]
+		path := "services." + name
+
+		// Settings create would reject, including privileged mode combined
+		// with strict isolation
+		container := FromCommonContainer(&svc)
+		if err := validateContainer(path, container); err != nil {
+			if errs, ok := err.(validation.ValidationErrors); ok {
+				for _, e := range errs {
+					add(LintError, e.Path, "%s", e.Message)
+				}
+			} else {
+				add(LintError, path, "%v", err)
+			}
+		}
+
+		if svc.Image != "" && !imageHasTag(svc.Image) {
+			add(LintWarning, path+".image", "image %s has no tag, so whatever latest points to is used", svc.Image)
+		}
+
+		for _, dep := range svc.DependsOn {
+			switch {
+			case dep == name:
+				add(LintError, path+".depends_on", "service depends on itself")
+				knownDeps = false
+			case !hasService(cfg.Services, dep):
+				add(LintError, path+".depends_on", "unknown service '%s'", dep)
+				knownDeps = false
+			}
+		}
+
+		for _, ns := range validation.SharedNamespaces(svc.NetworkMode, svc.PidMode, svc.IpcMode) {
+			field := map[string]string{"net": "network_mode", "pid": "pid", "ipc": "ipc"}[ns.Kind]
+			switch {
+			case ns.Container == name:
+				add(LintError, path+"."+field, "service shares its own %s namespace", ns.Name())
+				knownDeps = false
+			case !hasService(cfg.Services, ns.Container):
+				add(LintWarning, path+"."+field, "'%s' is not a service in this file, so the container must already exist", ns.Container)
+			}
+		}
+
+		var forwards []common.PortForward
+		if svc.Network != nil {
+			forwards = append(forwards, svc.Network.PortForwards...)
+		}
+		forwards = append(forwards, svc.Ports...)
+		for _, pf := range forwards {
+			key := fmt.Sprintf("%s/%d", pf.Protocol, pf.Host)
+			if owner, ok := ports[key]; ok && owner != name {
+				add(LintError, path+".ports", "host port %s is also forwarded by service '%s'", key, owner)
+				continue
+			}
+			ports[key] = name
+		}
+
+		for _, network := range ServiceNetworks(svc) {
+			usedNetworks[network] = true
+			if _, ok := cfg.Networks[network]; !ok {
+				add(LintError, path+".network", "unknown network '%s'", network)
+			}
+		}
+
+		issues = append(issues, lintUnreachable(path, svc, forwards)...)
+	}
+
+	if knownDeps {
+		if _, err := ResolveServiceOrder(cfg.Services, nil, true); err != nil {
+			add(LintError, "services", "%v", err)
+		}
+	}
+
+	for _, network := range sortedNetworkNames(cfg.Networks) {
+		if !usedNetworks[network] {
+			add(LintWarning, "networks."+network, "network is not used by any service")
+		}
+	}
+
+	return issues
+}
+
+// lintUnreachable reports settings of a service that never take effect or
+// only fail once the container is created
+func lintUnreachable(path string, svc common.Container, forwards []common.PortForward) []LintIssue {
+	var issues []LintIssue
+
+	// Forwards without a network are already rejected by validation
+	if len(forwards) > 0 && svc.Network != nil && !hasStaticIP(svc.Network) {
+		issues = append(issues, LintIssue{
+			Severity: LintError,
+			Path:     path + ".ports",
+			Message:  "port forwarding requires at least one interface with static IP",
+		})
+	}
+
+	if len(svc.Volumes) > 0 {
+		issues = append(issues, LintIssue{
+			Severity: LintWarning,
+			Path:     path + ".volumes",
+			Message:  "volumes are not applied, use storage.mounts instead",
+		})
+	}
+	if len(svc.Env) > 0 {
+		issues = append(issues, LintIssue{
+			Severity: LintWarning,
+			Path:     path + ".env",
+			Message:  "env is not applied, use environment instead",
+		})
+	}
+
+	return issues
+}
+
+// hasStaticIP reports whether an interface gets a static address, either
+// directly or from the top-level network it attaches to
+func hasStaticIP(network *common.NetworkConfig) bool {
+	for _, iface := range network.Interfaces {
+		if !iface.DHCP && (iface.IP != "" || iface.Network != "") {
+			return true
+		}
+	}
+	return false
+}
+
+// imageHasTag reports whether an image reference names a tag or digest
+func imageHasTag(image string) bool {
+	if strings.Contains(image, "@") {
+		return true
+	}
+	last := image[strings.LastIndex(image, "/")+1:]
+	return strings.Contains(last, ":")
+}
+
+func hasService(services map[string]common.Container, name string) bool {
+	_, ok := services[name]
+	return ok
+}
@@ -0,0 +1,188 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+// lintStrings formats issues for comparison
+func lintStrings(issues []config.LintIssue) string {
+	lines := make([]string, len(issues))
+	for i, issue := range issues {
+		lines[i] = issue.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestLint(t *testing.T) {
+	staticNet := func(ip string) *common.NetworkConfig {
+		return &common.NetworkConfig{
+			Interfaces: []common.NetworkInterface{{Type: "veth", Bridge: "lxcbr0", IP: ip}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		cfg      common.ComposeConfig
+		contains []string
+		clean    bool
+	}{
+		{
+			name: "clean",
+			cfg: common.ComposeConfig{
+				Services: map[string]common.Container{
+					"db":  {Image: "postgres:16"},
+					"web": {Image: "nginx@sha256:" + strings.Repeat("a", 64), DependsOn: []string{"db"}},
+				},
+			},
+			clean: true,
+		},
+		{
+			name: "untagged image",
+			cfg: common.ComposeConfig{
+				Services: map[string]common.Container{
+					"web": {Image: "registry:5000/nginx"},
+				},
+			},
+			contains: []string{"warning: services.web.image: image registry:5000/nginx has no tag"},
+		},
+		{
+			name: "unknown and self dependencies",
+			cfg: common.ComposeConfig{
+				Services: map[string]common.Container{
+					"web": {Image: "nginx:1", DependsOn: []string{"cache", "web"}},
+				},
+			},
+			contains: []string{
+				"error: services.web.depends_on: unknown service 'cache'",
+				"error: services.web.depends_on: service depends on itself",
+			},
+		},
+		{
+			name: "network mode",
+			cfg: common.ComposeConfig{
+				Services: map[string]common.Container{
+					"app": {Image: "alpine:3", NetworkMode: "container:app"},
+					"web": {Image: "nginx:1", NetworkMode: "container:vpn"},
+				},
+			},
+			contains: []string{
+				"error: services.app.network_mode: service shares its own network",
+				"warning: services.web.network_mode: 'vpn' is not a service in this file",
+			},
+		},
+		{
+			name: "dependency cycle",
+			cfg: common.ComposeConfig{
+				Services: map[string]common.Container{
+					"a": {Image: "alpine:3", DependsOn: []string{"b"}},
+					"b": {Image: "alpine:3", DependsOn: []string{"a"}},
+				},
+			},
+			contains: []string{"error: services: "},
+		},
+		{
+			name: "duplicate host ports",
+			cfg: common.ComposeConfig{
+				Services: map[string]common.Container{
+					"a": {Image: "nginx:1", Network: staticNet("10.0.3.2/24"), Ports: []common.PortForward{{Protocol: "tcp", Host: 80, Guest: 80}}},
+					"b": {Image: "nginx:1", Network: staticNet("10.0.3.3/24"), Ports: []common.PortForward{{Protocol: "tcp", Host: 80, Guest: 8080}}},
+					"c": {Image: "nginx:1", Network: staticNet("10.0.3.4/24"), Ports: []common.PortForward{{Protocol: "tcp", Host: 80, Guest: 80}}},
+				},
+			},
+			contains: []string{
+				"error: services.b.ports: host port tcp/80 is also forwarded by service 'a'",
+				"error: services.c.ports: host port tcp/80 is also forwarded by service 'a'",
+			},
+		},
+		{
+			name: "privileged with strict isolation",
+			cfg: common.ComposeConfig{
+				Services: map[string]common.Container{
+					"web": {Image: "nginx:1", Security: &common.SecurityConfig{Isolation: "strict", Privileged: true}},
+				},
+			},
+			contains: []string{"error: services.web.security.privileged: cannot use privileged mode with strict isolation"},
+		},
+		{
+			name: "unreachable settings",
+			cfg: common.ComposeConfig{
+				Services: map[string]common.Container{
+					"web": {
+						Image:   "nginx:1",
+						Network: &common.NetworkConfig{Interfaces: []common.NetworkInterface{{Type: "veth", Bridge: "lxcbr0", DHCP: true}}},
+						Ports:   []common.PortForward{{Protocol: "tcp", Host: 80, Guest: 80}},
+						Volumes: []string{"/data:/data"},
+						Env:     map[string]string{"A": "1"},
+					},
+				},
+				Networks: map[string]common.NetworkDefinition{
+					"backend": {Bridge: "br-backend"},
+				},
+			},
+			contains: []string{
+				"error: services.web.ports: port forwarding requires at least one interface with static IP",
+				"warning: services.web.volumes: volumes are not applied",
+				"warning: services.web.env: env is not applied",
+				"warning: networks.backend: network is not used by any service",
+			},
+		},
+		{
+			name: "missing image",
+			cfg: common.ComposeConfig{
+				Services: map[string]common.Container{
+					"web": {},
+				},
+			},
+			contains: []string{"error: services.web.image: image is required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintStrings(config.Lint(&tt.cfg))
+			if tt.clean {
+				testing_internal.AssertEqual(t, "", got)
+				return
+			}
+			for _, want := range tt.contains {
+				testing_internal.AssertContains(t, got, want)
+			}
+		})
+	}
+}
+
+func TestLintFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "lxc-compose.yml")
+	data := `
+x-base: &base
+  image: alpine:3
+x-unused: &unused
+  image: nginx:1
+services:
+  web:
+    <<: *base
+  web:
+    image: nginx:1
+`
+	testing_internal.AssertNoError(t, os.WriteFile(path, []byte(data), 0644))
+
+	issues, err := config.LintFile(path)
+	testing_internal.AssertNoError(t, err)
+	got := lintStrings(issues)
+	testing_internal.AssertContains(t, got, "warning: line 4: anchor &unused is never used")
+	testing_internal.AssertContains(t, got, "error: services.web: service defined again at line 9, replacing the definition at line 7")
+	if strings.Contains(got, "&base") {
+		t.Errorf("expected used anchor not to be reported, got:\n%s", got)
+	}
+
+	_, err = config.LintFile(filepath.Join(dir, "missing.yml"))
+	testing_internal.AssertError(t, err)
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
+)
+
+// maxBridgeNameLen is the longest interface name the kernel accepts
+const maxBridgeNameLen = 15
+
+// BridgeName returns the host bridge backing a network definition
+func BridgeName(name string, def common.NetworkDefinition) string {
+	if def.Bridge != "" {
+		return def.Bridge
+	}
+	return name
+}
+
+// ValidateNetworks checks each network's bridge name and that its gateway lies
+// within its subnet
+func ValidateNetworks(networks map[string]common.NetworkDefinition) error {
+	var errs validation.ValidationErrors
+	for _, name := range sortedNetworkNames(networks) {
+		errs.Add("networks."+name, validateNetworkDefinition(name, networks[name]))
+	}
+	return errs.ErrorOrNil()
+}
+
+func validateNetworkDefinition(name string, def common.NetworkDefinition) error {
+	bridge := BridgeName(name, def)
+	if len(bridge) > maxBridgeNameLen || strings.ContainsAny(bridge, " /:") {
+		return validation.WithPath("bridge", fmt.Errorf("invalid bridge name %q", bridge))
+	}
+
+	if def.Subnet == "" {
+		if def.Gateway != "" {
+			return validation.WithPath("gateway", fmt.Errorf("gateway requires a subnet"))
+		}
+		return nil
+	}
+
+	_, subnet, err := net.ParseCIDR(def.Subnet)
+	if err != nil {
+		return validation.WithPath("subnet", fmt.Errorf("invalid subnet %q", def.Subnet))
+	}
+	if def.Gateway == "" {
+		return nil
+	}
+
+	gateway := net.ParseIP(def.Gateway)
+	if gateway == nil {
+		return validation.WithPath("gateway", fmt.Errorf("invalid gateway %q", def.Gateway))
+	}
+	if !subnet.Contains(gateway) {
+		return validation.WithPath("gateway", fmt.Errorf("gateway %s is outside subnet %s", def.Gateway, def.Subnet))
+	}
+	if gateway.Equal(subnet.IP) {
+		return validation.WithPath("gateway", fmt.Errorf("gateway %s is the network address of subnet %s", def.Gateway, def.Subnet))
+	}
+	return nil
+}
+
+// ResolveNetworks returns services with every reference to a top-level
+// network replaced by a bridge interface on that network's bridge. Static
+// addresses must lie within the network's subnet and take its prefix length
+// if they have none, and the network's gateway is used when an interface
+// sets none.
+func ResolveNetworks(networks map[string]common.NetworkDefinition, services map[string]common.Container) (map[string]common.Container, error) {
+	resolved := make(map[string]common.Container, len(services))
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		svc := services[name]
+		if svc.Network == nil {
+			resolved[name] = svc
+			continue
+		}
+
+		network := *svc.Network
+		if network.Network != "" {
+			iface := common.NetworkInterface{
+				Type:    network.Type,
+				Network: network.Network,
+				Bridge:  network.Bridge,
+				IP:      network.IP,
+				Gateway: network.Gateway,
+				DHCP:    network.DHCP,
+			}
+			if err := resolveInterface(networks, &iface); err != nil {
+				return nil, fmt.Errorf("service '%s': %w", name, err)
+			}
+			network.Type, network.Bridge = iface.Type, iface.Bridge
+			network.IP, network.Gateway = iface.IP, iface.Gateway
+		}
+
+		network.Interfaces = append([]common.NetworkInterface(nil), network.Interfaces...)
+		for i := range network.Interfaces {
+			if network.Interfaces[i].Network == "" {
+				continue
+			}
+			if err := resolveInterface(networks, &network.Interfaces[i]); err != nil {
+				return nil, fmt.Errorf("service '%s': interface %d: %w", name, i, err)
+			}
+		}
+
+		svc.Network = &network
+		resolved[name] = svc
+	}
+	return resolved, nil
+}
+
+// resolveInterface points iface at the bridge of the network it references
+func resolveInterface(networks map[string]common.NetworkDefinition, iface *common.NetworkInterface) error {
+	def, ok := networks[iface.Network]
+	if !ok {
+		return fmt.Errorf("undefined network '%s'", iface.Network)
+	}
+
+	bridge := BridgeName(iface.Network, def)
+	if iface.Bridge != "" && iface.Bridge != bridge {
+		return fmt.Errorf("bridge %s conflicts with network '%s' on bridge %s", iface.Bridge, iface.Network, bridge)
+	}
+	if iface.Type == "" {
+		iface.Type = "bridge"
+	}
+	iface.Bridge = bridge
+
+	if iface.DHCP || def.Subnet == "" {
+		return nil
+	}
+	if iface.IP != "" {
+		_, subnet, err := net.ParseCIDR(def.Subnet)
+		if err != nil {
+			return fmt.Errorf("network '%s': invalid subnet %q", iface.Network, def.Subnet)
+		}
+		ip, _, hasPrefix := strings.Cut(iface.IP, "/")
+		if addr := net.ParseIP(ip); addr == nil || !subnet.Contains(addr) {
+			return fmt.Errorf("IP %s is outside network '%s' subnet %s", iface.IP, iface.Network, def.Subnet)
+		}
+		if !hasPrefix {
+			ones, _ := subnet.Mask.Size()
+			iface.IP = fmt.Sprintf("%s/%d", ip, ones)
+		}
+	}
+	if iface.Gateway == "" {
+		iface.Gateway = def.Gateway
+	}
+	return nil
+}
+
+// ServiceNetworks returns the top-level networks a service references
+func ServiceNetworks(svc common.Container) []string {
+	if svc.Network == nil {
+		return nil
+	}
+	var names []string
+	if svc.Network.Network != "" {
+		names = append(names, svc.Network.Network)
+	}
+	for _, iface := range svc.Network.Interfaces {
+		if iface.Network != "" {
+			names = append(names, iface.Network)
+		}
+	}
+	return names
+}
+
+func sortedNetworkNames(networks map[string]common.NetworkDefinition) []string {
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
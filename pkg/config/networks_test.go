@@ -0,0 +1,90 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestValidateNetworks(t *testing.T) {
+	tests := []struct {
+		name        string
+		network     common.NetworkDefinition
+		errContains string
+	}{
+		{name: "bridge only", network: common.NetworkDefinition{Bridge: "br-app"}},
+		{name: "subnet and gateway", network: common.NetworkDefinition{Subnet: "10.0.3.0/24", Gateway: "10.0.3.1"}},
+		{name: "invalid subnet", network: common.NetworkDefinition{Subnet: "10.0.3.0"}, errContains: "invalid subnet"},
+		{name: "gateway without subnet", network: common.NetworkDefinition{Gateway: "10.0.3.1"}, errContains: "requires a subnet"},
+		{name: "gateway outside subnet", network: common.NetworkDefinition{Subnet: "10.0.3.0/24", Gateway: "10.0.4.1"}, errContains: "outside subnet"},
+		{name: "gateway is network address", network: common.NetworkDefinition{Subnet: "10.0.3.0/24", Gateway: "10.0.3.0"}, errContains: "network address"},
+		{name: "bridge name too long", network: common.NetworkDefinition{Bridge: "a-very-long-bridge"}, errContains: "invalid bridge name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.ValidateNetworks(map[string]common.NetworkDefinition{"app": tt.network})
+			if tt.errContains == "" {
+				testing_internal.AssertNoError(t, err)
+				return
+			}
+			testing_internal.AssertError(t, err)
+			testing_internal.AssertContains(t, err.Error(), "networks.app")
+			testing_internal.AssertContains(t, err.Error(), tt.errContains)
+		})
+	}
+}
+
+func TestResolveNetworks(t *testing.T) {
+	networks := map[string]common.NetworkDefinition{
+		"frontend": {Bridge: "br-front", Subnet: "10.0.3.0/24", Gateway: "10.0.3.1"},
+		"backend":  {},
+	}
+
+	t.Run("legacy and interfaces", func(t *testing.T) {
+		services, err := config.ResolveNetworks(networks, map[string]common.Container{
+			"web": {Network: &common.NetworkConfig{
+				Network: "frontend",
+				IP:      "10.0.3.10",
+				Interfaces: []common.NetworkInterface{
+					{Network: "backend", DHCP: true},
+					{Type: "veth", Bridge: "lxcbr0"},
+				},
+			}},
+			"plain": {Image: "alpine"},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		web := services["web"].Network
+		testing_internal.AssertEqual(t, "bridge", web.Type)
+		testing_internal.AssertEqual(t, "br-front", web.Bridge)
+		testing_internal.AssertEqual(t, "10.0.3.10/24", web.IP)
+		testing_internal.AssertEqual(t, "10.0.3.1", web.Gateway)
+		testing_internal.AssertEqual(t, "backend", web.Interfaces[0].Bridge)
+		testing_internal.AssertEqual(t, "lxcbr0", web.Interfaces[1].Bridge)
+		testing_internal.AssertEqual(t, "alpine", services["plain"].Image)
+		testing_internal.AssertEqual(t, "frontend,backend", strings.Join(config.ServiceNetworks(services["web"]), ","))
+	})
+
+	errorTests := []struct {
+		name        string
+		network     common.NetworkConfig
+		errContains string
+	}{
+		{name: "undefined network", network: common.NetworkConfig{Network: "missing"}, errContains: "undefined network 'missing'"},
+		{name: "IP outside subnet", network: common.NetworkConfig{Network: "frontend", IP: "192.168.1.5/24"}, errContains: "outside network 'frontend'"},
+		{name: "conflicting bridge", network: common.NetworkConfig{Network: "frontend", Bridge: "lxcbr0"}, errContains: "conflicts with network"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			network := tt.network
+			_, err := config.ResolveNetworks(networks, map[string]common.Container{"web": {Network: &network}})
+			testing_internal.AssertError(t, err)
+			testing_internal.AssertContains(t, err.Error(), "service 'web'")
+			testing_internal.AssertContains(t, err.Error(), tt.errContains)
+		})
+	}
+}
@@ -3,7 +3,11 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/oci"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
 
 	"gopkg.in/yaml.v3"
@@ -28,20 +32,38 @@ func Load(path string) (*Container, error) {
 
 	if len(composeConfig.Services) > 0 {
 		// Get the "app" container if it exists, otherwise get the first container
-		container, exists := composeConfig.Services["app"]
-		if !exists {
+		name := "app"
+		if _, exists := composeConfig.Services[name]; !exists {
 			// Get the first container
-			for _, c := range composeConfig.Services {
-				container = c
+			for n := range composeConfig.Services {
+				name = n
 				break
 			}
 		}
 
-		if container == nil {
+		if composeConfig.Services[name] == nil {
 			return nil, fmt.Errorf("invalid configuration: service is empty")
 		}
 
-		if err := validateContainer("app", container); err != nil {
+		// Merge in any base services referenced through extends
+		resolved, err := common.NewExtendsResolver().Resolve(path, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration: %w", err)
+		}
+		container, err := decodeContainer(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+		dir, err := filepath.Abs(filepath.Dir(path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve config path: %w", err)
+		}
+		container.resolvePaths(dir)
+		if err := container.resolveEnvFiles(dir); err != nil {
+			return nil, fmt.Errorf("invalid configuration: %w", validation.WithPath("services."+name+".env_file", err))
+		}
+
+		if err := validateContainer("services."+name, container); err != nil {
 			return nil, fmt.Errorf("invalid configuration: %w", err)
 		}
 
@@ -49,16 +71,62 @@ func Load(path string) (*Container, error) {
 	}
 
 	// Try to parse as a single container config
-	var container Container
-	if err := yaml.Unmarshal(data, &container); err != nil {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	if err := validateContainer("default", &container); err != nil {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	resolved, err := common.NewExtendsResolver().ResolveMap(absPath, raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	container, err := decodeContainer(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	container.resolvePaths(filepath.Dir(absPath))
+	if err := container.resolveEnvFiles(filepath.Dir(absPath)); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", validation.WithPath("env_file", err))
+	}
+
+	if err := validateContainer("", container); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	return &container, nil
+	return container, nil
+}
+
+// resolvePaths resolves the container's relative file paths against dir like
+// common.Container.ResolvePaths: bind mount sources, env files,
+// include_configs and the VPN config
+func (c *Container) resolvePaths(dir string) {
+	if c.Storage != nil {
+		for i, mount := range c.Storage.Mounts {
+			if common.IsBindMount(mount.Type) {
+				c.Storage.Mounts[i].Source = common.ResolvePath(dir, mount.Source)
+			}
+		}
+	}
+	c.EnvFile = common.ResolvePathList(dir, c.EnvFile)
+	c.IncludeConfigs = common.ResolvePathList(dir, c.IncludeConfigs)
+	if c.Network != nil && c.Network.VPN != nil {
+		c.Network.VPN.Config = common.ResolvePath(dir, c.Network.VPN.Config)
+	}
+}
+
+// resolveEnvFiles merges the container's env_file entries, relative to dir,
+// into its environment, see common.MergeEnvFiles
+func (c *Container) resolveEnvFiles(dir string) error {
+	files, env, err := common.MergeEnvFiles(dir, c.EnvFile, c.Environment)
+	if err != nil {
+		return err
+	}
+	c.EnvFile, c.Environment = files, env
+	return nil
 }
 
 func toValidationNetworkConfig(cfg *NetworkConfig) *validation.NetworkConfig {
@@ -68,16 +136,17 @@ func toValidationNetworkConfig(cfg *NetworkConfig) *validation.NetworkConfig {
 
 	// Convert to validation package's type
 	return &validation.NetworkConfig{
-		Type:      cfg.Type,
-		Bridge:    cfg.Bridge,
-		Interface: cfg.Interface,
-		IP:        cfg.IP,
-		Gateway:   cfg.Gateway,
-		DNS:       cfg.DNS,
-		DHCP:      cfg.DHCP,
-		Hostname:  cfg.Hostname,
-		MTU:       cfg.MTU,
-		MAC:       cfg.MAC,
+		Type:       cfg.Type,
+		Bridge:     cfg.Bridge,
+		Interface:  cfg.Interface,
+		IP:         cfg.IP,
+		Gateway:    cfg.Gateway,
+		DNS:        cfg.DNS,
+		DNSOptions: cfg.DNSOptions,
+		DHCP:       cfg.DHCP,
+		Hostname:   cfg.Hostname,
+		MTU:        cfg.MTU,
+		MAC:        cfg.MAC,
 	}
 }
 
@@ -111,15 +180,29 @@ func Validate(container *Container) error {
 	// Validate network configuration
 	if container.Network != nil {
 		if err := validation.ValidateNetworkConfig(toValidationNetworkConfig(container.Network)); err != nil {
-			return fmt.Errorf("invalid network configuration: %w", err)
+			return validation.WithPath("network", err)
 		}
 	}
 
 	// Validate security configuration
 	if container.Security != nil {
-		fmt.Printf("DEBUG: Validating security config: %+v\n", container.Security)
 		if err := validation.ValidateSecurityProfile(toValidationSecurityProfile(container.Security)); err != nil {
-			return fmt.Errorf("invalid security configuration: %w", err)
+			return validation.WithPath("security", err)
+		}
+		if container.Privileged != nil {
+			if err := validation.ValidatePrivilegedShortcut(*container.Privileged, container.Security.Isolation, container.Security.Privileged); err != nil {
+				return validation.WithPath("privileged", err)
+			}
+		}
+	}
+
+	// Validate CPU and memory limits
+	if r := container.Resources; r != nil {
+		if err := validation.ValidateCPULimits(r.Cores, r.CPUShares, r.CPUQuota, r.CPUPeriod); err != nil {
+			return validation.WithPath("resources", err)
+		}
+		if err := validation.ValidateMemoryLimits(r.Memory, r.MemorySwap, ""); err != nil {
+			return validation.WithPath("resources", err)
 		}
 	}
 
@@ -136,19 +219,31 @@ func ValidateConfig(config *ComposeConfig) error {
 		return fmt.Errorf("at least one service must be defined")
 	}
 
-	for name, container := range config.Services {
-		if err := validateContainer(name, &container); err != nil {
-			return err
-		}
+	// Report every invalid service, in a stable order
+	names := make([]string, 0, len(config.Services))
+	for name := range config.Services {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	return nil
+	var errs validation.ValidationErrors
+	for _, name := range names {
+		container := config.Services[name]
+		errs.Add("", validateContainer("services."+name, &container))
+	}
+
+	return errs.ErrorOrNil()
 }
 
-// validateContainer validates a single container configuration
-func validateContainer(name string, container *Container) error {
+// validateContainer validates a single container configuration, reporting
+// failures as validation.ValidationErrors located under path
+func validateContainer(path string, container *Container) error {
+	var errs validation.ValidationErrors
 	if container.Image == "" {
-		return fmt.Errorf("service '%s' must specify an image", name)
+		errs.Add(validation.JoinPath(path, "image"), fmt.Errorf("image is required"))
+	} else if _, err := oci.ParseImageReference(container.Image); err != nil {
+		// Caught here rather than when the image is pulled
+		errs.Add(validation.JoinPath(path, "image"), fmt.Errorf("invalid image reference %q: %w", container.Image, err))
 	}
 
 	// Apply storage defaults
@@ -157,9 +252,7 @@ func validateContainer(name string, container *Container) error {
 	}
 
 	// Validate container configuration
-	if err := validateContainerConfig(container); err != nil {
-		return fmt.Errorf("service '%s' has invalid configuration: %w", name, err)
-	}
+	errs.Add(path, validateContainerConfig(container))
 
-	return nil
+	return errs.ErrorOrNil()
 }
@@ -1,11 +1,15 @@
 package config_test
 
 import (
+	"errors"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
 	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -86,3 +90,187 @@ func TestLoadConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadConfigValidationErrors(t *testing.T) {
+	_, err := config.Load(filepath.Join("testdata", "invalid.yaml"))
+	testing_internal.AssertError(t, err)
+
+	var errs validation.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected validation.ValidationErrors, got %T: %v", err, err)
+	}
+
+	paths := make([]string, len(errs))
+	for i, e := range errs {
+		paths[i] = e.Path
+	}
+	for _, want := range []string{
+		"services.app.image",
+		"services.app.storage.root",
+		"services.app.network.type",
+		"services.app.security.isolation",
+	} {
+		testing_internal.AssertContains(t, strings.Join(paths, ","), want)
+	}
+}
+
+func TestLoadConfigEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(dir, "app.env"), []byte("LEVEL=info\nREGION=eu\n"), 0644))
+	path := filepath.Join(dir, "lxc-compose.yml")
+	testing_internal.AssertNoError(t, os.WriteFile(path, []byte(`services:
+  app:
+    image: ubuntu:22.04
+    env_file: [app.env]
+    environment:
+      REGION: us
+`), 0644))
+	container, err := config.Load(path)
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertEqual(t, "info", container.Environment["LEVEL"])
+	testing_internal.AssertEqual(t, "us", container.Environment["REGION"])
+	testing_internal.AssertEqual(t, filepath.Join(dir, "app.env"), container.EnvFile[0])
+
+	testing_internal.AssertNoError(t, os.Remove(filepath.Join(dir, "app.env")))
+	_, err = config.Load(path)
+	testing_internal.AssertError(t, err)
+	testing_internal.AssertContains(t, err.Error(), "services.app.env_file")
+	testing_internal.AssertContains(t, err.Error(), "does not exist")
+}
+
+func TestLoadConfigRelativePaths(t *testing.T) {
+	dir := t.TempDir()
+	testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(dir, "app.env"), []byte("A=1\n"), 0644))
+	path := filepath.Join(dir, "lxc-compose.yml")
+	testing_internal.AssertNoError(t, os.WriteFile(path, []byte(`services:
+  app:
+    image: ubuntu:22.04
+    env_file: [./app.env]
+    storage:
+      root: 10G
+      mounts:
+        - source: ./data
+          target: /data
+        - source: /srv/shared
+          target: /shared
+        - source: tmpfs
+          target: /cache
+          type: tmpfs
+    network:
+      type: veth
+      dhcp: true
+      vpn:
+        remote: vpn.example.com
+        port: 1194
+        protocol: udp
+        config: vpn/client.ovpn
+`), 0644))
+
+	container, err := config.Load(path)
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertEqual(t, filepath.Join(dir, "data"), container.Storage.Mounts[0].Source)
+	testing_internal.AssertEqual(t, "/srv/shared", container.Storage.Mounts[1].Source)
+	testing_internal.AssertEqual(t, "tmpfs", container.Storage.Mounts[2].Source)
+	testing_internal.AssertEqual(t, filepath.Join(dir, "app.env"), container.EnvFile[0])
+	testing_internal.AssertEqual(t, filepath.Join(dir, "vpn", "client.ovpn"), container.Network.VPN.Config)
+}
+
+func TestValidateConfigIncludeConfigs(t *testing.T) {
+	include := filepath.Join(t.TempDir(), "common.conf")
+	testing_internal.AssertNoError(t, os.WriteFile(include, []byte(""), 0644))
+
+	err := config.ValidateConfig(&config.ComposeConfig{
+		Version:  "1.0",
+		Services: map[string]config.Container{"web": {Image: "ubuntu:20.04", IncludeConfigs: []string{include, "/missing.conf"}}},
+	})
+	testing_internal.AssertError(t, err)
+	testing_internal.AssertContains(t, err.Error(), "services.web.include_configs[1]: include config /missing.conf does not exist")
+	testing_internal.AssertNotContains(t, err.Error(), "include_configs[0]")
+}
+
+func TestValidateConfigImageReference(t *testing.T) {
+	for _, image := range []string{"ubuntu::", "org/app/", "Ubuntu:20.04"} {
+		t.Run(image, func(t *testing.T) {
+			err := config.ValidateConfig(&config.ComposeConfig{
+				Version:  "1.0",
+				Services: map[string]config.Container{"web": {Image: image}},
+			})
+			testing_internal.AssertError(t, err)
+			testing_internal.AssertContains(t, err.Error(), "services.web.image: invalid image reference")
+		})
+	}
+
+	err := config.ValidateConfig(&config.ComposeConfig{
+		Version:  "1.0",
+		Services: map[string]config.Container{"web": {Image: "localhost:5000/web:1.0"}},
+	})
+	testing_internal.AssertNoError(t, err)
+}
+
+func TestValidateConfigResources(t *testing.T) {
+	tests := []struct {
+		name        string
+		resources   *config.ResourceConfig
+		errContains string
+	}{
+		{name: "unset", resources: &config.ResourceConfig{}},
+		{name: "valid", resources: &config.ResourceConfig{Cores: 2, CPUQuota: 50000, CPUPeriod: 100000, Memory: "1G", MemorySwap: "2G"}},
+		{name: "negative cores", resources: &config.ResourceConfig{Cores: -2}, errContains: "cores must be at least 1"},
+		{name: "quota without period", resources: &config.ResourceConfig{CPUQuota: 50000}, errContains: "requires a cpu period"},
+		{name: "negative period", resources: &config.ResourceConfig{CPUQuota: 50000, CPUPeriod: -1}, errContains: "period must be positive"},
+		{name: "invalid memory", resources: &config.ResourceConfig{Memory: "half"}, errContains: "invalid memory limit"},
+		{name: "invalid swap", resources: &config.ResourceConfig{MemorySwap: "1Q"}, errContains: "invalid memory swap"},
+		{name: "swap below memory", resources: &config.ResourceConfig{Memory: "2G", MemorySwap: "1G"}, errContains: "must not be below"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := &config.Container{Image: "ubuntu:20.04", Resources: tt.resources}
+			for _, err := range []error{
+				config.ValidateConfig(&config.ComposeConfig{Version: "1.0", Services: map[string]config.Container{"web": *container}}),
+				config.Validate(container),
+			} {
+				if tt.errContains == "" {
+					testing_internal.AssertNoError(t, err)
+					continue
+				}
+				testing_internal.AssertError(t, err)
+				testing_internal.AssertContains(t, err.Error(), "resources")
+				testing_internal.AssertContains(t, err.Error(), tt.errContains)
+			}
+		})
+	}
+}
+
+func TestValidateConfigPrivileged(t *testing.T) {
+	privileged, unprivileged := true, false
+	tests := []struct {
+		name        string
+		privileged  *bool
+		security    *config.SecurityConfig
+		errContains string
+	}{
+		{name: "shortcut only", privileged: &privileged},
+		{name: "shortcut with matching block", privileged: &privileged, security: &config.SecurityConfig{Isolation: "privileged", Privileged: true}},
+		{name: "conflicting isolation", privileged: &privileged, security: &config.SecurityConfig{Isolation: "strict"}, errContains: "conflicts with security.isolation: strict"},
+		{name: "conflicting privileged", privileged: &unprivileged, security: &config.SecurityConfig{Privileged: true}, errContains: "conflicts with security.privileged: true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container := &config.Container{Image: "ubuntu:20.04", Privileged: tt.privileged, Security: tt.security}
+			for _, err := range []error{
+				config.ValidateConfig(&config.ComposeConfig{Version: "1.0", Services: map[string]config.Container{"web": *container}}),
+				config.Validate(container),
+			} {
+				if tt.errContains == "" {
+					testing_internal.AssertNoError(t, err)
+					continue
+				}
+				testing_internal.AssertError(t, err)
+				testing_internal.AssertContains(t, err.Error(), "privileged")
+				testing_internal.AssertContains(t, err.Error(), tt.errContains)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+)
+
+// CheckPortConflicts returns an error if two services forward the same protocol and host port
+func CheckPortConflicts(services map[string]common.Container) error {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	owners := make(map[string]string)
+	for _, name := range names {
+		svc := services[name]
+
+		var forwards []common.PortForward
+		if svc.Network != nil {
+			forwards = append(forwards, svc.Network.PortForwards...)
+		}
+		forwards = append(forwards, svc.Ports...)
+
+		for _, pf := range forwards {
+			key := fmt.Sprintf("%s/%d", pf.Protocol, pf.Host)
+			owner, ok := owners[key]
+			if !ok {
+				owners[key] = name
+				continue
+			}
+			// A service may declare the same port in both Ports and Network.PortForwards
+			if owner != name {
+				return fmt.Errorf("host port %s is forwarded by both service '%s' and service '%s'", key, owner, name)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestCheckPortConflicts(t *testing.T) {
+	tests := []struct {
+		name        string
+		services    map[string]common.Container
+		wantErr     bool
+		errContains []string
+	}{
+		{
+			name: "no conflicts",
+			services: map[string]common.Container{
+				"web": {Ports: []common.PortForward{{Protocol: "tcp", Host: 80, Guest: 80}}},
+				"api": {Ports: []common.PortForward{{Protocol: "tcp", Host: 8080, Guest: 80}}},
+			},
+		},
+		{
+			name: "same port different protocol",
+			services: map[string]common.Container{
+				"web": {Ports: []common.PortForward{{Protocol: "tcp", Host: 53, Guest: 53}}},
+				"dns": {Ports: []common.PortForward{{Protocol: "udp", Host: 53, Guest: 53}}},
+			},
+		},
+		{
+			name: "same port declared twice in one service",
+			services: map[string]common.Container{
+				"web": {
+					Network: &common.NetworkConfig{
+						PortForwards: []common.PortForward{{Protocol: "tcp", Host: 80, Guest: 80}},
+					},
+					Ports: []common.PortForward{{Protocol: "tcp", Host: 80, Guest: 8080}},
+				},
+			},
+		},
+		{
+			name: "conflict across services",
+			services: map[string]common.Container{
+				"web": {Ports: []common.PortForward{{Protocol: "tcp", Host: 80, Guest: 80}}},
+				"api": {
+					Network: &common.NetworkConfig{
+						PortForwards: []common.PortForward{{Protocol: "tcp", Host: 80, Guest: 8080}},
+					},
+				},
+			},
+			wantErr:     true,
+			errContains: []string{"tcp/80", "'api'", "'web'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.CheckPortConflicts(tt.services)
+			if !tt.wantErr {
+				testing_internal.AssertNoError(t, err)
+				return
+			}
+			testing_internal.AssertError(t, err)
+			for _, s := range tt.errContains {
+				testing_internal.AssertContains(t, err.Error(), s)
+			}
+		})
+	}
+}
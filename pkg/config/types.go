@@ -6,34 +6,81 @@ type ComposeConfig struct {
 	Version string `yaml:"version" json:"version"`
 	// Services defines the container configurations
 	Services map[string]Container `yaml:"services" json:"services"`
+	// TopLevelExtensions holds the file's x- keys, see Extensions
+	TopLevelExtensions map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 // Container represents a single LXC container configuration
 type Container struct {
-	Image       string            `yaml:"image" json:"image"`
-	Resources   *ResourceConfig   `yaml:"resources,omitempty" json:"resources,omitempty"`
-	Storage     *StorageConfig    `yaml:"storage,omitempty" json:"storage,omitempty"`
-	Network     *NetworkConfig    `yaml:"network,omitempty" json:"network,omitempty"`
-	Environment map[string]string `yaml:"environment,omitempty" json:"environment,omitempty"`
-	Command     []string          `yaml:"command,omitempty" json:"command,omitempty"`
-	Entrypoint  []string          `yaml:"entrypoint,omitempty" json:"entrypoint,omitempty"`
-	Devices     []DeviceConfig    `yaml:"devices,omitempty" json:"devices,omitempty"`
-	Security    *SecurityConfig   `yaml:"security,omitempty" json:"security,omitempty"`
+	Image       string                 `yaml:"image" json:"image"`
+	Extends     *ExtendsConfig         `yaml:"extends,omitempty" json:"extends,omitempty"` // Resolved by Load, cleared afterwards
+	Resources   *ResourceConfig        `yaml:"resources,omitempty" json:"resources,omitempty"`
+	Storage     *StorageConfig         `yaml:"storage,omitempty" json:"storage,omitempty"`
+	Network     *NetworkConfig         `yaml:"network,omitempty" json:"network,omitempty"`
+	Environment map[string]string      `yaml:"environment,omitempty" json:"environment,omitempty"`
+	Command     []string               `yaml:"command,omitempty" json:"command,omitempty"`
+	Entrypoint  []string               `yaml:"entrypoint,omitempty" json:"entrypoint,omitempty"`
+	Init        string                 `yaml:"init,omitempty" json:"init,omitempty"`       // none (default), systemd or sysvinit
+	AutoDev     *bool                  `yaml:"autodev,omitempty" json:"autodev,omitempty"` // Populate a minimal /dev, on by default with systemd
+	TTYs        *int                   `yaml:"ttys,omitempty" json:"ttys,omitempty"`       // Number of ttys, lxc.tty.max
+	Restart     string                 `yaml:"restart,omitempty" json:"restart,omitempty"` // no (default), always or unless-stopped
+	Devices     []DeviceConfig         `yaml:"devices,omitempty" json:"devices,omitempty"`
+	Security    *SecurityConfig        `yaml:"security,omitempty" json:"security,omitempty"`
+	Ports       []PortForward          `yaml:"ports,omitempty" json:"ports,omitempty"` // Merged into Network.PortForwards, which win on conflicts
+	DependsOn   []string               `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	Logging     *LoggingConfig         `yaml:"logging,omitempty" json:"logging,omitempty"`
+	HealthCheck *HealthCheck           `yaml:"healthcheck,omitempty" json:"healthcheck,omitempty"`
+	Ulimits     map[string]Ulimit      `yaml:"ulimits,omitempty" json:"ulimits,omitempty"` // Keyed by limit name, e.g. nofile
+	Sysctls     map[string]string      `yaml:"sysctls,omitempty" json:"sysctls,omitempty"` // Kernel parameters, e.g. net.core.somaxconn
+	StopSignal  string                 `yaml:"stop_signal,omitempty" json:"stop_signal,omitempty"`
+	Timezone    string                 `yaml:"timezone,omitempty" json:"timezone,omitempty"` // IANA name, e.g. Europe/Berlin
+	AutoStart   bool                   `yaml:"autostart,omitempty" json:"autostart,omitempty"`
+	StartOrder  int                    `yaml:"start_order,omitempty" json:"start_order,omitempty"` // Boot order, lower starts first
+	StartDelay  int                    `yaml:"start_delay,omitempty" json:"start_delay,omitempty"` // Seconds to wait after starting
+	Labels      map[string]string      `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Extensions  map[string]interface{} `yaml:",inline" json:"extensions,omitempty"` // x- keys for custom tooling
+
+	// IncludeConfigs are LXC config files included before the generated keys
+	IncludeConfigs []string `yaml:"include_configs,omitempty" json:"include_configs,omitempty"`
+
+	// Privileged is docker-compose's top-level privileged flag, see common.Container.ResolvedSecurity
+	Privileged *bool `yaml:"privileged,omitempty" json:"privileged,omitempty"`
+	// StopGracePeriod is how long stopping waits before killing, e.g. 30s
+	StopGracePeriod string `yaml:"stop_grace_period,omitempty" json:"stop_grace_period,omitempty"`
+	// NetworkMode container:<name> joins another container's network namespace
+	NetworkMode string `yaml:"network_mode,omitempty" json:"network_mode,omitempty"`
+	// PidMode and IpcMode are host or container:<name> to share that
+	// namespace instead of creating one
+	PidMode string `yaml:"pid,omitempty" json:"pid,omitempty"`
+	IpcMode string `yaml:"ipc,omitempty" json:"ipc,omitempty"`
+	// EnvFile lists env files merged into Environment when the compose file is
+	// loaded, relative to the compose file's directory
+	EnvFile []string `yaml:"env_file,omitempty" json:"env_file,omitempty"`
+}
+
+// ExtendsConfig references a base service whose configuration is merged before this one
+type ExtendsConfig struct {
+	File    string `yaml:"file,omitempty" json:"file,omitempty"` // Defaults to the current file
+	Service string `yaml:"service" json:"service"`
 }
 
 // CPUConfig represents CPU resource limits
 type CPUConfig struct {
-	Shares *int64 `yaml:"shares,omitempty" json:"shares,omitempty"`
-	Quota  *int64 `yaml:"quota,omitempty" json:"quota,omitempty"`
-	Period *int64 `yaml:"period,omitempty" json:"period,omitempty"`
-	Cores  *int   `yaml:"cores,omitempty" json:"cores,omitempty"`
+	Shares      *int64 `yaml:"shares,omitempty" json:"shares,omitempty"`
+	Quota       *int64 `yaml:"quota,omitempty" json:"quota,omitempty"`
+	Period      *int64 `yaml:"period,omitempty" json:"period,omitempty"`
+	Cores       *int   `yaml:"cores,omitempty" json:"cores,omitempty"`
+	CPUSet      string `yaml:"cpuset,omitempty" json:"cpuset,omitempty"`             // CPUs to pin to, e.g. "0-3,6"
+	MemoryNodes string `yaml:"memory_nodes,omitempty" json:"memory_nodes,omitempty"` // NUMA nodes to allocate from, same syntax
 }
 
 // MemoryConfig represents memory resource limits
 type MemoryConfig struct {
-	Limit   string `yaml:"limit,omitempty" json:"limit,omitempty"`
-	Swap    string `yaml:"swap,omitempty" json:"swap,omitempty"`
-	Reserve string `yaml:"reserve,omitempty" json:"reserve,omitempty"`
+	Limit          string `yaml:"limit,omitempty" json:"limit,omitempty"`
+	Swap           string `yaml:"swap,omitempty" json:"swap,omitempty"`
+	Reserve        string `yaml:"reserve,omitempty" json:"reserve,omitempty"`
+	Swappiness     *int   `yaml:"swappiness,omitempty" json:"swappiness,omitempty"`             // 0-100, nil keeps the host default
+	OOMKillDisable bool   `yaml:"oom_kill_disable,omitempty" json:"oom_kill_disable,omitempty"` // Pause instead of OOM-killing tasks
 }
 
 // StorageConfig represents storage configuration
@@ -43,6 +90,15 @@ type StorageConfig struct {
 	Pool      string        `yaml:"pool,omitempty" json:"pool,omitempty"`
 	Mounts    []MountConfig `yaml:"mounts,omitempty" json:"mounts,omitempty"`
 	AutoMount bool          `yaml:"auto_mount,omitempty" json:"auto_mount,omitempty"`
+	// TmpfsMounts are in-memory filesystems discarded when the container stops
+	TmpfsMounts []TmpfsMount `yaml:"tmpfs,omitempty" json:"tmpfs,omitempty"`
+}
+
+// TmpfsMount represents a tmpfs mount inside the container
+type TmpfsMount struct {
+	Target string `yaml:"target" json:"target"`
+	Size   string `yaml:"size,omitempty" json:"size,omitempty"`
+	Mode   string `yaml:"mode,omitempty" json:"mode,omitempty"`
 }
 
 // MountConfig represents a volume mount configuration
@@ -70,6 +126,41 @@ type NetworkInterface struct {
 	BandwidthOut int64    `yaml:"bandwidth_out,omitempty" json:"bandwidth_out,omitempty"` // Egress bandwidth limit in bytes per second
 }
 
+// LoggingConfig represents console log rotation settings
+type LoggingConfig struct {
+	MaxSize  string `yaml:"max_size,omitempty" json:"max_size,omitempty"`   // Rotate once the console log exceeds this size
+	MaxFiles int    `yaml:"max_files,omitempty" json:"max_files,omitempty"` // Rotated files to keep
+}
+
+// Ulimit represents a process resource limit, applied as lxc.prlimit
+type Ulimit struct {
+	Soft int64 `yaml:"soft" json:"soft"`
+	Hard int64 `yaml:"hard" json:"hard"`
+}
+
+// HealthCheck represents a probe deciding whether a container is healthy
+type HealthCheck struct {
+	Type     string   `yaml:"type,omitempty" json:"type,omitempty"`         // command (default), tcp or http
+	Command  []string `yaml:"command,omitempty" json:"command,omitempty"`   // command: run in the container, healthy on exit 0
+	Port     int      `yaml:"port,omitempty" json:"port,omitempty"`         // tcp, http: port on the container IP
+	Path     string   `yaml:"path,omitempty" json:"path,omitempty"`         // http: path to GET, healthy on 2xx
+	Interval string   `yaml:"interval,omitempty" json:"interval,omitempty"` // Time between probes, e.g. 5s
+	Timeout  string   `yaml:"timeout,omitempty" json:"timeout,omitempty"`   // Time a single probe may take
+	Retries  int      `yaml:"retries,omitempty" json:"retries,omitempty"`   // Consecutive failures before unhealthy
+
+	// OnHealthy and OnUnhealthy are run once each time the container becomes
+	// healthy or unhealthy
+	OnHealthy   *HealthHook `yaml:"on_healthy,omitempty" json:"on_healthy,omitempty"`
+	OnUnhealthy *HealthHook `yaml:"on_unhealthy,omitempty" json:"on_unhealthy,omitempty"`
+}
+
+// HealthHook is a notification of a health status change: a command run on
+// the host or a webhook URL, exactly one of which must be set
+type HealthHook struct {
+	Command []string `yaml:"command,omitempty" json:"command,omitempty"` // Run with LXC_COMPOSE_CONTAINER and LXC_COMPOSE_HEALTH set
+	URL     string   `yaml:"url,omitempty" json:"url,omitempty"`         // POSTed a JSON body with the container and health
+}
+
 // PortForward represents a port forwarding configuration
 type PortForward struct {
 	Protocol string `yaml:"protocol" json:"protocol"` // tcp or udp
@@ -83,9 +174,13 @@ type NetworkConfig struct {
 	PortForwards  []PortForward      `yaml:"port_forwards,omitempty" json:"port_forwards,omitempty"`
 	DNSServers    []string           `yaml:"dns_servers,omitempty" json:"dns_servers,omitempty"`
 	SearchDomains []string           `yaml:"search_domains,omitempty" json:"search_domains,omitempty"`
+	DNSOptions    []string           `yaml:"dns_options,omitempty" json:"dns_options,omitempty"` // resolv.conf options, e.g. ndots:2
 	Isolated      bool               `yaml:"isolated,omitempty" json:"isolated,omitempty"`
 	VPN           *VPNConfig         `yaml:"vpn,omitempty" json:"vpn,omitempty"`
 
+	// DisableAutoMAC leaves MAC assignment to LXC instead of deriving one from the container name
+	DisableAutoMAC bool `yaml:"disable_auto_mac,omitempty" json:"disable_auto_mac,omitempty"`
+
 	// Legacy fields for backward compatibility
 	Type      string   `yaml:"type,omitempty" json:"type,omitempty"`
 	Bridge    string   `yaml:"bridge,omitempty" json:"bridge,omitempty"`
@@ -158,11 +253,16 @@ func (c *Container) DefaultStorageConfig() *StorageConfig {
 
 // ResourceConfig defines resource limits and reservations
 type ResourceConfig struct {
-	Cores        int    `yaml:"cores,omitempty" json:"cores,omitempty"`
-	CPUShares    int64  `yaml:"cpu_shares,omitempty" json:"cpu_shares,omitempty"`
-	CPUQuota     int64  `yaml:"cpu_quota,omitempty" json:"cpu_quota,omitempty"`
-	CPUPeriod    int64  `yaml:"cpu_period,omitempty" json:"cpu_period,omitempty"`
-	Memory       string `yaml:"memory,omitempty" json:"memory,omitempty"`
-	MemorySwap   string `yaml:"memory_swap,omitempty" json:"memory_swap,omitempty"`
-	KernelMemory string `yaml:"kernel_memory,omitempty" json:"kernel_memory,omitempty"`
+	Cores            int    `yaml:"cores,omitempty" json:"cores,omitempty"`
+	CPUShares        int64  `yaml:"cpu_shares,omitempty" json:"cpu_shares,omitempty"`
+	CPUQuota         int64  `yaml:"cpu_quota,omitempty" json:"cpu_quota,omitempty"`
+	CPUPeriod        int64  `yaml:"cpu_period,omitempty" json:"cpu_period,omitempty"`
+	CPUSet           string `yaml:"cpuset,omitempty" json:"cpuset,omitempty"`
+	MemoryNodes      string `yaml:"memory_nodes,omitempty" json:"memory_nodes,omitempty"`
+	Memory           string `yaml:"memory,omitempty" json:"memory,omitempty"`
+	MemorySwap       string `yaml:"memory_swap,omitempty" json:"memory_swap,omitempty"`
+	MemoryReserve    string `yaml:"memory_reserve,omitempty" json:"memory_reserve,omitempty"`
+	MemorySwappiness *int   `yaml:"memory_swappiness,omitempty" json:"memory_swappiness,omitempty"`
+	OOMKillDisable   bool   `yaml:"oom_kill_disable,omitempty" json:"oom_kill_disable,omitempty"`
+	KernelMemory     string `yaml:"kernel_memory,omitempty" json:"kernel_memory,omitempty"`
 }
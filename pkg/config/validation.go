@@ -3,23 +3,31 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
 )
 
 // validateStorage validates storage configuration
 func validateStorage(cfg *StorageConfig) error {
 	if cfg.Root == "" {
-		return fmt.Errorf("root storage size is required")
+		return validation.WithPath("root", fmt.Errorf("root storage size is required"))
 	}
 	// Convert size to bytes for validation
 	bytes, err := parseSize(cfg.Root)
 	if err != nil {
-		return fmt.Errorf("invalid root storage size: %w", err)
+		return validation.WithPath("root", fmt.Errorf("invalid root storage size: %w", err))
 	}
 	// Size must be at least 1MB
 	if bytes < 1024*1024 {
-		return fmt.Errorf("root storage size must be at least 1MB")
+		return validation.WithPath("root", fmt.Errorf("root storage size must be at least 1MB"))
+	}
+	for i, mount := range ToCommonTmpfsMounts(cfg.TmpfsMounts) {
+		if err := validation.ValidateTmpfsMount(mount); err != nil {
+			return validation.WithPath(fmt.Sprintf("tmpfs[%d]", i), err)
+		}
 	}
 	return nil
 }
@@ -57,16 +65,22 @@ func parseSize(size string) (int64, error) {
 // validateNetwork validates network configuration
 func validateNetwork(cfg *NetworkConfig) error {
 	if cfg.Type != "" && !isValidNetworkType(cfg.Type) {
-		return fmt.Errorf("invalid network type: %s", cfg.Type)
+		return validation.WithPath("type", fmt.Errorf("invalid network type: %s", cfg.Type))
 	}
 	if cfg.Type == "bridge" && cfg.Bridge == "" {
-		return fmt.Errorf("bridge name is required for bridge network type")
+		return validation.WithPath("bridge", fmt.Errorf("bridge name is required for bridge network type"))
+	}
+	if err := validation.ValidateDHCPStatics(cfg.DHCP, cfg.IP, cfg.Gateway); err != nil {
+		return err
 	}
 	if cfg.IP != "" {
 		if err := validateIP(cfg.IP); err != nil {
-			return fmt.Errorf("invalid IP address: %w", err)
+			return validation.WithPath("ip", fmt.Errorf("invalid IP address: %w", err))
 		}
 	}
+	if err := validation.ValidateDNSOptions(cfg.DNSOptions); err != nil {
+		return validation.WithPath("dns_options", err)
+	}
 	return nil
 }
 
@@ -99,24 +113,22 @@ func validateSecurity(cfg *SecurityConfig) error {
 		case "default", "strict", "privileged":
 			// Valid values
 		default:
-			return fmt.Errorf("invalid isolation level: %s", cfg.Isolation)
+			return validation.WithPath("isolation", fmt.Errorf("invalid isolation level: %s", cfg.Isolation))
 		}
 	}
 	if cfg.Privileged && strings.ToLower(cfg.Isolation) == "strict" {
-		return fmt.Errorf("cannot use privileged mode with strict isolation")
+		return validation.WithPath("privileged", fmt.Errorf("cannot use privileged mode with strict isolation"))
 	}
-	for _, cap := range cfg.Capabilities {
+	for i, cap := range cfg.Capabilities {
 		if !isValidCapability(cap) {
-			return fmt.Errorf("invalid capability: %s", cap)
+			return validation.WithPath(fmt.Sprintf("capabilities[%d]", i), fmt.Errorf("invalid capability: %s", cap))
 		}
 	}
-	return nil
+	return validation.ValidateCapabilityConflicts(cfg.Isolation, cfg.Privileged, cfg.Capabilities)
 }
 
 // isValidCapability checks if a Linux capability is valid
 func isValidCapability(capability string) bool {
-	fmt.Printf("DEBUG: Checking capability: %s\n", capability)
-
 	// First try exact match
 	if validCaps[strings.ToUpper(capability)] {
 		return true
@@ -125,14 +137,12 @@ func isValidCapability(capability string) bool {
 	// Try with CAP_ prefix if not present
 	if !strings.HasPrefix(strings.ToUpper(capability), "CAP_") {
 		withPrefix := "CAP_" + strings.ToUpper(capability)
-		fmt.Printf("DEBUG: Checking with CAP_ prefix: %s\n", withPrefix)
 		return validCaps[withPrefix]
 	}
 
 	// Try without CAP_ prefix if present
 	if strings.HasPrefix(strings.ToUpper(capability), "CAP_") {
 		withoutPrefix := strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
-		fmt.Printf("DEBUG: Checking without CAP_ prefix: %s\n", withoutPrefix)
 		return validCaps[withoutPrefix]
 	}
 
@@ -221,32 +231,135 @@ var validCaps = map[string]bool{
 	"CAP_AUDIT_READ":       true,
 }
 
-// validateContainerConfig validates the complete container configuration
+// validateLogging validates console log rotation settings
+func validateLogging(cfg *LoggingConfig) error {
+	if cfg.MaxSize != "" {
+		size, err := parseSize(cfg.MaxSize)
+		if err != nil {
+			return validation.WithPath("max_size", fmt.Errorf("invalid max size: %w", err))
+		}
+		if size <= 0 {
+			return validation.WithPath("max_size", fmt.Errorf("max size must be positive"))
+		}
+	}
+	if cfg.MaxFiles < 0 {
+		return validation.WithPath("max_files", fmt.Errorf("max files must be non-negative"))
+	}
+	return nil
+}
+
+// validateContainerConfig validates the complete container configuration.
+// Every failure is collected into validation.ValidationErrors with a path
+// relative to the container (e.g. network.ip).
 func validateContainerConfig(container *Container) error {
 	if container == nil {
 		return fmt.Errorf("container configuration is required")
 	}
 
+	var errs validation.ValidationErrors
+
 	// Validate storage configuration
 	if container.Storage != nil {
-		if err := validateStorage(container.Storage); err != nil {
-			return fmt.Errorf("invalid storage configuration: %w", err)
-		}
+		errs.Add("storage", validateStorage(container.Storage))
 	}
 
 	// Validate network configuration
 	if container.Network != nil {
-		if err := validateNetwork(container.Network); err != nil {
-			return fmt.Errorf("invalid network configuration: %w", err)
-		}
+		errs.Add("network", validateNetwork(container.Network))
 	}
 
 	// Validate security configuration
 	if container.Security != nil {
-		if err := validateSecurity(container.Security); err != nil {
-			return fmt.Errorf("invalid security configuration: %w", err)
+		errs.Add("security", validateSecurity(container.Security))
+		if container.Privileged != nil {
+			errs.Add("privileged", validation.ValidatePrivilegedShortcut(*container.Privileged, container.Security.Isolation, container.Security.Privileged))
 		}
 	}
 
-	return nil
+	// Validate logging configuration
+	if container.Logging != nil {
+		errs.Add("logging", validateLogging(container.Logging))
+	}
+
+	// Validate process resource limits
+	ulimits := make([]string, 0, len(container.Ulimits))
+	for name := range container.Ulimits {
+		ulimits = append(ulimits, name)
+	}
+	sort.Strings(ulimits)
+	for _, name := range ulimits {
+		u := container.Ulimits[name]
+		errs.Add("ulimits."+name, validation.ValidateUlimit(name, u.Soft, u.Hard))
+	}
+
+	// Validate kernel parameters
+	sysctls := make([]string, 0, len(container.Sysctls))
+	for key := range container.Sysctls {
+		sysctls = append(sysctls, key)
+	}
+	sort.Strings(sysctls)
+	for _, key := range sysctls {
+		errs.Add("sysctls."+key, validation.ValidateSysctl(key, container.Sysctls[key]))
+	}
+
+	// Validate health check
+	errs.Add("healthcheck", validation.ValidateHealthCheck(container.HealthCheck.ToCommonHealthCheck()))
+
+	// Validate init system
+	errs.Add("init", validation.ValidateInit(container.Init, len(container.Command) > 0 || len(container.Entrypoint) > 0))
+	if container.TTYs != nil {
+		errs.Add("ttys", validation.ValidateTTYs(*container.TTYs))
+	}
+
+	// Validate restart policy
+	errs.Add("restart", validation.ValidateRestartPolicy(container.Restart))
+	errs.Add("timezone", validation.ValidateTimezone(container.Timezone))
+	for i, path := range container.IncludeConfigs {
+		errs.Add(fmt.Sprintf("include_configs[%d]", i), validation.ValidateIncludeConfig(path))
+	}
+
+	// Validate CPU and memory limits, pinning and memory tuning
+	if r := container.Resources; r != nil {
+		errs.Add("resources", validation.ValidateCPULimits(r.Cores, r.CPUShares, r.CPUQuota, r.CPUPeriod))
+		errs.Add("resources", validation.ValidateMemoryLimits(r.Memory, r.MemorySwap, r.MemoryReserve))
+		errs.Add("resources.cpuset", validation.ValidateCPUSet(container.Resources.CPUSet))
+		errs.Add("resources.memory_nodes", validation.ValidateCPUSet(container.Resources.MemoryNodes))
+		if container.Resources.MemorySwappiness != nil {
+			errs.Add("resources.memory_swappiness", validation.ValidateSwappiness(*container.Resources.MemorySwappiness))
+		}
+	}
+
+	// Validate service-level ports
+	for i, pf := range container.Ports {
+		errs.Add(fmt.Sprintf("ports[%d]", i), validation.ValidatePortForward(&validation.PortForward{
+			Protocol: pf.Protocol,
+			Host:     pf.Host,
+			Guest:    pf.Guest,
+		}))
+	}
+
+	// Validate stop signal
+	if container.StopSignal != "" {
+		if err := validation.ValidateSignal(container.StopSignal); err != nil {
+			errs.Add("stop_signal", fmt.Errorf("invalid stop signal: %w", err))
+		}
+	}
+	if container.StopGracePeriod != "" {
+		_, err := validation.ParseStopGracePeriod(container.StopGracePeriod)
+		errs.Add("stop_grace_period", err)
+	}
+
+	errs.Add("network_mode", validation.ValidateNetworkMode(container.NetworkMode, container.Network != nil))
+	errs.Add("pid", validation.ValidateNamespaceMode("pid", container.PidMode))
+	errs.Add("ipc", validation.ValidateNamespaceMode("ipc", container.IpcMode))
+
+	// Validate autostart configuration
+	if container.StartOrder < 0 {
+		errs.Add("start_order", fmt.Errorf("start order must be non-negative"))
+	}
+	if container.StartDelay < 0 {
+		errs.Add("start_delay", fmt.Errorf("start delay must be non-negative"))
+	}
+
+	return errs.ErrorOrNil()
 }
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"sort"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+)
+
+// VolumeSources returns the host paths services mount through
+// storage.mounts, sorted and without duplicates. lxc-compose has no named
+// volumes, so these are the volumes a project declares. The docker-compose
+// style volumes list is ignored, as it is by up.
+func VolumeSources(services map[string]common.Container) []string {
+	seen := make(map[string]bool)
+	var sources []string
+	for _, svc := range services {
+		if svc.Storage == nil {
+			continue
+		}
+		for _, m := range svc.Storage.Mounts {
+			if m.Source == "" || seen[m.Source] {
+				continue
+			}
+			seen[m.Source] = true
+			sources = append(sources, m.Source)
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
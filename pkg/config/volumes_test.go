@@ -0,0 +1,33 @@
+package config_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+)
+
+func TestVolumeSources(t *testing.T) {
+	services := map[string]common.Container{
+		"web": {Storage: &common.StorageConfig{Mounts: []common.Mount{
+			{Source: "/srv/www", Target: "/var/www", Type: "bind"},
+			{Source: "/srv/shared", Target: "/shared", Type: "bind"},
+		}}},
+		"db": {Storage: &common.StorageConfig{Mounts: []common.Mount{
+			{Source: "/srv/shared", Target: "/shared", Type: "bind"},
+			{Source: "/srv/db", Target: "/var/lib/postgresql", Type: "bind"},
+		}}},
+		"cache": {Volumes: []string{"cache:/data"}},
+	}
+
+	got := config.VolumeSources(services)
+	want := []string{"/srv/db", "/srv/shared", "/srv/www"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("VolumeSources() = %v, want %v", got, want)
+	}
+
+	if got := config.VolumeSources(map[string]common.Container{"web": {}}); len(got) != 0 {
+		t.Errorf("expected no volumes, got %v", got)
+	}
+}
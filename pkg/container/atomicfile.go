@@ -0,0 +1,67 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// atomicFile is a temporary file next to its destination that replaces it on
+// Commit, so a crash or error partway through writing leaves the previous
+// file intact
+type atomicFile struct {
+	*os.File
+	path      string
+	perm      os.FileMode
+	committed bool
+}
+
+// createAtomic starts writing a file that replaces path on Commit. Abort,
+// usually deferred, removes the temporary file unless it was committed.
+func createAtomic(path string, perm os.FileMode) (*atomicFile, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: f, path: path, perm: perm}, nil
+}
+
+// Commit flushes the file to disk and renames it over the destination
+func (f *atomicFile) Commit() error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Chmod(f.perm); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		return err
+	}
+	f.committed = true
+	return nil
+}
+
+// Abort discards the file if it wasn't committed
+func (f *atomicFile) Abort() {
+	if f.committed {
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// writeFileAtomic is os.WriteFile through an atomicFile
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := createAtomic(path, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Commit()
+}
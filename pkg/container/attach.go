@@ -0,0 +1,78 @@
+package container
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
+
+// AttachOptions represents options for an interactive session in a container
+type AttachOptions struct {
+	Command []string // Defaults to the container user's login shell
+	Console bool     // Attach to the container console with lxc-console instead of lxc-attach
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+// Attach starts an interactive session in a running container
+func (m *LXCManager) Attach(name string, opts AttachOptions) error {
+	container, err := m.lookup(name)
+	if err != nil {
+		return fmt.Errorf("failed to get container: %w", err)
+	}
+
+	if container.State != "RUNNING" {
+		return fmt.Errorf("container '%s' is not running (current state: %s), start it before attaching", name, container.State)
+	}
+
+	var args []string
+	command := "lxc-attach"
+	if opts.Console {
+		command = "lxc-console"
+		args = []string{"-n", name, "-t", "0"}
+	} else {
+		args = []string{"-n", name}
+		if len(opts.Command) > 0 {
+			args = append(args, "--")
+			args = append(args, opts.Command...)
+		}
+	}
+
+	cmd := m.lxcPathCommand(command, args...)
+	cmd.Stdin = opts.Stdin
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+
+	restore, err := makeRawTerminal(opts.Stdin)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("session in container '%s' failed: %w", name, err)
+	}
+
+	return nil
+}
+
+// makeRawTerminal puts stdin into raw mode if it is a local terminal, so
+// keystrokes pass straight through, and returns a function restoring it
+func makeRawTerminal(stdin io.Reader) (func(), error) {
+	f, ok := stdin.(*os.File)
+	if !ok || !isTerminal(int(f.Fd())) {
+		return func() {}, nil
+	}
+	restore, err := makeRaw(int(f.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set terminal to raw mode: %w", err)
+	}
+	return func() {
+		if err := restore(); err != nil {
+			logging.Warn("Failed to restore terminal", "error", err)
+		}
+	}, nil
+}
@@ -0,0 +1,79 @@
+package container_test
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestAttach(t *testing.T) {
+	containerName := "test-container-attach"
+	configPath := t.TempDir()
+
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(configPath)
+	testing_internal.AssertNoError(t, err)
+
+	err = manager.Create(containerName, &common.Container{})
+	testing_internal.AssertNoError(t, err)
+	err = mockCmd.AddContainer(containerName, "STOPPED")
+	testing_internal.AssertNoError(t, err)
+
+	t.Run("not_running", func(t *testing.T) {
+		err := manager.Attach(containerName, container.AttachOptions{})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "not running")
+	})
+
+	err = manager.Start(containerName)
+	testing_internal.AssertNoError(t, err)
+
+	tests := []struct {
+		name     string
+		opts     container.AttachOptions
+		wantCmd  string
+		wantArgs string
+	}{
+		{
+			name:     "default_shell",
+			opts:     container.AttachOptions{},
+			wantCmd:  "lxc-attach",
+			wantArgs: "-n " + containerName,
+		},
+		{
+			name:     "custom_command",
+			opts:     container.AttachOptions{Command: []string{"/bin/bash", "-l"}},
+			wantCmd:  "lxc-attach",
+			wantArgs: "-n " + containerName + " -- /bin/bash -l",
+		},
+		{
+			name:     "console",
+			opts:     container.AttachOptions{Console: true},
+			wantCmd:  "lxc-console",
+			wantArgs: "-n " + containerName + " -t 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, restore := mock.RecordCalls(&container.ExecCommand, "lxc-attach", "lxc-console")
+			defer restore()
+			for _, name := range []string{"lxc-attach", "lxc-console"} {
+				calls.Stub(name, func([]string) *exec.Cmd { return exec.Command("true") })
+			}
+
+			err := manager.Attach(containerName, tt.opts)
+			testing_internal.AssertNoError(t, err)
+			got := calls.Calls()
+			testing_internal.AssertEqual(t, 1, len(got))
+			testing_internal.AssertEqual(t, tt.wantCmd, got[0].Name)
+			testing_internal.AssertEqual(t, tt.wantArgs, got[0].String())
+		})
+	}
+}
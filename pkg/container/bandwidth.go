@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
 )
 
@@ -62,6 +63,50 @@ func (m *LXCManager) SetNetworkBandwidthLimit(name string, limit NetworkBandwidt
 
 // GetNetworkBandwidthLimits gets current bandwidth limits for a container's network interface
 func (m *LXCManager) GetNetworkBandwidthLimits(name, iface string) (*common.BandwidthLimit, error) {
+	limit, err := m.readBandwidthLimits(name, iface)
+	if err != nil {
+		return nil, err
+	}
+	if limit == nil {
+		return nil, fmt.Errorf("no bandwidth limits found for container %s interface %s", name, iface)
+	}
+	return limit, nil
+}
+
+// bandwidthLimits returns the bandwidth limits of a running container's
+// configured interfaces, keyed by interface name. Interfaces without limits,
+// or whose limits can't be read, are left out and nil is returned if none
+// have any.
+func (m *LXCManager) bandwidthLimits(name string, cfg *config.Container) map[string]*common.BandwidthLimit {
+	if cfg == nil {
+		return nil
+	}
+
+	var limits map[string]*common.BandwidthLimit
+	for i, iface := range networkInterfaces(cfg.Network.ToCommonNetworkConfig()) {
+		dev := iface.Interface
+		if dev == "" {
+			dev = fmt.Sprintf("eth%d", i)
+		}
+		limit, err := m.readBandwidthLimits(name, dev)
+		if err != nil {
+			logging.Debug("Failed to read bandwidth limits", "container", name, "interface", dev, "error", err)
+			continue
+		}
+		if limit == nil {
+			continue
+		}
+		if limits == nil {
+			limits = make(map[string]*common.BandwidthLimit)
+		}
+		limits[dev] = limit
+	}
+	return limits
+}
+
+// readBandwidthLimits reads the tc classes of a container's interface, returning
+// nil if it has no rate limits
+func (m *LXCManager) readBandwidthLimits(name, iface string) (*common.BandwidthLimit, error) {
 	// Read tc class info using lxc-attach
 	args := []string{"-n", name, "--", "tc", "class", "show", "dev", iface}
 	cmdStr := fmt.Sprintf("lxc-attach %s", strings.Join(args, " "))
@@ -71,7 +116,7 @@ func (m *LXCManager) GetNetworkBandwidthLimits(name, iface string) (*common.Band
 		"args", strings.Join(args, " "),
 		"full_command", cmdStr)
 
-	cmd := ExecCommand("lxc-attach", args...)
+	cmd := m.lxcPathCommand("lxc-attach", args...)
 	var output bytes.Buffer
 	cmd.Stdout = &output
 	cmd.Stderr = &output // Also capture stderr for debugging
@@ -122,7 +167,7 @@ func (m *LXCManager) GetNetworkBandwidthLimits(name, iface string) (*common.Band
 			"container", name,
 			"interface", iface,
 			"output", output.String())
-		return nil, fmt.Errorf("no bandwidth limits found for container %s interface %s", name, iface)
+		return nil, nil
 	}
 
 	logging.Debug("Get network bandwidth limits",
@@ -146,7 +191,7 @@ func (m *LXCManager) UpdateNetworkBandwidthLimits(name, iface string, limits *co
 	}
 
 	// Generate traffic control commands to update limits
-	cmd := ExecCommand("lxc-attach", "-n", name, "--",
+	cmd := m.lxcPathCommand("lxc-attach", "-n", name, "--",
 		"tc", "class", "replace", "dev", iface,
 		"parent", "1:", "classid", "1:10",
 		"htb", "rate", limits.IngressRate,
@@ -156,7 +201,7 @@ func (m *LXCManager) UpdateNetworkBandwidthLimits(name, iface string, limits *co
 		return fmt.Errorf("failed to update ingress bandwidth limit: %w", err)
 	}
 
-	cmd = ExecCommand("lxc-attach", "-n", name, "--",
+	cmd = m.lxcPathCommand("lxc-attach", "-n", name, "--",
 		"tc", "class", "replace", "dev", iface,
 		"parent", "1:", "classid", "1:20",
 		"htb", "rate", limits.EgressRate,
@@ -2,6 +2,7 @@ package container_test
 
 import (
 	"fmt"
+	"os/exec"
 	"testing"
 
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
@@ -101,3 +102,58 @@ func TestNetworkBandwidthControl(t *testing.T) {
 		testing_internal.AssertEqual(t, "2mbit", limits.EgressBurst)
 	})
 }
+
+func TestGetBandwidthLimits(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+
+	tcOutput := map[string]string{
+		"eth0": "class htb 1:10 root prio 0 rate 1Mbit burst 2Mbit\n" +
+			"class htb 1:20 root prio 0 rate 500Kbit burst 1Mbit\n",
+		"eth1": "",
+	}
+	calls, restore := mock.RecordCalls(&container.ExecCommand, "lxc-attach")
+	defer restore()
+	calls.Stub("lxc-attach", func(args []string) *exec.Cmd {
+		if len(args) < 4 || args[3] != "tc" {
+			return nil
+		}
+		out, ok := tcOutput[args[len(args)-1]]
+		if !ok {
+			return exec.Command("false")
+		}
+		return exec.Command("printf", "%s", out)
+	})
+
+	testing_internal.AssertNoError(t, manager.Create("web", &common.Container{
+		Image: "ubuntu:20.04",
+		Network: &common.NetworkConfig{Interfaces: []common.NetworkInterface{
+			{Type: "veth", Bridge: "br0"},
+			{Type: "veth", Bridge: "br1", Interface: "eth1"},
+			{Type: "veth", Bridge: "br2", Interface: "missing0"},
+		}},
+	}))
+	testing_internal.AssertNoError(t, mockCmd.AddContainer("web", "STOPPED"))
+
+	t.Run("stopped", func(t *testing.T) {
+		c, err := manager.Get("web")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(c.Bandwidth))
+	})
+
+	t.Run("running", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Start("web"))
+
+		c, err := manager.Get("web")
+		testing_internal.AssertNoError(t, err)
+		// eth1 has no limits and missing0 can't be read, neither is reported
+		testing_internal.AssertEqual(t, 1, len(c.Bandwidth))
+		want := common.BandwidthLimit{IngressRate: "1mbit", IngressBurst: "2mbit", EgressRate: "500kbit", EgressBurst: "1mbit"}
+		if got := c.Bandwidth["eth0"]; got == nil || *got != want {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	})
+}
@@ -0,0 +1,281 @@
+package container
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
+
+// EnsureBridge makes sure the host bridge for a compose network exists. A
+// missing bridge is created, given the network's gateway address and brought
+// up when create is set, and reported as an error otherwise.
+func EnsureBridge(bridge string, def common.NetworkDefinition, create bool) error {
+	if bridgeExists(bridge) {
+		return nil
+	}
+	if !create {
+		return fmt.Errorf("bridge %s does not exist (use --create-networks to create it)", bridge)
+	}
+
+	logging.Info("Creating network bridge", "bridge", bridge, "subnet", def.Subnet)
+
+	cmds := [][]string{{"ip", "link", "add", "name", bridge, "type", "bridge"}}
+	if def.Gateway != "" {
+		_, subnet, err := net.ParseCIDR(def.Subnet)
+		if err != nil {
+			return fmt.Errorf("invalid subnet %q: %w", def.Subnet, err)
+		}
+		ones, _ := subnet.Mask.Size()
+		cmds = append(cmds, []string{"ip", "addr", "add", fmt.Sprintf("%s/%d", def.Gateway, ones), "dev", bridge})
+	}
+	cmds = append(cmds, []string{"ip", "link", "set", "dev", bridge, "up"})
+
+	for _, args := range cmds {
+		if err := runHostCommand(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefaultBridgeSubnet is the subnet LXC's own lxcbr0 uses
+const DefaultBridgeSubnet = "10.0.3.0/24"
+
+// EnsureNATBridge sets up a bridge the way LXC's lxc-net does for lxcbr0, for
+// hosts that don't have one: the bridge gets the first address of subnet,
+// traffic leaving the subnet is masqueraded and a dnsmasq hands out the rest
+// of the subnet over DHCP. The bridge is recorded as owned in runDir, where
+// dnsmasq also keeps its pid and lease files. A bridge that already exists and
+// wasn't set up here is left to whatever manages it, e.g. lxc-net. Each step
+// is skipped if already done, so it is safe to run again.
+func EnsureNATBridge(bridge, subnet, runDir string) error {
+	gateway, first, last, err := natBridgeAddresses(subnet)
+	if err != nil {
+		return err
+	}
+
+	owned, err := ownedBridgeSubnet(bridge, runDir)
+	if err != nil {
+		return err
+	}
+	if owned != "" && owned != subnet {
+		return fmt.Errorf("bridge %s was set up with subnet %s, remove it first to change it", bridge, owned)
+	}
+
+	if !bridgeExists(bridge) {
+		def := common.NetworkDefinition{Subnet: subnet, Gateway: gateway}
+		if err := EnsureBridge(bridge, def, true); err != nil {
+			return err
+		}
+		if err := recordOwnedBridge(bridge, subnet, runDir); err != nil {
+			return err
+		}
+	} else if owned == "" {
+		logging.Info("Bridge already exists and was not set up by lxc-compose, leaving it as is", "bridge", bridge)
+		return nil
+	}
+
+	if err := runHostCommand("sysctl", "-w", "net.ipv4.ip_forward=1"); err != nil {
+		return err
+	}
+	rule := masqueradeRule(subnet)
+	if ExecCommand("iptables", append([]string{"-t", "nat", "-C"}, rule...)...).Run() != nil {
+		logging.Info("Enabling NAT for bridge", "bridge", bridge, "subnet", subnet)
+		if err := runHostCommand(append([]string{"iptables", "-t", "nat", "-A"}, rule...)...); err != nil {
+			return err
+		}
+	}
+
+	pidFile, leaseFile := dnsmasqFiles(bridge, runDir)
+	if dnsmasqPid(pidFile) != 0 {
+		return nil
+	}
+	logging.Info("Starting dnsmasq for bridge", "bridge", bridge, "range", first+"-"+last)
+	return runHostCommand("dnsmasq",
+		"--strict-order",
+		"--bind-interfaces",
+		"--pid-file="+pidFile,
+		"--dhcp-leasefile="+leaseFile,
+		"--listen-address="+gateway,
+		"--dhcp-range="+first+","+last,
+		"--dhcp-no-override",
+		"--dhcp-authoritative",
+		"--except-interface=lo",
+		"--interface="+bridge,
+		"--conf-file=",
+	)
+}
+
+// RemoveNATBridge undoes EnsureNATBridge for a bridge it set up: it stops the
+// bridge's dnsmasq, drops the NAT rule for the recorded subnet and deletes the
+// bridge. Bridges EnsureNATBridge didn't create are left alone, and steps
+// with nothing left to undo are skipped. IP forwarding is left on, other
+// bridges may still need it.
+func RemoveNATBridge(bridge, runDir string) error {
+	subnet, err := ownedBridgeSubnet(bridge, runDir)
+	if err != nil {
+		return err
+	}
+	if subnet == "" {
+		logging.Warn("Bridge was not set up by lxc-compose, leaving it as is", "bridge", bridge)
+		return nil
+	}
+
+	pidFile, leaseFile := dnsmasqFiles(bridge, runDir)
+	if pid := dnsmasqPid(pidFile); pid != 0 {
+		logging.Info("Stopping dnsmasq for bridge", "bridge", bridge, "pid", pid)
+		if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+			return fmt.Errorf("failed to stop dnsmasq: %w", err)
+		}
+	}
+	for _, file := range []string{pidFile, leaseFile} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", file, err)
+		}
+	}
+
+	rule := masqueradeRule(subnet)
+	if ExecCommand("iptables", append([]string{"-t", "nat", "-C"}, rule...)...).Run() == nil {
+		if err := runHostCommand(append([]string{"iptables", "-t", "nat", "-D"}, rule...)...); err != nil {
+			return err
+		}
+	}
+
+	if bridgeExists(bridge) {
+		logging.Info("Removing network bridge", "bridge", bridge)
+		if err := runHostCommand("ip", "link", "del", "dev", bridge); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(ownedBridgeFile(bridge, runDir)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", ownedBridgeFile(bridge, runDir), err)
+	}
+	return nil
+}
+
+// ownedBridgeFile records the subnet of a bridge EnsureNATBridge created
+func ownedBridgeFile(bridge, runDir string) string {
+	return filepath.Join(runDir, "bridge-"+bridge+".subnet")
+}
+
+// ownedBridgeSubnet returns the subnet EnsureNATBridge set bridge up with, or
+// "" if it didn't create it
+func ownedBridgeSubnet(bridge, runDir string) (string, error) {
+	data, err := os.ReadFile(ownedBridgeFile(bridge, runDir))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read bridge ownership: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// recordOwnedBridge records that EnsureNATBridge created bridge with subnet
+func recordOwnedBridge(bridge, subnet, runDir string) error {
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		return fmt.Errorf("failed to create run directory: %w", err)
+	}
+	if err := os.WriteFile(ownedBridgeFile(bridge, runDir), []byte(subnet+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to record bridge ownership: %w", err)
+	}
+	return nil
+}
+
+// natBridgeAddresses returns the gateway of an IPv4 subnet, its first
+// address, and the DHCP range after it up to the last host address
+func natBridgeAddresses(subnet string) (gateway, first, last string, err error) {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid subnet %q: %w", subnet, err)
+	}
+	ip := ipNet.IP.To4()
+	ones, bits := ipNet.Mask.Size()
+	if ip == nil || bits-ones < 2 {
+		return "", "", "", fmt.Errorf("invalid subnet %q: need an IPv4 subnet of /30 or larger", subnet)
+	}
+
+	base := uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
+	size := uint32(1) << uint(bits-ones)
+	addr := func(n uint32) string {
+		v := base + n
+		return net.IPv4(byte(v>>24), byte(v>>16), byte(v>>8), byte(v)).String()
+	}
+	return addr(1), addr(2), addr(size - 2), nil
+}
+
+// masqueradeRule is the POSTROUTING rule that NATs traffic from subnet to
+// anywhere outside it
+func masqueradeRule(subnet string) []string {
+	return []string{"POSTROUTING", "-s", subnet, "!", "-d", subnet, "-j", "MASQUERADE"}
+}
+
+// dnsmasqFiles returns the pid and lease files of a bridge's dnsmasq
+func dnsmasqFiles(bridge, runDir string) (pidFile, leaseFile string) {
+	return filepath.Join(runDir, "dnsmasq-"+bridge+".pid"), filepath.Join(runDir, "dnsmasq-"+bridge+".leases")
+}
+
+// dnsmasqPid returns the pid of the dnsmasq recorded in pidFile if it is
+// still running, and 0 otherwise
+func dnsmasqPid(pidFile string) int {
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 || syscall.Kill(pid, 0) != nil {
+		return 0
+	}
+	return pid
+}
+
+// runHostCommand runs a command on the host, returning its output with the
+// error if it fails
+func runHostCommand(args ...string) error {
+	if output, err := ExecCommand(args[0], args[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to run '%s': %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// bridgeExists reports whether a link with the given name exists on the host
+func bridgeExists(bridge string) bool {
+	return ExecCommand("ip", "link", "show", "dev", bridge).Run() == nil
+}
+
+// checkBridges makes sure every bridge a container's bridge or veth
+// interfaces link to exists on the host, since lxc-start only reports a
+// missing one as a generic network setup failure
+func checkBridges(cfg *config.NetworkConfig) error {
+	if cfg == nil || cfg.Isolated {
+		return nil
+	}
+
+	interfaces := cfg.Interfaces
+	if cfg.Type != "" {
+		interfaces = append([]config.NetworkInterface{{Type: cfg.Type, Bridge: cfg.Bridge}}, interfaces...)
+	}
+
+	for _, iface := range interfaces {
+		switch strings.ToLower(iface.Type) {
+		case "", "veth", "bridge":
+		default:
+			continue
+		}
+		if iface.Bridge == "" || bridgeExists(iface.Bridge) {
+			continue
+		}
+		return fmt.Errorf("bridge %s does not exist (create it with 'ip link add name %s type bridge', "+
+			"run up --ensure-bridge %s to set it up with NAT and DHCP, "+
+			"or declare it under networks and run up --create-networks)", iface.Bridge, iface.Bridge, iface.Bridge)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package container_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestEnsureBridge(t *testing.T) {
+	exists := false
+	calls, restore := mock.RecordCalls(&container.ExecCommand, "ip")
+	defer restore()
+	calls.Stub("ip", func(args []string) *exec.Cmd {
+		if strings.HasPrefix(strings.Join(args, " "), "link show") && !exists {
+			return exec.Command("false")
+		}
+		return exec.Command("true")
+	})
+
+	def := common.NetworkDefinition{Subnet: "10.0.3.0/24", Gateway: "10.0.3.1"}
+
+	t.Run("exists", func(t *testing.T) {
+		calls.Reset()
+		exists = true
+		testing_internal.AssertNoError(t, container.EnsureBridge("br-app", def, false))
+		testing_internal.AssertEqual(t, 1, len(calls.Calls()))
+	})
+
+	t.Run("missing_without_create", func(t *testing.T) {
+		calls.Reset()
+		exists = false
+		err := container.EnsureBridge("br-app", def, false)
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "--create-networks")
+	})
+
+	t.Run("create", func(t *testing.T) {
+		calls.Reset()
+		exists = false
+		testing_internal.AssertNoError(t, container.EnsureBridge("br-app", def, true))
+		testing_internal.AssertEqual(t, strings.Join([]string{
+			"ip link show dev br-app",
+			"ip link add name br-app type bridge",
+			"ip addr add 10.0.3.1/24 dev br-app",
+			"ip link set dev br-app up",
+		}, "\n"), strings.Join(calls.Commands(), "\n"))
+	})
+}
+
+func TestNATBridge(t *testing.T) {
+	exists, hasRule := false, false
+	tools := []string{"ip", "sysctl", "iptables", "dnsmasq"}
+	calls, restore := mock.RecordCalls(&container.ExecCommand, tools...)
+	defer restore()
+	for _, tool := range tools {
+		tool := tool
+		calls.Stub(tool, func(args []string) *exec.Cmd {
+			cmd := tool + " " + strings.Join(args, " ")
+			switch {
+			case strings.HasPrefix(cmd, "ip link show") && !exists,
+				strings.HasPrefix(cmd, "iptables -t nat -C") && !hasRule:
+				return exec.Command("false")
+			}
+			return exec.Command("true")
+		})
+	}
+
+	runDir := t.TempDir()
+	pidFile := filepath.Join(runDir, "dnsmasq-lxcbr0.pid")
+	rule := "POSTROUTING -s 10.0.3.0/24 ! -d 10.0.3.0/24 -j MASQUERADE"
+
+	t.Run("ensure", func(t *testing.T) {
+		calls.Reset()
+		exists, hasRule = false, false
+		testing_internal.AssertNoError(t, container.EnsureNATBridge("lxcbr0", container.DefaultBridgeSubnet, runDir))
+		testing_internal.AssertEqual(t, strings.Join([]string{
+			"ip link show dev lxcbr0",
+			"ip link show dev lxcbr0",
+			"ip link add name lxcbr0 type bridge",
+			"ip addr add 10.0.3.1/24 dev lxcbr0",
+			"ip link set dev lxcbr0 up",
+			"sysctl -w net.ipv4.ip_forward=1",
+			"iptables -t nat -C " + rule,
+			"iptables -t nat -A " + rule,
+			"dnsmasq --strict-order --bind-interfaces --pid-file=" + pidFile +
+				" --dhcp-leasefile=" + filepath.Join(runDir, "dnsmasq-lxcbr0.leases") +
+				" --listen-address=10.0.3.1 --dhcp-range=10.0.3.2,10.0.3.254 --dhcp-no-override" +
+				" --dhcp-authoritative --except-interface=lo --interface=lxcbr0 --conf-file=",
+		}, "\n"), strings.Join(calls.Commands(), "\n"))
+	})
+
+	t.Run("ensure_idempotent", func(t *testing.T) {
+		calls.Reset()
+		exists, hasRule = true, true
+		testing_internal.AssertNoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())), 0644))
+		defer os.Remove(pidFile)
+
+		testing_internal.AssertNoError(t, container.EnsureNATBridge("lxcbr0", container.DefaultBridgeSubnet, runDir))
+		testing_internal.AssertEqual(t, strings.Join([]string{
+			"ip link show dev lxcbr0",
+			"sysctl -w net.ipv4.ip_forward=1",
+			"iptables -t nat -C " + rule,
+		}, "\n"), strings.Join(calls.Commands(), "\n"))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		calls.Reset()
+		exists, hasRule = true, true
+		dnsmasq := exec.Command("sleep", "30")
+		testing_internal.AssertNoError(t, dnsmasq.Start())
+		testing_internal.AssertNoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(dnsmasq.Process.Pid)), 0644))
+
+		testing_internal.AssertNoError(t, container.RemoveNATBridge("lxcbr0", runDir))
+		testing_internal.AssertError(t, dnsmasq.Wait())
+		if _, err := os.Stat(pidFile); !os.IsNotExist(err) {
+			t.Errorf("expected pid file to be removed, got %v", err)
+		}
+		testing_internal.AssertEqual(t, strings.Join([]string{
+			"iptables -t nat -C " + rule,
+			"iptables -t nat -D " + rule,
+			"ip link show dev lxcbr0",
+			"ip link del dev lxcbr0",
+		}, "\n"), strings.Join(calls.Commands(), "\n"))
+	})
+
+	t.Run("remove_idempotent", func(t *testing.T) {
+		calls.Reset()
+		exists, hasRule = false, false
+		testing_internal.AssertNoError(t, container.RemoveNATBridge("lxcbr0", runDir))
+		testing_internal.AssertEqual(t, 0, len(calls.Calls()))
+	})
+
+	t.Run("existing_bridge_left_alone", func(t *testing.T) {
+		// e.g. lxcbr0 run by lxc-net, which has its own dnsmasq
+		calls.Reset()
+		exists, hasRule = true, false
+		testing_internal.AssertNoError(t, container.EnsureNATBridge("lxcbr0", container.DefaultBridgeSubnet, runDir))
+		testing_internal.AssertEqual(t, "ip link show dev lxcbr0", strings.Join(calls.Commands(), "\n"))
+
+		calls.Reset()
+		testing_internal.AssertNoError(t, container.RemoveNATBridge("lxcbr0", runDir))
+		testing_internal.AssertEqual(t, 0, len(calls.Calls()))
+	})
+
+	t.Run("subnet_changed", func(t *testing.T) {
+		calls.Reset()
+		exists, hasRule = false, false
+		testing_internal.AssertNoError(t, container.EnsureNATBridge("natbr0", "10.0.4.0/24", runDir))
+
+		exists = true
+		err := container.EnsureNATBridge("natbr0", container.DefaultBridgeSubnet, runDir)
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "was set up with subnet 10.0.4.0/24")
+	})
+
+	t.Run("invalid_subnet", func(t *testing.T) {
+		for _, subnet := range []string{"10.0.3.0", "fd00::/64", "10.0.3.0/31"} {
+			err := container.EnsureNATBridge("lxcbr0", subnet, runDir)
+			testing_internal.AssertError(t, err)
+			testing_internal.AssertContains(t, err.Error(), "invalid subnet")
+		}
+	})
+}
+
+func TestStartChecksBridges(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+
+	bridges := map[string]bool{"br0": true}
+	calls, restore := mock.RecordCalls(&container.ExecCommand, "ip")
+	defer restore()
+	calls.Stub("ip", func(args []string) *exec.Cmd {
+		if args[0] != "link" || args[1] != "show" {
+			return nil
+		}
+		if !bridges[args[3]] {
+			return exec.Command("false")
+		}
+		return exec.Command("true")
+	})
+
+	create := func(t *testing.T, name string, network *common.NetworkConfig) {
+		t.Helper()
+		testing_internal.AssertNoError(t, manager.Create(name, &common.Container{Image: "ubuntu:20.04", Network: network}))
+		testing_internal.AssertNoError(t, mockCmd.AddContainer(name, "STOPPED"))
+	}
+
+	t.Run("existing_bridge", func(t *testing.T) {
+		create(t, "ok", &common.NetworkConfig{Type: "veth", Bridge: "br0", DHCP: true})
+		testing_internal.AssertNoError(t, manager.Start("ok"))
+	})
+
+	t.Run("missing_interface_bridge", func(t *testing.T) {
+		create(t, "missing", &common.NetworkConfig{Interfaces: []common.NetworkInterface{
+			{Type: "veth", Bridge: "br0", DHCP: true},
+			{Type: "bridge", Bridge: "br1", DHCP: true},
+		}})
+		err := manager.Start("missing")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "bridge br1 does not exist")
+
+		c, err := manager.Get("missing")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "STOPPED", c.State)
+	})
+
+	t.Run("macvlan_not_checked", func(t *testing.T) {
+		create(t, "macvlan", &common.NetworkConfig{Interfaces: []common.NetworkInterface{
+			{Type: "macvlan", Bridge: "eth9", DHCP: true},
+		}})
+		testing_internal.AssertNoError(t, manager.Start("macvlan"))
+	})
+}
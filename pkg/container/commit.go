@@ -0,0 +1,234 @@
+package container
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/oci"
+)
+
+// imageConfig is the subset of the OCI image config written by Commit
+type imageConfig struct {
+	Architecture string         `json:"architecture"`
+	OS           string         `json:"os"`
+	Created      time.Time      `json:"created"`
+	Config       imageRunConfig `json:"config"`
+	RootFS       imageRootFS    `json:"rootfs"`
+	History      []imageHistory `json:"history,omitempty"`
+}
+
+type imageRunConfig struct {
+	Env        []string          `json:"Env,omitempty"`
+	Entrypoint []string          `json:"Entrypoint,omitempty"`
+	Cmd        []string          `json:"Cmd,omitempty"`
+	Labels     map[string]string `json:"Labels,omitempty"`
+}
+
+type imageRootFS struct {
+	Type    string   `json:"type"`
+	DiffIDs []string `json:"diff_ids"`
+}
+
+type imageHistory struct {
+	Created   time.Time `json:"created"`
+	CreatedBy string    `json:"created_by"`
+}
+
+// Commit captures a container's rootfs as a single-layer image stored under
+// ref, like docker commit. A running container is frozen while its rootfs is
+// read and thawed afterwards. The image keeps the container's environment,
+// entrypoint, command and labels.
+func (m *LXCManager) Commit(name string, ref oci.ImageReference) error {
+	if m.images == nil {
+		return fmt.Errorf("no image store configured")
+	}
+
+	container, err := m.lookup(name)
+	if err != nil {
+		return fmt.Errorf("failed to get container: %w", err)
+	}
+
+	var layer []byte
+	err = m.whileFrozen(name, container.State, "committing", func() error {
+		rootfs, err := m.containerRootfs(name)
+		if err != nil {
+			return err
+		}
+		if layer, err = archiveRootfs(rootfs); err != nil {
+			return fmt.Errorf("failed to archive rootfs: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg := imageConfig{
+		Architecture: runtime.GOARCH,
+		OS:           "linux",
+		Created:      time.Now().UTC(),
+		History:      []imageHistory{{Created: time.Now().UTC(), CreatedBy: "lxc-compose commit " + name}},
+	}
+	if c := container.Config; c != nil {
+		for key, value := range c.Environment {
+			cfg.Config.Env = append(cfg.Config.Env, key+"="+value)
+		}
+		cfg.Config.Entrypoint = c.Entrypoint
+		cfg.Config.Cmd = c.Command
+		cfg.Config.Labels = c.Labels
+	}
+
+	archive, err := imageArchive(ref, cfg, layer)
+	if err != nil {
+		return fmt.Errorf("failed to build image: %w", err)
+	}
+	if err := m.images.Commit(ref, archive, name); err != nil {
+		return fmt.Errorf("failed to store image: %w", err)
+	}
+
+	logging.Info("Committed container", "name", name, "image", ref.String(), "size", len(archive))
+	return nil
+}
+
+// whileFrozen runs fn with the container frozen if it is running, thawing it
+// afterwards. Stopped and frozen containers are left as they are, other states
+// are rejected, naming action in the error.
+func (m *LXCManager) whileFrozen(name, state, action string, fn func() error) error {
+	switch state {
+	case "STOPPED", "FROZEN":
+		return fn()
+	case "RUNNING":
+		if err := m.execLXCCommand("lxc-freeze", "-n", name); err != nil {
+			return fmt.Errorf("failed to freeze container: %w", err)
+		}
+		defer func() {
+			if err := m.execLXCCommand("lxc-unfreeze", "-n", name); err != nil {
+				logging.Error("Failed to thaw container", "name", name, "error", err)
+			}
+		}()
+		return fn()
+	default:
+		return fmt.Errorf("container '%s' is not in a valid state for %s (current state: %s)", name, action, state)
+	}
+}
+
+// archiveRootfs writes a rootfs to an uncompressed layer tarball with paths
+// relative to its root. Symlinks are stored as links, not followed.
+func archiveRootfs(rootfs string) ([]byte, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	err := filepath.WalkDir(rootfs, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(rootfs, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case info.IsDir(), info.Mode().IsRegular():
+		default:
+			// Device nodes, sockets and fifos are recreated by LXC
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// imageArchive wraps a layer and its config in a docker save archive
+func imageArchive(ref oci.ImageReference, cfg imageConfig, layer []byte) ([]byte, error) {
+	layerSum := sha256.Sum256(layer)
+	layerID := hex.EncodeToString(layerSum[:])
+	cfg.RootFS = imageRootFS{Type: "layers", DiffIDs: []string{"sha256:" + layerID}}
+
+	configData, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	configSum := sha256.Sum256(configData)
+	configName := hex.EncodeToString(configSum[:]) + ".json"
+
+	manifest, err := json.Marshal([]imageManifest{{
+		Config:   configName,
+		RepoTags: []string{ref.Repository + ":" + ref.Tag},
+		Layers:   []string{layerID + "/layer.tar"},
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{layerID + "/layer.tar", layer},
+		{configName, configData},
+		{"manifest.json", manifest},
+	}
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.data)), ModTime: cfg.Created}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
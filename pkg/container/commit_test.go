@@ -0,0 +1,85 @@
+package container_test
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/oci"
+)
+
+func TestCommit(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	calls, restore := mock.RecordCalls(&container.ExecCommand, "lxc-freeze", "lxc-unfreeze")
+	defer restore()
+
+	dir := t.TempDir()
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+
+	store := newFakeImageStore(map[string][]byte{
+		"base:1": buildImage(t, []layerEntry{
+			{name: "etc/", typeflag: tar.TypeDir},
+			{name: "etc/os-release", body: "base"},
+		}),
+	})
+	manager.SetImageStore(store)
+
+	testing_internal.AssertNoError(t, manager.Create("src", &common.Container{Image: "base:1"}))
+	testing_internal.AssertNoError(t, mockCmd.AddContainer("src", "STOPPED"))
+	rootfs := filepath.Join(dir, "src", "rootfs")
+	testing_internal.AssertNoError(t, os.MkdirAll(filepath.Join(rootfs, "srv"), 0755))
+	testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(rootfs, "srv", "data"), []byte("state"), 0600))
+	testing_internal.AssertNoError(t, os.Symlink("/srv/data", filepath.Join(rootfs, "data")))
+
+	ref, err := oci.ParseImageReference("snapshot:v1")
+	testing_internal.AssertNoError(t, err)
+
+	t.Run("running_is_frozen", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Start("src"))
+		calls.Reset()
+		testing_internal.AssertNoError(t, manager.Commit("src", ref))
+		got := calls.Calls()
+		testing_internal.AssertEqual(t, 2, len(got))
+		testing_internal.AssertEqual(t, "lxc-freeze", got[0].Name)
+		testing_internal.AssertEqual(t, "lxc-unfreeze", got[1].Name)
+		testing_internal.AssertEqual(t, "src", store.sources[ref.String()])
+	})
+
+	t.Run("stopped_is_not_frozen", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Stop("src"))
+		calls.Reset()
+		testing_internal.AssertNoError(t, manager.Commit("src", ref))
+		testing_internal.AssertEqual(t, 0, len(calls.Calls()))
+	})
+
+	t.Run("round_trip", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Create("copy", &common.Container{Image: ref.String()}))
+		copyRootfs := filepath.Join(dir, "copy", "rootfs")
+
+		data, err := os.ReadFile(filepath.Join(copyRootfs, "srv", "data"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "state", string(data))
+		data, err = os.ReadFile(filepath.Join(copyRootfs, "etc", "os-release"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "base", string(data))
+
+		link, err := os.Readlink(filepath.Join(copyRootfs, "data"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "/srv/data", link)
+		info, err := os.Stat(filepath.Join(copyRootfs, "srv", "data"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("missing_container", func(t *testing.T) {
+		testing_internal.AssertError(t, manager.Commit("nope", ref))
+	})
+}
@@ -4,9 +4,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
 )
 
 // applyConfig applies the container configuration
@@ -18,49 +23,156 @@ func (m *LXCManager) applyConfig(name string, cfg *common.Container) error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	f, err := os.Create(configPath)
+	// Written to a temporary file first so a failure never leaves a partial config
+	f, err := createAtomic(configPath, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to create config file: %w", err)
 	}
-	defer f.Close()
+	defer f.Abort()
+
+	// Included configs come first so the generated keys below override them
+	for _, path := range cfg.IncludeConfigs {
+		if err := writeConfig(f.File, "lxc.include", path); err != nil {
+			return err
+		}
+	}
 
 	// Write base configuration
-	if err := writeConfig(f, "lxc.uts.name", name); err != nil {
+	if err := writeConfig(f.File, "lxc.uts.name", name); err != nil {
+		return err
+	}
+
+	if err := m.applyStopSignalConfig(f.File, cfg.StopSignal); err != nil {
+		return err
+	}
+
+	// Apply autostart configuration
+	if err := m.applyAutoStartConfig(f.File, cfg); err != nil {
+		return err
+	}
+
+	// Apply console logging configuration
+	if err := m.applyLoggingConfig(f.File, name, cfg.Logging); err != nil {
 		return err
 	}
 
 	// Apply security configuration
-	if err := m.applySecurityConfig(f, cfg.Security); err != nil {
+	if err := m.applySecurityConfig(f.File, cfg.ResolvedSecurity()); err != nil {
 		return err
 	}
 
 	// Apply resource limits
-	if err := m.applyCPUConfig(f, cfg.CPU); err != nil {
+	if err := m.applyCPUConfig(f.File, cfg.CPU); err != nil {
+		return err
+	}
+	if err := m.applyMemoryConfig(f.File, cfg.Memory); err != nil {
 		return err
 	}
-	if err := m.applyMemoryConfig(f, cfg.Memory); err != nil {
+
+	if err := m.applyUlimitConfig(f.File, cfg.Ulimits); err != nil {
+		return err
+	}
+
+	if err := m.applySysctlConfig(f.File, name, cfg.Sysctls); err != nil {
 		return err
 	}
 
 	// Apply network configuration
-	if err := m.applyNetworkConfig(f, cfg.Network); err != nil {
+	if err := m.applyNetworkModeConfig(f.File, cfg.NetworkMode); err != nil {
+		return err
+	}
+	if err := m.applyNetworkConfig(f.File, name, cfg.Network); err != nil {
+		return err
+	}
+	if err := m.applyNamespaceConfig(f.File, cfg.PidMode, cfg.IpcMode); err != nil {
 		return err
 	}
 
 	// Apply storage configuration
-	if err := m.applyStorageConfig(f, cfg.Storage); err != nil {
+	if err := m.applyStorageConfig(f.File, name, cfg.Storage); err != nil {
 		return err
 	}
 
 	// Apply environment variables and entrypoint configuration
-	if err := m.applyEnvironmentConfig(f, cfg.Environment); err != nil {
+	if err := m.applyEnvironmentConfig(f.File, cfg.Environment); err != nil {
+		return err
+	}
+
+	if err := m.applyInitConfig(f.File, cfg); err != nil {
+		return err
+	}
+
+	if err := m.applyDevConfig(f.File, cfg); err != nil {
+		return err
+	}
+
+	if err := m.applyDeviceConfig(f.File, cfg.Devices); err != nil {
+		return err
+	}
+
+	if err := f.Commit(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+func (m *LXCManager) applyStopSignalConfig(f *os.File, signal string) error {
+	// Default to SIGTERM when unset
+	if signal == "" {
+		signal = "SIGTERM"
+	}
+
+	n, err := validation.ParseSignal(signal)
+	if err != nil {
+		return fmt.Errorf("invalid stop signal: %w", err)
+	}
+
+	return writeConfig(f, "lxc.signal.stop", fmt.Sprintf("%d", n))
+}
+
+func (m *LXCManager) applyAutoStartConfig(f *os.File, cfg *common.Container) error {
+	if !cfg.AutoStart {
+		return nil
+	}
+
+	if err := writeConfig(f, "lxc.start.auto", "1"); err != nil {
 		return err
 	}
 
-	if err := m.applyEntrypointConfig(f, cfg.Entrypoint, cfg.Command); err != nil {
+	if cfg.StartOrder > 0 {
+		if err := writeConfig(f, "lxc.start.order", fmt.Sprintf("%d", cfg.StartOrder)); err != nil {
+			return err
+		}
+	}
+
+	if cfg.StartDelay > 0 {
+		if err := writeConfig(f, "lxc.start.delay", fmt.Sprintf("%d", cfg.StartDelay)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyLoggingConfig always sends the console to a log file, so GetLogs has
+// the container's boot and application output to show
+func (m *LXCManager) applyLoggingConfig(f *os.File, name string, cfg *common.LoggingConfig) error {
+	logPath := m.consoleLogPath(name)
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	if err := writeConfig(f, "lxc.console.logfile", logPath); err != nil {
 		return err
 	}
 
+	// The monitor rotates the log, see RotateLogs. lxc.console.size is left
+	// out, LXC would truncate the log at that size before it is rotated.
+	if cfg != nil && cfg.MaxSize != "" {
+		if _, err := config.ValidateStorageSize(cfg.MaxSize); err != nil {
+			return fmt.Errorf("invalid logging max size: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -93,6 +205,27 @@ func (m *LXCManager) applyCPUConfig(f *os.File, cfg *common.CPUConfig) error {
 		}
 	}
 
+	if m.cgroupVersion == 2 {
+		for _, limit := range cgroup2CPULimits(cfg) {
+			if err := writeConfig(f, "lxc.cgroup2."+limit[0], limit[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if cfg.CPUSet != "" {
+		if err := writeConfig(f, "lxc.cgroup.cpuset.cpus", cfg.CPUSet); err != nil {
+			return err
+		}
+	}
+
+	if cfg.MemoryNodes != "" {
+		if err := writeConfig(f, "lxc.cgroup.cpuset.mems", cfg.MemoryNodes); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -101,6 +234,19 @@ func (m *LXCManager) applyMemoryConfig(f *os.File, cfg *common.MemoryConfig) err
 		return nil
 	}
 
+	if m.cgroupVersion == 2 {
+		limits, err := cgroup2MemoryLimits(cfg)
+		if err != nil {
+			return err
+		}
+		for _, limit := range limits {
+			if err := writeConfig(f, "lxc.cgroup2."+limit[0], limit[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	if cfg.Limit != "" {
 		if err := writeConfig(f, "lxc.cgroup.memory.limit_in_bytes", cfg.Limit); err != nil {
 			return err
@@ -113,10 +259,188 @@ func (m *LXCManager) applyMemoryConfig(f *os.File, cfg *common.MemoryConfig) err
 		}
 	}
 
+	if cfg.Reserve != "" {
+		if err := writeConfig(f, "lxc.cgroup.memory.soft_limit_in_bytes", cfg.Reserve); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Swappiness != nil {
+		if err := writeConfig(f, "lxc.cgroup.memory.swappiness", fmt.Sprintf("%d", *cfg.Swappiness)); err != nil {
+			return err
+		}
+	}
+
+	if cfg.OOMKillDisable {
+		if err := writeConfig(f, "lxc.cgroup.memory.oom_control", "1"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cgroup2CPULimits translates CPU settings to cgroup2 controller files. Shares
+// map onto cpu.weight, scaled from the v1 range 2-262144 to 1-10000, and the
+// CFS quota and period are combined in cpu.max.
+func cgroup2CPULimits(cfg *common.CPUConfig) [][2]string {
+	limits := make([][2]string, 0)
+	if cfg.Shares != nil && *cfg.Shares > 0 {
+		limits = append(limits, [2]string{"cpu.weight", fmt.Sprintf("%d", cpuWeight(*cfg.Shares))})
+	}
+	if cfg.Period != nil && *cfg.Period > 0 {
+		quota := "max"
+		if cfg.Quota != nil && *cfg.Quota > 0 {
+			quota = fmt.Sprintf("%d", *cfg.Quota)
+		}
+		limits = append(limits, [2]string{"cpu.max", fmt.Sprintf("%s %d", quota, *cfg.Period)})
+	}
+	if cfg.CPUSet != "" {
+		limits = append(limits, [2]string{"cpuset.cpus", cfg.CPUSet})
+	}
+	if cfg.MemoryNodes != "" {
+		limits = append(limits, [2]string{"cpuset.mems", cfg.MemoryNodes})
+	}
+	return limits
+}
+
+// cpuWeight converts cgroup v1 cpu.shares to a cgroup2 cpu.weight
+func cpuWeight(shares int64) int64 {
+	if shares < 2 {
+		shares = 2
+	}
+	if shares > 262144 {
+		shares = 262144
+	}
+	return 1 + (shares-2)*9999/262142
+}
+
+// cgroup2MemoryLimits translates memory settings to cgroup2 controller files,
+// in bytes. cgroup2 limits swap on its own rather than memory plus swap, so
+// memory.swap.max is the swap setting less the memory limit. Swappiness and
+// the OOM killer switch have no cgroup2 equivalent and are skipped.
+func cgroup2MemoryLimits(cfg *common.MemoryConfig) ([][2]string, error) {
+	limits := make([][2]string, 0)
+	var limit int64
+	if cfg.Limit != "" {
+		size, err := validation.ValidateStorageSize(cfg.Limit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory limit: %w", err)
+		}
+		limit = size
+		limits = append(limits, [2]string{"memory.max", fmt.Sprintf("%d", limit)})
+	}
+	if cfg.Swap != "" {
+		size, err := validation.ValidateStorageSize(cfg.Swap)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory swap: %w", err)
+		}
+		if limit > 0 {
+			limits = append(limits, [2]string{"memory.swap.max", fmt.Sprintf("%d", size-limit)})
+		} else {
+			logging.Warn("Memory swap without a memory limit is not supported on cgroup2, ignoring it", "swap", cfg.Swap)
+		}
+	}
+	if cfg.Reserve != "" {
+		size, err := validation.ValidateStorageSize(cfg.Reserve)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory reserve: %w", err)
+		}
+		limits = append(limits, [2]string{"memory.low", fmt.Sprintf("%d", size)})
+	}
+	if cfg.Swappiness != nil {
+		logging.Warn("Memory swappiness is not supported on cgroup2, ignoring it", "swappiness", *cfg.Swappiness)
+	}
+	if cfg.OOMKillDisable {
+		logging.Warn("Disabling the OOM killer is not supported on cgroup2, ignoring it")
+	}
+	return limits, nil
+}
+
+// applyUlimitConfig writes process resource limits, sorted by name
+func (m *LXCManager) applyUlimitConfig(f *os.File, ulimits map[string]common.Ulimit) error {
+	names := make([]string, 0, len(ulimits))
+	for name := range ulimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		u := ulimits[name]
+		if err := writeConfig(f, "lxc.prlimit."+name, fmt.Sprintf("%d:%d", u.Soft, u.Hard)); err != nil {
+			return fmt.Errorf("failed to set ulimit %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// applySysctlConfig writes kernel parameters, sorted by name. Sysctls outside
+// the container's namespaces are written anyway but warned about, since they
+// either fail at start in an unprivileged container or change the host.
+func (m *LXCManager) applySysctlConfig(f *os.File, name string, sysctls map[string]string) error {
+	keys := make([]string, 0, len(sysctls))
+	for key := range sysctls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !validation.IsNamespacedSysctl(key) {
+			logging.Warn("Sysctl is not namespaced and may affect the host",
+				"container", name,
+				"sysctl", key,
+			)
+		}
+		if err := writeConfig(f, "lxc.sysctl."+key, sysctls[key]); err != nil {
+			return fmt.Errorf("failed to set sysctl %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// applyNetworkModeConfig joins the network namespace of the container named
+// by a network_mode of container:<name> instead of creating one
+func (m *LXCManager) applyNetworkModeConfig(f *os.File, mode string) error {
+	target, err := validation.ParseNetworkMode(mode)
+	if err != nil || target == "" {
+		return err
+	}
+	if err := writeConfig(f, "lxc.net.0.type", "none"); err != nil {
+		return fmt.Errorf("failed to set network mode: %w", err)
+	}
+	if err := writeConfig(f, "lxc.namespace.share.net", target); err != nil {
+		return fmt.Errorf("failed to set network mode: %w", err)
+	}
+	return nil
+}
+
+// applyNamespaceConfig keeps the host's pid and ipc namespaces or joins
+// another container's, as set by the pid and ipc modes
+func (m *LXCManager) applyNamespaceConfig(f *os.File, pidMode, ipcMode string) error {
+	var keep []string
+	for _, ns := range []struct{ kind, mode string }{{"pid", pidMode}, {"ipc", ipcMode}} {
+		host, target, err := validation.ParseNamespaceMode(ns.kind, ns.mode)
+		if err != nil {
+			return err
+		}
+		if host {
+			keep = append(keep, ns.kind)
+		}
+		if target != "" {
+			if err := writeConfig(f, "lxc.namespace.share."+ns.kind, target); err != nil {
+				return fmt.Errorf("failed to set %s mode: %w", ns.kind, err)
+			}
+		}
+	}
+	if len(keep) > 0 {
+		if err := writeConfig(f, "lxc.namespace.keep", strings.Join(keep, " ")); err != nil {
+			return fmt.Errorf("failed to set namespace modes: %w", err)
+		}
+	}
 	return nil
 }
 
-func (m *LXCManager) applyNetworkConfig(f *os.File, cfg *common.NetworkConfig) error {
+func (m *LXCManager) applyNetworkConfig(f *os.File, name string, cfg *common.NetworkConfig) error {
 	if cfg == nil {
 		return nil
 	}
@@ -185,9 +509,16 @@ func (m *LXCManager) applyNetworkConfig(f *os.File, cfg *common.NetworkConfig) e
 		}
 	}
 
-	// Write MAC address if specified
-	if cfg.MAC != "" {
-		if err := writeConfig(f, "lxc.net.0.hwaddr", cfg.MAC); err != nil {
+	// Write MAC address, generating a stable one if unset
+	mac := cfg.MAC
+	if mac == "" && !cfg.DisableAutoMAC && name != "" {
+		var err error
+		if mac, err = generateMAC(name, 0, cfg.Type); err != nil {
+			return err
+		}
+	}
+	if mac != "" {
+		if err := writeConfig(f, "lxc.net.0.hwaddr", mac); err != nil {
 			return err
 		}
 	}
@@ -244,8 +575,15 @@ func (m *LXCManager) applyNetworkConfig(f *os.File, cfg *common.NetworkConfig) e
 				}
 			}
 
-			if iface.MAC != "" {
-				if err := writeConfig(f, prefix+".hwaddr", iface.MAC); err != nil {
+			mac := iface.MAC
+			if mac == "" && !cfg.DisableAutoMAC && name != "" {
+				var err error
+				if mac, err = generateMAC(name, i, iface.Type); err != nil {
+					return err
+				}
+			}
+			if mac != "" {
+				if err := writeConfig(f, prefix+".hwaddr", mac); err != nil {
 					return err
 				}
 			}
@@ -274,11 +612,19 @@ func (m *LXCManager) applyNetworkConfig(f *os.File, cfg *common.NetworkConfig) e
 	return nil
 }
 
-func (m *LXCManager) applyStorageConfig(f *os.File, cfg *common.StorageConfig) error {
+func (m *LXCManager) applyStorageConfig(f *os.File, name string, cfg *common.StorageConfig) error {
 	if cfg == nil {
 		return nil
 	}
 
+	// LXC mounts a logical volume rootfs itself, the others are mounted on
+	// the default rootfs directory
+	if cfg.Backend == "lvm" && name != "" {
+		if err := writeConfig(f, "lxc.rootfs.path", "lvm:"+lvmDevice(cfg.Pool, name)); err != nil {
+			return err
+		}
+	}
+
 	// Apply root storage configuration
 	if cfg.Root != "" {
 		if err := writeConfig(f, "lxc.rootfs.size", cfg.Root); err != nil {
@@ -328,6 +674,25 @@ func (m *LXCManager) applyStorageConfig(f *os.File, cfg *common.StorageConfig) e
 		}
 	}
 
+	// Apply tmpfs mounts
+	for _, mount := range cfg.TmpfsMounts {
+		var options []string
+		if mount.Size != "" {
+			options = append(options, "size="+mount.Size)
+		}
+		if mount.Mode != "" {
+			options = append(options, "mode="+mount.Mode)
+		}
+		if len(options) == 0 {
+			options = []string{"defaults"}
+		}
+
+		value := fmt.Sprintf("tmpfs %s tmpfs %s 0 0", mount.Target, strings.Join(options, ","))
+		if err := writeConfig(f, "lxc.mount.entry", value); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -367,7 +732,7 @@ func (m *LXCManager) applySecurityConfig(f *os.File, cfg *common.SecurityConfig)
 			if err := writeConfig(f, "lxc.cap.drop", "all"); err != nil {
 				return err
 			}
-			if err := writeConfig(f, "lxc.cap.keep", strings.Join(cfg.Capabilities, " ")); err != nil {
+			if err := writeConfig(f, "lxc.cap.keep", strings.Join(validation.NormalizeCapabilities(cfg.Capabilities), " ")); err != nil {
 				return err
 			}
 		}
@@ -396,6 +761,62 @@ func (m *LXCManager) applyEnvironmentConfig(f *os.File, env map[string]string) e
 	return nil
 }
 
+// applyInitConfig configures PID 1: a full init system boots /sbin/init,
+// otherwise the entrypoint and command are run directly
+func (m *LXCManager) applyInitConfig(f *os.File, cfg *common.Container) error {
+	var settings [][2]string
+	switch strings.ToLower(cfg.Init) {
+	case common.InitSystemd:
+		settings = [][2]string{
+			{"lxc.init.cmd", "/sbin/init"},
+			{"lxc.mount.auto", "proc:mixed sys:mixed cgroup:mixed"},
+			// systemd shuts down cleanly on SIGRTMIN+3, not SIGPWR
+			{"lxc.signal.halt", "SIGRTMIN+3"},
+		}
+	case common.InitSysVInit:
+		settings = [][2]string{
+			{"lxc.init.cmd", "/sbin/init"},
+			{"lxc.signal.halt", "SIGPWR"},
+		}
+	default:
+		return m.applyEntrypointConfig(f, cfg.Entrypoint, cfg.Command)
+	}
+
+	for _, s := range settings {
+		if err := writeConfig(f, s[0], s[1]); err != nil {
+			return fmt.Errorf("failed to set init configuration: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyDevConfig writes lxc.autodev and lxc.tty.max. Unless set, autodev is
+// turned on for systemd, which expects a populated /dev, and left to LXC
+// otherwise; the tty count is left to LXC when unset.
+func (m *LXCManager) applyDevConfig(f *os.File, cfg *common.Container) error {
+	autodev := cfg.AutoDev
+	if autodev == nil && strings.EqualFold(cfg.Init, common.InitSystemd) {
+		on := true
+		autodev = &on
+	}
+	if autodev != nil {
+		value := "0"
+		if *autodev {
+			value = "1"
+		}
+		if err := writeConfig(f, "lxc.autodev", value); err != nil {
+			return fmt.Errorf("failed to set autodev: %w", err)
+		}
+	}
+
+	if cfg.TTYs != nil {
+		if err := writeConfig(f, "lxc.tty.max", fmt.Sprintf("%d", *cfg.TTYs)); err != nil {
+			return fmt.Errorf("failed to set tty count: %w", err)
+		}
+	}
+	return nil
+}
+
 func (m *LXCManager) applyEntrypointConfig(f *os.File, entrypoint, command []string) error {
 	// If neither entrypoint nor command is set, return
 	if len(entrypoint) == 0 && len(command) == 0 {
@@ -422,39 +843,336 @@ exec %s
 	return nil
 }
 
+// applyLiveResourceLimits applies cgroup limits to a running container
+func (m *LXCManager) applyLiveResourceLimits(name string, cfg *common.Container) error {
+	if m.cgroupVersion == 2 {
+		return m.applyLiveCgroup2Limits(name, cfg)
+	}
+
+	limits := make([][2]string, 0)
+
+	if cfg.CPU != nil {
+		if cfg.CPU.Shares != nil && *cfg.CPU.Shares > 0 {
+			limits = append(limits, [2]string{"cpu.shares", fmt.Sprintf("%d", *cfg.CPU.Shares)})
+		}
+		if cfg.CPU.Quota != nil && *cfg.CPU.Quota > 0 {
+			limits = append(limits, [2]string{"cpu.cfs_quota_us", fmt.Sprintf("%d", *cfg.CPU.Quota)})
+		}
+		if cfg.CPU.Period != nil && *cfg.CPU.Period > 0 {
+			limits = append(limits, [2]string{"cpu.cfs_period_us", fmt.Sprintf("%d", *cfg.CPU.Period)})
+		}
+		if cfg.CPU.CPUSet != "" {
+			limits = append(limits, [2]string{"cpuset.cpus", cfg.CPU.CPUSet})
+		}
+		if cfg.CPU.MemoryNodes != "" {
+			limits = append(limits, [2]string{"cpuset.mems", cfg.CPU.MemoryNodes})
+		}
+	}
+
+	if cfg.Memory != nil {
+		if cfg.Memory.Limit != "" {
+			limits = append(limits, [2]string{"memory.limit_in_bytes", cfg.Memory.Limit})
+		}
+		if cfg.Memory.Swap != "" {
+			limits = append(limits, [2]string{"memory.memsw.limit_in_bytes", cfg.Memory.Swap})
+		}
+		if cfg.Memory.Swappiness != nil {
+			limits = append(limits, [2]string{"memory.swappiness", fmt.Sprintf("%d", *cfg.Memory.Swappiness)})
+		}
+		if cfg.Memory.OOMKillDisable {
+			limits = append(limits, [2]string{"memory.oom_control", "1"})
+		}
+	}
+
+	return m.setLiveCgroupLimits(name, limits)
+}
+
+// applyLiveCgroup2Limits applies the cgroup2 equivalents of a container's
+// resource limits to it while it runs
+func (m *LXCManager) applyLiveCgroup2Limits(name string, cfg *common.Container) error {
+	limits := make([][2]string, 0)
+	if cfg.CPU != nil {
+		limits = append(limits, cgroup2CPULimits(cfg.CPU)...)
+	}
+	if cfg.Memory != nil {
+		memory, err := cgroup2MemoryLimits(cfg.Memory)
+		if err != nil {
+			return err
+		}
+		limits = append(limits, memory...)
+	}
+	return m.setLiveCgroupLimits(name, limits)
+}
+
+// setLiveCgroupLimits writes cgroup controller files of a running container
+// with lxc-cgroup
+func (m *LXCManager) setLiveCgroupLimits(name string, limits [][2]string) error {
+	for _, limit := range limits {
+		if err := m.execLXCCommand("lxc-cgroup", "-n", name, limit[0], limit[1]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", limit[0], err)
+		}
+		logging.Debug("Applied live cgroup limit",
+			"container", name,
+			"key", limit[0],
+			"value", limit[1],
+		)
+	}
+
+	return nil
+}
+
+// resolvedSecurity returns a stored config's security settings with its
+// top-level privileged flag applied, see common.Container.ResolvedSecurity
+func resolvedSecurity(c *config.Container) *common.SecurityConfig {
+	container := common.Container{Security: c.Security.ToCommonSecurityConfig(), Privileged: c.Privileged}
+	return container.ResolvedSecurity()
+}
+
+// warnRestartRequired logs a warning for each changed setting that only takes effect after a restart
+func warnRestartRequired(name string, current, updated *config.Container) {
+	if current == nil || updated == nil {
+		return
+	}
+
+	changed := map[string]bool{
+		"network":     !reflect.DeepEqual(current.Network, updated.Network),
+		"storage":     !reflect.DeepEqual(current.Storage, updated.Storage),
+		"security":    !reflect.DeepEqual(resolvedSecurity(current), resolvedSecurity(updated)),
+		"environment": !reflect.DeepEqual(current.Environment, updated.Environment),
+		"command":     !reflect.DeepEqual(current.Command, updated.Command),
+		"entrypoint":  !reflect.DeepEqual(current.Entrypoint, updated.Entrypoint),
+		"init":        current.Init != updated.Init,
+		"devices":     !reflect.DeepEqual(current.Devices, updated.Devices),
+		"logging":     !reflect.DeepEqual(current.Logging, updated.Logging),
+		"ulimits":     !reflect.DeepEqual(current.Ulimits, updated.Ulimits),
+		"sysctls":     !reflect.DeepEqual(current.Sysctls, updated.Sysctls),
+	}
+
+	for _, setting := range []string{"network", "storage", "security", "environment", "command", "entrypoint", "init", "devices", "logging", "ulimits", "sysctls"} {
+		if changed[setting] {
+			logging.Warn("Setting changed on a running container and requires a restart to take effect",
+				"container", name,
+				"setting", setting,
+			)
+		}
+	}
+}
+
 func writeConfig(f *os.File, key, value string) error {
 	_, err := fmt.Fprintf(f, "%s = %s\n", key, value)
 	return err
 }
 
+// ValidateConfig checks a service configuration the way Create and Update do,
+// reporting every failure as validation.ValidationErrors located by yaml
+// field name, so a caller can check all services before creating any
+func ValidateConfig(container *common.Container) error {
+	return validateContainerConfig(container)
+}
+
 func validateContainerConfig(container *common.Container) error {
+	var errs validation.ValidationErrors
+
+	if container.Privileged != nil && container.Security != nil {
+		if err := validation.ValidatePrivilegedShortcut(*container.Privileged, container.Security.Isolation, container.Security.Privileged); err != nil {
+			errs.Add("privileged", fmt.Errorf("invalid security configuration: %w", err))
+		}
+	}
+	if sec := container.ResolvedSecurity(); sec != nil {
+		if err := validation.ValidateCapabilityConflicts(sec.Isolation, sec.Privileged, sec.Capabilities); err != nil {
+			errs.Add("security", fmt.Errorf("invalid security configuration: %w", err))
+		}
+	}
+
 	// Validate network configuration
 	if container.Network != nil {
 		if container.Network.Type != "" && container.Network.Type != "bridge" && container.Network.Type != "veth" {
-			return fmt.Errorf("invalid network type: %s", container.Network.Type)
-		}
-
-		networkCfg := &common.NetworkConfig{
-			Type:      container.Network.Type,
-			Bridge:    container.Network.Bridge,
-			Interface: container.Network.Interface,
-			IP:        container.Network.IP,
-			Gateway:   container.Network.Gateway,
-			DNS:       container.Network.DNS,
-			DHCP:      container.Network.DHCP,
-			Hostname:  container.Network.Hostname,
-			MTU:       container.Network.MTU,
-			MAC:       container.Network.MAC,
-		}
-		err := common.ValidateNetworkConfig(networkCfg)
-		if err != nil {
-			return fmt.Errorf("invalid network configuration: %w", err)
+			errs.Add("network.type", fmt.Errorf("invalid network type: %s", container.Network.Type))
+		} else {
+			networkCfg := &common.NetworkConfig{
+				Type:      container.Network.Type,
+				Bridge:    container.Network.Bridge,
+				Interface: container.Network.Interface,
+				IP:        container.Network.IP,
+				Gateway:   container.Network.Gateway,
+				DNS:       container.Network.DNS,
+				DHCP:      container.Network.DHCP,
+				Hostname:  container.Network.Hostname,
+				MTU:       container.Network.MTU,
+				MAC:       container.Network.MAC,
+			}
+			if err := common.ValidateNetworkConfig(networkCfg); err != nil {
+				errs.Add("network", fmt.Errorf("invalid network configuration: %w", err))
+			}
+		}
+		if err := validation.ValidateDNSOptions(container.Network.DNSOptions); err != nil {
+			errs.Add("network.dns_options", fmt.Errorf("invalid network configuration: %w", err))
 		}
 	}
 
-	// ... existing code ...
+	// Validate storage backend and tmpfs mounts
+	if container.Storage != nil {
+		if err := validation.ValidateStorageBackend(container.Storage.Backend, container.Storage.Pool); err != nil {
+			errs.Add("storage", fmt.Errorf("invalid storage configuration: %w", err))
+		}
+		for i, mount := range container.Storage.TmpfsMounts {
+			if err := validation.ValidateTmpfsMount(mount); err != nil {
+				errs.Add(fmt.Sprintf("storage.tmpfs[%d]", i), fmt.Errorf("invalid tmpfs mount: %w", err))
+			}
+		}
+	}
 
-	return nil
+	// Validate init system
+	if err := validation.ValidateInit(container.Init, len(container.Command) > 0 || len(container.Entrypoint) > 0); err != nil {
+		errs.Add("init", fmt.Errorf("invalid init configuration: %w", err))
+	}
+	if container.TTYs != nil {
+		if err := validation.ValidateTTYs(*container.TTYs); err != nil {
+			errs.Add("ttys", fmt.Errorf("invalid init configuration: %w", err))
+		}
+	}
+
+	// Validate restart policy
+	if err := validation.ValidateRestartPolicy(container.Restart); err != nil {
+		errs.Add("restart", fmt.Errorf("invalid restart policy: %w", err))
+	}
+
+	if err := validation.ValidateTimezone(container.Timezone); err != nil {
+		errs.Add("timezone", fmt.Errorf("invalid timezone configuration: %w", err))
+	}
+
+	for i, path := range container.IncludeConfigs {
+		if err := validation.ValidateIncludeConfig(path); err != nil {
+			errs.Add(fmt.Sprintf("include_configs[%d]", i), fmt.Errorf("invalid include config: %w", err))
+		}
+	}
+
+	// Validate CPU limits and pinning
+	if cpu := container.CPU; cpu != nil {
+		cores := 0
+		if cpu.Cores != nil {
+			cores = *cpu.Cores
+		}
+		if err := validation.ValidateCPULimits(cores, int64Value(cpu.Shares), int64Value(cpu.Quota), int64Value(cpu.Period)); err != nil {
+			errs.Add("cpu", fmt.Errorf("invalid cpu configuration: %w", err))
+		}
+		if err := validation.ValidateCPUSet(cpu.CPUSet); err != nil {
+			errs.Add("cpu.cpuset", fmt.Errorf("invalid cpuset: %w", err))
+		}
+		if err := validation.ValidateCPUSet(cpu.MemoryNodes); err != nil {
+			errs.Add("cpu.memory_nodes", fmt.Errorf("invalid memory nodes: %w", err))
+		}
+	}
+
+	// Validate memory limits and tuning
+	if mem := container.Memory; mem != nil {
+		if err := validation.ValidateMemoryLimits(mem.Limit, mem.Swap, mem.Reserve); err != nil {
+			errs.Add("memory", fmt.Errorf("invalid memory configuration: %w", err))
+		}
+		if mem.Swappiness != nil {
+			if err := validation.ValidateSwappiness(*mem.Swappiness); err != nil {
+				errs.Add("memory.swappiness", fmt.Errorf("invalid memory configuration: %w", err))
+			}
+		}
+	}
+
+	// Validate logging configuration
+	if container.Logging != nil {
+		if container.Logging.MaxSize != "" {
+			size, err := config.ValidateStorageSize(container.Logging.MaxSize)
+			if err != nil {
+				errs.Add("logging.max_size", fmt.Errorf("invalid logging max size: %w", err))
+			} else if size <= 0 {
+				errs.Add("logging.max_size", fmt.Errorf("logging max size must be positive"))
+			}
+		}
+		if container.Logging.MaxFiles < 0 {
+			errs.Add("logging.max_files", fmt.Errorf("logging max files must be non-negative"))
+		}
+	}
+
+	// Validate service-level ports
+	for i, pf := range container.Ports {
+		if err := validation.ValidatePortForward(&validation.PortForward{
+			Protocol: pf.Protocol,
+			Host:     pf.Host,
+			Guest:    pf.Guest,
+		}); err != nil {
+			errs.Add(fmt.Sprintf("ports[%d]", i), fmt.Errorf("invalid port %d: %w", i, err))
+		}
+	}
+	if len(container.Ports) > 0 && container.Network == nil {
+		errs.Add("ports", fmt.Errorf("ports require a network configuration"))
+	}
+	if err := validation.ValidateNetworkMode(container.NetworkMode, container.Network != nil); err != nil {
+		errs.Add("network_mode", fmt.Errorf("invalid network mode: %w", err))
+	}
+	if err := validation.ValidateNamespaceMode("pid", container.PidMode); err != nil {
+		errs.Add("pid", fmt.Errorf("invalid pid mode: %w", err))
+	}
+	if err := validation.ValidateNamespaceMode("ipc", container.IpcMode); err != nil {
+		errs.Add("ipc", fmt.Errorf("invalid ipc mode: %w", err))
+	}
+
+	// Validate stop signal
+	if container.StopSignal != "" {
+		if err := validation.ValidateSignal(container.StopSignal); err != nil {
+			errs.Add("stop_signal", fmt.Errorf("invalid stop signal: %w", err))
+		}
+	}
+	if container.StopGracePeriod != "" {
+		if _, err := validation.ParseStopGracePeriod(container.StopGracePeriod); err != nil {
+			errs.Add("stop_grace_period", err)
+		}
+	}
+
+	// Validate process resource limits, sorted by name
+	ulimits := make([]string, 0, len(container.Ulimits))
+	for name := range container.Ulimits {
+		ulimits = append(ulimits, name)
+	}
+	sort.Strings(ulimits)
+	for _, name := range ulimits {
+		u := container.Ulimits[name]
+		if err := validation.ValidateUlimit(name, u.Soft, u.Hard); err != nil {
+			errs.Add("ulimits."+name, fmt.Errorf("invalid ulimits: %w", err))
+		}
+	}
+
+	// Validate kernel parameters, sorted by name
+	sysctls := make([]string, 0, len(container.Sysctls))
+	for key := range container.Sysctls {
+		sysctls = append(sysctls, key)
+	}
+	sort.Strings(sysctls)
+	for _, key := range sysctls {
+		if err := validation.ValidateSysctl(key, container.Sysctls[key]); err != nil {
+			errs.Add("sysctls."+key, fmt.Errorf("invalid sysctls: %w", err))
+		}
+	}
+
+	// Validate health check
+	if err := validation.ValidateHealthCheck(container.HealthCheck); err != nil {
+		errs.Add("healthcheck", fmt.Errorf("invalid health check: %w", err))
+	}
+
+	// Validate autostart configuration
+	if container.StartOrder < 0 {
+		errs.Add("start_order", fmt.Errorf("start order must be non-negative"))
+	}
+	if container.StartDelay < 0 {
+		errs.Add("start_delay", fmt.Errorf("start delay must be non-negative"))
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// int64Value returns the value p points to, zero if it is nil
+func int64Value(p *int64) int64 {
+	if p == nil {
+		return 0
+	}
+	return *p
 }
 
 // ApplyConfig applies the container configuration
@@ -472,14 +1190,18 @@ func (m *LXCManager) ApplyMemoryConfig(f *os.File, cfg *common.MemoryConfig) err
 	return m.applyMemoryConfig(f, cfg)
 }
 
-// ApplyNetworkConfig applies network configuration to the container
+// ApplyNetworkConfig applies network configuration to the container. MAC
+// addresses are generated from the container name, so interfaces without one
+// only get a generated address from ApplyConfig.
 func (m *LXCManager) ApplyNetworkConfig(f *os.File, cfg *common.NetworkConfig) error {
-	return m.applyNetworkConfig(f, cfg)
+	return m.applyNetworkConfig(f, "", cfg)
 }
 
-// ApplyStorageConfig applies storage configuration to the container
+// ApplyStorageConfig applies storage configuration to the container. The
+// rootfs path of the lvm backend names the container and is only written by
+// ApplyConfig.
 func (m *LXCManager) ApplyStorageConfig(f *os.File, cfg *common.StorageConfig) error {
-	return m.applyStorageConfig(f, cfg)
+	return m.applyStorageConfig(f, "", cfg)
 }
 
 // ApplySecurityConfig applies security configuration to the container
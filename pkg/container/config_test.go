@@ -1,12 +1,18 @@
 package container_test
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
 	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
 )
 
 func TestContainerConfiguration(t *testing.T) {
@@ -100,4 +106,644 @@ func TestContainerConfiguration(t *testing.T) {
 		err = manager.Create(containerName, commonCfg)
 		testing_internal.AssertError(t, err)
 	})
+	t.Run("dhcp_with_static_gateway", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{
+			Network: &common.NetworkConfig{
+				Type:    "bridge",
+				Bridge:  "br0",
+				DHCP:    true,
+				Gateway: "192.168.1.1",
+			},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "cannot specify gateway when DHCP is enabled")
+	})
+
+	t.Run("stop_signal", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.ApplyConfig(containerName, &common.Container{StopSignal: "SIGQUIT"})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.signal.stop = 3")
+	})
+
+	t.Run("memory_tuning", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+		manager.SetCgroupVersion(1)
+
+		swappiness := 10
+		err = manager.ApplyConfig(containerName, &common.Container{
+			Memory: &common.MemoryConfig{
+				Limit:          "1G",
+				Swappiness:     &swappiness,
+				OOMKillDisable: true,
+			},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.cgroup.memory.swappiness = 10")
+		testing_internal.AssertContains(t, string(data), "lxc.cgroup.memory.oom_control = 1")
+	})
+
+	t.Run("systemd_init", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.ApplyConfig(containerName, &common.Container{Init: common.InitSystemd})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.init.cmd = /sbin/init")
+		testing_internal.AssertContains(t, string(data), "lxc.signal.halt = SIGRTMIN+3")
+		testing_internal.AssertContains(t, string(data), "lxc.autodev = 1")
+		testing_internal.AssertNotContains(t, string(data), "init.sh")
+	})
+
+	t.Run("autodev_and_ttys", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		autodev, ttys := false, 4
+		err = manager.ApplyConfig(containerName, &common.Container{Init: common.InitSystemd, AutoDev: &autodev, TTYs: &ttys})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.autodev = 0")
+		testing_internal.AssertContains(t, string(data), "lxc.tty.max = 4")
+		testing_internal.AssertNotContains(t, string(data), "lxc.autodev = 1")
+
+		// Without init, both are left to LXC
+		err = manager.ApplyConfig(containerName, &common.Container{})
+		testing_internal.AssertNoError(t, err)
+		data, err = os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertNotContains(t, string(data), "lxc.autodev")
+		testing_internal.AssertNotContains(t, string(data), "lxc.tty.max")
+	})
+
+	t.Run("negative_ttys", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		ttys := -1
+		err = manager.Create(containerName, &common.Container{TTYs: &ttys})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "ttys must not be negative")
+	})
+
+	t.Run("init_with_command", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{
+			Init:    common.InitSystemd,
+			Command: []string{"nginx"},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "cannot be used with init")
+	})
+
+	t.Run("cpu_pinning", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+		manager.SetCgroupVersion(1)
+
+		err = manager.ApplyConfig(containerName, &common.Container{
+			CPU: &common.CPUConfig{
+				CPUSet:      "0-3,6",
+				MemoryNodes: "0",
+			},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.cgroup.cpuset.cpus = 0-3,6")
+		testing_internal.AssertContains(t, string(data), "lxc.cgroup.cpuset.mems = 0")
+	})
+
+	t.Run("cgroup2_limits", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+		manager.SetCgroupVersion(2)
+
+		shares, quota, period, swappiness := int64(1024), int64(50000), int64(100000), 10
+		err = manager.ApplyConfig(containerName, &common.Container{
+			CPU: &common.CPUConfig{
+				Shares:      &shares,
+				Quota:       &quota,
+				Period:      &period,
+				CPUSet:      "0-3,6",
+				MemoryNodes: "0",
+			},
+			Memory: &common.MemoryConfig{
+				Limit:      "1G",
+				Swap:       "1536M",
+				Reserve:    "256M",
+				Swappiness: &swappiness,
+			},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		for _, line := range []string{
+			"lxc.cgroup2.cpu.weight = 39",
+			"lxc.cgroup2.cpu.max = 50000 100000",
+			"lxc.cgroup2.cpuset.cpus = 0-3,6",
+			"lxc.cgroup2.cpuset.mems = 0",
+			"lxc.cgroup2.memory.max = 1073741824",
+			"lxc.cgroup2.memory.swap.max = 536870912",
+			"lxc.cgroup2.memory.low = 268435456",
+		} {
+			testing_internal.AssertContains(t, string(data), line)
+		}
+		testing_internal.AssertNotContains(t, string(data), "lxc.cgroup.")
+	})
+
+	t.Run("invalid_cpuset", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{
+			CPU: &common.CPUConfig{CPUSet: "3-1"},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "cpuset")
+	})
+
+	t.Run("invalid_swappiness", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		swappiness := 150
+		err = manager.Create(containerName, &common.Container{
+			Memory: &common.MemoryConfig{Swappiness: &swappiness},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "swappiness")
+	})
+
+	t.Run("invalid_resource_limits", func(t *testing.T) {
+		cores, quota, period := -1, int64(50000), int64(-1)
+		tests := []struct {
+			name        string
+			cfg         *common.Container
+			errContains string
+		}{
+			{name: "negative cores", cfg: &common.Container{CPU: &common.CPUConfig{Cores: &cores}}, errContains: "cores must be at least 1"},
+			{name: "quota without period", cfg: &common.Container{CPU: &common.CPUConfig{Quota: &quota}}, errContains: "requires a cpu period"},
+			{name: "negative period", cfg: &common.Container{CPU: &common.CPUConfig{Quota: &quota, Period: &period}}, errContains: "period must be positive"},
+			{name: "invalid memory", cfg: &common.Container{Memory: &common.MemoryConfig{Limit: "lots"}}, errContains: "invalid memory limit"},
+			{name: "invalid swap", cfg: &common.Container{Memory: &common.MemoryConfig{Limit: "1G", Swap: "x"}}, errContains: "invalid memory swap"},
+			{name: "reserve above limit", cfg: &common.Container{Memory: &common.MemoryConfig{Limit: "1G", Reserve: "2G"}}, errContains: "must not exceed"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				manager, err := container.NewLXCManager(t.TempDir())
+				testing_internal.AssertNoError(t, err)
+
+				err = manager.Create(containerName, tt.cfg)
+				testing_internal.AssertError(t, err)
+				testing_internal.AssertContains(t, err.Error(), tt.errContains)
+			})
+		}
+	})
+
+	t.Run("default_stop_signal", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.ApplyConfig(containerName, &common.Container{})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.signal.stop = 15")
+	})
+
+	t.Run("invalid_stop_signal", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		mock, cleanup := mock.SetupMockCommand(&execCommand)
+		defer cleanup()
+
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		mock.AddContainer(containerName, "STOPPED")
+		err = manager.Create(containerName, &common.Container{StopSignal: "SIGBOGUS"})
+		testing_internal.AssertError(t, err)
+	})
+	t.Run("autostart", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.ApplyConfig(containerName, &common.Container{
+			AutoStart:  true,
+			StartOrder: 2,
+			StartDelay: 10,
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.start.auto = 1")
+		testing_internal.AssertContains(t, string(data), "lxc.start.order = 2")
+		testing_internal.AssertContains(t, string(data), "lxc.start.delay = 10")
+	})
+
+	t.Run("ulimits", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.ApplyConfig(containerName, &common.Container{
+			Ulimits: map[string]common.Ulimit{
+				"nproc":  {Soft: 512, Hard: 1024},
+				"nofile": {Soft: 65536, Hard: 65536},
+			},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.prlimit.nofile = 65536:65536\nlxc.prlimit.nproc = 512:1024\n")
+	})
+
+	t.Run("invalid_ulimits", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{
+			Ulimits: map[string]common.Ulimit{"nofile": {Soft: 2048, Hard: 1024}},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "exceeds hard limit")
+
+		err = manager.Create(containerName, &common.Container{
+			Ulimits: map[string]common.Ulimit{"files": {Soft: 1, Hard: 1}},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "unsupported ulimit")
+	})
+
+	t.Run("tmpfs_mounts", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.ApplyConfig(containerName, &common.Container{
+			Storage: &common.StorageConfig{
+				TmpfsMounts: []common.TmpfsMount{
+					{Target: "/run/cache", Size: "64M", Mode: "1777"},
+					{Target: "/scratch"},
+				},
+			},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.mount.entry = tmpfs /run/cache tmpfs size=64M,mode=1777 0 0\n")
+		testing_internal.AssertContains(t, string(data), "lxc.mount.entry = tmpfs /scratch tmpfs defaults 0 0\n")
+	})
+
+	t.Run("namespace_modes", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.ApplyConfig(containerName, &common.Container{PidMode: "host", IpcMode: "host"})
+		testing_internal.AssertNoError(t, err)
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.namespace.keep = pid ipc\n")
+
+		err = manager.ApplyConfig(containerName, &common.Container{PidMode: "container:app", IpcMode: "host"})
+		testing_internal.AssertNoError(t, err)
+		data, err = os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.namespace.share.pid = app\nlxc.namespace.keep = ipc\n")
+
+		err = manager.Create(containerName, &common.Container{IpcMode: "shareable"})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "unsupported ipc mode")
+	})
+
+	t.Run("invalid_tmpfs_mounts", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{
+			Storage: &common.StorageConfig{
+				TmpfsMounts: []common.TmpfsMount{{Target: "run/cache"}},
+			},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "must be an absolute path")
+
+		err = manager.Create(containerName, &common.Container{
+			Storage: &common.StorageConfig{
+				TmpfsMounts: []common.TmpfsMount{{Target: "/run/cache", Size: "lots"}},
+			},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "invalid tmpfs size")
+	})
+
+	t.Run("sysctls", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.ApplyConfig(containerName, &common.Container{
+			Sysctls: map[string]string{
+				"net.ipv4.ip_forward": "1",
+				"net.core.somaxconn":  "1024",
+			},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.sysctl.net.core.somaxconn = 1024\nlxc.sysctl.net.ipv4.ip_forward = 1\n")
+	})
+
+	t.Run("include_configs", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		include := filepath.Join(tmpDir, "common.conf")
+		testing_internal.AssertNoError(t, os.WriteFile(include, []byte("lxc.arch = linux64\n"), 0644))
+
+		err = manager.ApplyConfig(containerName, &common.Container{
+			IncludeConfigs: []string{include},
+			Security:       &common.SecurityConfig{Isolation: "strict"},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		// Includes come first, so generated keys override them
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.include = "+include+"\nlxc.uts.name = "+containerName+"\n")
+		testing_internal.AssertConfigKey(t, string(data), "lxc.include", "/usr/share/lxc/config/strict.conf")
+
+		err = manager.Create("missing-include", &common.Container{IncludeConfigs: []string{filepath.Join(tmpDir, "missing.conf")}})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "invalid include config")
+	})
+
+	t.Run("capabilities_normalized", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.ApplyConfig(containerName, &common.Container{
+			Security: &common.SecurityConfig{
+				Isolation:    "default",
+				Capabilities: []string{"SYS_TIME", "NET_ADMIN", "net_admin", "CAP_CHOWN"},
+			},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertConfigKey(t, string(data), "lxc.cap.keep", "chown net_admin sys_time")
+	})
+
+	t.Run("failed_write_keeps_config", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.ApplyConfig(containerName, &common.Container{StopSignal: "SIGINT"})
+		testing_internal.AssertNoError(t, err)
+		configPath := filepath.Join(tmpDir, containerName, "config")
+		before, err := os.ReadFile(configPath)
+		testing_internal.AssertNoError(t, err)
+
+		// The invalid log size fails after earlier keys were already written
+		err = manager.ApplyConfig(containerName, &common.Container{
+			StopSignal: "SIGQUIT",
+			Logging:    &common.LoggingConfig{MaxSize: "lots"},
+		})
+		testing_internal.AssertError(t, err)
+
+		after, err := os.ReadFile(configPath)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, string(before), string(after))
+
+		leftovers, err := filepath.Glob(filepath.Join(tmpDir, containerName, ".config.tmp-*"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(leftovers))
+	})
+
+	t.Run("privileged_shortcut", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		privileged := true
+		err = manager.ApplyConfig(containerName, &common.Container{
+			Privileged: &privileged,
+			Security:   &common.SecurityConfig{SeccompProfile: "/etc/lxc/seccomp"},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.include = /usr/share/lxc/config/privileged.conf")
+		testing_internal.AssertConfigKey(t, string(data), "lxc.apparmor.profile", "unconfined")
+	})
+
+	t.Run("privileged_shortcut_conflict", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		privileged := true
+		err = manager.Create(containerName, &common.Container{
+			Privileged: &privileged,
+			Security:   &common.SecurityConfig{Isolation: "strict"},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "privileged: true conflicts with security.isolation: strict")
+	})
+
+	t.Run("capability_conflicts", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{
+			Security: &common.SecurityConfig{Isolation: "strict", Capabilities: []string{"SYS_ADMIN"}},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "SYS_ADMIN is dropped by strict isolation")
+
+		// The privileged shortcut keeps every capability, so a keep-list is a conflict
+		privileged := true
+		err = manager.Create(containerName, &common.Container{
+			Privileged: &privileged,
+			Security:   &common.SecurityConfig{Capabilities: []string{"NET_ADMIN"}},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "privileged containers keep all capabilities")
+	})
+
+	t.Run("reports_every_failure", func(t *testing.T) {
+		err := container.ValidateConfig(&common.Container{
+			Restart:    "sometimes",
+			StopSignal: "SIGNOPE",
+			Sysctls:    map[string]string{"somaxconn": "1024"},
+			Ports:      []common.PortForward{{Protocol: "tcp", Host: 8080, Guest: 80}},
+		})
+		var errs validation.ValidationErrors
+		testing_internal.AssertEqual(t, true, errors.As(err, &errs))
+
+		var paths []string
+		for _, e := range errs {
+			paths = append(paths, e.Path)
+		}
+		testing_internal.AssertEqual(t, "restart ports stop_signal sysctls.somaxconn", strings.Join(paths, " "))
+	})
+
+	t.Run("invalid_sysctls", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{
+			Sysctls: map[string]string{"somaxconn": "1024"},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "invalid sysctl name")
+	})
+
+	t.Run("negative_start_order", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		mock, cleanup := mock.SetupMockCommand(&execCommand)
+		defer cleanup()
+
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		mock.AddContainer(containerName, "STOPPED")
+		err = manager.Create(containerName, &common.Container{AutoStart: true, StartOrder: -1})
+		testing_internal.AssertError(t, err)
+	})
+	t.Run("update_regenerates_config", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		mock, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+		defer cleanup()
+
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{})
+		testing_internal.AssertNoError(t, err)
+		mock.AddContainer(containerName, "STOPPED")
+
+		err = manager.Update(containerName, &common.Container{
+			StopSignal: "SIGINT",
+			Network: &common.NetworkConfig{
+				Type:   "bridge",
+				Bridge: "br0",
+			},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.signal.stop = 2")
+
+		data, err = os.ReadFile(filepath.Join(tmpDir, containerName, "network.conf"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "br0")
+	})
+
+	t.Run("update_running_container", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		mock, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+		defer cleanup()
+
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{})
+		testing_internal.AssertNoError(t, err)
+		mock.AddContainer(containerName, "STOPPED")
+
+		err = manager.Start(containerName)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Update(containerName, &common.Container{
+			Memory: &common.MemoryConfig{
+				Limit: "512M",
+			},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "512M")
+
+		c, err := manager.Get(containerName)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "RUNNING", c.State)
+	})
+	t.Run("update_running_container_cgroup2", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+		defer cleanup()
+
+		limits, restore := mock.RecordCalls(&container.ExecCommand, "lxc-cgroup")
+		defer restore()
+
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+		manager.SetCgroupVersion(2)
+
+		err = manager.Create(containerName, &common.Container{})
+		testing_internal.AssertNoError(t, err)
+		mockCmd.AddContainer(containerName, "STOPPED")
+		testing_internal.AssertNoError(t, manager.Start(containerName))
+
+		shares := int64(2)
+		err = manager.Update(containerName, &common.Container{
+			CPU:    &common.CPUConfig{Shares: &shares},
+			Memory: &common.MemoryConfig{Limit: "512M"},
+		})
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "-n "+containerName+" cpu.weight 1,-n "+containerName+" memory.max 536870912", strings.Join(limits.Args(), ","))
+	})
 }
@@ -0,0 +1,377 @@
+package container
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+)
+
+// parseContainerConfig reads an LXC config file of "key = value" lines.
+// Values are kept in file order and repeated keys, such as several
+// lxc.hook.pre-start entries, keep every value. Comments and blank lines are
+// skipped. A missing file returns an error satisfying os.IsNotExist.
+func parseContainerConfig(path string) (map[string][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string][]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		values[key] = append(values[key], strings.TrimSpace(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return values, nil
+}
+
+// ReadConfig reconstructs a container's configuration from its generated LXC
+// config file. Only what applyConfig writes can be recovered: the image,
+// health check, devices and the like aren't part of the file, settings left
+// at their defaults read back empty, and network interfaces are all returned
+// in Interfaces.
+func (m *LXCManager) ReadConfig(name string) (*common.Container, error) {
+	values, err := parseContainerConfig(filepath.Join(m.configPath, name, "config"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("container '%s' has no config file", name)
+		}
+		return nil, fmt.Errorf("failed to read config of container '%s': %w", name, err)
+	}
+	return containerFromConfig(values)
+}
+
+// containerFromConfig builds the typed configuration described by parsed
+// LXC config values
+func containerFromConfig(values map[string][]string) (*common.Container, error) {
+	var err error
+	parseInt := func(key string) int64 {
+		v := lastValue(values, key)
+		if v == "" || err != nil {
+			return 0
+		}
+		n, perr := strconv.ParseInt(v, 10, 64)
+		if perr != nil {
+			err = fmt.Errorf("invalid %s: %w", key, perr)
+		}
+		return n
+	}
+
+	cfg := &common.Container{}
+
+	// SIGTERM is what an unset stop signal is written as
+	if signal := lastValue(values, "lxc.signal.stop"); signal != "" && signal != "15" {
+		cfg.StopSignal = signal
+	}
+
+	cfg.AutoStart = lastValue(values, "lxc.start.auto") == "1"
+	cfg.StartOrder = int(parseInt("lxc.start.order"))
+	cfg.StartDelay = int(parseInt("lxc.start.delay"))
+
+	cfg.IncludeConfigs, cfg.Security = securityFromConfig(values)
+	cfg.CPU = cpuFromConfig(values, parseInt)
+	cfg.Memory = memoryFromConfig(values, parseInt)
+	cfg.Network = networkFromConfig(values)
+	cfg.Storage = storageFromConfig(values)
+
+	for key, v := range values {
+		switch {
+		case strings.HasPrefix(key, "lxc.prlimit."):
+			soft, hard, _ := strings.Cut(v[len(v)-1], ":")
+			s, serr := strconv.ParseInt(soft, 10, 64)
+			h, herr := strconv.ParseInt(hard, 10, 64)
+			if serr != nil || herr != nil {
+				return nil, fmt.Errorf("invalid %s: %s", key, v[len(v)-1])
+			}
+			if cfg.Ulimits == nil {
+				cfg.Ulimits = make(map[string]common.Ulimit)
+			}
+			cfg.Ulimits[strings.TrimPrefix(key, "lxc.prlimit.")] = common.Ulimit{Soft: s, Hard: h}
+		case strings.HasPrefix(key, "lxc.sysctl."):
+			if cfg.Sysctls == nil {
+				cfg.Sysctls = make(map[string]string)
+			}
+			cfg.Sysctls[strings.TrimPrefix(key, "lxc.sysctl.")] = v[len(v)-1]
+		}
+	}
+
+	for _, env := range values["lxc.environment"] {
+		key, value, _ := strings.Cut(env, "=")
+		if cfg.Environment == nil {
+			cfg.Environment = make(map[string]string)
+		}
+		cfg.Environment[key] = value
+	}
+
+	if lastValue(values, "lxc.init.cmd") == "/sbin/init" {
+		switch lastValue(values, "lxc.signal.halt") {
+		case "SIGRTMIN+3":
+			cfg.Init = common.InitSystemd
+		case "SIGPWR":
+			cfg.Init = common.InitSysVInit
+		}
+	}
+
+	// systemd turns autodev on, only an explicit setting differing from that
+	// default is read back
+	if autodev := lastValue(values, "lxc.autodev"); autodev != "" && (autodev != "1" || cfg.Init != common.InitSystemd) {
+		on := autodev == "1"
+		cfg.AutoDev = &on
+	}
+	if len(values["lxc.tty.max"]) > 0 {
+		n := int(parseInt("lxc.tty.max"))
+		cfg.TTYs = &n
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// securityFromConfig returns the included configs and the security settings.
+// The isolation level is also written as an include, after the configured
+// ones, so the last include of a stock LXC config is taken as the isolation
+// level unless the security settings are the defaults.
+func securityFromConfig(values map[string][]string) ([]string, *common.SecurityConfig) {
+	includes := values["lxc.include"]
+	profile := lastValue(values, "lxc.apparmor.profile")
+	if profile == "lxc-container-default" && len(values["lxc.selinux.context"]) == 0 &&
+		len(values["lxc.seccomp.profile"]) == 0 && len(values["lxc.cap.drop"]) == 0 {
+		return includes, nil
+	}
+
+	sec := &common.SecurityConfig{
+		SELinuxContext: lastValue(values, "lxc.selinux.context"),
+		SeccompProfile: lastValue(values, "lxc.seccomp.profile"),
+	}
+	if n := len(includes); n > 0 && strings.HasPrefix(includes[n-1], "/usr/share/lxc/config/") {
+		sec.Isolation = strings.TrimSuffix(filepath.Base(includes[n-1]), ".conf")
+		includes = includes[:n-1]
+	}
+	if profile == "unconfined" && lastValue(values, "lxc.cap.drop") == "" && len(values["lxc.cap.drop"]) > 0 {
+		sec.Privileged = true
+	} else {
+		sec.AppArmorProfile = profile
+	}
+	if keep := lastValue(values, "lxc.cap.keep"); keep != "" {
+		sec.Capabilities = strings.Fields(keep)
+	}
+	if len(includes) == 0 {
+		includes = nil
+	}
+	return includes, sec
+}
+
+func cpuFromConfig(values map[string][]string, parseInt func(string) int64) *common.CPUConfig {
+	cpu := &common.CPUConfig{
+		CPUSet:      firstSet(lastValue(values, "lxc.cgroup.cpuset.cpus"), lastValue(values, "lxc.cgroup2.cpuset.cpus")),
+		MemoryNodes: firstSet(lastValue(values, "lxc.cgroup.cpuset.mems"), lastValue(values, "lxc.cgroup2.cpuset.mems")),
+	}
+	set := cpu.CPUSet != "" || cpu.MemoryNodes != ""
+	for key, field := range map[string]**int64{
+		"lxc.cpu.shares":        &cpu.Shares,
+		"lxc.cpu.cfs_quota_us":  &cpu.Quota,
+		"lxc.cpu.cfs_period_us": &cpu.Period,
+	} {
+		if len(values[key]) > 0 {
+			n := parseInt(key)
+			*field = &n
+			set = true
+		}
+	}
+	if len(values["lxc.cpu.nr_cpus"]) > 0 {
+		n := int(parseInt("lxc.cpu.nr_cpus"))
+		cpu.Cores = &n
+		set = true
+	}
+	if !set {
+		return nil
+	}
+	return cpu
+}
+
+func memoryFromConfig(values map[string][]string, parseInt func(string) int64) *common.MemoryConfig {
+	mem := &common.MemoryConfig{
+		Limit:          lastValue(values, "lxc.cgroup.memory.limit_in_bytes"),
+		Swap:           lastValue(values, "lxc.cgroup.memory.memsw.limit_in_bytes"),
+		Reserve:        lastValue(values, "lxc.cgroup.memory.soft_limit_in_bytes"),
+		OOMKillDisable: lastValue(values, "lxc.cgroup.memory.oom_control") == "1",
+	}
+	if mem.Limit == "" {
+		mem.Limit = lastValue(values, "lxc.cgroup2.memory.max")
+		if swap := lastValue(values, "lxc.cgroup2.memory.swap.max"); swap != "" && mem.Limit != "" {
+			mem.Swap = fmt.Sprintf("%d", parseInt("lxc.cgroup2.memory.swap.max")+parseInt("lxc.cgroup2.memory.max"))
+		}
+	}
+	if mem.Reserve == "" {
+		mem.Reserve = lastValue(values, "lxc.cgroup2.memory.low")
+	}
+	if len(values["lxc.cgroup.memory.swappiness"]) > 0 {
+		n := int(parseInt("lxc.cgroup.memory.swappiness"))
+		mem.Swappiness = &n
+	}
+	if *mem == (common.MemoryConfig{}) {
+		return nil
+	}
+	return mem
+}
+
+// networkFromConfig collects the lxc.net.N interfaces, in index order, and the
+// port forwards written as DNAT pre-start hooks
+func networkFromConfig(values map[string][]string) *common.NetworkConfig {
+	ifaces := make(map[int]*common.NetworkInterface)
+	for key, v := range values {
+		rest, ok := strings.CutPrefix(key, "lxc.net.")
+		if !ok {
+			continue
+		}
+		index, field, ok := strings.Cut(rest, ".")
+		i, err := strconv.Atoi(index)
+		if !ok || err != nil {
+			continue
+		}
+		iface := ifaces[i]
+		if iface == nil {
+			iface = &common.NetworkInterface{}
+			ifaces[i] = iface
+		}
+
+		value := v[len(v)-1]
+		switch field {
+		case "type":
+			iface.Type = value
+		case "link":
+			iface.Bridge = value
+		case "name":
+			iface.Interface = value
+		case "ipv4.method":
+			iface.DHCP = value == "dhcp"
+		case "ipv4.address":
+			iface.IP = value
+		case "ipv4.gateway":
+			iface.Gateway = value
+		case "hostname":
+			iface.Hostname = value
+		case "mtu":
+			iface.MTU, _ = strconv.Atoi(value)
+		case "hwaddr":
+			iface.MAC = value
+		}
+	}
+
+	var ports []common.PortForward
+	for _, hook := range values["lxc.hook.pre-start"] {
+		if pf, ok := parseDNATRule(hook); ok {
+			ports = append(ports, common.PortForward{Protocol: pf.Protocol, Host: pf.Host, Guest: pf.Guest})
+		}
+	}
+	if len(ifaces) == 0 && len(ports) == 0 {
+		return nil
+	}
+
+	indexes := make([]int, 0, len(ifaces))
+	for i := range ifaces {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	network := &common.NetworkConfig{PortForwards: ports}
+	for _, i := range indexes {
+		iface := ifaces[i]
+		// Nameservers are numbered, read them back in that order
+		for j := 0; ; j++ {
+			dns := lastValue(values, fmt.Sprintf("lxc.net.%d.ipv4.nameserver.%d", i, j))
+			if dns == "" {
+				break
+			}
+			iface.DNS = append(iface.DNS, dns)
+		}
+		network.Interfaces = append(network.Interfaces, *iface)
+	}
+	return network
+}
+
+func storageFromConfig(values map[string][]string) *common.StorageConfig {
+	storage := &common.StorageConfig{
+		Root:      lastValue(values, "lxc.rootfs.size"),
+		Backend:   lastValue(values, "lxc.rootfs.backend"),
+		Pool:      lastValue(values, "lxc.rootfs.pool"),
+		AutoMount: lastValue(values, "lxc.rootfs.mount.auto") == "1",
+	}
+
+	for i := 0; ; i++ {
+		entry := lastValue(values, fmt.Sprintf("lxc.mount.entry.%d", i))
+		if entry == "" {
+			break
+		}
+		fields := strings.Fields(entry)
+		if len(fields) < 4 {
+			continue
+		}
+		mount := common.Mount{Source: fields[0], Target: fields[1], Type: fields[2]}
+		if fields[3] != "defaults" {
+			mount.Options = strings.Split(fields[3], ",")
+		}
+		storage.Mounts = append(storage.Mounts, mount)
+	}
+
+	for _, entry := range values["lxc.mount.entry"] {
+		fields := strings.Fields(entry)
+		if len(fields) < 4 || fields[0] != "tmpfs" || fields[2] != "tmpfs" {
+			continue
+		}
+		tmpfs := common.TmpfsMount{Target: fields[1]}
+		for _, opt := range strings.Split(fields[3], ",") {
+			if size, ok := strings.CutPrefix(opt, "size="); ok {
+				tmpfs.Size = size
+			} else if mode, ok := strings.CutPrefix(opt, "mode="); ok {
+				tmpfs.Mode = mode
+			}
+		}
+		storage.TmpfsMounts = append(storage.TmpfsMounts, tmpfs)
+	}
+
+	if storage.Root == "" && storage.Backend == "" && storage.Pool == "" && !storage.AutoMount &&
+		len(storage.Mounts) == 0 && len(storage.TmpfsMounts) == 0 {
+		return nil
+	}
+	return storage
+}
+
+// lastValue returns the last value of a key, which is the one LXC applies
+func lastValue(values map[string][]string, key string) string {
+	if v := values[key]; len(v) > 0 {
+		return v[len(v)-1]
+	}
+	return ""
+}
+
+// firstSet returns the first non-empty value
+func firstSet(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
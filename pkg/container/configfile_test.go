@@ -0,0 +1,140 @@
+package container_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestReadConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	manager, err := container.NewLXCManager(tmpDir)
+	testing_internal.AssertNoError(t, err)
+	manager.SetCgroupVersion(1)
+
+	t.Run("round_trip", func(t *testing.T) {
+		shares, cores, swappiness, ttys := int64(512), 2, 10, 2
+		include := filepath.Join(tmpDir, "common.conf")
+		testing_internal.AssertNoError(t, os.WriteFile(include, nil, 0644))
+		cfg := &common.Container{
+			IncludeConfigs: []string{include, "/usr/share/lxc/config/common.conf"},
+			StopSignal:     "SIGINT",
+			AutoStart:      true,
+			StartOrder:     2,
+			StartDelay:     5,
+			Init:           common.InitSystemd,
+			TTYs:           &ttys,
+			Security: &common.SecurityConfig{
+				Isolation:       "strict",
+				AppArmorProfile: "custom",
+				Capabilities:    []string{"net_admin", "sys_time"},
+			},
+			CPU:    &common.CPUConfig{Shares: &shares, Cores: &cores, CPUSet: "0-1"},
+			Memory: &common.MemoryConfig{Limit: "512M", Reserve: "256M", Swappiness: &swappiness, OOMKillDisable: true},
+			Network: &common.NetworkConfig{
+				Interfaces: []common.NetworkInterface{
+					{Type: "veth", Bridge: "br0", IP: "10.0.3.10/24", Gateway: "10.0.3.1", DNS: []string{"1.1.1.1", "8.8.8.8"}, MAC: "00:16:3e:00:00:01"},
+					{Type: "veth", Bridge: "br1", DHCP: true, MTU: 9000, MAC: "00:16:3e:00:00:02"},
+				},
+				PortForwards: []common.PortForward{
+					{Protocol: "tcp", Host: 8080, Guest: 80},
+					{Protocol: "udp", Host: 5353, Guest: 53},
+				},
+			},
+			Storage: &common.StorageConfig{
+				Root:        "10G",
+				Mounts:      []common.Mount{{Source: "/srv/data", Target: "data", Type: "none", Options: []string{"bind", "ro"}}},
+				TmpfsMounts: []common.TmpfsMount{{Target: "/tmp", Size: "64M", Mode: "1777"}},
+			},
+			Environment: map[string]string{"MODE": "prod", "URL": "http://x/?a=b"},
+			Ulimits:     map[string]common.Ulimit{"nofile": {Soft: 1024, Hard: 4096}},
+			Sysctls:     map[string]string{"net.core.somaxconn": "1024"},
+		}
+		testing_internal.AssertNoError(t, manager.ApplyConfig("web", cfg))
+
+		got, err := manager.ReadConfig("web")
+		testing_internal.AssertNoError(t, err)
+
+		// Signals are stored by number
+		testing_internal.AssertEqual(t, "2", got.StopSignal)
+		got.StopSignal = cfg.StopSignal
+		if !reflect.DeepEqual(cfg, got) {
+			t.Errorf("expected %+v, got %+v", cfg, got)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.ApplyConfig("plain", &common.Container{}))
+
+		got, err := manager.ReadConfig("plain")
+		testing_internal.AssertNoError(t, err)
+		if !reflect.DeepEqual(&common.Container{}, got) {
+			t.Errorf("expected an empty config, got %+v", got)
+		}
+	})
+
+	t.Run("privileged", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.ApplyConfig("priv", &common.Container{
+			Security: &common.SecurityConfig{Privileged: true},
+		}))
+
+		got, err := manager.ReadConfig("priv")
+		testing_internal.AssertNoError(t, err)
+		if !reflect.DeepEqual(&common.SecurityConfig{Privileged: true}, got.Security) {
+			t.Errorf("expected privileged security, got %+v", got.Security)
+		}
+	})
+
+	t.Run("cgroup2", func(t *testing.T) {
+		v2, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+		v2.SetCgroupVersion(2)
+
+		testing_internal.AssertNoError(t, v2.ApplyConfig("unified", &common.Container{
+			CPU:    &common.CPUConfig{CPUSet: "0-1"},
+			Memory: &common.MemoryConfig{Limit: "512M", Swap: "1G", Reserve: "256M"},
+		}))
+
+		got, err := v2.ReadConfig("unified")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "0-1", got.CPU.CPUSet)
+		// Sizes are stored in bytes
+		expected := &common.MemoryConfig{Limit: "536870912", Swap: "1073741824", Reserve: "268435456"}
+		if !reflect.DeepEqual(expected, got.Memory) {
+			t.Errorf("expected %+v, got %+v", expected, got.Memory)
+		}
+	})
+
+	t.Run("comments_and_repeated_keys", func(t *testing.T) {
+		dir := filepath.Join(tmpDir, "manual")
+		testing_internal.AssertNoError(t, os.MkdirAll(dir, 0755))
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(dir, "config"), []byte(
+			"# edited by hand\n\nlxc.sysctl.vm.swappiness = 10\nlxc.sysctl.vm.swappiness = 20\nnot a setting\n"), 0644))
+
+		got, err := manager.ReadConfig("manual")
+		testing_internal.AssertNoError(t, err)
+		// The last value wins, as in LXC
+		testing_internal.AssertEqual(t, "20", got.Sysctls["vm.swappiness"])
+	})
+
+	t.Run("invalid_value", func(t *testing.T) {
+		dir := filepath.Join(tmpDir, "broken")
+		testing_internal.AssertNoError(t, os.MkdirAll(dir, 0755))
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(dir, "config"), []byte("lxc.cpu.shares = lots\n"), 0644))
+
+		_, err := manager.ReadConfig("broken")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "invalid lxc.cpu.shares")
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := manager.ReadConfig("missing")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "has no config file")
+	})
+}
@@ -0,0 +1,359 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
+
+// maxSymlinks bounds symlink resolution inside a rootfs, matching the kernel's ELOOP limit
+const maxSymlinks = 40
+
+// CopyProgress is called as data is copied with the bytes copied so far and
+// the total number of bytes to copy
+type CopyProgress func(copied, total int64)
+
+// CopyToContainer copies a host file or directory into a container's rootfs.
+// Directories are copied recursively, preserving structure, modes and symlinks.
+// If dst is an existing directory, src is copied inside it.
+func (m *LXCManager) CopyToContainer(name, src, dst string, progress CopyProgress) error {
+	rootfs, err := m.containerRootfs(name)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("failed to access source: %w", err)
+	}
+
+	// Copy into an existing directory under the source's name
+	resolved, err := resolveInRootfs(rootfs, dst)
+	if err != nil {
+		return fmt.Errorf("failed to resolve destination: %w", err)
+	}
+	if st, err := os.Stat(resolved); err == nil && st.IsDir() {
+		dst = filepath.Join(dst, filepath.Base(src))
+	}
+
+	target := func(rel string) (string, error) {
+		// Resolve only the parent so an existing symlink at the final
+		// component is replaced rather than followed out of the rootfs
+		p := filepath.Join(dst, rel)
+		parent, err := resolveInRootfs(rootfs, filepath.Dir(p))
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(parent, filepath.Base(p)), nil
+	}
+
+	logging.Debug("Copying to container", "container", name, "src", src, "dst", dst)
+	if err := copyTree(src, info, target, progress); err != nil {
+		return fmt.Errorf("failed to copy to container: %w", err)
+	}
+	return nil
+}
+
+// CopyFromContainer copies a file or directory out of a container's rootfs to
+// the host. Symlinks inside the container are resolved relative to its rootfs.
+// If dst is an existing directory, src is copied inside it.
+func (m *LXCManager) CopyFromContainer(name, src, dst string, progress CopyProgress) error {
+	rootfs, err := m.containerRootfs(name)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Copying from container", "container", name, "src", src, "dst", dst)
+	if err := copyFromRootfs(rootfs, src, dst, progress); err != nil {
+		return fmt.Errorf("failed to copy from container: %w", err)
+	}
+	return nil
+}
+
+// CopyFromTemplate copies a file or directory out of a stored template's
+// rootfs to the host without creating a container from it
+func (m *LXCManager) CopyFromTemplate(name, src, dst string, progress CopyProgress) error {
+	if _, err := m.GetTemplate(name); err != nil {
+		return fmt.Errorf("template '%s' not found: %w", name, err)
+	}
+	rootfs := filepath.Join(m.configPath, "templates", name, "rootfs")
+	if info, err := os.Stat(rootfs); err != nil || !info.IsDir() {
+		return fmt.Errorf("template '%s' has no rootfs", name)
+	}
+
+	logging.Debug("Copying from template", "template", name, "src", src, "dst", dst)
+	if err := copyFromRootfs(rootfs, src, dst, progress); err != nil {
+		return fmt.Errorf("failed to copy from template: %w", err)
+	}
+	return nil
+}
+
+// CopyFromImage copies a file or directory out of an image in the image
+// store to the host. Only the copied entries are streamed from the image's
+// layers, topmost first, with whiteouts and symlinks applied as they would be
+// in a container created from the image.
+func (m *LXCManager) CopyFromImage(ctx context.Context, image, src, dst string, progress CopyProgress) error {
+	if m.images == nil {
+		return fmt.Errorf("no image store configured")
+	}
+
+	archive, err := m.images.Image(ctx, image)
+	if err != nil {
+		return fmt.Errorf("failed to get image %s: %w", image, err)
+	}
+
+	logging.Debug("Copying from image", "image", image, "src", src, "dst", dst)
+	if err := copyFromArchive(archive, src, dst, progress); err != nil {
+		return fmt.Errorf("failed to copy from image: %w", err)
+	}
+	return nil
+}
+
+// copyFromRootfs copies src, a path inside rootfs, to dst on the host
+func copyFromRootfs(rootfs, src, dst string, progress CopyProgress) error {
+	resolved, err := resolveInRootfs(rootfs, src)
+	if err != nil {
+		return fmt.Errorf("failed to resolve source: %w", err)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return fmt.Errorf("failed to access source: %w", err)
+	}
+
+	if st, err := os.Stat(dst); err == nil && st.IsDir() {
+		dst = filepath.Join(dst, filepath.Base(filepath.Clean("/"+src)))
+	}
+
+	target := func(rel string) (string, error) {
+		return filepath.Join(dst, rel), nil
+	}
+	return copyTree(resolved, info, target, progress)
+}
+
+// containerRootfs returns the host path of a container's root filesystem
+func (m *LXCManager) containerRootfs(name string) (string, error) {
+	rootfs := filepath.Join(m.configPath, name, "rootfs")
+	info, err := os.Stat(rootfs)
+	if err != nil {
+		return "", fmt.Errorf("failed to access rootfs for container '%s': %w", name, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("rootfs for container '%s' is not a directory", name)
+	}
+	return rootfs, nil
+}
+
+// resolveInRootfs maps a container path to a host path under rootfs. Symlinks
+// are followed as if rootfs were "/", so neither absolute links nor ".." can
+// escape it. Components that don't exist yet are kept as-is.
+func resolveInRootfs(rootfs, path string) (string, error) {
+	var current string
+	pending := strings.Split(path, "/")
+	links := 0
+
+	for len(pending) > 0 {
+		part := pending[0]
+		pending = pending[1:]
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if i := strings.LastIndex(current, "/"); i >= 0 {
+				current = current[:i]
+			} else {
+				current = ""
+			}
+			continue
+		}
+
+		next := part
+		if current != "" {
+			next = current + "/" + part
+		}
+
+		info, err := os.Lstat(filepath.Join(rootfs, next))
+		if err != nil {
+			if os.IsNotExist(err) {
+				current = next
+				continue
+			}
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", fmt.Errorf("too many levels of symbolic links in %s", path)
+		}
+		link, err := os.Readlink(filepath.Join(rootfs, next))
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(link, "/") {
+			current = ""
+		}
+		pending = append(strings.Split(link, "/"), pending...)
+	}
+
+	return filepath.Join(rootfs, current), nil
+}
+
+// progressCounter tracks bytes written and reports them to a CopyProgress
+type progressCounter struct {
+	copied   int64
+	total    int64
+	progress CopyProgress
+}
+
+func (p *progressCounter) Write(b []byte) (int, error) {
+	p.copied += int64(len(b))
+	if p.progress != nil {
+		p.progress(p.copied, p.total)
+	}
+	return len(b), nil
+}
+
+// copyTree copies src, which may be a file or directory, to the paths
+// returned by target for each entry relative to src
+func copyTree(src string, info fs.FileInfo, target func(rel string) (string, error), progress CopyProgress) error {
+	counter := &progressCounter{progress: progress}
+	if info.IsDir() {
+		total, err := treeSize(src)
+		if err != nil {
+			return err
+		}
+		counter.total = total
+	} else {
+		counter.total = info.Size()
+	}
+
+	// Directory modes are applied last so read-only directories can still be filled
+	type dirMode struct {
+		path string
+		mode fs.FileMode
+	}
+	var dirs []dirMode
+
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		dst, err := target(rel)
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		// Never write through an existing symlink at the destination
+		if st, err := os.Lstat(dst); err == nil && st.Mode()&os.ModeSymlink != 0 {
+			if err := os.Remove(dst); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case d.IsDir():
+			if err := os.MkdirAll(dst, 0755); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirMode{path: dst, mode: info.Mode().Perm()})
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if err := os.Symlink(link, dst); err != nil {
+				return err
+			}
+		case d.Type().IsRegular():
+			if err := copyFileProgress(path, dst, info.Mode().Perm(), counter); err != nil {
+				return err
+			}
+		default:
+			logging.Warn("Skipping special file during copy", "path", path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].mode); err != nil {
+			return err
+		}
+	}
+
+	// Report completion even when there was nothing to copy
+	if progress != nil && counter.total == 0 {
+		progress(0, 0)
+	}
+	return nil
+}
+
+// copyFileProgress copies a regular file, reporting bytes written to counter
+func copyFileProgress(src, dst string, mode fs.FileMode, counter *progressCounter) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFileProgress(in, dst, mode, counter)
+}
+
+// writeFileProgress writes r to dst, reporting bytes written to counter
+func writeFileProgress(r io.Reader, dst string, mode fs.FileMode, counter *progressCounter) error {
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(io.MultiWriter(out, counter), r); err != nil {
+		return err
+	}
+
+	// OpenFile's mode is masked by the umask and ignored for existing files
+	return out.Chmod(mode)
+}
+
+// treeSize returns the total size of regular files under root
+func treeSize(root string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(root, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
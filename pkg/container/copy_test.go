@@ -0,0 +1,214 @@
+package container_test
+
+import (
+	"archive/tar"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestCopy(t *testing.T) {
+	containerName := "test-container"
+
+	tmpDir := t.TempDir()
+	rootfs := filepath.Join(tmpDir, containerName, "rootfs")
+	testing_internal.AssertNoError(t, os.MkdirAll(filepath.Join(rootfs, "srv"), 0755))
+
+	manager, err := container.NewLXCManager(tmpDir)
+	testing_internal.AssertNoError(t, err)
+
+	t.Run("file_to_container", func(t *testing.T) {
+		src := filepath.Join(t.TempDir(), "app.conf")
+		testing_internal.AssertNoError(t, os.WriteFile(src, []byte("hello"), 0600))
+
+		var copied, total int64
+		err := manager.CopyToContainer(containerName, src, "/srv", func(c, tot int64) {
+			copied, total = c, tot
+		})
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, int64(5), copied)
+		testing_internal.AssertEqual(t, int64(5), total)
+
+		info, err := os.Stat(filepath.Join(rootfs, "srv", "app.conf"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("directory_to_container", func(t *testing.T) {
+		src := filepath.Join(t.TempDir(), "site")
+		testing_internal.AssertNoError(t, os.MkdirAll(filepath.Join(src, "static"), 0750))
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(src, "index.html"), []byte("index"), 0644))
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(src, "static", "run.sh"), []byte("#!/bin/sh"), 0755))
+		testing_internal.AssertNoError(t, os.Symlink("index.html", filepath.Join(src, "default.html")))
+
+		var copied, total int64
+		err := manager.CopyToContainer(containerName, src, "/srv", func(c, tot int64) {
+			copied, total = c, tot
+		})
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, int64(14), total)
+		testing_internal.AssertEqual(t, total, copied)
+
+		data, err := os.ReadFile(filepath.Join(rootfs, "srv", "site", "index.html"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "index", string(data))
+
+		info, err := os.Stat(filepath.Join(rootfs, "srv", "site", "static"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, os.FileMode(0750), info.Mode().Perm())
+
+		info, err = os.Stat(filepath.Join(rootfs, "srv", "site", "static", "run.sh"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, os.FileMode(0755), info.Mode().Perm())
+
+		link, err := os.Readlink(filepath.Join(rootfs, "srv", "site", "default.html"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "index.html", link)
+	})
+
+	t.Run("symlinks_stay_in_rootfs", func(t *testing.T) {
+		outside := t.TempDir()
+
+		// Both links point outside the rootfs when followed on the host
+		testing_internal.AssertNoError(t, os.Symlink(outside, filepath.Join(rootfs, "abs")))
+		testing_internal.AssertNoError(t, os.Symlink("../../../../../..", filepath.Join(rootfs, "srv", "rel")))
+
+		src := filepath.Join(t.TempDir(), "payload")
+		testing_internal.AssertNoError(t, os.WriteFile(src, []byte("data"), 0644))
+
+		err := manager.CopyToContainer(containerName, src, "/srv/rel", nil)
+		testing_internal.AssertNoError(t, err)
+		_, err = os.Stat(filepath.Join(rootfs, "payload"))
+		testing_internal.AssertNoError(t, err)
+
+		// The final component is replaced rather than followed
+		err = manager.CopyToContainer(containerName, src, "/abs", nil)
+		testing_internal.AssertNoError(t, err)
+		_, err = os.Stat(filepath.Join(outside, "payload"))
+		testing_internal.AssertError(t, err)
+
+		info, err := os.Lstat(filepath.Join(rootfs, "abs"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, true, info.Mode().IsRegular())
+	})
+
+	t.Run("directory_from_container", func(t *testing.T) {
+		testing_internal.AssertNoError(t, os.MkdirAll(filepath.Join(rootfs, "var", "log", "app"), 0755))
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(rootfs, "var", "log", "app", "app.log"), []byte("log line"), 0640))
+		testing_internal.AssertNoError(t, os.Symlink("/var/log/app", filepath.Join(rootfs, "logs")))
+
+		dst := t.TempDir()
+		err := manager.CopyFromContainer(containerName, "/logs", dst, nil)
+		testing_internal.AssertNoError(t, err)
+
+		info, err := os.Stat(filepath.Join(dst, "logs", "app.log"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, os.FileMode(0640), info.Mode().Perm())
+	})
+
+	t.Run("missing_container", func(t *testing.T) {
+		err := manager.CopyFromContainer("nonexistent", "/etc", t.TempDir(), nil)
+		testing_internal.AssertError(t, err)
+	})
+
+	t.Run("file_from_template", func(t *testing.T) {
+		templates := filepath.Join(tmpDir, "templates")
+		templateRootfs := filepath.Join(templates, "base", "rootfs")
+		testing_internal.AssertNoError(t, os.MkdirAll(filepath.Join(templateRootfs, "etc"), 0755))
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(templateRootfs, "etc", "app.conf"), []byte("port=80"), 0644))
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(templates, "base.json"), []byte(`{"name": "base"}`), 0644))
+
+		dst := filepath.Join(t.TempDir(), "app.conf")
+		err := manager.CopyFromTemplate("base", "/etc/app.conf", dst, nil)
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(dst)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "port=80", string(data))
+
+		err = manager.CopyFromTemplate("missing", "/etc/app.conf", dst, nil)
+		testing_internal.AssertError(t, err)
+	})
+
+	t.Run("file_from_image", func(t *testing.T) {
+		err := manager.CopyFromImage(context.Background(), "app:1.0", "/etc/app.conf", t.TempDir(), nil)
+		testing_internal.AssertError(t, err)
+
+		archive := buildImage(t,
+			[]layerEntry{
+				{name: "etc/", typeflag: tar.TypeDir},
+				{name: "etc/app.conf", body: "old"},
+				{name: "etc/stale.conf", body: "stale"},
+			},
+			[]layerEntry{
+				{name: "etc/app.conf", body: "new"},
+				{name: "etc/.wh.stale.conf"},
+				{name: "conf", typeflag: tar.TypeSymlink, linkname: "/etc"},
+			},
+		)
+		manager.SetImageStore(newFakeImageStore(map[string][]byte{"app:1.0": archive}))
+
+		dst := t.TempDir()
+		err = manager.CopyFromImage(context.Background(), "app:1.0", "/conf/app.conf", dst, nil)
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(dst, "app.conf"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "new", string(data))
+
+		err = manager.CopyFromImage(context.Background(), "app:1.0", "/etc/stale.conf", dst, nil)
+		testing_internal.AssertError(t, err)
+
+		err = manager.CopyFromImage(context.Background(), "missing:1.0", "/etc/app.conf", dst, nil)
+		testing_internal.AssertError(t, err)
+	})
+
+	t.Run("directory_from_image", func(t *testing.T) {
+		archive := buildImage(t,
+			[]layerEntry{
+				{name: "srv/", typeflag: tar.TypeDir},
+				{name: "srv/app/", typeflag: tar.TypeDir},
+				{name: "srv/app/old.txt", body: "old"},
+				{name: "srv/app/keep.txt", body: "keep"},
+				{name: "srv/cache/", typeflag: tar.TypeDir},
+				{name: "srv/cache/entry", body: "cached"},
+				{name: "etc/passwd", body: "root"},
+			},
+			[]layerEntry{
+				{name: "srv/app/.wh.old.txt"},
+				{name: "srv/app/run.sh", body: "#!/bin/sh", mode: 0755},
+				{name: "srv/app/start.sh", typeflag: tar.TypeLink, linkname: "srv/app/run.sh"},
+				{name: "srv/app/current", typeflag: tar.TypeSymlink, linkname: "run.sh"},
+				{name: "srv/cache/", typeflag: tar.TypeDir},
+				{name: "srv/cache/.wh..wh..opq"},
+			},
+		)
+		manager.SetImageStore(newFakeImageStore(map[string][]byte{"app:1.0": archive}))
+
+		dst := t.TempDir()
+		err := manager.CopyFromImage(context.Background(), "app:1.0", "/srv", dst, nil)
+		testing_internal.AssertNoError(t, err)
+
+		for name, want := range map[string]string{"app/keep.txt": "keep", "app/run.sh": "#!/bin/sh", "app/start.sh": "#!/bin/sh"} {
+			data, err := os.ReadFile(filepath.Join(dst, "srv", name))
+			testing_internal.AssertNoError(t, err)
+			testing_internal.AssertEqual(t, want, string(data))
+		}
+		info, err := os.Stat(filepath.Join(dst, "srv", "app", "run.sh"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, os.FileMode(0755), info.Mode().Perm())
+		link, err := os.Readlink(filepath.Join(dst, "srv", "app", "current"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "run.sh", link)
+
+		// Whited out and opaque-hidden files stay behind, as does the rest of the image
+		testing_internal.AssertFileNotExists(t, filepath.Join(dst, "srv", "app", "old.txt"))
+		testing_internal.AssertFileNotExists(t, filepath.Join(dst, "srv", "cache", "entry"))
+		testing_internal.AssertFileExists(t, filepath.Join(dst, "srv", "cache"))
+		testing_internal.AssertFileNotExists(t, filepath.Join(dst, "etc"))
+	})
+}
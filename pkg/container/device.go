@@ -0,0 +1,217 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
+)
+
+// AttachDevice adds a device to a container. A running container gets it
+// right away through lxc-device; every container also gets it as a bind mount
+// in its config, with a device cgroup rule allowing device nodes, so it is
+// still there after a restart. nic devices can only be moved into a running
+// container and aren't kept across restarts.
+func (m *LXCManager) AttachDevice(name string, dev common.DeviceConfig) error {
+	if err := validation.ValidateDevice(dev.Name, dev.Type, dev.Source, dev.Destination, dev.Options); err != nil {
+		return fmt.Errorf("invalid device: %w", err)
+	}
+
+	container, err := m.lookup(name)
+	if err != nil {
+		return fmt.Errorf("failed to get container: %w", err)
+	}
+	if container.Config == nil {
+		container.Config = &config.Container{}
+	}
+	for _, existing := range container.Config.Devices {
+		if existing.Name == dev.Name {
+			return fmt.Errorf("container '%s' already has a device named '%s'", name, dev.Name)
+		}
+	}
+
+	isNIC := strings.EqualFold(dev.Type, "nic")
+	running := container.State == "RUNNING"
+	if isNIC && !running {
+		return fmt.Errorf("nic devices can only be attached to a running container (current state: %s)", container.State)
+	}
+
+	if running {
+		args := []string{"-n", name, "add", dev.Source}
+		if dev.Destination != "" {
+			args = append(args, dev.Destination)
+		}
+		if err := m.execLXCCommand("lxc-device", args...); err != nil {
+			return fmt.Errorf("failed to attach device: %w", err)
+		}
+	}
+	if isNIC {
+		logging.Info("Attached device", "name", name, "device", dev.Name)
+		return nil
+	}
+
+	configPath := filepath.Join(m.configPath, name, "config")
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	data = append(data, fmt.Sprintf("lxc.mount.entry = %s\n", deviceMountEntry(dev))...)
+	if key, value, ok := m.deviceAllowEntry(dev); ok {
+		data = append(data, fmt.Sprintf("%s = %s\n", key, value)...)
+	}
+	if err := writeFileAtomic(configPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	cfg := *container.Config
+	cfg.Devices = append(append([]config.DeviceConfig(nil), cfg.Devices...), config.FromCommonDeviceConfigs([]common.DeviceConfig{dev})...)
+	if err := m.state.SaveContainerState(name, &cfg, container.State); err != nil {
+		return fmt.Errorf("failed to save container state: %w", err)
+	}
+
+	logging.Info("Attached device", "name", name, "device", dev.Name)
+	return nil
+}
+
+// DetachDevice removes a device added by AttachDevice or listed in the
+// container's devices. A running container loses it right away.
+func (m *LXCManager) DetachDevice(name, deviceName string) error {
+	container, err := m.lookup(name)
+	if err != nil {
+		return fmt.Errorf("failed to get container: %w", err)
+	}
+
+	index := -1
+	if container.Config != nil {
+		for i, existing := range container.Config.Devices {
+			if existing.Name == deviceName {
+				index = i
+				break
+			}
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("container '%s' has no device named '%s'", name, deviceName)
+	}
+	dev := config.ToCommonDeviceConfigs(container.Config.Devices[index : index+1])[0]
+
+	if container.State == "RUNNING" {
+		target := dev.Destination
+		if target == "" {
+			target = dev.Source
+		}
+		if err := m.execLXCCommand("lxc-device", "-n", name, "del", target); err != nil {
+			return fmt.Errorf("failed to detach device: %w", err)
+		}
+	}
+
+	configPath := filepath.Join(m.configPath, name, "config")
+	data, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err == nil {
+		allowKey, allowValue, allow := m.deviceAllowEntry(dev)
+		var kept []string
+		for _, line := range strings.SplitAfter(string(data), "\n") {
+			if isDeviceMountEntry(line, dev) {
+				continue
+			}
+			// Dropped once, another device may use the same node
+			if key, value, ok := strings.Cut(line, "="); allow && ok &&
+				strings.TrimSpace(key) == allowKey && strings.TrimSpace(value) == allowValue {
+				allow = false
+				continue
+			}
+			kept = append(kept, line)
+		}
+		if err := writeFileAtomic(configPath, []byte(strings.Join(kept, "")), 0644); err != nil {
+			return fmt.Errorf("failed to write config file: %w", err)
+		}
+	}
+
+	cfg := *container.Config
+	cfg.Devices = append(append([]config.DeviceConfig(nil), cfg.Devices[:index]...), cfg.Devices[index+1:]...)
+	if err := m.state.SaveContainerState(name, &cfg, container.State); err != nil {
+		return fmt.Errorf("failed to save container state: %w", err)
+	}
+
+	logging.Info("Detached device", "name", name, "device", deviceName)
+	return nil
+}
+
+// applyDeviceConfig bind mounts each device into the container and lets the
+// device cgroup open device nodes. nic devices are left out, they only exist
+// while attached to a running container.
+func (m *LXCManager) applyDeviceConfig(f *os.File, devices []common.DeviceConfig) error {
+	for _, dev := range devices {
+		if strings.EqualFold(dev.Type, "nic") {
+			continue
+		}
+		if err := writeConfig(f, "lxc.mount.entry", deviceMountEntry(dev)); err != nil {
+			return fmt.Errorf("failed to set device %s: %w", dev.Name, err)
+		}
+		if key, value, ok := m.deviceAllowEntry(dev); ok {
+			if err := writeConfig(f, key, value); err != nil {
+				return fmt.Errorf("failed to set device %s: %w", dev.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// deviceAllowEntry returns the devices.allow config entry that lets the
+// container open a device whose source is a block or character device node.
+// ok is false for other sources, which the device cgroup doesn't restrict.
+func (m *LXCManager) deviceAllowEntry(dev common.DeviceConfig) (key, value string, ok bool) {
+	kind, major, minor, ok := deviceNumber(dev.Source)
+	if !ok {
+		return "", "", false
+	}
+	key = "lxc.cgroup.devices.allow"
+	if m.cgroupVersion == 2 {
+		key = "lxc.cgroup2.devices.allow"
+	}
+	return key, fmt.Sprintf("%c %d:%d rwm", kind, major, minor), true
+}
+
+// deviceMountEntry returns the lxc.mount.entry value that binds a device's
+// source to its destination, or to the same path when it has none
+func deviceMountEntry(dev common.DeviceConfig) string {
+	create := "file"
+	if info, err := os.Stat(dev.Source); err == nil && info.IsDir() {
+		create = "dir"
+	}
+	options := []string{"bind", "create=" + create}
+	for _, opt := range dev.Options {
+		switch opt = strings.ToLower(opt); opt {
+		case "ro", "optional":
+			options = append(options, opt)
+		}
+	}
+	return fmt.Sprintf("%s %s none %s 0 0", dev.Source, deviceTarget(dev), strings.Join(options, ","))
+}
+
+// isDeviceMountEntry reports whether a config line is the mount entry of dev
+func isDeviceMountEntry(line string, dev common.DeviceConfig) bool {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok || strings.TrimSpace(key) != "lxc.mount.entry" {
+		return false
+	}
+	fields := strings.Fields(value)
+	return len(fields) >= 3 && fields[0] == dev.Source && fields[1] == deviceTarget(dev) && fields[2] == "none"
+}
+
+// deviceTarget is where a device is mounted, relative to the container's rootfs
+func deviceTarget(dev common.DeviceConfig) string {
+	target := dev.Destination
+	if target == "" {
+		target = dev.Source
+	}
+	return strings.TrimPrefix(target, "/")
+}
@@ -0,0 +1,134 @@
+package container_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestAttachDevice(t *testing.T) {
+	disk := common.DeviceConfig{Name: "data", Type: "unix-block", Source: "/dev/sdb", Destination: "/dev/data"}
+	entry := "lxc.mount.entry = /dev/sdb dev/data none bind,create=file 0 0\n"
+	configPath := t.TempDir()
+
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	// Record lxc-device calls
+	calls, restore := mock.RecordCalls(&container.ExecCommand, "lxc-device")
+	defer restore()
+
+	manager, err := container.NewLXCManager(configPath)
+	testing_internal.AssertNoError(t, err)
+	for _, name := range []string{"running", "stopped"} {
+		testing_internal.AssertNoError(t, manager.Create(name, &common.Container{}))
+		testing_internal.AssertNoError(t, manager.Update(name, &common.Container{}))
+		testing_internal.AssertNoError(t, mockCmd.AddContainer(name, "STOPPED"))
+	}
+	testing_internal.AssertNoError(t, manager.Start("running"))
+
+	t.Run("running", func(t *testing.T) {
+		calls.Reset()
+		testing_internal.AssertNoError(t, manager.AttachDevice("running", disk))
+		testing_internal.AssertEqual(t, "-n running add /dev/sdb /dev/data", strings.Join(calls.Args(), "\n"))
+
+		data, err := os.ReadFile(filepath.Join(configPath, "running", "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), entry)
+
+		c, err := manager.Get("running")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 1, len(c.Config.Devices))
+		testing_internal.AssertEqual(t, "data", c.Config.Devices[0].Name)
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		calls.Reset()
+		testing_internal.AssertNoError(t, manager.AttachDevice("stopped", disk))
+		testing_internal.AssertEqual(t, 0, len(calls.Calls()))
+
+		data, err := os.ReadFile(filepath.Join(configPath, "stopped", "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.uts.name = stopped\n")
+		testing_internal.AssertContains(t, string(data), entry)
+	})
+
+	t.Run("detach", func(t *testing.T) {
+		calls.Reset()
+		testing_internal.AssertNoError(t, manager.DetachDevice("running", "data"))
+		testing_internal.AssertEqual(t, "-n running del /dev/data", strings.Join(calls.Args(), "\n"))
+
+		data, err := os.ReadFile(filepath.Join(configPath, "running", "config"))
+		testing_internal.AssertNoError(t, err)
+		if strings.Contains(string(data), "/dev/sdb") {
+			t.Errorf("device still in config:\n%s", data)
+		}
+		testing_internal.AssertContains(t, string(data), "lxc.uts.name = running\n")
+
+		c, err := manager.Get("running")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(c.Config.Devices))
+	})
+
+	t.Run("device_cgroup", func(t *testing.T) {
+		manager.SetCgroupVersion(1)
+		null := common.DeviceConfig{Name: "null", Type: "unix-char", Source: "/dev/null", Destination: "/dev/sink"}
+		path := filepath.Join(configPath, "stopped", "config")
+
+		// The node alone would be blocked by the device cgroup after a restart
+		testing_internal.AssertNoError(t, manager.AttachDevice("stopped", null))
+		data, err := os.ReadFile(path)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertConfigKey(t, string(data), "lxc.cgroup.devices.allow", "c 1:3 rwm")
+
+		testing_internal.AssertNoError(t, manager.DetachDevice("stopped", "null"))
+		data, err = os.ReadFile(path)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertConfigKeyAbsent(t, string(data), "lxc.cgroup.devices.allow")
+
+		manager.SetCgroupVersion(2)
+		testing_internal.AssertNoError(t, manager.Update("stopped", &common.Container{Devices: []common.DeviceConfig{null, disk}}))
+		data, err = os.ReadFile(path)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertConfigKey(t, string(data), "lxc.cgroup2.devices.allow", "c 1:3 rwm")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Create("app", &common.Container{}))
+
+		err := manager.AttachDevice("app", common.DeviceConfig{Name: "data", Type: "floppy", Source: "/dev/fd0"})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "unsupported device type")
+
+		err = manager.AttachDevice("app", common.DeviceConfig{Name: "eth1", Type: "nic", Source: "eth1"})
+		testing_internal.AssertError(t, err)
+
+		err = manager.AttachDevice("app", common.DeviceConfig{Name: "wan", Type: "nic", Source: "/sys/class/net/eth1"})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "running container")
+
+		testing_internal.AssertNoError(t, manager.AttachDevice("app", disk))
+		err = manager.AttachDevice("app", disk)
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "already has a device")
+
+		err = manager.DetachDevice("app", "missing")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "no device named")
+	})
+
+	t.Run("config_lists_devices", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Create("listed", &common.Container{}))
+		testing_internal.AssertNoError(t, manager.Update("listed", &common.Container{Devices: []common.DeviceConfig{disk}}))
+
+		data, err := os.ReadFile(filepath.Join(configPath, "listed", "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), entry)
+	})
+}
@@ -0,0 +1,22 @@
+package container
+
+import "golang.org/x/sys/unix"
+
+// deviceNumber returns whether path is a block ('b') or character ('c')
+// device node, with its major and minor numbers. ok is false for anything
+// else.
+func deviceNumber(path string) (kind byte, major, minor uint32, ok bool) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return 0, 0, 0, false
+	}
+	switch st.Mode & unix.S_IFMT {
+	case unix.S_IFBLK:
+		kind = 'b'
+	case unix.S_IFCHR:
+		kind = 'c'
+	default:
+		return 0, 0, 0, false
+	}
+	return kind, unix.Major(uint64(st.Rdev)), unix.Minor(uint64(st.Rdev)), true
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package container
+
+// deviceNumber is only supported on Linux, device nodes are never found
+func deviceNumber(_ string) (kind byte, major, minor uint32, ok bool) {
+	return 0, 0, 0, false
+}
@@ -0,0 +1,82 @@
+package container
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
+
+// DiskUsage represents the disk space consumed by a container
+type DiskUsage struct {
+	Rootfs  int64
+	Logs    int64
+	Config  int64 // Config files and everything else outside rootfs and logs
+	Total   int64
+	Partial bool // Set when some files could not be read
+}
+
+// DiskUsage returns the disk space consumed by a container's directory
+func (m *LXCManager) DiskUsage(name string) (DiskUsage, error) {
+	var usage DiskUsage
+
+	containerDir := filepath.Join(m.configPath, name)
+	if _, err := os.Stat(containerDir); err != nil {
+		return usage, fmt.Errorf("failed to access container directory: %w", err)
+	}
+
+	err := filepath.WalkDir(containerDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Report what we could read rather than failing the whole walk
+			if os.IsPermission(err) {
+				logging.Warn("Skipping unreadable path in disk usage",
+					"container", name,
+					"path", path,
+					"error", err,
+				)
+				usage.Partial = true
+				if d != nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			logging.Warn("Failed to stat file in disk usage",
+				"container", name,
+				"path", path,
+				"error", err,
+			)
+			usage.Partial = true
+			return nil
+		}
+
+		rel, _ := filepath.Rel(containerDir, path)
+		size := info.Size()
+		switch {
+		case rel == "rootfs" || strings.HasPrefix(rel, "rootfs"+string(filepath.Separator)):
+			usage.Rootfs += size
+		case rel == "logs" || strings.HasPrefix(rel, "logs"+string(filepath.Separator)):
+			usage.Logs += size
+		default:
+			usage.Config += size
+		}
+		usage.Total += size
+		return nil
+	})
+	if err != nil {
+		return usage, fmt.Errorf("failed to walk container directory: %w", err)
+	}
+
+	return usage, nil
+}
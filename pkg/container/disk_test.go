@@ -0,0 +1,47 @@
+package container_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestDiskUsage(t *testing.T) {
+	tmpDir := t.TempDir()
+	containerName := "test-container"
+	containerDir := filepath.Join(tmpDir, containerName)
+
+	files := map[string]int{
+		filepath.Join("rootfs", "bin", "sh"):    100,
+		filepath.Join("rootfs", "etc", "hosts"): 20,
+		filepath.Join("logs", "console.log"):    30,
+		"config":                                5,
+		"network.conf":                          7,
+	}
+	for path, size := range files {
+		fullPath := filepath.Join(containerDir, path)
+		testing_internal.AssertNoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		testing_internal.AssertNoError(t, os.WriteFile(fullPath, make([]byte, size), 0644))
+	}
+
+	manager, err := container.NewLXCManager(tmpDir)
+	testing_internal.AssertNoError(t, err)
+
+	t.Run("sums_by_category", func(t *testing.T) {
+		usage, err := manager.DiskUsage(containerName)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, int64(120), usage.Rootfs)
+		testing_internal.AssertEqual(t, int64(30), usage.Logs)
+		testing_internal.AssertEqual(t, int64(12), usage.Config)
+		testing_internal.AssertEqual(t, int64(162), usage.Total)
+		testing_internal.AssertEqual(t, false, usage.Partial)
+	})
+
+	t.Run("missing_container", func(t *testing.T) {
+		_, err := manager.DiskUsage("nonexistent")
+		testing_internal.AssertError(t, err)
+	})
+}
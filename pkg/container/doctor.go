@@ -0,0 +1,188 @@
+package container
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultBridge is the bridge LXC's default network configuration uses
+const DefaultBridge = "lxcbr0"
+
+// DoctorOptions configures Diagnose. Empty fields fall back to the host defaults.
+type DoctorOptions struct {
+	ConfigDir  string
+	Bridge     string
+	User       string
+	CgroupRoot string
+	SubUIDFile string
+	SubGIDFile string
+}
+
+// DoctorCheck is the outcome of one environment check. Hint says how to fix
+// a failed check; optional checks only matter for some commands.
+type DoctorCheck struct {
+	Name     string
+	OK       bool
+	Optional bool
+	Detail   string
+	Hint     string
+}
+
+// Diagnose checks the host for what lxc-compose needs: the LXC tools, a
+// usable cgroup hierarchy, the default bridge, subordinate ids for
+// unprivileged containers, a writable config directory and docker for
+// image operations
+func Diagnose(opts DoctorOptions) []DoctorCheck {
+	if opts.Bridge == "" {
+		opts.Bridge = DefaultBridge
+	}
+	if opts.CgroupRoot == "" {
+		opts.CgroupRoot = "/sys/fs/cgroup"
+	}
+	if opts.SubUIDFile == "" {
+		opts.SubUIDFile = "/etc/subuid"
+	}
+	if opts.SubGIDFile == "" {
+		opts.SubGIDFile = "/etc/subgid"
+	}
+	if opts.User == "" {
+		if u, err := user.Current(); err == nil {
+			opts.User = u.Username
+		}
+	}
+
+	return []DoctorCheck{
+		checkLXCBinaries(),
+		checkCgroupVersion(opts.CgroupRoot),
+		checkDefaultBridge(opts.Bridge),
+		checkSubordinateIDs("Subordinate uids", opts.SubUIDFile, opts.User, "--add-subuids"),
+		checkSubordinateIDs("Subordinate gids", opts.SubGIDFile, opts.User, "--add-subgids"),
+		checkConfigDir(opts.ConfigDir),
+		checkDocker(),
+	}
+}
+
+func checkLXCBinaries() DoctorCheck {
+	check := DoctorCheck{Name: "LXC binaries"}
+	if missing := missingLXCBinaries(); len(missing) > 0 {
+		check.Detail = "missing " + strings.Join(missing, ", ") + " on PATH"
+		check.Hint = "install LXC with 'apt install lxc'"
+		return check
+	}
+	check.OK = true
+	check.Detail = strings.Join(requiredLXCBinaries, ", ") + " found"
+	return check
+}
+
+func checkCgroupVersion(root string) DoctorCheck {
+	check := DoctorCheck{Name: "cgroup version"}
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		check.OK = true
+		check.Detail = "cgroup v2 (unified) mounted at " + root
+		return check
+	}
+	for _, controller := range []string{"memory", "cpu"} {
+		if _, err := os.Stat(filepath.Join(root, controller)); err == nil {
+			check.OK = true
+			check.Detail = "cgroup v1 (legacy) mounted at " + root
+			check.Hint = "cgroup v2 is recommended; boot with systemd.unified_cgroup_hierarchy=1"
+			return check
+		}
+	}
+	check.Detail = "no cgroup hierarchy found at " + root
+	check.Hint = "mount cgroup2 at /sys/fs/cgroup, e.g. 'mount -t cgroup2 none /sys/fs/cgroup'"
+	return check
+}
+
+func checkDefaultBridge(bridge string) DoctorCheck {
+	check := DoctorCheck{Name: "Default bridge"}
+	if !bridgeExists(bridge) {
+		check.Detail = bridge + " not found"
+		check.Hint = "enable LXC networking with USE_LXC_BRIDGE=\"true\" in /etc/default/lxc-net and 'systemctl restart lxc-net'"
+		return check
+	}
+	check.OK = true
+	check.Detail = bridge + " exists"
+	return check
+}
+
+// checkSubordinateIDs looks for a range for username in a subuid or subgid file
+func checkSubordinateIDs(name, path, username, usermodFlag string) DoctorCheck {
+	check := DoctorCheck{Name: name, Optional: true}
+	hint := fmt.Sprintf("add a range for unprivileged containers with 'usermod %s 100000-165535 %s'", usermodFlag, username)
+
+	f, err := os.Open(path)
+	if err != nil {
+		check.Detail = fmt.Sprintf("failed to read %s: %v", path, err)
+		check.Hint = hint
+		return check
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		owner, _, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if ok && username != "" && owner == username {
+			check.OK = true
+			check.Detail = fmt.Sprintf("%s has a range in %s", username, path)
+			return check
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		check.Detail = fmt.Sprintf("failed to read %s: %v", path, err)
+		check.Hint = hint
+		return check
+	}
+
+	check.Detail = fmt.Sprintf("no range for %s in %s", username, path)
+	check.Hint = hint
+	return check
+}
+
+// checkConfigDir makes sure dir can be created and written to
+func checkConfigDir(dir string) DoctorCheck {
+	check := DoctorCheck{Name: "Config directory"}
+	hint := "fix the directory's permissions or point --config-dir or LXC_COMPOSE_DIR at a writable directory"
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		check.Detail = fmt.Sprintf("cannot create %s: %v", dir, err)
+		check.Hint = hint
+		return check
+	}
+	f, err := os.CreateTemp(dir, ".doctor-")
+	if err != nil {
+		check.Detail = fmt.Sprintf("cannot write to %s: %v", dir, err)
+		check.Hint = hint
+		return check
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	check.OK = true
+	check.Detail = dir + " is writable"
+	return check
+}
+
+// checkDocker checks docker is installed and its daemon answers, which image
+// pull, commit and convert rely on
+func checkDocker() DoctorCheck {
+	check := DoctorCheck{Name: "Docker", Optional: true}
+	if _, err := exec.LookPath("docker"); err != nil {
+		check.Detail = "docker not found on PATH"
+		check.Hint = "install docker to pull, commit and convert images"
+		return check
+	}
+	if err := ExecCommand("docker", "info").Run(); err != nil {
+		check.Detail = fmt.Sprintf("docker daemon not reachable: %v", err)
+		check.Hint = "start it with 'systemctl start docker' and make sure your user can access /var/run/docker.sock"
+		return check
+	}
+	check.OK = true
+	check.Detail = "docker daemon reachable"
+	return check
+}
@@ -0,0 +1,92 @@
+package container_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	dir := t.TempDir()
+	cgroupRoot := filepath.Join(dir, "cgroup")
+	testing_internal.AssertNoError(t, os.MkdirAll(cgroupRoot, 0755))
+	testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(cgroupRoot, "cgroup.controllers"), []byte("cpu memory"), 0644))
+	testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(dir, "subuid"), []byte("alice:100000:65536\n"), 0644))
+	testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(dir, "subgid"), []byte("bob:100000:65536\n"), 0644))
+
+	// Stub binaries so the PATH lookups succeed
+	bin := filepath.Join(dir, "bin")
+	testing_internal.AssertNoError(t, os.MkdirAll(bin, 0755))
+	for _, name := range []string{"lxc-start", "lxc-stop", "lxc-info", "lxc-destroy", "docker"} {
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"), 0755))
+	}
+	t.Setenv("PATH", bin)
+
+	opts := container.DoctorOptions{
+		ConfigDir:  filepath.Join(dir, "config"),
+		User:       "alice",
+		CgroupRoot: cgroupRoot,
+		SubUIDFile: filepath.Join(dir, "subuid"),
+		SubGIDFile: filepath.Join(dir, "subgid"),
+	}
+
+	results := func(checks []container.DoctorCheck) map[string]container.DoctorCheck {
+		byName := make(map[string]container.DoctorCheck)
+		for _, check := range checks {
+			byName[check.Name] = check
+		}
+		return byName
+	}
+
+	t.Run("reports_each_check", func(t *testing.T) {
+		checks := results(container.Diagnose(opts))
+		testing_internal.AssertEqual(t, 7, len(checks))
+		testing_internal.AssertEqual(t, true, checks["LXC binaries"].OK)
+		testing_internal.AssertEqual(t, true, checks["cgroup version"].OK)
+		testing_internal.AssertContains(t, checks["cgroup version"].Detail, "cgroup v2")
+		testing_internal.AssertEqual(t, true, checks["Default bridge"].OK)
+		testing_internal.AssertEqual(t, true, checks["Subordinate uids"].OK)
+		testing_internal.AssertEqual(t, true, checks["Config directory"].OK)
+		testing_internal.AssertEqual(t, true, checks["Docker"].OK)
+
+		gids := checks["Subordinate gids"]
+		testing_internal.AssertEqual(t, false, gids.OK)
+		testing_internal.AssertEqual(t, true, gids.Optional)
+		testing_internal.AssertContains(t, gids.Hint, "usermod --add-subgids 100000-165535 alice")
+	})
+
+	t.Run("failures_have_hints", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+
+		// The bridge and docker lookups fail, there is no cgroup v2 hierarchy
+		// and the config dir sits under a file
+		calls, restore := mock.RecordCalls(&container.ExecCommand, "ip", "docker")
+		defer restore()
+		calls.Stub("ip", func([]string) *exec.Cmd { return exec.Command("/bin/false") })
+		calls.Stub("docker", func([]string) *exec.Cmd { return exec.Command("/bin/false") })
+		blocker := filepath.Join(t.TempDir(), "file")
+		testing_internal.AssertNoError(t, os.WriteFile(blocker, nil, 0644))
+
+		opts := opts
+		opts.CgroupRoot = t.TempDir()
+		opts.ConfigDir = filepath.Join(blocker, "config")
+		opts.User = "carol"
+
+		for _, check := range container.Diagnose(opts) {
+			if check.OK {
+				t.Errorf("check %q passed: %s", check.Name, check.Detail)
+			}
+			if check.Hint == "" {
+				t.Errorf("check %q failed without a hint", check.Name)
+			}
+		}
+	})
+}
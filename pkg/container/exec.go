@@ -1,7 +1,96 @@
 package container
 
-import "os/exec"
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
 
 // ExecCommand is a variable that holds the exec.Command function.
 // This allows us to replace it with a mock during testing.
-var ExecCommand = exec.Command
+var ExecCommand = lxcCommand
+
+// ExecOptions represents options for running a command inside a container
+type ExecOptions struct {
+	Command []string
+	Env     map[string]string
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+	// TTY puts a terminal Stdin into raw mode for the session. lxc-attach
+	// gives the command a pty of its own when its stdin is a terminal.
+	TTY bool
+	// Signals received on the channel are forwarded to the command
+	Signals <-chan os.Signal
+}
+
+// Exec runs a command inside a running container, streaming its output
+func (m *LXCManager) Exec(name string, opts ExecOptions) error {
+	if len(opts.Command) == 0 {
+		return fmt.Errorf("command is required")
+	}
+
+	container, err := m.lookup(name)
+	if err != nil {
+		return fmt.Errorf("failed to get container: %w", err)
+	}
+
+	if container.State != "RUNNING" {
+		return fmt.Errorf("container '%s' is not running (current state: %s)", name, container.State)
+	}
+
+	args := []string{"-n", name}
+
+	// Sort for a stable command line
+	keys := make([]string, 0, len(opts.Env))
+	for k := range opts.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--set-var", fmt.Sprintf("%s=%s", k, opts.Env[k]))
+	}
+
+	args = append(args, "--")
+	args = append(args, opts.Command...)
+
+	cmd := m.lxcPathCommand("lxc-attach", args...)
+	cmd.Stdin = opts.Stdin
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+
+	if opts.TTY {
+		restore, err := makeRawTerminal(opts.Stdin)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("command failed in container '%s': %w", name, err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-opts.Signals:
+				if err := cmd.Process.Signal(sig); err != nil {
+					logging.Debug("Failed to forward signal", "container", name, "signal", sig, "error", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("command failed in container '%s': %w", name, err)
+	}
+
+	return nil
+}
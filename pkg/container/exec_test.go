@@ -0,0 +1,80 @@
+package container_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestExec(t *testing.T) {
+	containerName := "test-container-exec"
+	configPath := t.TempDir()
+
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(configPath)
+	testing_internal.AssertNoError(t, err)
+
+	err = manager.Create(containerName, &common.Container{})
+	testing_internal.AssertNoError(t, err)
+	err = mockCmd.AddContainer(containerName, "STOPPED")
+	testing_internal.AssertNoError(t, err)
+
+	t.Run("not_running", func(t *testing.T) {
+		err := manager.Exec(containerName, container.ExecOptions{Command: []string{"true"}})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "not running")
+	})
+
+	err = manager.Start(containerName)
+	testing_internal.AssertNoError(t, err)
+
+	t.Run("missing_command", func(t *testing.T) {
+		err := manager.Exec(containerName, container.ExecOptions{})
+		testing_internal.AssertError(t, err)
+	})
+
+	t.Run("streams_output", func(t *testing.T) {
+		calls, restore := mock.RecordCalls(&container.ExecCommand, "lxc-attach")
+		defer restore()
+		calls.Stub("lxc-attach", func([]string) *exec.Cmd { return exec.Command("echo", "hello") })
+
+		var stdout bytes.Buffer
+		err := manager.Exec(containerName, container.ExecOptions{
+			Command: []string{"echo", "hello"},
+			Env:     map[string]string{"FOO": "bar"},
+			Stdout:  &stdout,
+		})
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "hello\n", stdout.String())
+		testing_internal.AssertEqual(t,
+			"-n "+containerName+" --set-var FOO=bar -- echo hello",
+			strings.Join(calls.Args(), "\n"))
+	})
+
+	t.Run("forwards_signals", func(t *testing.T) {
+		calls, restore := mock.RecordCalls(&container.ExecCommand, "lxc-attach")
+		defer restore()
+		calls.Stub("lxc-attach", func([]string) *exec.Cmd { return exec.Command("sleep", "30") })
+
+		signals := make(chan os.Signal, 1)
+		signals <- syscall.SIGTERM
+		start := time.Now()
+		err := manager.Exec(containerName, container.ExecOptions{Command: []string{"sleep", "30"}, Signals: signals})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "terminated")
+		if time.Since(start) > 10*time.Second {
+			t.Errorf("command was not signalled, took %s", time.Since(start))
+		}
+	})
+}
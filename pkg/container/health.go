@@ -0,0 +1,329 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
+
+// Defaults for unset HealthCheck fields
+const (
+	defaultHealthInterval = 5 * time.Second
+	defaultHealthTimeout  = 3 * time.Second
+	defaultHealthRetries  = 3
+
+	// healthHookTimeout bounds how long a health hook may run
+	healthHookTimeout = 30 * time.Second
+)
+
+// CheckHealth runs a container's health check probe once
+func (m *LXCManager) CheckHealth(ctx context.Context, name string) error {
+	container, hc, err := m.healthCheck(name)
+	if err != nil {
+		return err
+	}
+	_, timeout, _ := healthCheckTiming(hc)
+	return m.probe(ctx, name, container.Config, hc, timeout)
+}
+
+// WaitHealthy probes a running container until its health check passes,
+// recording it as starting, healthy or unhealthy along the way. It gives up
+// once the check has failed Retries times in a row.
+func (m *LXCManager) WaitHealthy(ctx context.Context, name string) error {
+	container, hc, err := m.healthCheck(name)
+	if err != nil {
+		return err
+	}
+	if container.State != "RUNNING" {
+		return fmt.Errorf("container '%s' is not running (current state: %s)", name, container.State)
+	}
+
+	interval, timeout, retries := healthCheckTiming(hc)
+	if err := m.setHealth(ctx, name, hc, HealthStarting); err != nil {
+		return fmt.Errorf("failed to record health: %w", err)
+	}
+
+	failures := 0
+	for {
+		err := m.probe(ctx, name, container.Config, hc, timeout)
+		if err == nil {
+			if err := m.setHealth(ctx, name, hc, HealthHealthy); err != nil {
+				return fmt.Errorf("failed to record health: %w", err)
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		failures++
+		logging.Debug("Health check failed",
+			"container", name,
+			"failures", failures,
+			"error", err,
+		)
+		if failures >= retries {
+			if err := m.setHealth(ctx, name, hc, HealthUnhealthy); err != nil {
+				return fmt.Errorf("failed to record health: %w", err)
+			}
+			return fmt.Errorf("container '%s' is unhealthy: %w", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// MonitorHealth probes every running container that has a health check
+// once. failures carries each container's consecutive failures from one call
+// to the next: a container is marked healthy when its probe passes and
+// unhealthy once it has failed Retries times in a row. Probe and hook
+// failures are logged rather than returned.
+func (m *LXCManager) MonitorHealth(ctx context.Context, failures map[string]int) error {
+	containers, err := m.ListWithOptions(ListOptions{State: "RUNNING"})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range containers {
+		if c.Config == nil || c.Config.HealthCheck == nil {
+			continue
+		}
+		hc := c.Config.HealthCheck.ToCommonHealthCheck()
+		_, timeout, retries := healthCheckTiming(hc)
+
+		health := HealthHealthy
+		if err := m.probe(ctx, c.Name, c.Config, hc, timeout); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			failures[c.Name]++
+			logging.Debug("Health check failed",
+				"container", c.Name,
+				"failures", failures[c.Name],
+				"error", err,
+			)
+			if failures[c.Name] < retries {
+				continue
+			}
+			health = HealthUnhealthy
+		} else {
+			delete(failures, c.Name)
+		}
+
+		if err := m.setHealth(ctx, c.Name, hc, health); err != nil {
+			logging.Error("Failed to record health", "name", c.Name, "error", err)
+		}
+	}
+	return nil
+}
+
+// setHealth records a container's health and runs its health check's hook
+// when the container has just become healthy or unhealthy. Hook failures are
+// logged, only failing to record the health is an error.
+func (m *LXCManager) setHealth(ctx context.Context, name string, hc *common.HealthCheck, health string) error {
+	if err := m.state.SetHealth(name, health); err != nil {
+		return err
+	}
+
+	var hook *common.HealthHook
+	switch health {
+	case HealthHealthy:
+		hook = hc.OnHealthy
+	case HealthUnhealthy:
+		hook = hc.OnUnhealthy
+	default:
+		return nil
+	}
+
+	changed, err := m.state.NotifyHealth(name, health)
+	if err != nil {
+		return err
+	}
+	if !changed || hook == nil {
+		return nil
+	}
+	if err := runHealthHook(ctx, name, health, hook); err != nil {
+		logging.Error("Health hook failed", "name", name, "health", health, "error", err)
+	}
+	return nil
+}
+
+// runHealthHook runs a command on the host or POSTs to a webhook to notify
+// that a container's health changed, giving up after healthHookTimeout
+func runHealthHook(ctx context.Context, name, health string, hook *common.HealthHook) error {
+	ctx, cancel := context.WithTimeout(ctx, healthHookTimeout)
+	defer cancel()
+
+	if hook.URL != "" {
+		body, err := json.Marshal(map[string]string{
+			"container": name,
+			"health":    health,
+			"time":      time.Now().UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("webhook failed: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("webhook failed: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("webhook returned %s", resp.Status)
+		}
+		return nil
+	}
+
+	cmd := ExecCommand(hook.Command[0], hook.Command[1:]...)
+	cmd.Env = append(os.Environ(), "LXC_COMPOSE_CONTAINER="+name, "LXC_COMPOSE_HEALTH="+health)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("hook command failed: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("hook command failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		<-done
+		return fmt.Errorf("hook command timed out: %w", ctx.Err())
+	}
+}
+
+// healthCheck returns a container along with its health check
+func (m *LXCManager) healthCheck(name string) (*Container, *common.HealthCheck, error) {
+	container, err := m.lookup(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get container: %w", err)
+	}
+	if container.Config == nil || container.Config.HealthCheck == nil {
+		return nil, nil, fmt.Errorf("container '%s' has no health check", name)
+	}
+	return container, container.Config.HealthCheck.ToCommonHealthCheck(), nil
+}
+
+// healthCheckTiming returns the interval, timeout and retries of hc with
+// defaults applied. Durations are validated when the container is created.
+func healthCheckTiming(hc *common.HealthCheck) (time.Duration, time.Duration, int) {
+	interval, timeout, retries := defaultHealthInterval, defaultHealthTimeout, defaultHealthRetries
+	if d, err := time.ParseDuration(hc.Interval); err == nil && d > 0 {
+		interval = d
+	}
+	if d, err := time.ParseDuration(hc.Timeout); err == nil && d > 0 {
+		timeout = d
+	}
+	if hc.Retries > 0 {
+		retries = hc.Retries
+	}
+	return interval, timeout, retries
+}
+
+// probe runs a single health check, failing if it takes longer than timeout
+func (m *LXCManager) probe(ctx context.Context, name string, cfg *config.Container, hc *common.HealthCheck, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch strings.ToLower(hc.Type) {
+	case common.HealthCheckTCP:
+		ip, err := m.containerIP(name, cfg)
+		if err != nil {
+			return err
+		}
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(hc.Port)))
+		if err != nil {
+			return fmt.Errorf("tcp probe failed: %w", err)
+		}
+		return conn.Close()
+
+	case common.HealthCheckHTTP:
+		ip, err := m.containerIP(name, cfg)
+		if err != nil {
+			return err
+		}
+		path := hc.Path
+		if path == "" {
+			path = "/"
+		}
+		url := fmt.Sprintf("http://%s%s", net.JoinHostPort(ip, strconv.Itoa(hc.Port)), path)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("http probe failed: %w", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("http probe failed: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("http probe returned %s", resp.Status)
+		}
+		return nil
+
+	default:
+		args := append([]string{"-n", name, "--"}, hc.Command...)
+		cmd := m.lxcPathCommand("lxc-attach", args...)
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("command probe failed: %w", err)
+		}
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+		select {
+		case err := <-done:
+			if err != nil {
+				return fmt.Errorf("command probe failed: %w", err)
+			}
+			return nil
+		case <-ctx.Done():
+			_ = cmd.Process.Kill()
+			<-done
+			return fmt.Errorf("command probe timed out after %s", timeout)
+		}
+	}
+}
+
+// containerIP returns the first static address in a container's network
+// config, falling back to the address LXC reports, e.g. from a DHCP lease
+func (m *LXCManager) containerIP(name string, cfg *config.Container) (string, error) {
+	if cfg != nil && cfg.Network != nil {
+		for _, iface := range networkInterfaces(cfg.Network.ToCommonNetworkConfig()) {
+			if iface.IP != "" && !iface.DHCP {
+				ip, _, _ := strings.Cut(iface.IP, "/")
+				return ip, nil
+			}
+		}
+	}
+
+	ips, err := m.GetIP(name)
+	if err != nil {
+		return "", err
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("container '%s' has no IP address", name)
+	}
+	return ips[0].String(), nil
+}
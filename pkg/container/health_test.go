@@ -0,0 +1,191 @@
+package container_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestWaitHealthy(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+
+	start := func(name string, hc *common.HealthCheck) {
+		t.Helper()
+		hc.Interval, hc.Retries = "10ms", 2
+		testing_internal.AssertNoError(t, manager.Create(name, &common.Container{
+			Image:       "ubuntu:20.04",
+			Network:     &common.NetworkConfig{Type: "veth", IP: "127.0.0.1/8"},
+			HealthCheck: hc,
+		}))
+		testing_internal.AssertNoError(t, mockCmd.AddContainer(name, "STOPPED"))
+		testing_internal.AssertNoError(t, manager.Start(name))
+	}
+	health := func(name string) string {
+		t.Helper()
+		status, err := manager.HealthStatus(name)
+		testing_internal.AssertNoError(t, err)
+		return status
+	}
+
+	t.Run("tcp", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		testing_internal.AssertNoError(t, err)
+		defer ln.Close()
+
+		start("tcp", &common.HealthCheck{Type: "tcp", Port: ln.Addr().(*net.TCPAddr).Port})
+		testing_internal.AssertNoError(t, manager.WaitHealthy(context.Background(), "tcp"))
+		testing_internal.AssertEqual(t, container.HealthHealthy, health("tcp"))
+	})
+
+	t.Run("http", func(t *testing.T) {
+		status := http.StatusServiceUnavailable
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/healthz" {
+				http.NotFound(w, r)
+				return
+			}
+			w.WriteHeader(status)
+		}))
+		defer srv.Close()
+		_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+		p, _ := strconv.Atoi(port)
+
+		start("http", &common.HealthCheck{Type: "http", Port: p, Path: "/healthz"})
+		err := manager.WaitHealthy(context.Background(), "http")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "503")
+		testing_internal.AssertEqual(t, container.HealthUnhealthy, health("http"))
+
+		status = http.StatusOK
+		testing_internal.AssertNoError(t, manager.WaitHealthy(context.Background(), "http"))
+		testing_internal.AssertEqual(t, container.HealthHealthy, health("http"))
+	})
+
+	t.Run("command", func(t *testing.T) {
+		healthy := "false"
+		calls, restore := mock.RecordCalls(&container.ExecCommand, "lxc-attach")
+		defer restore()
+		calls.Stub("lxc-attach", func([]string) *exec.Cmd { return exec.Command(healthy) })
+
+		start("command", &common.HealthCheck{Command: []string{"pg_isready"}})
+		testing_internal.AssertError(t, manager.CheckHealth(context.Background(), "command"))
+		testing_internal.AssertEqual(t, "-n command -- pg_isready", strings.Join(calls.Args(), "\n"))
+
+		healthy = "true"
+		testing_internal.AssertNoError(t, manager.WaitHealthy(context.Background(), "command"))
+	})
+
+	t.Run("no_health_check", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Create("plain", &common.Container{Image: "ubuntu:20.04"}))
+		err := manager.CheckHealth(context.Background(), "plain")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "no health check")
+	})
+}
+
+func TestHealthHooks(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+
+	var webhooks []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		testing_internal.AssertNoError(t, json.NewDecoder(r.Body).Decode(&body))
+		webhooks = append(webhooks, body)
+	}))
+	defer srv.Close()
+
+	var hook *exec.Cmd
+	hooks, restore := mock.RecordCalls(&container.ExecCommand, "notify")
+	defer restore()
+	hooks.Stub("notify", func([]string) *exec.Cmd {
+		hook = exec.Command("true")
+		return hook
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	testing_internal.AssertNoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	testing_internal.AssertNoError(t, ln.Close())
+
+	testing_internal.AssertNoError(t, manager.Create("web", &common.Container{
+		Image:   "ubuntu:20.04",
+		Network: &common.NetworkConfig{Type: "veth", IP: "127.0.0.1/8"},
+		HealthCheck: &common.HealthCheck{
+			Type: "tcp", Port: port, Interval: "10ms", Retries: 2,
+			OnHealthy:   &common.HealthHook{Command: []string{"notify"}},
+			OnUnhealthy: &common.HealthHook{URL: srv.URL},
+		},
+	}))
+	testing_internal.AssertNoError(t, mockCmd.AddContainer("web", "STOPPED"))
+	testing_internal.AssertNoError(t, manager.Start("web"))
+
+	t.Run("fires once per transition", func(t *testing.T) {
+		testing_internal.AssertError(t, manager.WaitHealthy(context.Background(), "web"))
+		testing_internal.AssertError(t, manager.WaitHealthy(context.Background(), "web"))
+		testing_internal.AssertEqual(t, 1, len(webhooks))
+		testing_internal.AssertEqual(t, "web", webhooks[0]["container"])
+		testing_internal.AssertEqual(t, container.HealthUnhealthy, webhooks[0]["health"])
+
+		ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		testing_internal.AssertNoError(t, err)
+		defer ln.Close()
+
+		testing_internal.AssertNoError(t, manager.WaitHealthy(context.Background(), "web"))
+		testing_internal.AssertNotNil(t, hook)
+		testing_internal.AssertContains(t, strings.Join(hook.Env, " "), "LXC_COMPOSE_CONTAINER=web")
+		testing_internal.AssertContains(t, strings.Join(hook.Env, " "), "LXC_COMPOSE_HEALTH=healthy")
+
+		hook = nil
+		testing_internal.AssertNoError(t, manager.WaitHealthy(context.Background(), "web"))
+		testing_internal.AssertEqual(t, (*exec.Cmd)(nil), hook)
+		testing_internal.AssertEqual(t, 1, len(webhooks))
+	})
+
+	t.Run("monitor", func(t *testing.T) {
+		failures := make(map[string]int)
+		// The listener of the previous test is closed, so probes fail again
+		testing_internal.AssertNoError(t, manager.MonitorHealth(context.Background(), failures))
+		testing_internal.AssertEqual(t, 1, failures["web"])
+		testing_internal.AssertEqual(t, 1, len(webhooks))
+
+		testing_internal.AssertNoError(t, manager.MonitorHealth(context.Background(), failures))
+		testing_internal.AssertEqual(t, 2, len(webhooks))
+		status, err := manager.HealthStatus("web")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, container.HealthUnhealthy, status)
+	})
+
+	t.Run("failing hook", func(t *testing.T) {
+		srv.Close()
+		ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertNoError(t, manager.WaitHealthy(context.Background(), "web"))
+		testing_internal.AssertNoError(t, ln.Close())
+
+		// The webhook server is gone, which is logged rather than returned
+		failures := map[string]int{"web": 1}
+		testing_internal.AssertNoError(t, manager.MonitorHealth(context.Background(), failures))
+		status, err := manager.HealthStatus("web")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, container.HealthUnhealthy, status)
+	})
+}
@@ -0,0 +1,86 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
+
+// selfHostsAddress is the loopback address Debian maps the host's own name to
+const selfHostsAddress = "127.0.1.1"
+
+// resolveHostname returns a container's hostname: the top-level network
+// hostname, then the first interface hostname, then the container name
+func resolveHostname(name string, cfg *common.Container) string {
+	if cfg != nil && cfg.Network != nil {
+		if cfg.Network.Hostname != "" {
+			return cfg.Network.Hostname
+		}
+		for _, iface := range cfg.Network.Interfaces {
+			if iface.Hostname != "" {
+				return iface.Hostname
+			}
+		}
+	}
+	return strings.ReplaceAll(name, "_", "-")
+}
+
+// writeHostname writes /etc/hostname and the 127.0.1.1 self-entry in
+// /etc/hosts, following Debian conventions. An FQDN hostname is split so
+// /etc/hostname gets the short name and /etc/hosts lists both. It does
+// nothing until the image has been extracted into the rootfs.
+func (m *LXCManager) writeHostname(name, hostname string) error {
+	rootfs := filepath.Join(m.configPath, name, "rootfs")
+	if info, err := os.Stat(filepath.Join(rootfs, "etc")); err != nil || !info.IsDir() {
+		logging.Debug("Skipping hostname files, rootfs not extracted", "container", name)
+		return nil
+	}
+
+	short, _, _ := strings.Cut(hostname, ".")
+	names := short
+	if short != hostname {
+		names = hostname + " " + short
+	}
+
+	hostnamePath, err := resolveInRootfs(rootfs, "/etc/hostname")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(hostnamePath, []byte(short+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write /etc/hostname: %w", err)
+	}
+
+	hostsPath, err := resolveInRootfs(rootfs, "/etc/hosts")
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(hostsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read /etc/hosts: %w", err)
+	}
+	if os.IsNotExist(err) {
+		data = []byte("127.0.0.1\tlocalhost\n")
+	}
+
+	entry := selfHostsAddress + "\t" + names
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	replaced := false
+	for i, line := range lines {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == selfHostsAddress {
+			lines[i] = entry
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		lines = append(lines, entry)
+	}
+	if err := os.WriteFile(hostsPath, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write /etc/hosts: %w", err)
+	}
+	return nil
+}
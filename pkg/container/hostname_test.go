@@ -0,0 +1,77 @@
+package container_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestCreateWritesHostname(t *testing.T) {
+	tmpDir := t.TempDir()
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(tmpDir)
+	testing_internal.AssertNoError(t, err)
+
+	t.Run("network_hostname", func(t *testing.T) {
+		etc := filepath.Join(tmpDir, "web", "rootfs", "etc")
+		testing_internal.AssertNoError(t, os.MkdirAll(etc, 0755))
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(etc, "hosts"), []byte("127.0.0.1\tlocalhost\n127.0.1.1\tdebian\n::1\tlocalhost ip6-localhost\n"), 0644))
+
+		err := manager.Create("web", &common.Container{
+			Network: &common.NetworkConfig{Type: "veth", Hostname: "frontend", DHCP: true},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(etc, "hostname"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "frontend\n", string(data))
+
+		data, err = os.ReadFile(filepath.Join(etc, "hosts"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "127.0.0.1\tlocalhost\n127.0.1.1\tfrontend\n::1\tlocalhost ip6-localhost\n", string(data))
+	})
+
+	t.Run("fqdn", func(t *testing.T) {
+		etc := filepath.Join(tmpDir, "db", "rootfs", "etc")
+		testing_internal.AssertNoError(t, os.MkdirAll(etc, 0755))
+
+		err := manager.Create("db", &common.Container{
+			Network: &common.NetworkConfig{Type: "veth", Hostname: "db.example.com", DHCP: true},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(etc, "hostname"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "db\n", string(data))
+
+		data, err = os.ReadFile(filepath.Join(etc, "hosts"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "127.0.0.1\tlocalhost\n127.0.1.1\tdb.example.com db\n", string(data))
+	})
+
+	t.Run("container_name_fallback", func(t *testing.T) {
+		etc := filepath.Join(tmpDir, "web_1", "rootfs", "etc")
+		testing_internal.AssertNoError(t, os.MkdirAll(etc, 0755))
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(etc, "hosts"), []byte("127.0.0.1\tlocalhost\n"), 0644))
+
+		testing_internal.AssertNoError(t, manager.Create("web_1", &common.Container{}))
+
+		data, err := os.ReadFile(filepath.Join(etc, "hostname"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "web-1\n", string(data))
+	})
+
+	t.Run("rootfs_not_extracted", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Create("bare", &common.Container{}))
+
+		_, err := os.Stat(filepath.Join(tmpDir, "bare", "rootfs", "etc"))
+		testing_internal.AssertEqual(t, true, os.IsNotExist(err))
+	})
+}
@@ -0,0 +1,305 @@
+package container
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/oci"
+)
+
+// ImageStore provides the images containers are created from
+type ImageStore interface {
+	// Image returns the docker save archive of the named image, pulling it
+	// first if the store's pull policy calls for it
+	Image(ctx context.Context, image string) ([]byte, error)
+	// Commit stores a docker save archive under ref, recording the
+	// container it was committed from
+	Commit(ref oci.ImageReference, archive []byte, source string) error
+}
+
+// SetImageStore makes Create unpack each container's image into its rootfs
+// and lets Commit store images. Without one, Create leaves the rootfs empty.
+func (m *LXCManager) SetImageStore(images ImageStore) {
+	m.images = images
+}
+
+// Layer whiteouts as written by docker save, see the OCI image layer spec
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// imageManifest is an entry of the manifest.json in a docker save archive
+type imageManifest struct {
+	Config   string   `json:"Config,omitempty"`
+	RepoTags []string `json:"RepoTags,omitempty"`
+	Layers   []string `json:"Layers"`
+}
+
+// extractImage unpacks the layers of a docker save archive into rootfs in
+// order, applying whiteouts. Entries are resolved with resolveInRootfs, so
+// symlinks in the image can't point writes outside rootfs.
+func extractImage(archive []byte, rootfs string) error {
+	layers, err := imageLayers(archive)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range layers {
+		layer, err := archiveFile(archive, name)
+		if err != nil {
+			return err
+		}
+		if err := extractLayer(layer, rootfs); err != nil {
+			return fmt.Errorf("failed to extract layer %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// imageLayers returns the layer tarballs of a docker save archive, lowest first
+func imageLayers(archive []byte) ([]string, error) {
+	manifestData, err := archiveFile(archive, "manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	var manifests []imageManifest
+	if err := json.Unmarshal(manifestData, &manifests); err != nil {
+		return nil, fmt.Errorf("failed to parse image manifest: %w", err)
+	}
+	if len(manifests) != 1 {
+		return nil, fmt.Errorf("expected one image in archive, found %d", len(manifests))
+	}
+	return manifests[0].Layers, nil
+}
+
+// layerReader streams a layer tarball of a docker save archive, gunzipping
+// it if needed, without reading the whole layer into memory
+func layerReader(archive []byte, name string) (io.Reader, error) {
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in image archive", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image archive: %w", err)
+		}
+		if path.Clean(hdr.Name) != path.Clean(name) {
+			continue
+		}
+		br := bufio.NewReader(tr)
+		if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+			return gzip.NewReader(br)
+		}
+		return br, nil
+	}
+}
+
+// archiveFile returns the contents of a file in a tar archive
+func archiveFile(archive []byte, name string) ([]byte, error) {
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s not found in image archive", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image archive: %w", err)
+		}
+		if path.Clean(hdr.Name) == path.Clean(name) {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// extractLayer applies one layer tarball, plain or gzipped, to rootfs.
+// Whiteouts only remove what lower layers created, so they are applied in a
+// first pass before any of the layer's own entries are written.
+func extractLayer(layer []byte, rootfs string) error {
+	if bytes.HasPrefix(layer, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(layer))
+		if err != nil {
+			return err
+		}
+		if layer, err = io.ReadAll(zr); err != nil {
+			return err
+		}
+	}
+
+	if err := walkLayer(bytes.NewReader(layer), func(hdr *tar.Header, _ io.Reader) error {
+		dir, base := path.Split(path.Clean("/" + hdr.Name))
+		switch {
+		case base == whiteoutOpaque:
+			target, err := resolveInRootfs(rootfs, dir)
+			if err != nil {
+				return err
+			}
+			entries, err := os.ReadDir(target)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			for _, entry := range entries {
+				if err := os.RemoveAll(filepath.Join(target, entry.Name())); err != nil {
+					return err
+				}
+			}
+		case strings.HasPrefix(base, whiteoutPrefix):
+			target, err := layerTarget(rootfs, dir+strings.TrimPrefix(base, whiteoutPrefix))
+			if err != nil {
+				return err
+			}
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var links []*tar.Header
+	if err := walkLayer(bytes.NewReader(layer), func(hdr *tar.Header, r io.Reader) error {
+		if strings.HasPrefix(path.Base(hdr.Name), whiteoutPrefix) {
+			return nil
+		}
+		// Hard links may point at entries later in the layer
+		if hdr.Typeflag == tar.TypeLink {
+			links = append(links, hdr)
+			return nil
+		}
+		return extractEntry(rootfs, hdr, r)
+	}); err != nil {
+		return err
+	}
+	for _, hdr := range links {
+		if err := extractEntry(rootfs, hdr, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkLayer calls fn for each entry of an uncompressed layer tarball
+func walkLayer(layer io.Reader, fn func(hdr *tar.Header, r io.Reader) error) error {
+	tr := tar.NewReader(layer)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(hdr, tr); err != nil {
+			return fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+	}
+}
+
+// setOwnerAndMode gives an extracted file or directory the owner and mode
+// recorded in its header. The owner goes first, chown clears setuid and
+// setgid bits, and the mode is set explicitly so the umask doesn't apply.
+func setOwnerAndMode(target string, hdr *tar.Header, mode os.FileMode) error {
+	if err := chownEntry(target, hdr); err != nil {
+		return err
+	}
+	return os.Chmod(target, mode)
+}
+
+// chownEntry gives an extracted entry, without following symlinks, the
+// owner recorded in its header. Only root can change owners, so when not
+// running as root entries are left owned by the current user.
+func chownEntry(target string, hdr *tar.Header) error {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+	return os.Lchown(target, hdr.Uid, hdr.Gid)
+}
+
+// layerTarget maps a layer entry to a host path under rootfs. Only the parent
+// is resolved, so an existing symlink at the final component is replaced
+// rather than followed.
+func layerTarget(rootfs, name string) (string, error) {
+	clean := path.Clean("/" + name)
+	if clean == "/" {
+		return rootfs, nil
+	}
+	parent, err := resolveInRootfs(rootfs, path.Dir(clean))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(parent, path.Base(clean)), nil
+}
+
+// extractEntry writes a single layer entry to rootfs
+func extractEntry(rootfs string, hdr *tar.Header, r io.Reader) error {
+	target, err := layerTarget(rootfs, hdr.Name)
+	if err != nil {
+		return err
+	}
+	// Keep the setuid, setgid and sticky bits along with the permissions
+	mode := hdr.FileInfo().Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+
+	if hdr.Typeflag != tar.TypeDir {
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		// Later layers replace files from lower ones
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if info, err := os.Lstat(target); err == nil && !info.IsDir() {
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+		return setOwnerAndMode(target, hdr, mode)
+	case tar.TypeReg:
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, r); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return setOwnerAndMode(target, hdr, mode)
+	case tar.TypeSymlink:
+		if err := os.Symlink(hdr.Linkname, target); err != nil {
+			return err
+		}
+		return chownEntry(target, hdr)
+	case tar.TypeLink:
+		source, err := resolveInRootfs(rootfs, path.Clean("/"+hdr.Linkname))
+		if err != nil {
+			return err
+		}
+		return os.Link(source, target)
+	default:
+		// Device nodes and fifos are created by LXC, not taken from images
+		logging.Debug("Skipping unsupported image entry", "name", hdr.Name, "type", string(hdr.Typeflag))
+		return nil
+	}
+}
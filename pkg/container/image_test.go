@@ -0,0 +1,223 @@
+package container_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/oci"
+)
+
+// layerEntry is a file, directory or symlink in a test image layer
+type layerEntry struct {
+	name     string
+	body     string
+	typeflag byte
+	linkname string
+	mode     int64 // Defaults to 0644 for files and 0755 for directories
+	uid, gid int
+}
+
+// buildTar writes entries to a tar archive
+func buildTar(t *testing.T, entries []layerEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: e.typeflag, Linkname: e.linkname}
+		switch e.typeflag {
+		case 0:
+			hdr.Typeflag = tar.TypeReg
+		case tar.TypeDir:
+			hdr.Mode = 0755
+		}
+		if e.typeflag != 0 {
+			hdr.Size = 0
+		}
+		if e.mode != 0 {
+			hdr.Mode = e.mode
+		}
+		hdr.Uid, hdr.Gid = e.uid, e.gid
+		testing_internal.AssertNoError(t, tw.WriteHeader(hdr))
+		if hdr.Size > 0 {
+			_, err := tw.Write([]byte(e.body))
+			testing_internal.AssertNoError(t, err)
+		}
+	}
+	testing_internal.AssertNoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+// buildImage builds a docker save archive from layers, lowest first
+func buildImage(t *testing.T, layers ...[]layerEntry) []byte {
+	t.Helper()
+	var entries []layerEntry
+	var names []string
+	for i, layer := range layers {
+		name := fmt.Sprintf("layer%d/layer.tar", i)
+		names = append(names, name)
+		entries = append(entries, layerEntry{name: name, body: string(buildTar(t, layer))})
+	}
+	manifest, err := json.Marshal([]map[string]interface{}{{"Layers": names}})
+	testing_internal.AssertNoError(t, err)
+	entries = append(entries, layerEntry{name: "manifest.json", body: string(manifest)})
+	return buildTar(t, entries)
+}
+
+// fakeImageStore serves archives by image name, committed images are stored
+// under their full reference
+type fakeImageStore struct {
+	images  map[string][]byte
+	sources map[string]string
+}
+
+func newFakeImageStore(images map[string][]byte) *fakeImageStore {
+	return &fakeImageStore{images: images, sources: map[string]string{}}
+}
+
+func (s *fakeImageStore) Image(_ context.Context, image string) ([]byte, error) {
+	if archive, ok := s.images[image]; ok {
+		return archive, nil
+	}
+	return nil, fmt.Errorf("image %s not found", image)
+}
+
+func (s *fakeImageStore) Commit(ref oci.ImageReference, archive []byte, source string) error {
+	s.images[ref.String()] = archive
+	s.sources[ref.String()] = source
+	return nil
+}
+
+func TestCreateExtractsImage(t *testing.T) {
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	dir := t.TempDir()
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+
+	outside := t.TempDir()
+	manager.SetImageStore(newFakeImageStore(map[string][]byte{
+		"app:1": buildImage(t,
+			[]layerEntry{
+				{name: "etc/", typeflag: tar.TypeDir},
+				{name: "etc/hostname", body: "image"},
+				{name: "etc/motd", body: "welcome"},
+				{name: "var/cache/", typeflag: tar.TypeDir},
+				{name: "var/cache/old", body: "stale"},
+				{name: "bin", typeflag: tar.TypeSymlink, linkname: "usr/bin"},
+				{name: "usr/bin/", typeflag: tar.TypeDir},
+			},
+			[]layerEntry{
+				{name: "etc/.wh.motd"},
+				{name: "var/cache/.wh..wh..opq"},
+				{name: "var/cache/new", body: "fresh"},
+				{name: "bin/app", body: "binary"},
+				{name: "usr/bin/app-link", typeflag: tar.TypeLink, linkname: "usr/bin/app"},
+			},
+		),
+		"modes:1": buildImage(t, []layerEntry{
+			{name: "usr/bin/", typeflag: tar.TypeDir},
+			{name: "usr/bin/passwd", body: "suid", mode: 04755},
+			{name: "tmp/", typeflag: tar.TypeDir, mode: 01777},
+			{name: "home/app/", typeflag: tar.TypeDir, mode: 0700, uid: 1000, gid: 1000},
+			{name: "home/app/.profile", body: "x", mode: 0600, uid: 1000, gid: 1000},
+			{name: "home/app/.shrc", typeflag: tar.TypeSymlink, linkname: ".profile", uid: 1000, gid: 1000},
+		}),
+		"escape:1": buildImage(t, []layerEntry{
+			{name: "etc/", typeflag: tar.TypeDir},
+			{name: "escape", typeflag: tar.TypeSymlink, linkname: outside},
+			{name: "escape/pwned", body: "x"},
+			{name: "../../pwned", body: "x"},
+		}),
+	}))
+
+	t.Run("layers_and_whiteouts", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Create("app", &common.Container{Image: "app:1"}))
+		rootfs := filepath.Join(dir, "app", "rootfs")
+
+		read := func(path string) string {
+			t.Helper()
+			data, err := os.ReadFile(filepath.Join(rootfs, path))
+			testing_internal.AssertNoError(t, err)
+			return string(data)
+		}
+		missing := func(path string) {
+			t.Helper()
+			if _, err := os.Lstat(filepath.Join(rootfs, path)); !os.IsNotExist(err) {
+				t.Errorf("expected %s to be removed, got %v", path, err)
+			}
+		}
+
+		missing("etc/motd")
+		missing("var/cache/old")
+		testing_internal.AssertEqual(t, "fresh", read("var/cache/new"))
+		// Written through the bin -> usr/bin symlink inside the rootfs
+		testing_internal.AssertEqual(t, "binary", read("usr/bin/app"))
+		testing_internal.AssertEqual(t, "binary", read("usr/bin/app-link"))
+		// The hostname written by Create replaces the image's
+		testing_internal.AssertEqual(t, "app\n", read("etc/hostname"))
+	})
+
+	t.Run("modes_and_owners", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Create("modes", &common.Container{Image: "modes:1"}))
+		rootfs := filepath.Join(dir, "modes", "rootfs")
+
+		modes := map[string]os.FileMode{
+			"usr/bin/passwd":    0755 | os.ModeSetuid,
+			"tmp":               0777 | os.ModeSticky | os.ModeDir,
+			"home/app":          0700 | os.ModeDir,
+			"home/app/.profile": 0600,
+		}
+		for path, want := range modes {
+			info, err := os.Lstat(filepath.Join(rootfs, path))
+			testing_internal.AssertNoError(t, err)
+			if info.Mode() != want {
+				t.Errorf("%s: mode = %v, want %v", path, info.Mode(), want)
+			}
+		}
+
+		if os.Geteuid() != 0 {
+			t.Skip("restoring owners needs root")
+		}
+		for _, path := range []string{"home/app", "home/app/.profile", "home/app/.shrc"} {
+			info, err := os.Lstat(filepath.Join(rootfs, path))
+			testing_internal.AssertNoError(t, err)
+			st := info.Sys().(*syscall.Stat_t)
+			testing_internal.AssertEqual(t, "1000:1000", fmt.Sprintf("%d:%d", st.Uid, st.Gid))
+		}
+	})
+
+	t.Run("stays_in_rootfs", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Create("escape", &common.Container{Image: "escape:1"}))
+		rootfs := filepath.Join(dir, "escape", "rootfs")
+
+		entries, err := os.ReadDir(outside)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(entries))
+		if _, err := os.Stat(filepath.Join(dir, "pwned")); !os.IsNotExist(err) {
+			t.Errorf("expected no file outside the rootfs, got %v", err)
+		}
+
+		_, err = os.Stat(filepath.Join(rootfs, outside, "pwned"))
+		testing_internal.AssertNoError(t, err)
+		_, err = os.Stat(filepath.Join(rootfs, "pwned"))
+		testing_internal.AssertNoError(t, err)
+	})
+
+	t.Run("missing_image", func(t *testing.T) {
+		err := manager.Create("missing", &common.Container{Image: "missing:1"})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "image missing:1 not found")
+	})
+}
@@ -0,0 +1,301 @@
+package container
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
+
+// layerIndex holds the headers of one layer, keyed by absolute path
+type layerIndex struct {
+	entries   map[string]*tar.Header
+	whiteouts map[string]bool
+	opaque    map[string]bool
+}
+
+// imageEntry is the version of a path that is visible in the image, along
+// with the layer it comes from
+type imageEntry struct {
+	hdr   *tar.Header
+	layer int
+}
+
+// imageIndex is the merged view of an image's layers. Only headers are kept,
+// file contents are streamed from the archive when they are copied.
+type imageIndex struct {
+	archive []byte
+	layers  []string
+	index   []layerIndex
+}
+
+// newImageIndex reads the headers of every layer of a docker save archive
+func newImageIndex(archive []byte) (*imageIndex, error) {
+	layers, err := imageLayers(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	ix := &imageIndex{archive: archive, layers: layers}
+	for _, name := range layers {
+		r, err := layerReader(archive, name)
+		if err != nil {
+			return nil, err
+		}
+		l := layerIndex{
+			entries:   make(map[string]*tar.Header),
+			whiteouts: make(map[string]bool),
+			opaque:    make(map[string]bool),
+		}
+		if err := walkLayer(r, func(hdr *tar.Header, _ io.Reader) error {
+			clean := path.Clean("/" + hdr.Name)
+			dir, base := path.Split(clean)
+			switch {
+			case base == whiteoutOpaque:
+				l.opaque[path.Clean(dir)] = true
+			case strings.HasPrefix(base, whiteoutPrefix):
+				l.whiteouts[dir+strings.TrimPrefix(base, whiteoutPrefix)] = true
+			default:
+				l.entries[clean] = hdr
+			}
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("failed to read layer %s: %w", name, err)
+		}
+		ix.index = append(ix.index, l)
+	}
+	return ix, nil
+}
+
+// lookup returns the visible entry for p, a clean absolute path. Layers are
+// searched from the top, and a whiteout of p, an opaque parent directory or a
+// parent replaced by a non-directory hides it in the layers below.
+func (ix *imageIndex) lookup(p string) (imageEntry, bool) {
+	if p == "/" {
+		return imageEntry{hdr: &tar.Header{Name: "/", Typeflag: tar.TypeDir, Mode: 0755}, layer: -1}, true
+	}
+
+	for i := len(ix.index) - 1; i >= 0; i-- {
+		l := ix.index[i]
+		if hdr, ok := l.entries[p]; ok {
+			return imageEntry{hdr: hdr, layer: i}, true
+		}
+		if l.whiteouts[p] {
+			return imageEntry{}, false
+		}
+		for dir := path.Dir(p); ; dir = path.Dir(dir) {
+			if l.whiteouts[dir] || l.opaque[dir] {
+				return imageEntry{}, false
+			}
+			if hdr, ok := l.entries[dir]; ok && hdr.Typeflag != tar.TypeDir {
+				return imageEntry{}, false
+			}
+			if dir == "/" {
+				break
+			}
+		}
+	}
+	return imageEntry{}, false
+}
+
+// resolve follows symlinks in p the way resolveInRootfs does, treating the
+// image as the root. Components that don't exist are kept as-is.
+func (ix *imageIndex) resolve(p string) (string, error) {
+	var current string
+	pending := strings.Split(p, "/")
+	links := 0
+
+	for len(pending) > 0 {
+		part := pending[0]
+		pending = pending[1:]
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			current = path.Dir(current)
+			if current == "/" || current == "." {
+				current = ""
+			}
+			continue
+		}
+
+		next := current + "/" + part
+		entry, ok := ix.lookup(next)
+		if !ok || entry.hdr.Typeflag != tar.TypeSymlink {
+			current = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", fmt.Errorf("too many levels of symbolic links in %s", p)
+		}
+		if strings.HasPrefix(entry.hdr.Linkname, "/") {
+			current = ""
+		}
+		pending = append(strings.Split(entry.hdr.Linkname, "/"), pending...)
+	}
+
+	if current == "" {
+		return "/", nil
+	}
+	return current, nil
+}
+
+// tree returns the visible entries at and below root
+func (ix *imageIndex) tree(root string) map[string]imageEntry {
+	prefix := strings.TrimSuffix(root, "/") + "/"
+	entries := make(map[string]imageEntry)
+	for _, l := range ix.index {
+		for p := range l.entries {
+			if _, seen := entries[p]; seen || (p != root && !strings.HasPrefix(p, prefix)) {
+				continue
+			}
+			if entry, ok := ix.lookup(p); ok {
+				entries[p] = entry
+			}
+		}
+	}
+	if entry, ok := ix.lookup(root); ok {
+		entries[root] = entry
+	}
+	return entries
+}
+
+// copyFromArchive copies src, a path in a docker save archive's image, to
+// dst on the host. The layers are indexed by header first, then only the
+// layers holding the copied files are streamed again to write them.
+func copyFromArchive(archive []byte, src, dst string, progress CopyProgress) error {
+	ix, err := newImageIndex(archive)
+	if err != nil {
+		return err
+	}
+
+	resolved, err := ix.resolve(src)
+	if err != nil {
+		return fmt.Errorf("failed to resolve source: %w", err)
+	}
+	top, ok := ix.lookup(resolved)
+	if !ok {
+		return fmt.Errorf("failed to access source: %w", &fs.PathError{Op: "stat", Path: src, Err: fs.ErrNotExist})
+	}
+
+	if st, err := os.Stat(dst); err == nil && st.IsDir() {
+		dst = filepath.Join(dst, filepath.Base(filepath.Clean("/"+src)))
+	}
+
+	entries := map[string]imageEntry{resolved: top}
+	if top.hdr.Typeflag == tar.TypeDir {
+		entries = ix.tree(resolved)
+	}
+	paths := make([]string, 0, len(entries))
+	for p := range entries {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	// Directory modes are applied last so read-only directories can still be filled
+	type dirMode struct {
+		path string
+		mode fs.FileMode
+	}
+	var dirs []dirMode
+
+	// Regular files are written from the layer holding them, hard links
+	// from the layer entry they link to
+	wanted := make(map[int]map[string][]string)
+	counter := &progressCounter{progress: progress}
+
+	for _, p := range paths {
+		entry := entries[p]
+		target := filepath.Join(dst, filepath.FromSlash(strings.TrimPrefix(p, resolved)))
+
+		// Never write through an existing symlink at the destination
+		if st, err := os.Lstat(target); err == nil && st.Mode()&os.ModeSymlink != 0 {
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+		if entry.hdr.Typeflag != tar.TypeDir {
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+		}
+
+		switch entry.hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirMode{path: target, mode: entry.hdr.FileInfo().Mode().Perm()})
+		case tar.TypeSymlink:
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if err := os.Symlink(entry.hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeLink:
+			name := p
+			if entry.hdr.Typeflag == tar.TypeLink {
+				name = path.Clean("/" + entry.hdr.Linkname)
+			}
+			content, ok := ix.index[entry.layer].entries[name]
+			if !ok {
+				return fmt.Errorf("%s: hard link target %s not found", p, name)
+			}
+			if wanted[entry.layer] == nil {
+				wanted[entry.layer] = make(map[string][]string)
+			}
+			wanted[entry.layer][name] = append(wanted[entry.layer][name], target)
+			counter.total += content.Size
+		default:
+			logging.Warn("Skipping special file during copy", "path", p)
+		}
+	}
+
+	for layer, files := range wanted {
+		r, err := layerReader(ix.archive, ix.layers[layer])
+		if err != nil {
+			return err
+		}
+		if err := walkLayer(r, func(hdr *tar.Header, r io.Reader) error {
+			targets := files[path.Clean("/"+hdr.Name)]
+			if len(targets) == 0 {
+				return nil
+			}
+			mode := hdr.FileInfo().Mode().Perm()
+			if err := writeFileProgress(r, targets[0], mode, counter); err != nil {
+				return err
+			}
+			for _, target := range targets[1:] {
+				if err := copyFileProgress(targets[0], target, mode, counter); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("failed to read layer %s: %w", ix.layers[layer], err)
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].mode); err != nil {
+			return err
+		}
+	}
+
+	// Report completion even when there was nothing to copy
+	if progress != nil && counter.total == 0 {
+		progress(0, 0)
+	}
+	return nil
+}
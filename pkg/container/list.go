@@ -0,0 +1,134 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ListOptions narrows down the containers returned by ListWithOptions
+type ListOptions struct {
+	// State only includes containers in this state, e.g. "RUNNING"
+	State string
+	// Labels only includes containers carrying all of these labels
+	Labels map[string]string
+	// NamePrefix only includes containers whose name starts with this prefix
+	NamePrefix string
+	// NoRefresh reports the last saved state instead of querying lxc-info,
+	// and skips containers with no saved state
+	NoRefresh bool
+	// Offset skips this many matching containers
+	Offset int
+	// Limit caps the number of containers returned, 0 means no limit
+	Limit int
+	// Workers bounds how many containers are queried at once, 0 uses a
+	// default
+	Workers int
+}
+
+// defaultListWorkers is the number of containers ListWithOptions queries at once
+const defaultListWorkers = 8
+
+// ListWithOptions returns the containers matching opts, ordered by name
+func (m *LXCManager) ListWithOptions(opts ListOptions) ([]Container, error) {
+	if opts.Offset < 0 || opts.Limit < 0 {
+		return nil, fmt.Errorf("offset and limit must not be negative")
+	}
+
+	entries, err := os.ReadDir(m.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || name == "state" || name == "templates" {
+			continue
+		}
+		if strings.HasPrefix(name, opts.NamePrefix) {
+			names = append(names, name)
+		}
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultListWorkers
+	}
+
+	// Each lookup may shell out to lxc-info several times, so fan them out
+	// and collect the results by index to keep the name order
+	results := make([]*Container, len(names))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(names); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = m.listOne(names[i], opts)
+			}
+		}()
+	}
+	for i := range names {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var containers []Container
+	for _, c := range results {
+		if c != nil {
+			containers = append(containers, *c)
+		}
+	}
+
+	if opts.Offset >= len(containers) {
+		return nil, nil
+	}
+	containers = containers[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(containers) {
+		containers = containers[:opts.Limit]
+	}
+	return containers, nil
+}
+
+// listOne looks up a single container for ListWithOptions, returning nil if
+// it doesn't exist or doesn't match opts
+func (m *LXCManager) listOne(name string, opts ListOptions) *Container {
+	if opts.NoRefresh {
+		if _, err := m.state.GetContainerState(name); err != nil {
+			return nil
+		}
+	} else if !m.ContainerExists(name) {
+		return nil
+	}
+	// Labels come from the saved config, so filter on them before
+	// paying for an lxc-info round-trip
+	if !hasLabels(m.cachedState(name), opts.Labels) {
+		return nil
+	}
+
+	container := m.get(name, !opts.NoRefresh)
+	if opts.State != "" && container.State != strings.ToUpper(opts.State) {
+		return nil
+	}
+	return container
+}
+
+// hasLabels reports whether the saved config of state carries all labels
+func hasLabels(state *State, labels map[string]string) bool {
+	if len(labels) == 0 {
+		return true
+	}
+	if state.Config == nil {
+		return false
+	}
+	for k, v := range labels {
+		if got, ok := state.Config.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
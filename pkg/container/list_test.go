@@ -0,0 +1,116 @@
+package container_test
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestListWithOptions(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+
+	image := common.Container{Image: "ubuntu:20.04"}
+	for _, name := range []string{"web_1", "web_2", "web_3", "db"} {
+		cfg := container.WithProjectLabels(image, "shop", strings.Split(name, "_")[0])
+		testing_internal.AssertNoError(t, manager.Create(name, &cfg))
+		testing_internal.AssertNoError(t, mockCmd.AddContainer(name, "STOPPED"))
+	}
+	testing_internal.AssertNoError(t, manager.Start("web_2"))
+
+	names := func(opts container.ListOptions) string {
+		t.Helper()
+		containers, err := manager.ListWithOptions(opts)
+		testing_internal.AssertNoError(t, err)
+		var names []string
+		for _, c := range containers {
+			names = append(names, c.Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	testing_internal.AssertEqual(t, "db,web_1,web_2,web_3", names(container.ListOptions{}))
+	testing_internal.AssertEqual(t, "web_1,web_2,web_3", names(container.ListOptions{NamePrefix: "web"}))
+	testing_internal.AssertEqual(t, "web_2", names(container.ListOptions{State: "running"}))
+	testing_internal.AssertEqual(t, "db", names(container.ListOptions{
+		Labels: map[string]string{container.LabelService: "db"},
+	}))
+	testing_internal.AssertEqual(t, "web_2", names(container.ListOptions{NamePrefix: "web", Offset: 1, Limit: 1}))
+	testing_internal.AssertEqual(t, "", names(container.ListOptions{Offset: 10}))
+	testing_internal.AssertEqual(t, "web_2", names(container.ListOptions{NoRefresh: true, State: "RUNNING"}))
+
+	_, err = manager.ListWithOptions(container.ListOptions{Limit: -1})
+	testing_internal.AssertError(t, err)
+}
+
+func TestListIPs(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+	for _, name := range []string{"web", "db"} {
+		testing_internal.AssertNoError(t, manager.Create(name, &common.Container{Image: "ubuntu:20.04"}))
+		testing_internal.AssertNoError(t, mockCmd.AddContainer(name, "STOPPED"))
+	}
+	testing_internal.AssertNoError(t, manager.Start("web"))
+
+	// Count lxc-info runs, which report web's addresses with its state
+	infos, restore := mock.RecordCalls(&container.ExecCommand, "lxc-info")
+	defer restore()
+	infos.Stub("lxc-info", func(args []string) *exec.Cmd {
+		if args[1] == "web" {
+			return exec.Command("printf", "Name: web\nState: RUNNING\nIP: 10.0.3.5\nIP: 127.0.0.1\nIP: fd42::5\n")
+		}
+		return exec.Command("printf", "Name: db\nState: STOPPED\n")
+	})
+
+	containers, err := manager.List()
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertEqual(t, 2, len(infos.Calls()))
+
+	ips := make(map[string]string)
+	for _, c := range containers {
+		ips[c.Name] = strings.Join(c.IPs, ",")
+	}
+	testing_internal.AssertEqual(t, "10.0.3.5,fd42::5", ips["web"])
+	testing_internal.AssertEqual(t, "", ips["db"])
+}
+
+func BenchmarkListWithOptions(b *testing.B) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 16; i++ {
+		name := fmt.Sprintf("bench_%d", i)
+		if err := manager.Create(name, &common.Container{Image: "ubuntu:20.04"}); err != nil {
+			b.Fatal(err)
+		}
+		if err := mockCmd.AddContainer(name, "STOPPED"); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := manager.ListWithOptions(container.ListOptions{Workers: workers}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
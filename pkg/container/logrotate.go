@@ -0,0 +1,93 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
+
+// consoleLogPath returns the path LXC writes a container's console log to
+func (m *LXCManager) consoleLogPath(name string) string {
+	return filepath.Join(m.configPath, name, "logs", "console.log")
+}
+
+// RotateLogs rotates a container's console log if it exceeds the configured max size
+func (m *LXCManager) RotateLogs(name string) error {
+	container, err := m.lookup(name)
+	if err != nil {
+		return fmt.Errorf("failed to get container: %w", err)
+	}
+
+	if container.Config == nil || container.Config.Logging == nil || container.Config.Logging.MaxSize == "" {
+		return nil
+	}
+
+	maxSize, err := config.ValidateStorageSize(container.Config.Logging.MaxSize)
+	if err != nil {
+		return fmt.Errorf("invalid logging max size: %w", err)
+	}
+
+	logPath := m.consoleLogPath(name)
+	info, err := os.Stat(logPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to stat console log: %w", err)
+	}
+
+	if info.Size() <= maxSize {
+		return nil
+	}
+
+	logging.Debug("Rotating console log",
+		"container", name,
+		"size", info.Size(),
+		"max_size", maxSize,
+	)
+
+	return rotateFile(logPath, container.Config.Logging.MaxFiles)
+}
+
+// rotateFile shifts path.1..path.N up by one and moves the current contents to path.1.
+// LXC keeps the log open, so the file is copied and truncated rather than renamed.
+func rotateFile(path string, maxFiles int) error {
+	if maxFiles > 0 {
+		if err := os.Remove(fmt.Sprintf("%s.%d", path, maxFiles)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove oldest log: %w", err)
+		}
+		for i := maxFiles - 1; i >= 1; i-- {
+			from := fmt.Sprintf("%s.%d", path, i)
+			to := fmt.Sprintf("%s.%d", path, i+1)
+			if err := os.Rename(from, to); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to rotate log: %w", err)
+			}
+		}
+		if err := copyFile(path, path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate log: %w", err)
+		}
+	}
+
+	if err := os.Truncate(path, 0); err != nil {
+		return fmt.Errorf("failed to truncate log: %w", err)
+	}
+	return nil
+}
+
+// rotateAllLogs rotates the console logs of all containers that exceed their
+// configured max size
+func (m *LXCManager) rotateAllLogs() {
+	containers, err := m.List()
+	if err != nil {
+		logging.Error("Failed to list containers for log rotation", "error", err)
+		return
+	}
+	for _, c := range containers {
+		if err := m.RotateLogs(c.Name); err != nil {
+			logging.Error("Failed to rotate console log", "container", c.Name, "error", err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package container_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestLogRotation(t *testing.T) {
+	containerName := "test-container-logs"
+
+	t.Run("console_config", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.ApplyConfig(containerName, &common.Container{
+			Logging: &common.LoggingConfig{MaxSize: "1M", MaxFiles: 3},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(tmpDir, containerName, "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.console.logfile = "+filepath.Join(tmpDir, containerName, "logs", "console.log"))
+		// Left to the monitor's rotation, LXC would truncate the log itself
+		testing_internal.AssertConfigKeyAbsent(t, string(data), "lxc.console.size")
+	})
+
+	t.Run("invalid_max_size", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+		defer cleanup()
+
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{
+			Logging: &common.LoggingConfig{MaxSize: "lots"},
+		})
+		testing_internal.AssertError(t, err)
+	})
+
+	t.Run("rotates_when_over_limit", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+		defer cleanup()
+
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{
+			Logging: &common.LoggingConfig{MaxSize: "1M", MaxFiles: 2},
+		})
+		testing_internal.AssertNoError(t, err)
+		err = mockCmd.AddContainer(containerName, "STOPPED")
+		testing_internal.AssertNoError(t, err)
+
+		logPath := filepath.Join(tmpDir, containerName, "logs", "console.log")
+		testing_internal.AssertNoError(t, os.MkdirAll(filepath.Dir(logPath), 0755))
+
+		// Under the limit nothing happens
+		err = os.WriteFile(logPath, []byte("small"), 0644)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertNoError(t, manager.RotateLogs(containerName))
+		_, err = os.Stat(logPath + ".1")
+		testing_internal.AssertEqual(t, true, os.IsNotExist(err))
+
+		// Rotate three times, only two rotated files are kept
+		for _, marker := range []string{"a", "b", "c"} {
+			err = os.WriteFile(logPath, []byte(strings.Repeat(marker, 1024*1024+1)), 0644)
+			testing_internal.AssertNoError(t, err)
+			testing_internal.AssertNoError(t, manager.RotateLogs(containerName))
+		}
+
+		info, err := os.Stat(logPath)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, int64(0), info.Size())
+
+		data, err := os.ReadFile(logPath + ".1")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, byte('c'), data[0])
+
+		data, err = os.ReadFile(logPath + ".2")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, byte('b'), data[0])
+
+		_, err = os.Stat(logPath + ".3")
+		testing_internal.AssertEqual(t, true, os.IsNotExist(err))
+	})
+
+	t.Run("monitor_rotates", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+		defer cleanup()
+
+		manager, err := container.NewLXCManager(tmpDir)
+		testing_internal.AssertNoError(t, err)
+		err = manager.Create(containerName, &common.Container{
+			Logging: &common.LoggingConfig{MaxSize: "1M", MaxFiles: 1},
+		})
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertNoError(t, mockCmd.AddContainer(containerName, "RUNNING"))
+
+		logPath := filepath.Join(tmpDir, containerName, "logs", "console.log")
+		testing_internal.AssertNoError(t, os.MkdirAll(filepath.Dir(logPath), 0755))
+		testing_internal.AssertNoError(t, os.WriteFile(logPath, []byte(strings.Repeat("a", 1024*1024+1)), 0644))
+
+		// A cancelled monitor still makes its first pass
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		testing_internal.AssertError(t, manager.Monitor(ctx, time.Hour))
+
+		info, err := os.Stat(logPath)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, int64(0), info.Size())
+		_, err = os.Stat(logPath + ".1")
+		testing_internal.AssertNoError(t, err)
+	})
+}
@@ -2,13 +2,16 @@ package container
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
 )
 
 // LogOptions represents options for log streaming
@@ -16,27 +19,32 @@ type LogOptions struct {
 	Follow    bool
 	Tail      int
 	Since     time.Time
+	Until     time.Time
 	Timestamp bool
+	// TimestampFormat is the time layout lines are prefixed with, optionally
+	// in brackets, used to filter by Since and Until. Defaults to RFC3339.
+	TimestampFormat string
 }
 
-// GetLogs returns the logs for a container
+// GetLogs returns the logs for a container, read from the LXC console log
+// when there is one and from the application log otherwise
 func (m *LXCManager) GetLogs(name string, opts LogOptions) (io.ReadCloser, error) {
 	if !m.ContainerExists(name) {
 		return nil, fmt.Errorf("container %s does not exist", name)
 	}
 
-	logPath := filepath.Join(m.configPath, name, "console.log")
+	logPath := m.logPath(name)
 	file, err := os.Open(logPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open log file: %w", err)
 	}
 
 	if opts.Follow {
-		return m.followLogs(name, file, opts)
+		return m.followLogs(file, opts)
 	}
 
-	// If we're not following, handle tail and since options
-	if opts.Tail > 0 || !opts.Since.IsZero() {
+	// If we're not following, handle tail, since and until options
+	if opts.Tail > 0 || !opts.Since.IsZero() || !opts.Until.IsZero() {
 		filtered, err := m.filterLogs(file, opts)
 		if err != nil {
 			file.Close()
@@ -60,49 +68,177 @@ func (m *LXCManager) FollowLogs(name string, w io.Writer) error {
 	return err
 }
 
-// followLogs returns a ReadCloser that follows the log output
-func (m *LXCManager) followLogs(name string, file io.ReadCloser, _ LogOptions) (io.ReadCloser, error) {
-	// Use lxc-attach to tail the logs
-	logPath := filepath.Join(m.configPath, name, "console.log")
-	cmd := ExecCommand("lxc-attach", "-n", name, "--", "tail", "-f", logPath)
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		file.Close()
-		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
-	}
-	if err := cmd.Start(); err != nil {
-		file.Close()
-		return nil, fmt.Errorf("failed to start log following: %w", err)
-	}
-	return &logReader{
-		cmd:    cmd,
-		stdout: stdout,
-		file:   file,
-	}, nil
+// LogLine is a single line of log output from one container
+type LogLine struct {
+	Container string
+	Text      string
+}
+
+// logRetryInterval is how long MultiplexLogs waits before reopening the logs
+// of a container that isn't running or stopped while being followed
+var logRetryInterval = time.Second
+
+// MultiplexLogs reads the logs of several containers and passes each line to
+// handle, which is never called concurrently. Without Follow, containers are
+// read one after another. With Follow, all containers are followed at once
+// until ctx is cancelled, and containers that aren't running yet or stop
+// while being followed are picked up again once they are back.
+func (m *LXCManager) MultiplexLogs(ctx context.Context, names []string, opts LogOptions, handle func(LogLine)) error {
+	if !opts.Follow {
+		for _, name := range names {
+			logs, err := m.GetLogs(name, opts)
+			if err != nil {
+				return fmt.Errorf("failed to get logs for container %s: %w", name, err)
+			}
+			err = scanLogLines(name, logs, handle)
+			logs.Close()
+			if err != nil {
+				return fmt.Errorf("failed to read logs for container %s: %w", name, err)
+			}
+		}
+		return nil
+	}
+
+	var mu sync.Mutex
+	emit := func(line LogLine) {
+		mu.Lock()
+		defer mu.Unlock()
+		handle(line)
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			m.followLogLines(ctx, name, opts, emit)
+		}(name)
+	}
+	wg.Wait()
+
+	return nil
 }
 
-// filterLogs returns a reader that filters log lines based on options
+// followLogLines follows one container's logs until ctx is cancelled,
+// reopening them whenever the follower exits
+func (m *LXCManager) followLogLines(ctx context.Context, name string, opts LogOptions, handle func(LogLine)) {
+	for {
+		logs, err := m.GetLogs(name, opts)
+		if err != nil {
+			logging.Debug("Waiting for container logs", "container", name, "error", err)
+		} else {
+			var closeOnce sync.Once
+			closeLogs := func() { closeOnce.Do(func() { logs.Close() }) }
+
+			// Closing the reader unblocks the scanner when ctx is cancelled
+			done := make(chan struct{})
+			go func() {
+				select {
+				case <-ctx.Done():
+					closeLogs()
+				case <-done:
+				}
+			}()
+
+			if err := scanLogLines(name, logs, handle); err != nil && ctx.Err() == nil {
+				logging.Debug("Log follower exited", "container", name, "error", err)
+			}
+			close(done)
+			closeLogs()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(logRetryInterval):
+		}
+	}
+}
+
+// scanLogLines passes each line read from r to handle, tagged with name
+func scanLogLines(name string, r io.Reader, handle func(LogLine)) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		handle(LogLine{Container: name, Text: scanner.Text()})
+	}
+	return scanner.Err()
+}
+
+// logPath returns the log GetLogs reads: the console log set as
+// lxc.console.logfile in the container's config, or the default console log
+// path, if it exists, and the application log otherwise
+func (m *LXCManager) logPath(name string) string {
+	consoleLog := m.consoleLogPath(name)
+	if values, err := parseContainerConfig(filepath.Join(m.configPath, name, "config")); err == nil {
+		if path := lastValue(values, "lxc.console.logfile"); path != "" {
+			consoleLog = path
+		}
+	}
+	if _, err := os.Stat(consoleLog); err == nil {
+		return consoleLog
+	}
+	return filepath.Join(m.configPath, name, "console.log")
+}
+
+// logPollInterval is how often a followed log is checked for new output once
+// everything written so far has been read
+var logPollInterval = 250 * time.Millisecond
+
+// followLogs returns a ReadCloser that follows the log output. The log is a
+// file on the host, so it is read directly and polled for new output, starting
+// with its last opts.Tail lines if set and with all of it otherwise.
+func (m *LXCManager) followLogs(file *os.File, opts LogOptions) (io.ReadCloser, error) {
+	var pending io.Reader
+	if opts.Tail > 0 {
+		tail, err := m.filterLogs(file, LogOptions{Tail: opts.Tail})
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		pending = io.MultiReader(tail, strings.NewReader("\n"))
+	}
+	return &logFollower{file: file, pending: pending, closed: make(chan struct{})}, nil
+}
+
+// filterLogs returns a reader that filters log lines based on options.
+// Lines without a timestamp belong to the timestamped line before them, such
+// as the rest of a stack trace, and are kept only when the timestamped lines
+// on both sides of them are in range.
 func (m *LXCManager) filterLogs(r io.Reader, opts LogOptions) (io.Reader, error) {
-	var lines []string
+	layout := opts.TimestampFormat
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	inRange := func(t time.Time) bool {
+		return (opts.Since.IsZero() || !t.Before(opts.Since)) && (opts.Until.IsZero() || !t.After(opts.Until))
+	}
+	filtering := !opts.Since.IsZero() || !opts.Until.IsZero()
+
+	var lines, pending []string
+	prevInRange := false
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := scanner.Text()
-
-		// Parse timestamp if present
-		var lineTime time.Time
-		if strings.HasPrefix(line, "[") && strings.Contains(line, "]") {
-			ts := strings.TrimPrefix(strings.Split(line, "]")[0], "[")
-			if t, err := time.Parse(time.RFC3339, ts); err == nil {
-				lineTime = t
-			}
+		if !filtering {
+			lines = append(lines, line)
+			continue
 		}
 
-		// Filter by since if specified
-		if !opts.Since.IsZero() && !lineTime.IsZero() && lineTime.Before(opts.Since) {
+		lineTime, ok := parseLogTimestamp(line, layout)
+		if !ok {
+			if prevInRange {
+				pending = append(pending, line)
+			}
 			continue
 		}
 
-		lines = append(lines, line)
+		ok = inRange(lineTime)
+		if ok {
+			lines = append(lines, pending...)
+			lines = append(lines, line)
+		}
+		pending = nil
+		prevInRange = ok
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -117,21 +253,96 @@ func (m *LXCManager) filterLogs(r io.Reader, opts LogOptions) (io.Reader, error)
 	return strings.NewReader(strings.Join(lines, "\n")), nil
 }
 
-// logReader implements io.ReadCloser for log following
-type logReader struct {
-	cmd    *exec.Cmd
-	stdout io.ReadCloser
-	file   io.ReadCloser
+// parseLogTimestamp parses the timestamp a log line starts with, either in
+// brackets or as its first fields, in the given time layout
+func parseLogTimestamp(line, layout string) (time.Time, bool) {
+	var ts string
+	if rest, ok := strings.CutPrefix(line, "["); ok {
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return time.Time{}, false
+		}
+		ts = rest[:end]
+	} else {
+		// The layout's fields tell how many of the line's fields to take
+		n := len(strings.Fields(layout))
+		fields := strings.Fields(line)
+		if n == 0 || len(fields) < n {
+			return time.Time{}, false
+		}
+		ts = strings.Join(fields[:n], " ")
+	}
+
+	t, err := time.Parse(layout, ts)
+	return t, err == nil
 }
 
-func (r *logReader) Read(p []byte) (n int, err error) {
-	return r.stdout.Read(p)
+// logFollower reads a log file like tail -f until it is closed. A log that
+// shrinks was truncated by rotation and is read again from the start.
+type logFollower struct {
+	file    *os.File
+	pending io.Reader
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (f *logFollower) Read(p []byte) (int, error) {
+	if f.pending != nil {
+		n, err := f.pending.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+		f.pending = nil
+		if n > 0 {
+			return n, nil
+		}
+	}
+
+	for {
+		n, err := f.file.Read(p)
+		if n > 0 {
+			return n, nil
+		}
+		select {
+		case <-f.closed:
+			return 0, io.EOF
+		default:
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		if err := f.rewindIfTruncated(); err != nil {
+			return 0, err
+		}
+		select {
+		case <-f.closed:
+			return 0, io.EOF
+		case <-time.After(logPollInterval):
+		}
+	}
 }
 
-func (r *logReader) Close() error {
-	r.file.Close()
-	if err := r.cmd.Process.Kill(); err != nil {
-		return fmt.Errorf("failed to kill log process: %w", err)
+// rewindIfTruncated starts reading from the beginning again if the log is now
+// shorter than what was already read
+func (f *logFollower) rewindIfTruncated() error {
+	offset, err := f.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	info, err := f.file.Stat()
+	if err != nil {
+		return err
 	}
-	return r.cmd.Wait()
+	if info.Size() < offset {
+		_, err = f.file.Seek(0, io.SeekStart)
+	}
+	return err
+}
+
+// Close stops following and unblocks a pending Read
+func (f *logFollower) Close() error {
+	f.closeOnce.Do(func() { close(f.closed) })
+	return f.file.Close()
 }
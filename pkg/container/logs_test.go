@@ -1,6 +1,8 @@
 package container_test
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -91,5 +93,266 @@ func TestLogRetrieval(t *testing.T) {
 	})
 }
 
-// Move TestFollowLogs to integration_test.go when ready
-// It requires actual log streaming which is better suited for integration tests
+func TestFollowLogs(t *testing.T) {
+	dir := t.TempDir()
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertNoError(t, manager.Create("web", &common.Container{}))
+	logPath := filepath.Join(dir, "web", "console.log")
+	testing_internal.AssertNoError(t, os.WriteFile(logPath, []byte("line 1\nline 2\n"), 0644))
+
+	appendLog := func(line string) {
+		f, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+		testing_internal.AssertNoError(t, err)
+		_, err = f.WriteString(line + "\n")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertNoError(t, f.Close())
+	}
+
+	logs, err := manager.GetLogs("web", container.LogOptions{Follow: true, Tail: 1})
+	testing_internal.AssertNoError(t, err)
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(logs)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("no log line followed")
+			return ""
+		}
+	}
+
+	testing_internal.AssertEqual(t, "line 2", next())
+	appendLog("line 3")
+	testing_internal.AssertEqual(t, "line 3", next())
+
+	// Rotation truncates the log, which is then read from the start
+	testing_internal.AssertNoError(t, os.Truncate(logPath, 0))
+	time.Sleep(500 * time.Millisecond)
+	appendLog("line 4")
+	testing_internal.AssertEqual(t, "line 4", next())
+
+	// Closing ends the follow
+	testing_internal.AssertNoError(t, logs.Close())
+	select {
+	case _, ok := <-lines:
+		testing_internal.AssertEqual(t, false, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("following did not stop on close")
+	}
+}
+
+func TestMultiplexLogs(t *testing.T) {
+	dir := t.TempDir()
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+
+	logs := map[string]string{
+		"web": "web line 1\nweb line 2",
+		"db":  "db line 1",
+	}
+	for name, content := range logs {
+		testing_internal.AssertNoError(t, manager.Create(name, &common.Container{}))
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(dir, name, "console.log"), []byte(content), 0644))
+	}
+
+	t.Run("tags_lines_by_container", func(t *testing.T) {
+		var lines []string
+		err := manager.MultiplexLogs(context.Background(), []string{"db", "web"}, container.LogOptions{}, func(line container.LogLine) {
+			lines = append(lines, line.Container+": "+line.Text)
+		})
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "db: db line 1,web: web line 1,web: web line 2", strings.Join(lines, ","))
+	})
+
+	t.Run("missing_container", func(t *testing.T) {
+		err := manager.MultiplexLogs(context.Background(), []string{"web", "nonexistent"}, container.LogOptions{}, func(container.LogLine) {})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "nonexistent")
+	})
+
+	t.Run("follow_stops_on_cancel", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			// Logs are followed until cancelled, retrying a container that
+			// doesn't exist yet
+			done <- manager.MultiplexLogs(ctx, []string{"web", "later"}, container.LogOptions{Follow: true}, func(container.LogLine) {})
+		}()
+
+		select {
+		case err := <-done:
+			testing_internal.AssertNoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("MultiplexLogs did not return after cancellation")
+		}
+	})
+}
+
+func TestConsoleLogs(t *testing.T) {
+	dir := t.TempDir()
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+
+	read := func(t *testing.T, name string) string {
+		t.Helper()
+		logs, err := manager.GetLogs(name, container.LogOptions{})
+		testing_internal.AssertNoError(t, err)
+		defer logs.Close()
+		data, err := io.ReadAll(logs)
+		testing_internal.AssertNoError(t, err)
+		return string(data)
+	}
+
+	testing_internal.AssertNoError(t, manager.Create("web", &common.Container{}))
+	testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(dir, "web", "console.log"), []byte("app output"), 0644))
+
+	t.Run("falls_back_to_app_log", func(t *testing.T) {
+		testing_internal.AssertEqual(t, "app output", read(t, "web"))
+	})
+
+	t.Run("prefers_console_log", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.ApplyConfig("web", &common.Container{}))
+
+		consoleLog := filepath.Join(dir, "web", "logs", "console.log")
+		data, err := os.ReadFile(filepath.Join(dir, "web", "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertConfigKey(t, string(data), "lxc.console.logfile", consoleLog)
+
+		testing_internal.AssertNoError(t, os.WriteFile(consoleLog, []byte("booting"), 0644))
+		testing_internal.AssertEqual(t, "booting", read(t, "web"))
+	})
+
+	t.Run("configured_logfile", func(t *testing.T) {
+		custom := filepath.Join(dir, "custom.log")
+		testing_internal.AssertNoError(t, os.WriteFile(custom, []byte("custom output"), 0644))
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(dir, "web", "config"), []byte("lxc.console.logfile = "+custom+"\n"), 0644))
+		testing_internal.AssertEqual(t, "custom output", read(t, "web"))
+	})
+}
+
+func TestLogTimeFilter(t *testing.T) {
+	dir := t.TempDir()
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+
+	write := func(t *testing.T, name string, lines ...string) {
+		t.Helper()
+		if !manager.ContainerExists(name) {
+			testing_internal.AssertNoError(t, manager.Create(name, &common.Container{}))
+		}
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(dir, name, "console.log"), []byte(strings.Join(lines, "\n")), 0644))
+	}
+	read := func(t *testing.T, name string, opts container.LogOptions) string {
+		t.Helper()
+		logs, err := manager.GetLogs(name, opts)
+		testing_internal.AssertNoError(t, err)
+		defer logs.Close()
+		data, err := io.ReadAll(logs)
+		testing_internal.AssertNoError(t, err)
+		return string(data)
+	}
+
+	write(t, "app",
+		"starting up",
+		"["+at(0).Format(time.RFC3339)+"] first",
+		"["+at(10).Format(time.RFC3339)+"] panic",
+		"  trace line 1",
+		"  trace line 2",
+		"["+at(20).Format(time.RFC3339)+"] recovered",
+		"  detail",
+		"["+at(30).Format(time.RFC3339)+"] last",
+		"trailing",
+	)
+
+	tests := []struct {
+		name string
+		opts container.LogOptions
+		want []string
+	}{
+		{
+			name: "since",
+			opts: container.LogOptions{Since: at(10)},
+			want: []string{"panic", "trace line 1", "trace line 2", "recovered", "detail", "last"},
+		},
+		{
+			name: "until",
+			opts: container.LogOptions{Until: at(20)},
+			want: []string{"first", "panic", "trace line 1", "trace line 2", "recovered"},
+		},
+		{
+			name: "since_and_until",
+			opts: container.LogOptions{Since: at(5), Until: at(25)},
+			want: []string{"panic", "trace line 1", "trace line 2", "recovered"},
+		},
+		{
+			name: "since_with_tail",
+			opts: container.LogOptions{Since: at(10), Tail: 2},
+			want: []string{"detail", "last"},
+		},
+		{
+			name: "nothing_in_range",
+			opts: container.LogOptions{Since: at(40)},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, line := range strings.Split(read(t, "app", tt.opts), "\n") {
+				if line == "" {
+					continue
+				}
+				// Keep the message, without the timestamp prefix
+				if _, msg, ok := strings.Cut(line, "] "); ok {
+					line = msg
+				}
+				got = append(got, strings.TrimSpace(line))
+			}
+			testing_internal.AssertEqual(t, strings.Join(tt.want, ","), strings.Join(got, ","))
+		})
+	}
+
+	t.Run("custom_format", func(t *testing.T) {
+		layout := "2006-01-02 15:04:05"
+		write(t, "db",
+			at(0).Format(layout)+" checkpoint",
+			at(10).Format(layout)+" vacuum",
+			"continued",
+			at(20).Format(layout)+" shutdown",
+		)
+
+		got := read(t, "db", container.LogOptions{Since: at(5), TimestampFormat: layout})
+		testing_internal.AssertEqual(t, strings.Join([]string{
+			at(10).Format(layout) + " vacuum",
+			"continued",
+			at(20).Format(layout) + " shutdown",
+		}, "\n"), got)
+	})
+}
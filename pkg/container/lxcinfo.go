@@ -0,0 +1,135 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LXCInfo is what lxc-info reports about a container. Usage figures are only
+// reported while the container is running.
+type LXCInfo struct {
+	Name        string
+	State       string // Upper case, e.g. RUNNING
+	PID         int
+	IPs         []string
+	CPUTime     time.Duration
+	MemoryBytes int64
+	BlkIOBytes  int64
+}
+
+// lxcInfoUnits are the binary size units lxc-info prints usage in
+var lxcInfoUnits = map[string]int64{
+	"bytes": 1,
+	"KiB":   1 << 10,
+	"MiB":   1 << 20,
+	"GiB":   1 << 30,
+	"TiB":   1 << 40,
+}
+
+// Info runs lxc-info for a container and parses its output
+func (m *LXCManager) Info(name string) (*LXCInfo, error) {
+	output, err := m.lxcPathCommand("lxc-info", "-n", name).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info for container '%s': %w", name, err)
+	}
+	info, err := parseLXCInfo(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info for container '%s': %w", name, err)
+	}
+	return &info, nil
+}
+
+// GetIP returns the non-loopback addresses LXC reports for a running
+// container, including those leased over DHCP
+func (m *LXCManager) GetIP(name string) ([]net.IP, error) {
+	container, err := m.lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container: %w", err)
+	}
+	if container.State != "RUNNING" {
+		return nil, fmt.Errorf("container '%s' is not running (current state: %s)", name, container.State)
+	}
+
+	output, err := m.lxcPathCommand("lxc-info", "-n", name, "-iH").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IP of container '%s': %w", name, err)
+	}
+
+	var ips []net.IP
+	for _, field := range strings.Fields(string(output)) {
+		if ip := net.ParseIP(field); ip != nil && !ip.IsLoopback() {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// initPID returns the host pid of a running container's init process
+func (m *LXCManager) initPID(name string) (int, error) {
+	output, err := m.lxcPathCommand("lxc-info", "-n", name, "-p", "-H").Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get init pid of container '%s': %w", name, err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("container '%s' has no init process", name)
+	}
+	return pid, nil
+}
+
+// parseLXCInfo parses the "Key: value" lines of lxc-info's default output,
+// which includes the stats printed by -S. Lines it doesn't know, such as the
+// per-link byte counts, are ignored, as are usage figures it can't parse, so
+// an odd stat never hides the state.
+func parseLXCInfo(output []byte) (LXCInfo, error) {
+	var info LXCInfo
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "Name":
+			info.Name = value
+		case "State":
+			info.State = strings.ToUpper(value)
+		case "PID":
+			info.PID, _ = strconv.Atoi(value)
+		case "IP":
+			info.IPs = append(info.IPs, value)
+		case "CPU use":
+			if seconds, err := strconv.ParseFloat(strings.TrimSuffix(value, " seconds"), 64); err == nil {
+				info.CPUTime = time.Duration(seconds * float64(time.Second))
+			}
+		case "Memory use":
+			info.MemoryBytes = parseLXCInfoSize(value)
+		case "BlkIO use":
+			info.BlkIOBytes = parseLXCInfoSize(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return LXCInfo{}, err
+	}
+	if info.State == "" {
+		return LXCInfo{}, fmt.Errorf("no state in lxc-info output")
+	}
+	return info, nil
+}
+
+// parseLXCInfoSize parses a size such as "12.34 MiB", returning 0 if it can't
+func parseLXCInfoSize(value string) int64 {
+	number, unit, _ := strings.Cut(value, " ")
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(n * float64(lxcInfoUnits[unit]))
+}
@@ -0,0 +1,105 @@
+package container_test
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestInfo(t *testing.T) {
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+
+	output := ""
+	calls, restore := mock.RecordCalls(&container.ExecCommand, "lxc-info")
+	defer restore()
+	calls.Stub("lxc-info", func([]string) *exec.Cmd {
+		return exec.Command("printf", "%s", output)
+	})
+
+	t.Run("running", func(t *testing.T) {
+		output = `Name:           web
+State:          RUNNING
+PID:            12345
+IP:             10.0.3.15
+IP:             fd42::15
+CPU use:        1.50 seconds
+BlkIO use:      4.00 KiB
+Memory use:     12.50 MiB
+KMem use:       1.20 MiB
+Link:           vethABC123
+ TX bytes:      1.23 KiB
+ RX bytes:      4.56 KiB
+ Total bytes:   5.79 KiB
+`
+		info, err := manager.Info("web")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "web", info.Name)
+		testing_internal.AssertEqual(t, "RUNNING", info.State)
+		testing_internal.AssertEqual(t, 12345, info.PID)
+		testing_internal.AssertEqual(t, 2, len(info.IPs))
+		testing_internal.AssertEqual(t, "10.0.3.15", info.IPs[0])
+		testing_internal.AssertEqual(t, "fd42::15", info.IPs[1])
+		testing_internal.AssertEqual(t, 1500*time.Millisecond, info.CPUTime)
+		testing_internal.AssertEqual(t, int64(4096), info.BlkIOBytes)
+		testing_internal.AssertEqual(t, int64(12.5*1024*1024), info.MemoryBytes)
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		output = "Name:           web\nState:          STOPPED\n"
+		info, err := manager.Info("web")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "STOPPED", info.State)
+		testing_internal.AssertEqual(t, 0, info.PID)
+		testing_internal.AssertEqual(t, 0, len(info.IPs))
+	})
+
+	t.Run("no_state", func(t *testing.T) {
+		output = "web doesn't exist\n"
+		_, err := manager.Info("web")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "no state")
+	})
+}
+
+func TestGetIP(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+	manager.SetStatePolling(container.StatePolling{Attempts: 1})
+
+	testing_internal.AssertNoError(t, manager.Create("web", &common.Container{Image: "ubuntu:20.04"}))
+	testing_internal.AssertNoError(t, mockCmd.AddContainer("web", "STOPPED"))
+
+	t.Run("not_running", func(t *testing.T) {
+		_, err := manager.GetIP("web")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "is not running")
+	})
+
+	t.Run("running", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Start("web"))
+
+		calls, restore := mock.RecordCalls(&container.ExecCommand, "lxc-info")
+		defer restore()
+		calls.Stub("lxc-info", func(args []string) *exec.Cmd {
+			if len(args) != 3 || args[2] != "-iH" {
+				return nil
+			}
+			return exec.Command("printf", "10.0.3.15\n127.0.0.1\nfd42::15\n::1\n")
+		})
+
+		ips, err := manager.GetIP("web")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 2, len(ips))
+		testing_internal.AssertEqual(t, "10.0.3.15", ips[0].String())
+		testing_internal.AssertEqual(t, "fd42::15", ips[1].String())
+	})
+}
@@ -1,17 +1,24 @@
 package container
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"net"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/recovery"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
 )
 
 // Manager defines the interface for managing LXC containers
@@ -32,8 +39,11 @@ type Manager interface {
 	Pause(name string) error
 	// Resume unfreezes a paused container
 	Resume(name string) error
-	// Restart stops and then starts a container
+	// Restart stops a running or frozen container and starts it again. A
+	// stopped container is started.
 	Restart(name string) error
+	// Kill sends a signal to a container's init process
+	Kill(name, signal string) error
 	// Update updates a container's configuration
 	Update(name string, cfg *common.Container) error
 }
@@ -42,6 +52,25 @@ type Manager interface {
 type LXCManager struct {
 	configPath string
 	state      *StateManager
+
+	statePolling  StatePolling
+	images        ImageStore
+	cgroupVersion int
+}
+
+// StatePolling controls how often Get reads a container's state from
+// lxc-info before settling on it
+type StatePolling struct {
+	// Attempts is the maximum number of lxc-info reads
+	Attempts int
+	// Interval is the delay between reads
+	Interval time.Duration
+}
+
+// DefaultStatePolling provides the polling used by new managers
+var DefaultStatePolling = StatePolling{
+	Attempts: 3,
+	Interval: 100 * time.Millisecond,
 }
 
 // NewLXCManager creates a new LXC container manager
@@ -54,30 +83,112 @@ func NewLXCManager(configPath string) (*LXCManager, error) {
 	}
 
 	return &LXCManager{
-		configPath: configPath,
-		state:      stateManager,
+		configPath:    configPath,
+		state:         stateManager,
+		statePolling:  DefaultStatePolling,
+		cgroupVersion: hostCgroupVersion(),
+	}, nil
+}
+
+// NewReadOnlyLXCManager creates a manager whose container state is loaded
+// from configPath but never written back, see NewReadOnlyStateManager. It is
+// meant for inspection; commands that change containers still write their
+// LXC configuration.
+func NewReadOnlyLXCManager(configPath string) (*LXCManager, error) {
+	logging.Debug("Initializing read-only LXC manager", "configPath", configPath)
+
+	stateManager, err := NewReadOnlyStateManager(filepath.Join(configPath, "state"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state manager: %w", err)
+	}
+
+	return &LXCManager{
+		configPath:    configPath,
+		state:         stateManager,
+		statePolling:  DefaultStatePolling,
+		cgroupVersion: hostCgroupVersion(),
 	}, nil
 }
 
+// SetStatePolling changes how Get polls lxc-info, e.g. a single attempt on
+// hosts where state is never in flux
+func (m *LXCManager) SetStatePolling(p StatePolling) {
+	if p.Attempts < 1 {
+		p.Attempts = 1
+	}
+	m.statePolling = p
+}
+
+// SetCgroupVersion overrides the detected host cgroup version, 1 or 2, which
+// decides whether resource limits are written as lxc.cgroup or lxc.cgroup2
+// keys
+func (m *LXCManager) SetCgroupVersion(version int) {
+	m.cgroupVersion = version
+}
+
+// hostCgroupVersion reports 2 on hosts with a unified cgroup2 hierarchy
+// mounted at /sys/fs/cgroup and 1 otherwise, hybrid hosts keep their
+// controllers on the legacy hierarchy
+func hostCgroupVersion() int {
+	if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err == nil {
+		return 2
+	}
+	return 1
+}
+
+// lxcPathCommand returns an lxc-* command pointed at the manager's config path
+// with -P, the LXC tools would otherwise look for containers in the default
+// lxcpath
+func (m *LXCManager) lxcPathCommand(name string, args ...string) *exec.Cmd {
+	return ExecCommand(name, append([]string{"-P", m.configPath}, args...)...)
+}
+
+// lxcStopDefaultTimeout is how long lxc-stop waits for a clean shutdown when
+// it isn't given -t, and lxcStopMargin is the time it is given on top of its
+// own timeout to kill the container and return.
+const (
+	lxcStopDefaultTimeout = 60 * time.Second
+	lxcStopMargin         = 10 * time.Second
+)
+
 func (m *LXCManager) execLXCCommand(name string, args ...string) error {
+	// Create a context with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return m.retryLXCCommand(ctx, "5 seconds", func() *exec.Cmd {
+		return m.lxcPathCommand(name, args...)
+	}, name, args)
+}
+
+// execLXCCommandTimeout runs an LXC command like execLXCCommand, but kills it
+// if it is still running after timeout. It is used for lxc-stop, which can
+// take as long as the container's grace period.
+func (m *LXCManager) execLXCCommandTimeout(timeout time.Duration, name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return m.retryLXCCommand(ctx, timeout.String(), func() *exec.Cmd {
+		return commandContext(ctx, m.lxcPathCommand(name, args...))
+	}, name, args)
+}
+
+// retryLXCCommand runs the commands built by newCmd until one succeeds, the
+// failure isn't transient or ctx is done
+func (m *LXCManager) retryLXCCommand(ctx context.Context, timeout string, newCmd func() *exec.Cmd, name string, args []string) error {
 	logging.Debug("Executing LXC command",
 		"command", name,
 		"args", args,
 		"container", args[1], // args[1] is usually the container name
 	)
 
-	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
 	// Use retry with backoff for commands that might fail temporarily
 	return recovery.RetryWithBackoff(ctx, recovery.DefaultRetryConfig, func() error {
-		cmd := ExecCommand(name, args...)
-		output, err := cmd.CombinedOutput()
+		output, err := newCmd().CombinedOutput()
 
 		// Check if the command timed out
 		if ctx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("command timed out after 5 seconds")
+			return fmt.Errorf("command timed out after %s", timeout)
 		}
 
 		if err != nil {
@@ -87,25 +198,75 @@ func (m *LXCManager) execLXCCommand(name string, args ...string) error {
 				"output", string(output),
 				"error", err,
 			)
-			return fmt.Errorf("command failed: %w", err)
+			return &lxcCommandError{err: err, output: string(output)}
 		}
+		logging.Trace("LXC command succeeded", "command", name, "args", args, "output", string(output))
 		return nil
 	})
 }
 
-// ContainerExists checks if a container exists
-func (m *LXCManager) ContainerExists(name string) bool {
-	logging.Debug("Checking if container exists", "name", name)
+// commandContext rebuilds cmd with exec.CommandContext, so it is killed when
+// ctx is done. cmd comes from ExecCommand, which tests replace, so it is
+// copied rather than created with the context directly.
+func commandContext(ctx context.Context, cmd *exec.Cmd) *exec.Cmd {
+	c := exec.CommandContext(ctx, cmd.Path)
+	c.Args = cmd.Args
+	c.Env = cmd.Env
+	c.Dir = cmd.Dir
+	c.Err = cmd.Err
+	return c
+}
+
+// transientLXCErrors are output fragments of LXC commands that failed on a
+// lock or busy resource held by another process, which a retry can succeed on
+var transientLXCErrors = []string{
+	"resource busy",
+	"resource temporarily unavailable",
+	"try again",
+	"failed to acquire lock",
+	"locked",
+}
+
+// lxcCommandError is a failed LXC command. Only failures the output shows to
+// be transient are retried, anything else, such as a container that doesn't
+// exist, fails right away.
+type lxcCommandError struct {
+	err    error
+	output string
+}
+
+func (e *lxcCommandError) Error() string {
+	return fmt.Sprintf("command failed: %v", e.err)
+}
 
-	// Only check state first - directory existence is not enough
+func (e *lxcCommandError) Unwrap() error {
+	return e.err
+}
+
+// IsTemporary is checked by recovery.IsRetryable
+func (e *lxcCommandError) IsTemporary() bool {
+	output := strings.ToLower(e.output)
+	for _, fragment := range transientLXCErrors {
+		if strings.Contains(output, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainerExists reports whether a container exists, going by its saved
+// state and its LXC config file. It doesn't run lxc-info, use ExistsInLXC
+// for containers lxc-compose didn't create.
+func (m *LXCManager) ContainerExists(name string) bool {
 	if _, err := m.state.GetContainerState(name); err == nil {
 		logging.Debug("Container found in state", "name", name)
 		return true
 	}
 
-	// If not in state, check LXC
-	if err := m.execLXCCommand("lxc-info", "-n", name); err == nil {
-		logging.Debug("Container found in LXC", "name", name)
+	// A bare directory may be a rootfs or template staged before Create,
+	// only the config file makes it a container
+	if _, err := os.Stat(filepath.Join(m.configPath, name, "config")); err == nil {
+		logging.Debug("Container found in config path", "name", name)
 		return true
 	}
 
@@ -113,8 +274,29 @@ func (m *LXCManager) ContainerExists(name string) bool {
 	return false
 }
 
-// Create implements Manager.Create
+// ExistsInLXC asks LXC whether a container exists. lxc-info is run once, a
+// failure means the container doesn't exist so it isn't retried or logged.
+func (m *LXCManager) ExistsInLXC(name string) bool {
+	return m.lxcPathCommand("lxc-info", "-n", name).Run() == nil
+}
+
+// CreateOptions changes which steps CreateWithOptions takes
+type CreateOptions struct {
+	// SkipNetwork leaves the network unconfigured, to be set up later with
+	// ReconfigureNetwork. Service-level ports are kept and applied then.
+	SkipNetwork bool
+	// SkipStart leaves the created container stopped
+	SkipStart bool
+}
+
+// Create implements Manager.Create. The container is left stopped.
 func (m *LXCManager) Create(name string, cfg *common.Container) error {
+	return m.CreateWithOptions(name, cfg, CreateOptions{SkipStart: true})
+}
+
+// CreateWithOptions creates a container like Create and starts it, see
+// CreateOptions. A container that fails to start is kept, stopped.
+func (m *LXCManager) CreateWithOptions(name string, cfg *common.Container, opts CreateOptions) error {
 	if cfg == nil {
 		return fmt.Errorf("container configuration is required")
 	}
@@ -127,12 +309,37 @@ func (m *LXCManager) Create(name string, cfg *common.Container) error {
 	if m.ContainerExists(name) {
 		return fmt.Errorf("container %s already exists", name)
 	}
+	if err := m.checkSharedNamespaces(name, cfg); err != nil {
+		return err
+	}
 
+	// Only remove the directory on failure if Create made it
+	containerDir := filepath.Join(m.configPath, name)
+	_, statErr := os.Stat(containerDir)
+	createdDir := os.IsNotExist(statErr)
+
+	if err := m.create(name, cfg, opts); err != nil {
+		m.rollbackCreate(name, createdDir)
+		return err
+	}
+
+	logging.Debug("Container created and state saved", "name", name)
+
+	if !opts.SkipStart {
+		if err := m.Start(name); err != nil {
+			return fmt.Errorf("container '%s' was created but not started: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// create writes the files and state of a new container, see Create. A rootfs
+// volume it provisioned is destroyed again if a later step fails.
+func (m *LXCManager) create(name string, cfg *common.Container, opts CreateOptions) (err error) {
 	// Create container directory structure
 	containerDir := filepath.Join(m.configPath, name)
 	dirs := []string{
 		containerDir,
-		filepath.Join(containerDir, "rootfs"),
 		filepath.Join(containerDir, "logs"),
 	}
 
@@ -142,12 +349,84 @@ func (m *LXCManager) Create(name string, cfg *common.Container) error {
 		}
 	}
 
+	backend, err := newStorageBackend(m.configPath, cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("invalid storage configuration: %w", err)
+	}
+	size, err := storageSize(cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("invalid storage configuration: %w", err)
+	}
+	// A rootfs staged before Create, e.g. an unpacked template, is used as is
+	// and kept on failure. Only the dir backend can do that, the others create
+	// a new volume on the rootfs path, which would hide or fail on it.
+	_, statErr := os.Stat(rootfsPath(m.configPath, name))
+	preexisting := statErr == nil
+	if _, ok := backend.(dirBackend); preexisting && !ok {
+		return fmt.Errorf("a rootfs staged at %s can only be used with the dir storage backend", rootfsPath(m.configPath, name))
+	}
+	rootfs, err := backend.Provision(name, size)
+	if err != nil {
+		return fmt.Errorf("failed to provision rootfs: %w", err)
+	}
+	defer func() {
+		if err == nil || preexisting {
+			return
+		}
+		if destroyErr := backend.Destroy(name); destroyErr != nil {
+			logging.Warn("Failed to destroy rootfs after failed create", "name", name, "error", destroyErr)
+		}
+	}()
+	if mounter, ok := backend.(volumeMounter); ok {
+		unmount, err := mounter.Mount(name)
+		if err != nil {
+			return fmt.Errorf("failed to mount rootfs: %w", err)
+		}
+		defer unmount()
+	}
+
+	if m.images != nil && cfg.Image != "" {
+		archive, err := m.images.Image(context.Background(), cfg.Image)
+		if err != nil {
+			return fmt.Errorf("failed to get image %s: %w", cfg.Image, err)
+		}
+		if err := extractImage(archive, rootfs); err != nil {
+			return fmt.Errorf("failed to extract image %s: %w", cfg.Image, err)
+		}
+	}
+
+	if err := m.writeHostname(name, resolveHostname(name, cfg)); err != nil {
+		return fmt.Errorf("failed to write hostname: %w", err)
+	}
+	if !opts.SkipNetwork {
+		if err := m.writeResolvConf(name, cfg.Network); err != nil {
+			return fmt.Errorf("failed to write resolv.conf: %w", err)
+		}
+	}
+	if err := m.writeTimezone(name, cfg.Timezone); err != nil {
+		return fmt.Errorf("failed to write timezone: %w", err)
+	}
+
+	// The config leaves out the network when its setup is skipped
+	applied := cfg
+	if opts.SkipNetwork && cfg.Network != nil {
+		withoutNetwork := *cfg
+		withoutNetwork.Network = nil
+		applied = &withoutNetwork
+	}
+	if err := m.applyConfig(name, applied); err != nil {
+		return fmt.Errorf("failed to apply container configuration: %w", err)
+	}
+
 	// Convert common.Container to config.Container for state saving
 	configContainer := config.FromCommonContainer(cfg)
 
-	// Configure network if specified
-	if cfg.Network != nil {
-		networkCfg := config.FromCommonNetworkConfig(cfg.Network)
+	// The state records only the network that was configured
+	if opts.SkipNetwork {
+		configContainer.Network = nil
+	} else if cfg.Network != nil {
+		// Configure network if specified, including service-level ports
+		networkCfg := mergePortForwards(cfg)
 		if err := m.configureNetwork(name, networkCfg); err != nil {
 			return fmt.Errorf("failed to configure network: %w", err)
 		}
@@ -158,14 +437,28 @@ func (m *LXCManager) Create(name string, cfg *common.Container) error {
 		return fmt.Errorf("failed to save container state: %w", err)
 	}
 
-	logging.Debug("Container created and state saved", "name", name)
-
 	return nil
 }
 
+// rollbackCreate removes what a failed Create left behind, so the container
+// can be created again once the problem is fixed
+func (m *LXCManager) rollbackCreate(name string, removeDir bool) {
+	if removeDir {
+		if err := os.RemoveAll(filepath.Join(m.configPath, name)); err != nil {
+			logging.Warn("Failed to remove container directory after failed create", "name", name, "error", err)
+		}
+	}
+	if _, err := m.state.GetContainerState(name); err == nil {
+		if err := m.state.RemoveContainerState(name); err != nil {
+			logging.Warn("Failed to remove container state after failed create", "name", name, "error", err)
+		}
+	}
+	logging.Debug("Rolled back failed create", "name", name)
+}
+
 // Start implements Manager.Start
 func (m *LXCManager) Start(name string) error {
-	container, err := m.Get(name)
+	container, err := m.lookup(name)
 	if err != nil {
 		return fmt.Errorf("failed to get container: %w", err)
 	}
@@ -178,6 +471,15 @@ func (m *LXCManager) Start(name string) error {
 		return fmt.Errorf("container '%s' is not in a valid state for starting (current state: %s)", name, container.State)
 	}
 
+	if container.Config != nil {
+		if err := checkBridges(container.Config.Network); err != nil {
+			return err
+		}
+		if err := m.checkSharedNamespacesRunning(name, container.Config); err != nil {
+			return err
+		}
+	}
+
 	// Start the container
 	if err := m.execLXCCommand("lxc-start", "-n", name); err != nil {
 		return fmt.Errorf("failed to start container: %w", err)
@@ -191,9 +493,51 @@ func (m *LXCManager) Start(name string) error {
 	return nil
 }
 
-// Stop implements Manager.Stop
+// checkSharedNamespaces makes sure the containers whose namespaces name joins
+// with its network, pid and ipc modes exist
+func (m *LXCManager) checkSharedNamespaces(name string, cfg *common.Container) error {
+	for _, ns := range validation.SharedNamespaces(cfg.NetworkMode, cfg.PidMode, cfg.IpcMode) {
+		if ns.Container == name {
+			return fmt.Errorf("container '%s' cannot share its own %s namespace", name, ns.Name())
+		}
+		if !m.ContainerExists(ns.Container) && !m.ExistsInLXC(ns.Container) {
+			return fmt.Errorf("container '%s' shares the %s namespace of container '%s', which does not exist", name, ns.Name(), ns.Container)
+		}
+	}
+	return nil
+}
+
+// checkSharedNamespacesRunning makes sure the containers whose namespaces name
+// joins are running, LXC can only join the namespaces of a live container
+func (m *LXCManager) checkSharedNamespacesRunning(name string, cfg *config.Container) error {
+	for _, ns := range validation.SharedNamespaces(cfg.NetworkMode, cfg.PidMode, cfg.IpcMode) {
+		c, err := m.lookup(ns.Container)
+		if err != nil {
+			return fmt.Errorf("container '%s' shares the %s namespace of container '%s', which does not exist", name, ns.Name(), ns.Container)
+		}
+		if c.State != "RUNNING" {
+			return fmt.Errorf("container '%s' shares the %s namespace of container '%s', which is not running (current state: %s)", name, ns.Name(), ns.Container, c.State)
+		}
+	}
+	return nil
+}
+
+// StopOptions changes how StopWithOptions stops a container
+type StopOptions struct {
+	// Timeout overrides the service's stop_grace_period: how long lxc-stop
+	// waits for a clean shutdown before killing the container
+	Timeout *time.Duration
+}
+
+// Stop implements Manager.Stop. It waits for the service's
+// stop_grace_period, or LXC's default, before killing the container.
 func (m *LXCManager) Stop(name string) error {
-	container, err := m.Get(name)
+	return m.StopWithOptions(name, StopOptions{})
+}
+
+// StopWithOptions stops a container like Stop, see StopOptions
+func (m *LXCManager) StopWithOptions(name string, opts StopOptions) error {
+	container, err := m.lookup(name)
 	if err != nil {
 		return fmt.Errorf("failed to get container: %w", err)
 	}
@@ -206,8 +550,11 @@ func (m *LXCManager) Stop(name string) error {
 		return fmt.Errorf("container '%s' is not in a valid state for stopping (current state: %s)", name, container.State)
 	}
 
-	// Stop the container using execLXCCommand
-	if err := m.execLXCCommand("lxc-stop", "-n", name); err != nil {
+	args, deadline, err := stopArgs(name, container.Config, opts.Timeout)
+	if err != nil {
+		return err
+	}
+	if err := m.execLXCCommandTimeout(deadline, "lxc-stop", args...); err != nil {
 		return fmt.Errorf("failed to stop container: %w", err)
 	}
 
@@ -215,13 +562,38 @@ func (m *LXCManager) Stop(name string) error {
 	if err := m.state.SaveContainerState(name, container.Config, "STOPPED"); err != nil {
 		return fmt.Errorf("failed to update container state: %w", err)
 	}
+	if err := m.state.MarkStoppedByUser(name); err != nil {
+		return fmt.Errorf("failed to update container state: %w", err)
+	}
 
 	return nil
 }
 
+// stopArgs returns the lxc-stop arguments for a container, with -t set from
+// timeout or else the container's stop_grace_period. lxc-stop takes whole
+// seconds, so the grace period is rounded up. It also returns how long to let
+// lxc-stop run, which leaves it time to kill the container once the grace
+// period is over.
+func stopArgs(name string, cfg *config.Container, timeout *time.Duration) ([]string, time.Duration, error) {
+	args := []string{"-n", name}
+	if timeout == nil && cfg != nil && cfg.StopGracePeriod != "" {
+		d, err := validation.ParseStopGracePeriod(cfg.StopGracePeriod)
+		if err != nil {
+			return nil, 0, err
+		}
+		timeout = &d
+	}
+	if timeout == nil {
+		return args, lxcStopDefaultTimeout + lxcStopMargin, nil
+	}
+	seconds := (*timeout + time.Second - 1) / time.Second
+	args = append(args, "-t", strconv.Itoa(int(seconds)))
+	return args, seconds*time.Second + lxcStopMargin, nil
+}
+
 // Remove implements Manager.Remove
 func (m *LXCManager) Remove(name string) error {
-	container, err := m.Get(name)
+	container, err := m.lookup(name)
 	if err != nil {
 		return fmt.Errorf("failed to get container: %w", err)
 	}
@@ -235,7 +607,19 @@ func (m *LXCManager) Remove(name string) error {
 		return fmt.Errorf("failed to destroy container: %w", err)
 	}
 
-	// Remove container directory
+	// Destroy the rootfs volume, then the rest of the container directory
+	var storage *common.StorageConfig
+	if container.Config != nil {
+		storage = container.Config.Storage.ToCommonStorageConfig()
+	}
+	backend, err := newStorageBackend(m.configPath, storage)
+	if err != nil {
+		return fmt.Errorf("invalid storage configuration: %w", err)
+	}
+	if err := backend.Destroy(name); err != nil {
+		return fmt.Errorf("failed to destroy rootfs: %w", err)
+	}
+
 	containerPath := filepath.Join(m.configPath, name)
 	if err := os.RemoveAll(containerPath); err != nil {
 		return fmt.Errorf("failed to remove container directory: %w", err)
@@ -251,35 +635,34 @@ func (m *LXCManager) Remove(name string) error {
 
 // List implements Manager.List
 func (m *LXCManager) List() ([]Container, error) {
-	entries, err := os.ReadDir(m.configPath)
+	return m.ListWithOptions(ListOptions{})
+}
+
+// Get implements Manager.Get. The bandwidth limits of a running container's
+// interfaces are read along with its state.
+func (m *LXCManager) Get(name string) (*Container, error) {
+	container, err := m.lookup(name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config directory: %w", err)
+		return nil, err
 	}
-
-	var containers []Container
-	for _, entry := range entries {
-		if !entry.IsDir() || entry.Name() == "state" || entry.Name() == "templates" {
-			continue
-		}
-
-		container, err := m.Get(entry.Name())
-		if err != nil {
-			continue
-		}
-
-		containers = append(containers, *container)
+	if container.State == "RUNNING" {
+		container.Bandwidth = m.bandwidthLimits(name, container.Config)
 	}
-
-	return containers, nil
+	return container, nil
 }
 
-// Get implements Manager.Get
-func (m *LXCManager) Get(name string) (*Container, error) {
+// lookup returns an existing container with its current state, without the
+// bandwidth limits Get reads from inside the container
+func (m *LXCManager) lookup(name string) (*Container, error) {
 	if !m.ContainerExists(name) {
 		return nil, fmt.Errorf("container %s does not exist", name)
 	}
+	return m.get(name, true), nil
+}
 
-	// First check if we have state info
+// cachedState returns the saved state of a container, or a stopped
+// placeholder if none has been recorded
+func (m *LXCManager) cachedState(name string) *State {
 	state, err := m.state.GetContainerState(name)
 	if err != nil {
 		// Create default state if none exists
@@ -288,53 +671,89 @@ func (m *LXCManager) Get(name string) (*Container, error) {
 			Status: "STOPPED",
 		}
 	}
+	return state
+}
 
-	// Try up to 3 times to get a stable state
-	for i := 0; i < 3; i++ {
-		cmd := ExecCommand("lxc-info", "-n", name)
-		output, err := cmd.CombinedOutput()
-		if err == nil {
-			currentState := ""
-			// Parse lxc-info output to get state
-			for _, line := range strings.Split(string(output), "\n") {
-				if strings.HasPrefix(line, "State:") {
-					lxcState := strings.TrimSpace(strings.TrimPrefix(line, "State:"))
-					switch strings.ToUpper(lxcState) {
-					case "RUNNING":
-						currentState = "RUNNING"
-					case "STOPPED":
-						currentState = "STOPPED"
-					case "FROZEN":
-						currentState = "FROZEN"
-					}
-					break
-				}
-			}
-
-			// If we got a valid state that matches our saved state or we've tried 3 times,
-			// use this state
-			if currentState != "" && (currentState == state.Status || i == 2) {
-				state.Status = currentState
-				break
-			}
+// get builds a container from its saved state, refreshing the status from
+// lxc-info when refresh is set
+func (m *LXCManager) get(name string, refresh bool) *Container {
+	// Work on a copy, the cached state may be read concurrently
+	state := *m.cachedState(name)
+
+	// Read until lxc-info agrees with the saved state or with itself, taking
+	// the last read if it never settles
+	attempts := m.statePolling.Attempts
+	previous := ""
+	var ips []string
+	for i := 0; refresh && i < attempts; i++ {
+		currentState, currentIPs := m.readLXCState(name)
+		if currentState != "" && (currentState == state.Status || currentState == previous || i == attempts-1) {
+			state.Status = currentState
+			ips = currentIPs
+			m.state.observeStatus(name, currentState)
+			break
 		}
+		previous = currentState
 
 		// Wait a short time before retrying
-		if i < 2 {
-			time.Sleep(100 * time.Millisecond)
+		if i < attempts-1 {
+			time.Sleep(m.statePolling.Interval)
 		}
 	}
 
-	return &Container{
+	container := &Container{
 		Name:   name,
 		State:  state.Status,
 		Config: state.Config,
-	}, nil
+	}
+	if container.State == "RUNNING" || container.State == "FROZEN" {
+		container.Health = state.Health
+	}
+	if container.State == "RUNNING" {
+		container.IPs = ips
+	}
+	if container.State == "FROZEN" {
+		container.FrozenAt = state.LastFrozenAt
+	}
+	return container
+}
+
+// readLXCState returns the state lxc-info reports for a container, or ""
+// if it can't be read, with the non-loopback addresses it reports
+func (m *LXCManager) readLXCState(name string) (string, []string) {
+	output, err := m.lxcPathCommand("lxc-info", "-n", name).CombinedOutput()
+	if err != nil {
+		return "", nil
+	}
+	info, err := parseLXCInfo(output)
+	if err != nil {
+		return "", nil
+	}
+	switch info.State {
+	case "RUNNING", "STOPPED", "FROZEN":
+		var ips []string
+		for _, field := range info.IPs {
+			if ip := net.ParseIP(field); ip != nil && !ip.IsLoopback() {
+				ips = append(ips, ip.String())
+			}
+		}
+		return info.State, ips
+	}
+	return "", nil
+}
+
+// HealthStatus returns the latest recorded health of a container, empty if unknown
+func (m *LXCManager) HealthStatus(name string) (string, error) {
+	container, err := m.lookup(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get container: %w", err)
+	}
+	return container.Health, nil
 }
 
 // Pause implements Manager.Pause
 func (m *LXCManager) Pause(name string) error {
-	container, err := m.Get(name)
+	container, err := m.lookup(name)
 	if err != nil {
 		return fmt.Errorf("failed to get container: %w", err)
 	}
@@ -360,9 +779,55 @@ func (m *LXCManager) Pause(name string) error {
 	return nil
 }
 
+// Kill implements Manager.Kill
+func (m *LXCManager) Kill(name, signal string) error {
+	signum, err := validation.ParseSignal(signal)
+	if err != nil {
+		return fmt.Errorf("invalid signal: %w", err)
+	}
+
+	container, err := m.lookup(name)
+	if err != nil {
+		return fmt.Errorf("failed to get container: %w", err)
+	}
+
+	if container.State != "RUNNING" {
+		return fmt.Errorf("container '%s' is not running (current state: %s)", name, container.State)
+	}
+
+	// Send the signal to the container's init process from the host, so the
+	// container needs no kill binary. SIGKILL goes through lxc-stop -k, which
+	// also has LXC clean up after the container.
+	if signum == 9 {
+		if err := m.execLXCCommand("lxc-stop", "-n", name, "-k"); err != nil {
+			return fmt.Errorf("failed to kill container: %w", err)
+		}
+	} else {
+		pid, err := m.initPID(name)
+		if err != nil {
+			return fmt.Errorf("failed to kill container: %w", err)
+		}
+		if err := syscall.Kill(pid, syscall.Signal(signum)); err != nil {
+			return fmt.Errorf("failed to kill container: %w", err)
+		}
+	}
+
+	// SIGKILL always terminates init, other signals are left to the container to handle
+	if signum == 9 {
+		if err := m.state.SaveContainerState(name, container.Config, "STOPPED"); err != nil {
+			return fmt.Errorf("failed to update container state: %w", err)
+		}
+		if err := m.state.MarkStoppedByUser(name); err != nil {
+			return fmt.Errorf("failed to update container state: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // Resume implements Manager.Resume
 func (m *LXCManager) Resume(name string) error {
-	container, err := m.Get(name)
+	container, err := m.lookup(name)
 	if err != nil {
 		return fmt.Errorf("failed to get container: %w", err)
 	}
@@ -388,16 +853,50 @@ func (m *LXCManager) Resume(name string) error {
 	return nil
 }
 
+// RestartOptions changes how RestartWithOptions treats containers that
+// aren't running
+type RestartOptions struct {
+	// PreserveState resumes a frozen container instead of restarting it and
+	// leaves a stopped container stopped
+	PreserveState bool
+}
+
 // Restart implements Manager.Restart
 func (m *LXCManager) Restart(name string) error {
-	container, err := m.Get(name)
+	return m.RestartWithOptions(name, RestartOptions{})
+}
+
+// RestartWithOptions restarts a container like Restart, see RestartOptions
+func (m *LXCManager) RestartWithOptions(name string, opts RestartOptions) error {
+	container, err := m.lookup(name)
 	if err != nil {
 		return fmt.Errorf("failed to get container: %w", err)
 	}
 
+	if opts.PreserveState {
+		switch container.State {
+		case "FROZEN":
+			return m.Resume(name)
+		case "STOPPED":
+			logging.Debug("Leaving stopped container stopped", "name", name)
+			return nil
+		}
+	}
+
+	// Check before stopping, so a missing bridge doesn't leave it down
+	if container.Config != nil {
+		if err := checkBridges(container.Config.Network); err != nil {
+			return err
+		}
+	}
+
 	// If container is running or frozen, stop it first
 	if container.State == "RUNNING" || container.State == "FROZEN" {
-		if err := m.execLXCCommand("lxc-stop", "-n", name); err != nil {
+		args, deadline, err := stopArgs(name, container.Config, nil)
+		if err != nil {
+			return err
+		}
+		if err := m.execLXCCommandTimeout(deadline, "lxc-stop", args...); err != nil {
 			return fmt.Errorf("failed to stop container: %w", err)
 		}
 
@@ -407,7 +906,7 @@ func (m *LXCManager) Restart(name string) error {
 		}
 
 		// Verify state update
-		container, err = m.Get(name)
+		container, err = m.lookup(name)
 		if err != nil {
 			return fmt.Errorf("failed to get container state: %w", err)
 		}
@@ -432,15 +931,72 @@ func (m *LXCManager) Update(name string, cfg *common.Container) error {
 		return fmt.Errorf("container configuration is required")
 	}
 
-	container, err := m.Get(name)
+	// Validate container configuration
+	if err := validateContainerConfig(cfg); err != nil {
+		return fmt.Errorf("invalid container configuration: %w", err)
+	}
+
+	container, err := m.lookup(name)
 	if err != nil {
 		return fmt.Errorf("failed to get container: %w", err)
 	}
+	if err := m.checkSharedNamespaces(name, cfg); err != nil {
+		return err
+	}
+
+	// Regenerate the on-disk configuration so it takes effect on the next start
+	if err := m.applyConfig(name, cfg); err != nil {
+		return fmt.Errorf("failed to apply container configuration: %w", err)
+	}
+
+	if cfg.Network != nil {
+		networkCfg := mergePortForwards(cfg)
+		if err := m.configureNetwork(name, networkCfg); err != nil {
+			return fmt.Errorf("failed to configure network: %w", err)
+		}
+	} else {
+		networkPath := filepath.Join(m.configPath, name, "network.conf")
+		if err := os.Remove(networkPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove network config: %w", err)
+		}
+	}
 
 	// Convert common.Container to config.Container for state saving
 	configContainer := config.FromCommonContainer(cfg)
+
+	// Running containers only pick up cgroup limits live, everything else needs a restart
+	if container.State == "RUNNING" || container.State == "FROZEN" {
+		if err := m.applyLiveResourceLimits(name, cfg); err != nil {
+			return fmt.Errorf("failed to apply resource limits: %w", err)
+		}
+		warnRestartRequired(name, container.Config, configContainer)
+	}
+
 	if err := m.state.SaveContainerState(name, configContainer, container.State); err != nil {
 		return fmt.Errorf("failed to save container state: %w", err)
 	}
 	return nil
 }
+
+// ConfigChanged reports whether cfg differs from the configuration container
+// name was created or last updated with
+func (m *LXCManager) ConfigChanged(name string, cfg *common.Container) (bool, error) {
+	container, err := m.lookup(name)
+	if err != nil {
+		return false, fmt.Errorf("failed to get container: %w", err)
+	}
+	if container.Config == nil {
+		return true, nil
+	}
+
+	// Compared as saved, so empty and missing fields are the same
+	current, err := json.Marshal(container.Config)
+	if err != nil {
+		return false, err
+	}
+	wanted, err := json.Marshal(config.FromCommonContainer(cfg))
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(current, wanted), nil
+}
@@ -1,15 +1,22 @@
 package container_test
 
 import (
+	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
 	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -93,6 +100,7 @@ func TestBasicContainerLifecycle(t *testing.T) {
 		container, err = manager.Get(containerName)
 		testing_internal.AssertNoError(t, err)
 		testing_internal.AssertEqual(t, "FROZEN", container.State)
+		testing_internal.AssertNotNil(t, container.FrozenAt)
 
 		// Test resume
 		err = manager.Resume(containerName)
@@ -104,6 +112,9 @@ func TestBasicContainerLifecycle(t *testing.T) {
 		container, err = manager.Get(containerName)
 		testing_internal.AssertNoError(t, err)
 		testing_internal.AssertEqual(t, "RUNNING", container.State)
+		if container.FrozenAt != nil {
+			t.Errorf("expected no frozen time after resume, got %v", container.FrozenAt)
+		}
 
 		// Test stop
 		err = manager.Stop(containerName)
@@ -133,9 +144,505 @@ func TestBasicContainerLifecycle(t *testing.T) {
 	})
 }
 
+// TestKill tests sending signals to a container
+func TestKill(t *testing.T) {
+	containerName := "test-container-kill"
+	configPath := t.TempDir()
+
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	// A host process stands in for the container's init
+	initProc := exec.Command("sleep", "60")
+	testing_internal.AssertNoError(t, initProc.Start())
+	defer initProc.Process.Kill()
+
+	// Record how signals are delivered
+	kills, restore := mock.RecordCalls(&container.ExecCommand, "lxc-attach", "lxc-stop")
+	defer restore()
+	kills.Stub("lxc-info", func(args []string) *exec.Cmd {
+		if strings.Join(args, " ") != "-n "+containerName+" -p -H" {
+			return nil
+		}
+		return exec.Command("echo", strconv.Itoa(initProc.Process.Pid))
+	})
+
+	manager, err := container.NewLXCManager(configPath)
+	testing_internal.AssertNoError(t, err)
+
+	err = manager.Create(containerName, (&config.Container{}).ToCommonContainer())
+	testing_internal.AssertNoError(t, err)
+	err = mockCmd.AddContainer(containerName, "STOPPED")
+	testing_internal.AssertNoError(t, err)
+
+	t.Run("not_running", func(t *testing.T) {
+		err := manager.Kill(containerName, "SIGKILL")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "not running")
+	})
+
+	err = manager.Start(containerName)
+	testing_internal.AssertNoError(t, err)
+
+	t.Run("invalid_signal", func(t *testing.T) {
+		err := manager.Kill(containerName, "SIGFOO")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "invalid signal")
+	})
+
+	t.Run("custom_signal", func(t *testing.T) {
+		kills.Reset()
+		err := manager.Kill(containerName, "HUP")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(kills.Calls()))
+
+		// The signal reaches init straight from the host
+		err = initProc.Wait()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("expected init to be killed, got %v", err)
+		}
+		status := exitErr.Sys().(syscall.WaitStatus)
+		testing_internal.AssertEqual(t, syscall.SIGHUP, status.Signal())
+
+		c, err := manager.Get(containerName)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "RUNNING", c.State)
+	})
+
+	t.Run("sigkill", func(t *testing.T) {
+		kills.Reset()
+		err := manager.Kill(containerName, "SIGKILL")
+		testing_internal.AssertNoError(t, err)
+		calls := kills.Calls()
+		testing_internal.AssertEqual(t, 1, len(calls))
+		testing_internal.AssertEqual(t, "lxc-stop", calls[0].Name)
+		testing_internal.AssertEqual(t, "-n "+containerName+" -k", calls[0].String())
+
+		c, err := manager.Get(containerName)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "STOPPED", c.State)
+	})
+}
+
+func TestStopGracePeriod(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	// Record lxc-stop arguments
+	stops, restore := mock.RecordCalls(&container.ExecCommand, "lxc-stop")
+	defer restore()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+
+	timeout := 5 * time.Second
+	tests := []struct {
+		name        string
+		gracePeriod string
+		stop        func(name string) error
+		wantArgs    string
+	}{
+		{
+			name:        "service-grace-period",
+			gracePeriod: "1m30s",
+			stop:        manager.Stop,
+			wantArgs:    "-n service-grace-period -t 90",
+		},
+		{
+			name:        "rounds-up",
+			gracePeriod: "1500ms",
+			stop:        manager.Restart,
+			wantArgs:    "-n rounds-up -t 2",
+		},
+		{
+			name:        "timeout-overrides",
+			gracePeriod: "30s",
+			stop: func(name string) error {
+				return manager.StopWithOptions(name, container.StopOptions{Timeout: &timeout})
+			},
+			wantArgs: "-n timeout-overrides -t 5",
+		},
+		{
+			name:     "lxc-default",
+			stop:     manager.Stop,
+			wantArgs: "-n lxc-default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testing_internal.AssertNoError(t, manager.Create(tt.name, &common.Container{StopGracePeriod: tt.gracePeriod}))
+			testing_internal.AssertNoError(t, mockCmd.AddContainer(tt.name, "STOPPED"))
+			testing_internal.AssertNoError(t, manager.Start(tt.name))
+
+			stops.Reset()
+			testing_internal.AssertNoError(t, tt.stop(tt.name))
+			testing_internal.AssertEqual(t, tt.wantArgs, strings.Join(stops.Args(), "\n"))
+		})
+	}
+
+	t.Run("slow_stop_within_grace_period", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Create("slow", &common.Container{StopGracePeriod: "10s"}))
+		testing_internal.AssertNoError(t, mockCmd.AddContainer("slow", "STOPPED"))
+		testing_internal.AssertNoError(t, manager.Start("slow"))
+
+		// lxc-stop taking longer than the default command timeout, but within
+		// the grace period, is a clean stop
+		stops.Stub("lxc-stop", func([]string) *exec.Cmd { return exec.Command("sleep", "6") })
+		defer stops.Stub("lxc-stop", nil)
+		testing_internal.AssertNoError(t, manager.Stop("slow"))
+	})
+
+	t.Run("invalid_grace_period", func(t *testing.T) {
+		err := manager.Create("invalid", &common.Container{StopGracePeriod: "soon"})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "invalid stop grace period")
+	})
+}
+
+func TestGetStatePolling(t *testing.T) {
+	containerName := "test-container-polling"
+
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertNoError(t, manager.Create(containerName, (&config.Container{Image: "ubuntu:20.04"}).ToCommonContainer()))
+	testing_internal.AssertNoError(t, mockCmd.AddContainer(containerName, "STOPPED"))
+	manager.SetStatePolling(container.StatePolling{Attempts: 3, Interval: time.Millisecond})
+
+	lxcState := "STOPPED"
+	reads, restore := mock.RecordCalls(&container.ExecCommand, "lxc-info")
+	defer restore()
+	reads.Stub("lxc-info", func([]string) *exec.Cmd { return exec.Command("echo", "State: "+lxcState) })
+
+	get := func() string {
+		t.Helper()
+		reads.Reset()
+		c, err := manager.Get(containerName)
+		testing_internal.AssertNoError(t, err)
+		return c.State
+	}
+
+	t.Run("matches_saved_state", func(t *testing.T) {
+		testing_internal.AssertEqual(t, "STOPPED", get())
+		testing_internal.AssertEqual(t, 1, len(reads.Calls()))
+	})
+
+	t.Run("settles_on_repeated_read", func(t *testing.T) {
+		lxcState = "RUNNING"
+		testing_internal.AssertEqual(t, "RUNNING", get())
+		testing_internal.AssertEqual(t, 2, len(reads.Calls()))
+	})
+
+	t.Run("single_attempt", func(t *testing.T) {
+		manager.SetStatePolling(container.StatePolling{Attempts: 1, Interval: time.Hour})
+		lxcState = "FROZEN"
+		testing_internal.AssertEqual(t, "FROZEN", get())
+		testing_internal.AssertEqual(t, 1, len(reads.Calls()))
+	})
+}
+
+// TestContainerExists tests that existence checks only consult lxc-info
+// through ExistsInLXC
+func TestContainerExists(t *testing.T) {
+	configPath := t.TempDir()
+
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	infos, restore := mock.RecordCalls(&container.ExecCommand, "lxc-info")
+	defer restore()
+
+	manager, err := container.NewLXCManager(configPath)
+	testing_internal.AssertNoError(t, err)
+
+	t.Run("create_skips_lxc_info", func(t *testing.T) {
+		infos.Reset()
+		testing_internal.AssertNoError(t, manager.Create("web", &common.Container{}))
+		testing_internal.AssertEqual(t, 0, len(infos.Calls()))
+		testing_internal.AssertEqual(t, true, manager.ContainerExists("web"))
+	})
+
+	t.Run("config_file", func(t *testing.T) {
+		// A staged rootfs doesn't make a container, its config does
+		dir := filepath.Join(configPath, "db")
+		testing_internal.AssertNoError(t, os.MkdirAll(filepath.Join(dir, "rootfs"), 0755))
+		testing_internal.AssertEqual(t, false, manager.ContainerExists("db"))
+
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(dir, "config"), nil, 0644))
+		testing_internal.AssertEqual(t, true, manager.ContainerExists("db"))
+	})
+
+	t.Run("exists_in_lxc", func(t *testing.T) {
+		testing_internal.AssertNoError(t, mockCmd.AddContainer("external", "STOPPED"))
+
+		infos.Reset()
+		testing_internal.AssertEqual(t, false, manager.ContainerExists("external"))
+		testing_internal.AssertEqual(t, 0, len(infos.Calls()))
+		testing_internal.AssertEqual(t, true, manager.ExistsInLXC("external"))
+		calls := infos.Calls()
+		testing_internal.AssertEqual(t, 1, len(calls))
+		// Containers under a custom config dir are looked up there
+		testing_internal.AssertEqual(t, configPath, calls[0].LXCPath)
+		testing_internal.AssertEqual(t, "-n external", calls[0].String())
+	})
+}
+
+// TestLXCCommandsUseConfigPath tests that lxc-* commands look for containers
+// in the manager's config path
+func TestLXCCommandsUseConfigPath(t *testing.T) {
+	configPath := t.TempDir()
+
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	calls, restore := mock.RecordCalls(&container.ExecCommand)
+	defer restore()
+
+	manager, err := container.NewLXCManager(configPath)
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertNoError(t, manager.Create("web", &common.Container{}))
+	testing_internal.AssertNoError(t, mockCmd.AddContainer("web", "STOPPED"))
+	testing_internal.AssertNoError(t, manager.Start("web"))
+	manager.ExistsInLXC("web")
+
+	testing_internal.AssertEqual(t, true, len(calls.Calls()) > 0)
+	for _, call := range calls.Calls() {
+		if strings.HasPrefix(call.Name, "lxc-") && call.LXCPath != configPath {
+			t.Errorf("%s %s is missing -P %s", call.Name, call, configPath)
+		}
+	}
+}
+
 // Move the following tests to integration_test.go when ready:
 // TestPauseResume
 // TestRestart
 // TestUpdate
 // TestStartStop
 // TestCreateRemove
+
+func TestCreateRollback(t *testing.T) {
+	configPath := t.TempDir()
+
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(configPath)
+	testing_internal.AssertNoError(t, err)
+
+	// Port forwarding passes validation but fails while writing the network
+	// config, after the directories and hostname are in place
+	broken := &config.Container{
+		Network: &config.NetworkConfig{
+			Interfaces: []config.NetworkInterface{{Type: "veth", Bridge: "lxcbr0", DHCP: true}},
+		},
+		Ports: []config.PortForward{{Protocol: "tcp", Host: 8080, Guest: 80}},
+	}
+
+	t.Run("network_failure", func(t *testing.T) {
+		err := manager.Create("web", broken.ToCommonContainer())
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "failed to configure network")
+
+		if _, err := os.Stat(filepath.Join(configPath, "web")); !os.IsNotExist(err) {
+			t.Errorf("expected container directory to be removed, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(configPath, "state", "web.json")); !os.IsNotExist(err) {
+			t.Errorf("expected no state file, got %v", err)
+		}
+
+		// Nothing is left that would make a retry fail
+		err = manager.Create("web", (&config.Container{}).ToCommonContainer())
+		testing_internal.AssertNoError(t, err)
+	})
+
+	t.Run("existing_directory_kept", func(t *testing.T) {
+		etc := filepath.Join(configPath, "db", "rootfs", "etc")
+		testing_internal.AssertNoError(t, os.MkdirAll(etc, 0755))
+
+		err := manager.Create("db", broken.ToCommonContainer())
+		testing_internal.AssertError(t, err)
+
+		_, err = os.Stat(etc)
+		testing_internal.AssertNoError(t, err)
+	})
+}
+
+func TestConfigChanged(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+
+	cfg := &common.Container{
+		Image:       "ubuntu:20.04",
+		Environment: map[string]string{"PORT": "8080"},
+		Storage:     &common.StorageConfig{Root: "1G"},
+		Labels:      map[string]string{},
+	}
+	testing_internal.AssertNoError(t, manager.Create("web", cfg))
+	testing_internal.AssertNoError(t, mockCmd.AddContainer("web", "STOPPED"))
+
+	changed, err := manager.ConfigChanged("web", cfg)
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertEqual(t, false, changed)
+
+	// Empty and missing fields are the same configuration
+	same := *cfg
+	same.Labels = nil
+	changed, err = manager.ConfigChanged("web", &same)
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertEqual(t, false, changed)
+
+	updated := *cfg
+	updated.Environment = map[string]string{"PORT": "9090"}
+	changed, err = manager.ConfigChanged("web", &updated)
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertEqual(t, true, changed)
+
+	reserved := *cfg
+	reserved.Memory = &common.MemoryConfig{Reserve: "256M"}
+	changed, err = manager.ConfigChanged("web", &reserved)
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertEqual(t, true, changed)
+
+	_, err = manager.ConfigChanged("missing", cfg)
+	testing_internal.AssertError(t, err)
+}
+
+func TestRestartPreserveState(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+
+	calls, restore := mock.RecordCalls(&container.ExecCommand, "lxc-start", "lxc-stop", "lxc-freeze", "lxc-unfreeze")
+	defer restore()
+
+	for _, name := range []string{"frozen", "stopped"} {
+		testing_internal.AssertNoError(t, manager.Create(name, &common.Container{Image: "ubuntu:20.04"}))
+		testing_internal.AssertNoError(t, mockCmd.AddContainer(name, "STOPPED"))
+	}
+	testing_internal.AssertNoError(t, manager.Start("frozen"))
+	testing_internal.AssertNoError(t, mockCmd.SetContainerState("frozen", "RUNNING"))
+	testing_internal.AssertNoError(t, manager.Pause("frozen"))
+	testing_internal.AssertNoError(t, mockCmd.SetContainerState("frozen", "FROZEN"))
+
+	preserve := container.RestartOptions{PreserveState: true}
+
+	t.Run("frozen_resumed", func(t *testing.T) {
+		calls.Reset()
+		testing_internal.AssertNoError(t, manager.RestartWithOptions("frozen", preserve))
+		testing_internal.AssertEqual(t, "lxc-unfreeze", strings.Join(calls.Names(), ","))
+	})
+
+	t.Run("stopped_left_stopped", func(t *testing.T) {
+		calls.Reset()
+		testing_internal.AssertNoError(t, manager.RestartWithOptions("stopped", preserve))
+		testing_internal.AssertEqual(t, "", strings.Join(calls.Names(), ","))
+	})
+
+	t.Run("default_starts_stopped", func(t *testing.T) {
+		calls.Reset()
+		testing_internal.AssertNoError(t, manager.Restart("stopped"))
+		testing_internal.AssertEqual(t, "lxc-start", strings.Join(calls.Names(), ","))
+	})
+}
+
+func TestLXCCommandRetries(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+
+	// lxc-start fails with the given outputs, one per attempt, then succeeds
+	var outputs []string
+	attempts, restore := mock.RecordCalls(&container.ExecCommand, "lxc-start")
+	defer restore()
+	attempts.Stub("lxc-start", func([]string) *exec.Cmd {
+		if len(outputs) == 0 {
+			return exec.Command("true")
+		}
+		output := outputs[0]
+		outputs = outputs[1:]
+		return exec.Command("sh", "-c", "echo \"$1\"; exit 1", "sh", output)
+	})
+
+	testing_internal.AssertNoError(t, manager.Create("web", &common.Container{Image: "ubuntu:20.04"}))
+	testing_internal.AssertNoError(t, mockCmd.AddContainer("web", "STOPPED"))
+
+	t.Run("terminal_fails_fast", func(t *testing.T) {
+		attempts.Reset()
+		outputs = []string{"lxc-start: web: Container \"web\" does not exist"}
+		err := manager.Start("web")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "failed to start container")
+		testing_internal.AssertEqual(t, 1, len(attempts.Calls()))
+	})
+
+	t.Run("transient_retried", func(t *testing.T) {
+		attempts.Reset()
+		outputs = []string{"lxc-start: web: Failed to acquire lock: Device or resource busy"}
+		testing_internal.AssertNoError(t, manager.Start("web"))
+		testing_internal.AssertEqual(t, 2, len(attempts.Calls()))
+	})
+}
+
+func TestCreateWithOptions(t *testing.T) {
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	dir := t.TempDir()
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+
+	// Record what would set up the network or start the container
+	calls, restore := mock.RecordCalls(&container.ExecCommand, "lxc-start", "lxc-attach", "ip", "iptables")
+	defer restore()
+	// The mock only starts containers it was told about
+	calls.Stub("lxc-start", func([]string) *exec.Cmd { return exec.Command("true") })
+
+	network := &common.NetworkConfig{Type: "veth", Bridge: "lxcbr0", IP: "10.0.3.10/24"}
+
+	t.Run("skip_network", func(t *testing.T) {
+		calls.Reset()
+		err := manager.CreateWithOptions("staged", &common.Container{
+			Image:   "ubuntu:20.04",
+			Network: network,
+			Ports:   []common.PortForward{{Protocol: "tcp", Host: 8080, Guest: 80}},
+		}, container.CreateOptions{SkipNetwork: true, SkipStart: true})
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "", strings.Join(calls.Names(), ","))
+
+		_, err = os.Stat(filepath.Join(dir, "staged", "network.conf"))
+		testing_internal.AssertEqual(t, true, os.IsNotExist(err))
+		c, err := manager.Get("staged")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, true, c.Config.Network == nil)
+
+		// The network is applied later, with the service's ports
+		testing_internal.AssertNoError(t, manager.ReconfigureNetwork("staged", network))
+		data, err := os.ReadFile(filepath.Join(dir, "staged", "network.conf"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.net.0.link = lxcbr0")
+		testing_internal.AssertContains(t, string(data), "--dport 8080")
+	})
+
+	t.Run("starts_by_default", func(t *testing.T) {
+		calls.Reset()
+		testing_internal.AssertNoError(t, manager.CreateWithOptions("started", &common.Container{Image: "ubuntu:20.04"}, container.CreateOptions{}))
+		testing_internal.AssertEqual(t, "lxc-start", strings.Join(calls.Names(), ","))
+	})
+
+	t.Run("create_leaves_stopped", func(t *testing.T) {
+		calls.Reset()
+		testing_internal.AssertNoError(t, manager.Create("plain", &common.Container{Image: "ubuntu:20.04"}))
+		testing_internal.AssertEqual(t, "", strings.Join(calls.Names(), ","))
+	})
+}
@@ -0,0 +1,82 @@
+package container
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
+
+// RestartStopped starts every stopped container whose restart policy asks
+// for it and returns the names it restarted. unless-stopped containers are
+// only restarted if they exited or crashed, not if the user stopped them.
+// always containers are restarted after a user stop too, but only on the
+// boot pass, so a container stopped on purpose stays down until the monitor
+// itself is restarted. Containers that never ran are left alone.
+func (m *LXCManager) RestartStopped(boot bool) ([]string, error) {
+	containers, err := m.ListWithOptions(ListOptions{State: "STOPPED"})
+	if err != nil {
+		return nil, err
+	}
+
+	var restarted []string
+	for _, c := range containers {
+		state := m.cachedState(c.Name)
+		if !shouldRestart(state, boot) {
+			continue
+		}
+
+		logging.Info("Restarting stopped container", "name", c.Name, "policy", state.Config.Restart)
+		if err := m.Start(c.Name); err != nil {
+			logging.Error("Failed to restart container", "name", c.Name, "error", err)
+			continue
+		}
+		restarted = append(restarted, c.Name)
+	}
+	return restarted, nil
+}
+
+// shouldRestart reports whether the restart policy of a stopped container
+// asks for it to be started again
+func shouldRestart(state *State, boot bool) bool {
+	if state.Config == nil || state.LastStartedAt == nil {
+		return false
+	}
+	switch strings.ToLower(state.Config.Restart) {
+	case common.RestartAlways:
+		return boot || !state.StoppedByUser
+	case common.RestartUnlessStopped:
+		return !state.StoppedByUser
+	}
+	return false
+}
+
+// Monitor restarts stopped containers according to their restart policy,
+// probes the health of running ones and rotates console logs over their
+// logging max_size every interval until ctx is done. The first pass counts as
+// a boot, see RestartStopped.
+func (m *LXCManager) Monitor(ctx context.Context, interval time.Duration) error {
+	boot := true
+	failures := make(map[string]int)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.RestartStopped(boot); err != nil {
+			logging.Error("Failed to check containers", "error", err)
+		}
+		boot = false
+		if err := m.MonitorHealth(ctx, failures); err != nil && ctx.Err() == nil {
+			logging.Error("Failed to check container health", "error", err)
+		}
+		m.rotateAllLogs()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
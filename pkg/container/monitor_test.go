@@ -0,0 +1,120 @@
+package container_test
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestRestartStopped(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	dir := t.TempDir()
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+	manager.SetStatePolling(container.StatePolling{Attempts: 1})
+
+	// Track what lxc-info reports, a crash is simulated by setting a
+	// running container to STOPPED behind the manager's back
+	lxcStates := map[string]string{}
+	lxc, restore := mock.RecordCalls(&container.ExecCommand, "lxc-start", "lxc-stop")
+	defer restore()
+	lxc.Stub("lxc-info", func(args []string) *exec.Cmd {
+		if state, ok := lxcStates[args[1]]; ok {
+			return exec.Command("echo", "State: "+state)
+		}
+		return nil
+	})
+	lxc.Stub("lxc-start", func(args []string) *exec.Cmd {
+		lxcStates[args[1]] = "RUNNING"
+		return nil
+	})
+	lxc.Stub("lxc-stop", func(args []string) *exec.Cmd {
+		lxcStates[args[1]] = "STOPPED"
+		return nil
+	})
+
+	create := func(t *testing.T, name, policy string) {
+		t.Helper()
+		testing_internal.AssertNoError(t, manager.Create(name, &common.Container{Image: "ubuntu:20.04", Restart: policy}))
+		testing_internal.AssertNoError(t, mockCmd.AddContainer(name, "STOPPED"))
+		lxcStates[name] = "STOPPED"
+	}
+	start := func(t *testing.T, name, policy string) {
+		t.Helper()
+		create(t, name, policy)
+		testing_internal.AssertNoError(t, manager.Start(name))
+	}
+	stateOf := func(t *testing.T, name string) string {
+		t.Helper()
+		c, err := manager.Get(name)
+		testing_internal.AssertNoError(t, err)
+		return c.State
+	}
+
+	t.Run("unless_stopped_restarts_after_crash", func(t *testing.T) {
+		start(t, "crashed", common.RestartUnlessStopped)
+		lxcStates["crashed"] = "STOPPED"
+
+		restarted, err := manager.RestartStopped(false)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "crashed", strings.Join(restarted, ","))
+		testing_internal.AssertEqual(t, "RUNNING", stateOf(t, "crashed"))
+	})
+
+	t.Run("unless_stopped_stays_down_after_user_stop", func(t *testing.T) {
+		start(t, "stopped", common.RestartUnlessStopped)
+		testing_internal.AssertNoError(t, manager.Stop("stopped"))
+
+		// The flag is persisted, so it survives the monitor restarting
+		states, err := container.NewStateManager(filepath.Join(dir, "state"))
+		testing_internal.AssertNoError(t, err)
+		state, err := states.GetContainerState("stopped")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, true, state.StoppedByUser)
+
+		restarted, err := manager.RestartStopped(true)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(restarted))
+		testing_internal.AssertEqual(t, "STOPPED", stateOf(t, "stopped"))
+
+		// Starting again clears the flag, so a later crash is restarted
+		testing_internal.AssertNoError(t, manager.Start("stopped"))
+		lxcStates["stopped"] = "STOPPED"
+		restarted, err = manager.RestartStopped(false)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "stopped", strings.Join(restarted, ","))
+
+		testing_internal.AssertNoError(t, manager.Stop("stopped"))
+	})
+
+	t.Run("always_restarts_user_stop_on_boot", func(t *testing.T) {
+		start(t, "always", common.RestartAlways)
+		testing_internal.AssertNoError(t, manager.Stop("always"))
+
+		restarted, err := manager.RestartStopped(false)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(restarted))
+
+		restarted, err = manager.RestartStopped(true)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "always", strings.Join(restarted, ","))
+	})
+
+	t.Run("ignores_never_started_and_no_policy", func(t *testing.T) {
+		create(t, "created", common.RestartAlways)
+		start(t, "nopolicy", "")
+		lxcStates["nopolicy"] = "STOPPED"
+
+		restarted, err := manager.RestartStopped(true)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(restarted))
+	})
+}
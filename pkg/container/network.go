@@ -2,16 +2,75 @@ package container
 
 import (
 	"bufio"
+	"crypto/sha256"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
 )
 
+// mergePortForwards returns the container's network configuration with the
+// service-level Ports appended to Network.PortForwards. When both declare the
+// same protocol and host port, the Network.PortForwards entry wins.
+func mergePortForwards(cfg *common.Container) *config.NetworkConfig {
+	if cfg.Network == nil {
+		return nil
+	}
+
+	networkCfg := config.FromCommonNetworkConfig(cfg.Network)
+
+	seen := make(map[string]bool, len(networkCfg.PortForwards))
+	for _, pf := range networkCfg.PortForwards {
+		seen[fmt.Sprintf("%s/%d", pf.Protocol, pf.Host)] = true
+	}
+
+	for _, pf := range cfg.Ports {
+		key := fmt.Sprintf("%s/%d", pf.Protocol, pf.Host)
+		if seen[key] {
+			logging.Warn("Ignoring port already forwarded by network configuration",
+				"protocol", pf.Protocol,
+				"host", pf.Host,
+				"guest", pf.Guest,
+			)
+			continue
+		}
+		seen[key] = true
+		networkCfg.PortForwards = append(networkCfg.PortForwards, config.PortForward{
+			Protocol: pf.Protocol,
+			Host:     pf.Host,
+			Guest:    pf.Guest,
+		})
+	}
+
+	return networkCfg
+}
+
+// generateMAC derives a stable, locally administered unicast MAC address from
+// the container name and interface index, so recreating a container keeps its
+// address. Interface types that don't take a virtual MAC get none. The
+// address is checked like a configured one before it is used.
+func generateMAC(name string, index int, ifaceType string) (string, error) {
+	switch strings.ToLower(ifaceType) {
+	case "none", "phys":
+		return "", nil
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%d", name, index)))
+	// Set the locally administered bit and clear the multicast bit
+	sum[0] = (sum[0] | 0x02) &^ 0x01
+	mac := fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x", sum[0], sum[1], sum[2], sum[3], sum[4], sum[5])
+	if err := validation.ValidateMAC(mac); err != nil {
+		return "", fmt.Errorf("generated MAC address %s: %w", mac, err)
+	}
+	return mac, nil
+}
+
 // configureNetwork configures network settings for a container
 func (m *LXCManager) configureNetwork(name string, cfg *config.NetworkConfig) error {
 	if cfg == nil {
@@ -25,11 +84,6 @@ func (m *LXCManager) configureNetwork(name string, cfg *config.NetworkConfig) er
 	}
 	configPath := filepath.Join(containerDir, "network.conf")
 
-	// If the file exists, remove it first to avoid any issues
-	if err := os.Remove(configPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove existing network config: %w", err)
-	}
-
 	var lines []string
 
 	// Handle legacy configuration
@@ -53,7 +107,7 @@ func (m *LXCManager) configureNetwork(name string, cfg *config.NetworkConfig) er
 	// Configure network isolation if enabled
 	if cfg.Isolated {
 		lines = append(lines, "lxc.net.0.flags = down")
-		return os.WriteFile(configPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+		return writeFileAtomic(configPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
 	}
 
 	// Configure each network interface
@@ -106,8 +160,15 @@ func (m *LXCManager) configureNetwork(name string, cfg *config.NetworkConfig) er
 		if iface.MTU > 0 {
 			lines = append(lines, fmt.Sprintf("%s.mtu = %d", prefix, iface.MTU))
 		}
-		if iface.MAC != "" {
-			lines = append(lines, fmt.Sprintf("%s.hwaddr = %s", prefix, iface.MAC))
+		mac := iface.MAC
+		if mac == "" && !cfg.DisableAutoMAC {
+			var err error
+			if mac, err = generateMAC(name, i, iface.Type); err != nil {
+				return err
+			}
+		}
+		if mac != "" {
+			lines = append(lines, fmt.Sprintf("%s.hwaddr = %s", prefix, mac))
 		}
 	}
 
@@ -141,17 +202,21 @@ func (m *LXCManager) configureNetwork(name string, cfg *config.NetworkConfig) er
 	}
 
 	if len(lines) == 0 {
+		if err := os.Remove(configPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove existing network config: %w", err)
+		}
 		return nil
 	}
 
-	return os.WriteFile(configPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	// Replaced atomically so a failed write keeps the previous config
+	return writeFileAtomic(configPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
 }
 
 // GetNetworkConfig reads network configuration from a container's config file
 func (m *LXCManager) GetNetworkConfig(name string) (*config.NetworkConfig, error) {
 	logging.Debug("Reading network configuration", "container", name)
 
-	configPath := filepath.Join(m.configPath, name, "network", "config")
+	configPath := filepath.Join(m.configPath, name, "network.conf")
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -181,17 +246,10 @@ func (m *LXCManager) GetNetworkConfig(name string) (*config.NetworkConfig, error
 		key := strings.TrimSpace(parts[0])
 		value := strings.TrimSpace(parts[1])
 
-		// Parse port forwarding
-		if strings.HasPrefix(key, "lxc.net.port_forward") {
-			parts := strings.Split(value, ":")
-			if len(parts) == 3 {
-				hostPort, _ := strconv.Atoi(parts[1])
-				guestPort, _ := strconv.Atoi(parts[2])
-				cfg.PortForwards = append(cfg.PortForwards, config.PortForward{
-					Protocol: parts[0],
-					Host:     hostPort,
-					Guest:    guestPort,
-				})
+		// Port forwards are written as DNAT rules in pre-start hooks
+		if key == "lxc.hook.pre-start" {
+			if pf, ok := parseDNATRule(value); ok {
+				cfg.PortForwards = append(cfg.PortForwards, pf)
 			}
 			continue
 		}
@@ -202,8 +260,8 @@ func (m *LXCManager) GetNetworkConfig(name string) (*config.NetworkConfig, error
 			if n, err := fmt.Sscanf(key, "lxc.net.%d", &index); err == nil && n == 1 {
 				if index != currentIndex {
 					currentIndex = index
-					currentIface = &config.NetworkInterface{}
-					cfg.Interfaces = append(cfg.Interfaces, *currentIface)
+					cfg.Interfaces = append(cfg.Interfaces, config.NetworkInterface{})
+					currentIface = &cfg.Interfaces[len(cfg.Interfaces)-1]
 				}
 			}
 
@@ -259,3 +317,188 @@ func (m *LXCManager) GetNetworkConfig(name string) (*config.NetworkConfig, error
 
 	return cfg, nil
 }
+
+// parseDNATRule extracts the port forward from an iptables DNAT rule as
+// written by configureNetwork
+func parseDNATRule(rule string) (config.PortForward, bool) {
+	var pf config.PortForward
+	fields := strings.Fields(rule)
+	if len(fields) == 0 || fields[0] != "iptables" {
+		return pf, false
+	}
+
+	for i := 1; i+1 < len(fields); i++ {
+		switch fields[i] {
+		case "-p":
+			pf.Protocol = fields[i+1]
+		case "--dport":
+			pf.Host, _ = strconv.Atoi(fields[i+1])
+		case "--to":
+			if _, port, ok := strings.Cut(fields[i+1], ":"); ok {
+				pf.Guest, _ = strconv.Atoi(port)
+			}
+		}
+	}
+	return pf, pf.Protocol != "" && pf.Host > 0 && pf.Guest > 0
+}
+
+// ReconfigureNetwork replaces a container's network configuration without
+// recreating it. The network config is regenerated for the next start and,
+// for a running container, address, gateway and MTU changes are applied live.
+// Changes to interface type, link, name or MAC only take effect after a restart.
+func (m *LXCManager) ReconfigureNetwork(name string, cfg *common.NetworkConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("network configuration is required")
+	}
+	if err := validation.ValidateNetworkConfig(toValidationNetworkConfig(cfg)); err != nil {
+		return fmt.Errorf("invalid network configuration: %w", err)
+	}
+
+	container, err := m.lookup(name)
+	if err != nil {
+		return fmt.Errorf("failed to get container: %w", err)
+	}
+
+	stored := container.Config
+	if stored == nil {
+		stored = &config.Container{}
+	}
+	var current *common.NetworkConfig
+	if stored.Network != nil {
+		current = stored.Network.ToCommonNetworkConfig()
+	}
+
+	// Service-level ports still apply on top of the new network settings
+	updated := &common.Container{Network: cfg, Ports: config.ToCommonPortForwards(stored.Ports)}
+	if err := m.configureNetwork(name, mergePortForwards(updated)); err != nil {
+		return fmt.Errorf("failed to configure network: %w", err)
+	}
+
+	if container.State == "RUNNING" {
+		if err := m.applyLiveNetwork(name, current, cfg); err != nil {
+			return fmt.Errorf("failed to apply network changes: %w", err)
+		}
+	}
+
+	stored.Network = config.FromCommonNetworkConfig(cfg)
+	if err := m.state.SaveContainerState(name, stored, container.State); err != nil {
+		return fmt.Errorf("failed to save container state: %w", err)
+	}
+	return nil
+}
+
+// applyLiveNetwork applies the interface changes between current and updated
+// that can be made inside a running container, warning about the rest
+func (m *LXCManager) applyLiveNetwork(name string, current, updated *common.NetworkConfig) error {
+	before := networkInterfaces(current)
+	after := networkInterfaces(updated)
+
+	if len(before) != len(after) {
+		logging.Warn("Adding or removing network interfaces requires a restart to take effect",
+			"container", name,
+		)
+	}
+
+	for i := 0; i < len(before) && i < len(after); i++ {
+		old, iface := before[i], after[i]
+		if !strings.EqualFold(old.Type, iface.Type) || old.Bridge != iface.Bridge ||
+			old.Interface != iface.Interface || old.MAC != iface.MAC || old.DHCP != iface.DHCP {
+			logging.Warn("Interface type, link, name, MAC or DHCP changed and requires a restart to take effect",
+				"container", name,
+				"interface", i,
+			)
+			continue
+		}
+
+		dev := iface.Interface
+		if dev == "" {
+			dev = fmt.Sprintf("eth%d", i)
+		}
+
+		var cmds [][]string
+		if !iface.DHCP && old.IP != iface.IP {
+			cmds = append(cmds, []string{"ip", "addr", "flush", "dev", dev})
+			if iface.IP != "" {
+				cmds = append(cmds, []string{"ip", "addr", "add", iface.IP, "dev", dev})
+			}
+		}
+		if !iface.DHCP && iface.Gateway != "" && (old.Gateway != iface.Gateway || old.IP != iface.IP) {
+			cmds = append(cmds, []string{"ip", "route", "replace", "default", "via", iface.Gateway, "dev", dev})
+		}
+		if iface.MTU > 0 && old.MTU != iface.MTU {
+			cmds = append(cmds, []string{"ip", "link", "set", "dev", dev, "mtu", strconv.Itoa(iface.MTU)})
+		}
+
+		for _, cmd := range cmds {
+			args := append([]string{"-n", name, "--"}, cmd...)
+			if err := m.execLXCCommand("lxc-attach", args...); err != nil {
+				return fmt.Errorf("failed to run '%s' on %s: %w", strings.Join(cmd, " "), dev, err)
+			}
+			logging.Debug("Applied live network change", "container", name, "command", strings.Join(cmd, " "))
+		}
+	}
+	return nil
+}
+
+// networkInterfaces returns the interfaces of cfg with the legacy top-level
+// interface, if any, first, matching the order configureNetwork writes them
+func networkInterfaces(cfg *common.NetworkConfig) []common.NetworkInterface {
+	if cfg == nil {
+		return nil
+	}
+	var ifaces []common.NetworkInterface
+	if cfg.Type != "" {
+		ifaces = append(ifaces, common.NetworkInterface{
+			Type:      cfg.Type,
+			Bridge:    cfg.Bridge,
+			Interface: cfg.Interface,
+			IP:        cfg.IP,
+			Gateway:   cfg.Gateway,
+			DNS:       cfg.DNS,
+			DHCP:      cfg.DHCP,
+			Hostname:  cfg.Hostname,
+			MTU:       cfg.MTU,
+			MAC:       cfg.MAC,
+		})
+	}
+	return append(ifaces, cfg.Interfaces...)
+}
+
+// toValidationNetworkConfig converts a network config for the validation package
+func toValidationNetworkConfig(cfg *common.NetworkConfig) *validation.NetworkConfig {
+	out := &validation.NetworkConfig{
+		Type:       cfg.Type,
+		Bridge:     cfg.Bridge,
+		Interface:  cfg.Interface,
+		IP:         cfg.IP,
+		Gateway:    cfg.Gateway,
+		DNS:        cfg.DNS,
+		DNSOptions: cfg.DNSOptions,
+		DHCP:       cfg.DHCP,
+		Hostname:   cfg.Hostname,
+		MTU:        cfg.MTU,
+		MAC:        cfg.MAC,
+	}
+	for _, iface := range cfg.Interfaces {
+		out.Interfaces = append(out.Interfaces, validation.NetworkInterface{
+			Type:      iface.Type,
+			Bridge:    iface.Bridge,
+			Interface: iface.Interface,
+			IP:        iface.IP,
+			Gateway:   iface.Gateway,
+			DNS:       iface.DNS,
+			DHCP:      iface.DHCP,
+			Hostname:  iface.Hostname,
+			MTU:       iface.MTU,
+			MAC:       iface.MAC,
+		})
+	}
+	for _, pf := range cfg.PortForwards {
+		out.PortForwards = append(out.PortForwards, validation.PortForward{
+			Protocol: pf.Protocol,
+			Host:     pf.Host,
+			Guest:    pf.Guest,
+		})
+	}
+	return out
+}
@@ -2,13 +2,18 @@ package container_test
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
 	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
 )
 
 func TestConfigureNetwork(t *testing.T) {
@@ -53,14 +58,14 @@ func TestConfigureNetwork(t *testing.T) {
 				content := string(data)
 
 				// Verify DHCP settings
-				testing_internal.AssertContains(t, content, "lxc.net.0.type = veth")
-				testing_internal.AssertContains(t, content, "lxc.net.0.link = br0")
-				testing_internal.AssertContains(t, content, "lxc.net.0.name = eth0")
-				testing_internal.AssertContains(t, content, "lxc.net.0.ipv4.method = dhcp")
-				testing_internal.AssertContains(t, content, "lxc.net.0.ipv6.method = dhcp")
-				testing_internal.AssertContains(t, content, "lxc.net.0.hostname = test-host")
-				testing_internal.AssertContains(t, content, "lxc.net.0.mtu = 1500")
-				testing_internal.AssertContains(t, content, "lxc.net.0.hwaddr = 00:11:22:33:44:55")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.type", "veth")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.link", "br0")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.name", "eth0")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.ipv4.method", "dhcp")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.ipv6.method", "dhcp")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.hostname", "test-host")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.mtu", "1500")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.hwaddr", "00:11:22:33:44:55")
 			},
 		},
 		{
@@ -82,16 +87,16 @@ func TestConfigureNetwork(t *testing.T) {
 				content := string(data)
 
 				// Verify static IP settings
-				testing_internal.AssertContains(t, content, "lxc.net.0.type = veth")
-				testing_internal.AssertContains(t, content, "lxc.net.0.link = br0")
-				testing_internal.AssertContains(t, content, "lxc.net.0.name = eth0")
-				testing_internal.AssertContains(t, content, "lxc.net.0.ipv4.address = 192.168.1.100/24")
-				testing_internal.AssertContains(t, content, "lxc.net.0.ipv4.gateway = 192.168.1.1")
-				testing_internal.AssertContains(t, content, "lxc.net.0.ipv4.nameserver.0 = 8.8.8.8")
-				testing_internal.AssertContains(t, content, "lxc.net.0.ipv4.nameserver.1 = 8.8.4.4")
-				testing_internal.AssertContains(t, content, "lxc.net.0.hostname = test-host")
-				testing_internal.AssertContains(t, content, "lxc.net.0.mtu = 1500")
-				testing_internal.AssertContains(t, content, "lxc.net.0.hwaddr = 00:11:22:33:44:55")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.type", "veth")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.link", "br0")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.name", "eth0")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.ipv4.address", "192.168.1.100/24")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.ipv4.gateway", "192.168.1.1")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.ipv4.nameserver.0", "8.8.8.8")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.ipv4.nameserver.1", "8.8.4.4")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.hostname", "test-host")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.mtu", "1500")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.hwaddr", "00:11:22:33:44:55")
 			},
 		},
 		{
@@ -132,20 +137,20 @@ func TestConfigureNetwork(t *testing.T) {
 				content := string(data)
 
 				// First interface
-				testing_internal.AssertContains(t, content, "lxc.net.0.type = veth")
-				testing_internal.AssertContains(t, content, "lxc.net.0.link = br0")
-				testing_internal.AssertContains(t, content, "lxc.net.0.name = eth0")
-				testing_internal.AssertContains(t, content, "lxc.net.0.ipv4.method = dhcp")
-				testing_internal.AssertContains(t, content, "lxc.net.0.mtu = 1500")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.type", "veth")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.link", "br0")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.name", "eth0")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.ipv4.method", "dhcp")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.mtu", "1500")
 
 				// Second interface
-				testing_internal.AssertContains(t, content, "lxc.net.1.type = veth")
-				testing_internal.AssertContains(t, content, "lxc.net.1.link = br1")
-				testing_internal.AssertContains(t, content, "lxc.net.1.name = eth1")
-				testing_internal.AssertContains(t, content, "lxc.net.1.ipv4.address = 10.0.0.100/24")
-				testing_internal.AssertContains(t, content, "lxc.net.1.ipv4.gateway = 10.0.0.1")
-				testing_internal.AssertContains(t, content, "lxc.net.1.ipv4.nameserver.0 = 1.1.1.1")
-				testing_internal.AssertContains(t, content, "lxc.net.1.mtu = 1500")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.1.type", "veth")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.1.link", "br1")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.1.name", "eth1")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.1.ipv4.address", "10.0.0.100/24")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.1.ipv4.gateway", "10.0.0.1")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.1.ipv4.nameserver.0", "1.1.1.1")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.1.mtu", "1500")
 			},
 		},
 		{
@@ -178,16 +183,16 @@ func TestConfigureNetwork(t *testing.T) {
 				content := string(data)
 
 				// Basic network config
-				testing_internal.AssertContains(t, content, "lxc.net.0.type = veth")
-				testing_internal.AssertContains(t, content, "lxc.net.0.link = br0")
-				testing_internal.AssertContains(t, content, "lxc.net.0.name = eth0")
-				testing_internal.AssertContains(t, content, "lxc.net.0.ipv4.address = 192.168.1.100/24")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.type", "veth")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.link", "br0")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.name", "eth0")
+				testing_internal.AssertConfigKey(t, content, "lxc.net.0.ipv4.address", "192.168.1.100/24")
 
 				// Port forwards
-				testing_internal.AssertContains(t, content, "lxc.hook.pre-start = iptables -t nat -A PREROUTING -p tcp --dport 80 -j DNAT --to 192.168.1.100:8080")
-				testing_internal.AssertContains(t, content, "lxc.hook.pre-start = iptables -t nat -A PREROUTING -p udp --dport 53 -j DNAT --to 192.168.1.100:53")
-				testing_internal.AssertContains(t, content, "lxc.hook.post-stop = iptables -t nat -D PREROUTING -p tcp --dport 80 -j DNAT --to 192.168.1.100:8080")
-				testing_internal.AssertContains(t, content, "lxc.hook.post-stop = iptables -t nat -D PREROUTING -p udp --dport 53 -j DNAT --to 192.168.1.100:53")
+				testing_internal.AssertConfigKey(t, content, "lxc.hook.pre-start", "iptables -t nat -A PREROUTING -p tcp --dport 80 -j DNAT --to 192.168.1.100:8080")
+				testing_internal.AssertConfigKey(t, content, "lxc.hook.pre-start", "iptables -t nat -A PREROUTING -p udp --dport 53 -j DNAT --to 192.168.1.100:53")
+				testing_internal.AssertConfigKey(t, content, "lxc.hook.post-stop", "iptables -t nat -D PREROUTING -p tcp --dport 80 -j DNAT --to 192.168.1.100:8080")
+				testing_internal.AssertConfigKey(t, content, "lxc.hook.post-stop", "iptables -t nat -D PREROUTING -p udp --dport 53 -j DNAT --to 192.168.1.100:53")
 			},
 		},
 	}
@@ -233,6 +238,73 @@ func TestConfigureNetwork(t *testing.T) {
 	}
 }
 
+func TestServicePorts(t *testing.T) {
+	containerName := "test-container"
+
+	t.Run("merged_with_port_forwards", func(t *testing.T) {
+		dir := t.TempDir()
+		_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+		defer cleanup()
+
+		manager, err := container.NewLXCManager(dir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{
+			Network: &common.NetworkConfig{
+				Type:   "veth",
+				Bridge: "br0",
+				IP:     "192.168.1.100/24",
+				PortForwards: []common.PortForward{
+					{Protocol: "tcp", Host: 8080, Guest: 80},
+				},
+			},
+			Ports: []common.PortForward{
+				{Protocol: "tcp", Host: 8080, Guest: 8000},
+				{Protocol: "tcp", Host: 8443, Guest: 443},
+			},
+		})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(dir, containerName, "network.conf"))
+		testing_internal.AssertNoError(t, err)
+		content := string(data)
+
+		// Network.PortForwards wins on a conflicting host port
+		testing_internal.AssertContains(t, content, "--dport 8080 -j DNAT --to 192.168.1.100:80")
+		testing_internal.AssertNotContains(t, content, "192.168.1.100:8000")
+		testing_internal.AssertContains(t, content, "--dport 8443 -j DNAT --to 192.168.1.100:443")
+	})
+
+	t.Run("invalid_port", func(t *testing.T) {
+		dir := t.TempDir()
+		_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+		defer cleanup()
+
+		manager, err := container.NewLXCManager(dir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{
+			Network: &common.NetworkConfig{Type: "veth", IP: "192.168.1.100/24"},
+			Ports:   []common.PortForward{{Protocol: "sctp", Host: 80, Guest: 80}},
+		})
+		testing_internal.AssertError(t, err)
+	})
+
+	t.Run("ports_without_network", func(t *testing.T) {
+		dir := t.TempDir()
+		_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+		defer cleanup()
+
+		manager, err := container.NewLXCManager(dir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(containerName, &common.Container{
+			Ports: []common.PortForward{{Protocol: "tcp", Host: 80, Guest: 80}},
+		})
+		testing_internal.AssertError(t, err)
+	})
+}
+
 func TestGetContainerWithNetwork(t *testing.T) {
 	dir, cleanup := testing_internal.TempDir(t)
 	defer cleanup()
@@ -405,9 +477,9 @@ func TestBasicNetworkConfiguration(t *testing.T) {
 			verify: func(t *testing.T, configPath string) {
 				content, err := os.ReadFile(configPath)
 				testing_internal.AssertNoError(t, err)
-				testing_internal.AssertContains(t, string(content), "lxc.net.0.type = veth")
-				testing_internal.AssertContains(t, string(content), "lxc.net.0.link = br0")
-				testing_internal.AssertContains(t, string(content), "lxc.net.0.flags = up")
+				testing_internal.AssertConfigKey(t, string(content), "lxc.net.0.type", "veth")
+				testing_internal.AssertConfigKey(t, string(content), "lxc.net.0.link", "br0")
+				testing_internal.AssertConfigKey(t, string(content), "lxc.net.0.flags", "up")
 			},
 		},
 		{
@@ -424,8 +496,8 @@ func TestBasicNetworkConfiguration(t *testing.T) {
 			verify: func(t *testing.T, configPath string) {
 				content, err := os.ReadFile(configPath)
 				testing_internal.AssertNoError(t, err)
-				testing_internal.AssertContains(t, string(content), "lxc.net.0.ipv4.address = 192.168.1.100/24")
-				testing_internal.AssertContains(t, string(content), "lxc.net.0.ipv4.gateway = 192.168.1.1")
+				testing_internal.AssertConfigKey(t, string(content), "lxc.net.0.ipv4.address", "192.168.1.100/24")
+				testing_internal.AssertConfigKey(t, string(content), "lxc.net.0.ipv4.gateway", "192.168.1.1")
 			},
 		},
 		{
@@ -479,3 +551,341 @@ func TestBasicNetworkConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func TestGeneratedMAC(t *testing.T) {
+	createWithNetwork := func(t *testing.T, name string, network *common.NetworkConfig) string {
+		dir := t.TempDir()
+		_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+		defer cleanup()
+
+		manager, err := container.NewLXCManager(dir)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.Create(name, &common.Container{Network: network})
+		testing_internal.AssertNoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(dir, name, "network.conf"))
+		testing_internal.AssertNoError(t, err)
+		return string(data)
+	}
+
+	hwaddr := func(t *testing.T, content, key string) string {
+		for _, line := range strings.Split(content, "\n") {
+			if value, ok := strings.CutPrefix(line, key+" = "); ok {
+				return value
+			}
+		}
+		t.Fatalf("%s not found in %q", key, content)
+		return ""
+	}
+
+	t.Run("stable_across_recreation", func(t *testing.T) {
+		network := func() *common.NetworkConfig {
+			return &common.NetworkConfig{Type: "veth", Bridge: "br0"}
+		}
+		first := hwaddr(t, createWithNetwork(t, "web", network()), "lxc.net.0.hwaddr")
+		second := hwaddr(t, createWithNetwork(t, "web", network()), "lxc.net.0.hwaddr")
+		other := hwaddr(t, createWithNetwork(t, "db", network()), "lxc.net.0.hwaddr")
+
+		testing_internal.AssertNoError(t, validation.ValidateMAC(first))
+		testing_internal.AssertEqual(t, first, second)
+		if first == other {
+			t.Errorf("expected different MACs for different containers, got %s", first)
+		}
+
+		// Locally administered, unicast
+		octet, err := strconv.ParseUint(first[:2], 16, 8)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, uint64(0x02), octet&0x03)
+	})
+
+	t.Run("distinct_per_interface", func(t *testing.T) {
+		content := createWithNetwork(t, "web", &common.NetworkConfig{
+			Interfaces: []common.NetworkInterface{
+				{Type: "veth", Bridge: "br0"},
+				{Type: "veth", Bridge: "br1"},
+			},
+		})
+		if hwaddr(t, content, "lxc.net.0.hwaddr") == hwaddr(t, content, "lxc.net.1.hwaddr") {
+			t.Error("expected different MACs per interface")
+		}
+	})
+
+	t.Run("explicit_mac_kept", func(t *testing.T) {
+		content := createWithNetwork(t, "web", &common.NetworkConfig{
+			Type: "veth", Bridge: "br0", MAC: "00:11:22:33:44:55",
+		})
+		testing_internal.AssertEqual(t, "00:11:22:33:44:55", hwaddr(t, content, "lxc.net.0.hwaddr"))
+	})
+
+	t.Run("opt_out", func(t *testing.T) {
+		content := createWithNetwork(t, "web", &common.NetworkConfig{
+			Type: "veth", Bridge: "br0", DisableAutoMAC: true,
+		})
+		testing_internal.AssertConfigKeyAbsent(t, content, "lxc.net.0.hwaddr")
+	})
+}
+
+func TestReconfigureNetwork(t *testing.T) {
+	dir := t.TempDir()
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	// Record commands run inside the container
+	attached, restore := mock.RecordCalls(&container.ExecCommand, "lxc-attach")
+	defer restore()
+
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+
+	// Each subtest reconfigures its own container
+	states := map[string]string{
+		"live":    "RUNNING",
+		"restart": "RUNNING",
+		"stopped": "STOPPED",
+		"invalid": "STOPPED",
+		"failing": "STOPPED",
+	}
+	for name, state := range states {
+		err := manager.Create(name, &common.Container{
+			Network: &common.NetworkConfig{
+				Type:    "veth",
+				Bridge:  "br0",
+				IP:      "192.168.1.100/24",
+				Gateway: "192.168.1.1",
+			},
+			Ports: []common.PortForward{{Protocol: "tcp", Host: 8080, Guest: 80}},
+		})
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertNoError(t, mockCmd.AddContainer(name, "STOPPED"))
+		if state == "RUNNING" {
+			testing_internal.AssertNoError(t, manager.Start(name))
+		}
+	}
+
+	t.Run("running_applies_live", func(t *testing.T) {
+		attached.Reset()
+		err := manager.ReconfigureNetwork("live", &common.NetworkConfig{
+			Type:    "veth",
+			Bridge:  "br0",
+			IP:      "192.168.1.50/24",
+			Gateway: "192.168.1.254",
+			MTU:     1400,
+		})
+		testing_internal.AssertNoError(t, err)
+
+		commands := strings.Join(attached.Args(), "\n")
+		testing_internal.AssertContains(t, commands, "-n live -- ip addr flush dev eth0")
+		testing_internal.AssertContains(t, commands, "ip addr add 192.168.1.50/24 dev eth0")
+		testing_internal.AssertContains(t, commands, "ip route replace default via 192.168.1.254 dev eth0")
+		testing_internal.AssertContains(t, commands, "ip link set dev eth0 mtu 1400")
+
+		data, err := os.ReadFile(filepath.Join(dir, "live", "network.conf"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertConfigKey(t, string(data), "lxc.net.0.ipv4.address", "192.168.1.50/24")
+		// Service-level ports are kept and follow the new address
+		testing_internal.AssertContains(t, string(data), "--dport 8080 -j DNAT --to 192.168.1.50:80")
+
+		c, err := manager.Get("live")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "192.168.1.50/24", c.Config.Network.IP)
+		testing_internal.AssertEqual(t, "RUNNING", c.State)
+	})
+
+	t.Run("restart_only_changes", func(t *testing.T) {
+		attached.Reset()
+		err := manager.ReconfigureNetwork("restart", &common.NetworkConfig{
+			Type:    "macvlan",
+			Bridge:  "eth1",
+			IP:      "192.168.1.50/24",
+			Gateway: "192.168.1.1",
+		})
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(attached.Calls()))
+	})
+
+	t.Run("stopped_only_rewrites_config", func(t *testing.T) {
+		attached.Reset()
+		err := manager.ReconfigureNetwork("stopped", &common.NetworkConfig{
+			Type:   "veth",
+			Bridge: "br0",
+			IP:     "192.168.1.50/24",
+		})
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(attached.Calls()))
+	})
+
+	t.Run("invalid_config", func(t *testing.T) {
+		err := manager.ReconfigureNetwork("invalid", &common.NetworkConfig{Type: "veth", IP: "not-an-ip"})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "invalid network configuration")
+	})
+
+	t.Run("failed_write_keeps_config", func(t *testing.T) {
+		networkPath := filepath.Join(dir, "failing", "network.conf")
+		before, err := os.ReadFile(networkPath)
+		testing_internal.AssertNoError(t, err)
+
+		// The service's ports need a static address, so generation fails partway
+		err = manager.ReconfigureNetwork("failing", &common.NetworkConfig{Type: "veth", Bridge: "br0", DHCP: true})
+		testing_internal.AssertError(t, err)
+
+		after, err := os.ReadFile(networkPath)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, string(before), string(after))
+
+		leftovers, err := filepath.Glob(filepath.Join(dir, "failing", ".network.conf.tmp-*"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(leftovers))
+	})
+}
+
+func TestGetNetworkConfigPortForwards(t *testing.T) {
+	containerName := "test-container"
+	dir := t.TempDir()
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+
+	err = manager.Create(containerName, &common.Container{
+		Network: &common.NetworkConfig{
+			Type:   "veth",
+			Bridge: "br0",
+			IP:     "192.168.1.100/24",
+			PortForwards: []common.PortForward{
+				{Protocol: "tcp", Host: 8080, Guest: 80},
+			},
+		},
+		Ports: []common.PortForward{{Protocol: "udp", Host: 5353, Guest: 53}},
+	})
+	testing_internal.AssertNoError(t, err)
+
+	cfg, err := manager.GetNetworkConfig(containerName)
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertNotNil(t, cfg)
+
+	testing_internal.AssertEqual(t, 2, len(cfg.PortForwards))
+	testing_internal.AssertEqual(t, config.PortForward{Protocol: "tcp", Host: 8080, Guest: 80}, cfg.PortForwards[0])
+	testing_internal.AssertEqual(t, config.PortForward{Protocol: "udp", Host: 5353, Guest: 53}, cfg.PortForwards[1])
+
+	testing_internal.AssertEqual(t, 1, len(cfg.Interfaces))
+	testing_internal.AssertEqual(t, "192.168.1.100/24", cfg.Interfaces[0].IP)
+	testing_internal.AssertEqual(t, "br0", cfg.Interfaces[0].Bridge)
+}
+
+func TestGetNetworkConfigRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+
+	interfaces := []common.NetworkInterface{
+		{
+			Type:      "bridge",
+			Bridge:    "br0",
+			Interface: "eth0",
+			IP:        "192.168.1.100/24",
+			Gateway:   "192.168.1.1",
+			DNS:       []string{"8.8.8.8", "1.1.1.1"},
+			Hostname:  "web",
+			MTU:       1400,
+			MAC:       "02:00:00:00:00:01",
+		},
+		{
+			Type:      "veth",
+			Bridge:    "br1",
+			Interface: "eth1",
+			DHCP:      true,
+			MAC:       "02:00:00:00:00:02",
+		},
+	}
+	err = manager.Create("web", &common.Container{
+		Network: &common.NetworkConfig{Interfaces: interfaces},
+	})
+	testing_internal.AssertNoError(t, err)
+
+	cfg, err := manager.GetNetworkConfig("web")
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertNotNil(t, cfg)
+	testing_internal.AssertEqual(t, len(interfaces), len(cfg.Interfaces))
+	for i, want := range interfaces {
+		got := cfg.Interfaces[i]
+		testing_internal.AssertEqual(t, want.Type, got.Type)
+		testing_internal.AssertEqual(t, want.Bridge, got.Bridge)
+		testing_internal.AssertEqual(t, want.Interface, got.Interface)
+		testing_internal.AssertEqual(t, want.IP, got.IP)
+		testing_internal.AssertEqual(t, want.Gateway, got.Gateway)
+		testing_internal.AssertEqual(t, want.DHCP, got.DHCP)
+		testing_internal.AssertEqual(t, strings.Join(want.DNS, ","), strings.Join(got.DNS, ","))
+		testing_internal.AssertEqual(t, want.Hostname, got.Hostname)
+		testing_internal.AssertEqual(t, want.MTU, got.MTU)
+		testing_internal.AssertEqual(t, want.MAC, got.MAC)
+	}
+}
+
+func TestNetworkMode(t *testing.T) {
+	dir := t.TempDir()
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+	testing_internal.AssertNoError(t, manager.Create("proxy", &common.Container{
+		Network: &common.NetworkConfig{Type: "veth", Bridge: "lxcbr0", DHCP: true},
+	}))
+	testing_internal.AssertNoError(t, mockCmd.AddContainer("proxy", "STOPPED"))
+
+	t.Run("joins_target_namespace", func(t *testing.T) {
+		cfg := &common.Container{NetworkMode: "container:proxy"}
+		testing_internal.AssertNoError(t, manager.Create("app", cfg))
+		testing_internal.AssertNoError(t, manager.Update("app", cfg))
+
+		data, err := os.ReadFile(filepath.Join(dir, "app", "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "lxc.net.0.type = none\nlxc.namespace.share.net = proxy\n")
+	})
+
+	t.Run("target_must_exist", func(t *testing.T) {
+		calls, restore := mock.RecordCalls(&container.ExecCommand, "lxc-info")
+		defer restore()
+		calls.Stub("lxc-info", func(args []string) *exec.Cmd {
+			if strings.Contains(strings.Join(args, " "), "missing") {
+				return exec.Command("/bin/false")
+			}
+			return nil
+		})
+
+		err := manager.Create("orphan", &common.Container{NetworkMode: "container:missing"})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "does not exist")
+
+		err = manager.Create("self", &common.Container{NetworkMode: "container:self"})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "its own network")
+	})
+
+	t.Run("target_must_run", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Create("waiting", &common.Container{NetworkMode: "container:proxy"}))
+		testing_internal.AssertNoError(t, mockCmd.AddContainer("waiting", "STOPPED"))
+
+		err := manager.Start("waiting")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "which is not running")
+
+		testing_internal.AssertNoError(t, manager.Start("proxy"))
+		testing_internal.AssertNoError(t, manager.Start("waiting"))
+	})
+
+	t.Run("rejects_network", func(t *testing.T) {
+		err := manager.Create("combined", &common.Container{
+			NetworkMode: "container:proxy",
+			Network:     &common.NetworkConfig{Type: "veth", DHCP: true},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "cannot be combined")
+	})
+}
@@ -0,0 +1,51 @@
+package container
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// requiredLXCBinaries are the LXC tools every lifecycle command relies on
+var requiredLXCBinaries = []string{"lxc-start", "lxc-stop", "lxc-info", "lxc-destroy"}
+
+var (
+	lxcCheckOnce sync.Once
+	lxcCheckErr  error
+)
+
+// lxcCommand is the default ExecCommand. It behaves like exec.Command, but
+// the first lxc-* command checks that LXC is installed, so a host without it
+// gets an actionable error instead of an opaque exec failure. Tests that mock
+// ExecCommand never run the check.
+func lxcCommand(name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(name, args...)
+	if strings.HasPrefix(name, "lxc-") {
+		lxcCheckOnce.Do(func() { lxcCheckErr = checkLXCInstalled() })
+		if lxcCheckErr != nil {
+			cmd.Err = lxcCheckErr
+		}
+	}
+	return cmd
+}
+
+// checkLXCInstalled verifies the required LXC binaries are on PATH
+func checkLXCInstalled() error {
+	if missing := missingLXCBinaries(); len(missing) > 0 {
+		return fmt.Errorf("LXC does not appear to be installed (missing %s on PATH); install it with 'apt install lxc'",
+			strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// missingLXCBinaries returns the required LXC binaries not found on PATH
+func missingLXCBinaries() []string {
+	var missing []string
+	for _, bin := range requiredLXCBinaries {
+		if _, err := exec.LookPath(bin); err != nil {
+			missing = append(missing, bin)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,17 @@
+package container_test
+
+import (
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestLXCNotInstalled(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	err := container.ExecCommand("lxc-info", "-n", "test").Run()
+	testing_internal.AssertError(t, err)
+	testing_internal.AssertContains(t, err.Error(), "lxc-start, lxc-stop, lxc-info, lxc-destroy")
+	testing_internal.AssertContains(t, err.Error(), "apt install lxc")
+}
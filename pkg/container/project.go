@@ -0,0 +1,69 @@
+package container
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+)
+
+// LabelProject records the compose project a container was created for
+const LabelProject = "lxc-compose.project"
+
+// ProjectName derives a project name from the directory holding the compose
+// file, so containers from different checkouts don't mix
+func ProjectName(composeFile string) string {
+	dir := "."
+	if composeFile != "" {
+		dir = filepath.Dir(composeFile)
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return strings.ToLower(filepath.Base(dir))
+}
+
+// WithProjectLabels returns a copy of cfg labelled as service of project
+func WithProjectLabels(cfg common.Container, project, service string) common.Container {
+	labels := make(map[string]string, len(cfg.Labels)+2)
+	for k, v := range cfg.Labels {
+		labels[k] = v
+	}
+	labels[LabelProject] = project
+	labels[LabelService] = service
+	cfg.Labels = labels
+	return cfg
+}
+
+// ProjectOf returns the project a container was created for, or "" if it
+// wasn't created from a compose file
+func ProjectOf(c Container) string {
+	if c.Config == nil {
+		return ""
+	}
+	return c.Config.Labels[LabelProject]
+}
+
+// Orphans returns the containers of project whose service is no longer
+// defined in services, including replicas of removed services
+func (m *LXCManager) Orphans(project string, services map[string]common.Container) ([]Container, error) {
+	containers, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []Container
+	for _, c := range containers {
+		if ProjectOf(c) != project {
+			continue
+		}
+		service := ServiceOf(c)
+		if service == "" {
+			service = c.Name
+		}
+		if _, ok := services[service]; !ok {
+			orphans = append(orphans, c)
+		}
+	}
+	return orphans, nil
+}
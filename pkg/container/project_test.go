@@ -0,0 +1,47 @@
+package container_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestProjectName(t *testing.T) {
+	testing_internal.AssertEqual(t, "myapp", container.ProjectName(filepath.Join("/srv", "MyApp", "lxc-compose.yml")))
+}
+
+func TestOrphans(t *testing.T) {
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+
+	create := func(name string, cfg common.Container) {
+		t.Helper()
+		testing_internal.AssertNoError(t, manager.Create(name, &cfg))
+	}
+	image := common.Container{Image: "ubuntu:20.04"}
+	create("web", container.WithProjectLabels(image, "shop", "web"))
+	create("cache", container.WithProjectLabels(image, "shop", "cache"))
+	create("worker_1", container.WithProjectLabels(common.Container{
+		Image:  "ubuntu:20.04",
+		Labels: map[string]string{container.LabelReplica: "1"},
+	}, "shop", "worker"))
+	create("cache-other", container.WithProjectLabels(image, "blog", "cache"))
+	create("manual", image)
+
+	orphans, err := manager.Orphans("shop", map[string]common.Container{"web": image})
+	testing_internal.AssertNoError(t, err)
+
+	var names []string
+	for _, c := range orphans {
+		names = append(names, c.Name)
+	}
+	testing_internal.AssertEqual(t, "cache,worker_1", strings.Join(names, ","))
+}
@@ -0,0 +1,93 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
+
+// writeResolvConf injects the configured DNS servers and options into the
+// container's /etc/resolv.conf. Configured servers replace the image's
+// nameserver lines and configured options replace its options lines, anything
+// else in the file is kept. Like writeHostname, it does nothing until the
+// image has been extracted into the rootfs.
+func (m *LXCManager) writeResolvConf(name string, cfg *common.NetworkConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	servers := dnsServers(cfg)
+	if len(servers) == 0 && len(cfg.DNSOptions) == 0 {
+		return nil
+	}
+
+	rootfs := filepath.Join(m.configPath, name, "rootfs")
+	if info, err := os.Stat(filepath.Join(rootfs, "etc")); err != nil || !info.IsDir() {
+		logging.Debug("Skipping resolv.conf, rootfs not extracted", "container", name)
+		return nil
+	}
+
+	// Only /etc is resolved: images often ship resolv.conf as a symlink to a
+	// resolver's runtime file, which is replaced rather than followed
+	path, err := layerTarget(rootfs, "/etc/resolv.conf")
+	if err != nil {
+		return err
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to replace /etc/resolv.conf: %w", err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read /etc/resolv.conf: %w", err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case line == "":
+			continue
+		case len(fields) > 0 && fields[0] == "nameserver" && len(servers) > 0:
+			continue
+		case len(fields) > 0 && fields[0] == "options" && len(cfg.DNSOptions) > 0:
+			continue
+		}
+		lines = append(lines, line)
+	}
+	for _, server := range servers {
+		lines = append(lines, "nameserver "+server)
+	}
+	if len(cfg.DNSOptions) > 0 {
+		lines = append(lines, "options "+strings.Join(cfg.DNSOptions, " "))
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write /etc/resolv.conf: %w", err)
+	}
+	return nil
+}
+
+// dnsServers returns the DNS servers of a network config, top-level first,
+// without duplicates
+func dnsServers(cfg *common.NetworkConfig) []string {
+	seen := make(map[string]bool)
+	var servers []string
+	add := func(list []string) {
+		for _, server := range list {
+			if !seen[server] {
+				seen[server] = true
+				servers = append(servers, server)
+			}
+		}
+	}
+	add(cfg.DNS)
+	for _, iface := range cfg.Interfaces {
+		add(iface.DNS)
+	}
+	return servers
+}
@@ -0,0 +1,94 @@
+package container_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestCreateWritesResolvConf(t *testing.T) {
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	dir := t.TempDir()
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+
+	etc := func(t *testing.T, name string) string {
+		t.Helper()
+		path := filepath.Join(dir, name, "rootfs", "etc")
+		testing_internal.AssertNoError(t, os.MkdirAll(path, 0755))
+		return path
+	}
+	read := func(t *testing.T, path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		testing_internal.AssertNoError(t, err)
+		return string(data)
+	}
+
+	t.Run("servers_and_options", func(t *testing.T) {
+		path := filepath.Join(etc(t, "web"), "resolv.conf")
+		testing_internal.AssertNoError(t, os.WriteFile(path, []byte("# image default\nnameserver 1.1.1.1\nsearch example.com\noptions ndots:1\n"), 0644))
+
+		testing_internal.AssertNoError(t, manager.Create("web", &common.Container{
+			Network: &common.NetworkConfig{
+				DNSOptions: []string{"ndots:2", "timeout:1"},
+				Interfaces: []common.NetworkInterface{
+					{Type: "veth", Bridge: "lxcbr0", DHCP: true, DNS: []string{"10.0.0.1", "10.0.0.2"}},
+					{Type: "veth", Bridge: "lxcbr1", DHCP: true, DNS: []string{"10.0.0.2"}},
+				},
+			},
+		}))
+
+		testing_internal.AssertEqual(t,
+			"# image default\nsearch example.com\nnameserver 10.0.0.1\nnameserver 10.0.0.2\noptions ndots:2 timeout:1\n",
+			read(t, path))
+	})
+
+	t.Run("options_only_keep_servers", func(t *testing.T) {
+		path := filepath.Join(etc(t, "db"), "resolv.conf")
+		testing_internal.AssertNoError(t, os.WriteFile(path, []byte("nameserver 1.1.1.1\n"), 0644))
+
+		testing_internal.AssertNoError(t, manager.Create("db", &common.Container{
+			Network: &common.NetworkConfig{
+				DNSOptions: []string{"rotate"},
+				Interfaces: []common.NetworkInterface{{Type: "veth", Bridge: "lxcbr0", DHCP: true}},
+			},
+		}))
+
+		testing_internal.AssertEqual(t, "nameserver 1.1.1.1\noptions rotate\n", read(t, path))
+	})
+
+	t.Run("symlink_replaced", func(t *testing.T) {
+		dirEtc := etc(t, "cache")
+		outside := filepath.Join(t.TempDir(), "stub-resolv.conf")
+		testing_internal.AssertNoError(t, os.WriteFile(outside, []byte("nameserver 127.0.0.53\n"), 0644))
+		testing_internal.AssertNoError(t, os.Symlink(outside, filepath.Join(dirEtc, "resolv.conf")))
+
+		testing_internal.AssertNoError(t, manager.Create("cache", &common.Container{
+			Network: &common.NetworkConfig{
+				Interfaces: []common.NetworkInterface{{Type: "veth", Bridge: "lxcbr0", DHCP: true, DNS: []string{"10.0.0.1"}}},
+			},
+		}))
+
+		testing_internal.AssertEqual(t, "nameserver 10.0.0.1\n", read(t, filepath.Join(dirEtc, "resolv.conf")))
+		testing_internal.AssertEqual(t, "nameserver 127.0.0.53\n", read(t, outside))
+	})
+
+	t.Run("invalid_option", func(t *testing.T) {
+		err := manager.Create("bad", &common.Container{
+			Network: &common.NetworkConfig{
+				DNSOptions: []string{"ndots:99"},
+				Interfaces: []common.NetworkInterface{{Type: "veth", Bridge: "lxcbr0", DHCP: true}},
+			},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "invalid DNS option")
+	})
+}
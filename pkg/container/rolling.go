@@ -0,0 +1,122 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// RollingRestartOptions controls how RollingRestart works through replicas
+type RollingRestartOptions struct {
+	// Parallelism is the number of replicas restarted at once, defaults to 1
+	Parallelism int
+	// MaxUnavailable caps the replicas that may be down or not yet healthy
+	// at once. Replicas that weren't running or healthy to begin with are
+	// restarted first and count once, while they are still down.
+	// Defaults to Parallelism.
+	MaxUnavailable int
+	// Progress, if set, is called as each replica moves through the restart
+	Progress func(replica, status string)
+}
+
+// Replica progress reported through RollingRestartOptions.Progress
+const (
+	RollingRestarting = "restarting"
+	RollingWaiting    = "waiting for health check"
+	RollingReady      = "ready"
+	RollingFailed     = "failed"
+)
+
+// RollingRestart restarts the replicas of a scaled service in batches,
+// waiting for every replica in a batch to pass its health check, or just to
+// be running if it has none, before moving on. It stops at the first replica
+// that fails, leaving the remaining replicas untouched.
+func (m *LXCManager) RollingRestart(ctx context.Context, service string, opts RollingRestartOptions) error {
+	if opts.Parallelism < 0 || opts.MaxUnavailable < 0 {
+		return fmt.Errorf("parallelism and max unavailable must not be negative")
+	}
+	if opts.Parallelism == 0 {
+		opts.Parallelism = 1
+	}
+	if opts.MaxUnavailable == 0 {
+		opts.MaxUnavailable = opts.Parallelism
+	}
+	if opts.Progress == nil {
+		opts.Progress = func(string, string) {}
+	}
+
+	replicas, err := m.Replicas(service)
+	if err != nil {
+		return fmt.Errorf("failed to list replicas: %w", err)
+	}
+	if len(replicas) == 0 {
+		return fmt.Errorf("service '%s' has no replicas", service)
+	}
+
+	// Replicas already down go first, restarting them takes nothing else down
+	var down, up []Container
+	for _, c := range replicas {
+		if c.State != "RUNNING" || c.Health == HealthUnhealthy {
+			down = append(down, c)
+		} else {
+			up = append(up, c)
+		}
+	}
+	unavailable := len(down)
+	replicas = append(down, up...)
+
+	for start := 0; start < len(replicas); {
+		// Replicas taken down in this batch add to the ones still down
+		end := start
+		for end < len(replicas) && end-start < opts.Parallelism {
+			if end >= len(down) && unavailable+end-max(start, len(down)) >= opts.MaxUnavailable {
+				break
+			}
+			end++
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, end-start)
+		for i, c := range replicas[start:end] {
+			wg.Add(1)
+			go func(i int, c Container) {
+				defer wg.Done()
+				errs[i] = m.restartReplica(ctx, c, opts.Progress)
+			}(i, c)
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+
+		// Restarted replicas that were down are ready now
+		if start < len(down) {
+			unavailable -= min(end, len(down)) - start
+		}
+		start = end
+	}
+	return nil
+}
+
+// restartReplica restarts one replica and waits for it to become ready
+func (m *LXCManager) restartReplica(ctx context.Context, c Container, progress func(string, string)) error {
+	progress(c.Name, RollingRestarting)
+	if err := m.Restart(c.Name); err != nil {
+		progress(c.Name, RollingFailed)
+		return fmt.Errorf("failed to restart replica '%s': %w", c.Name, err)
+	}
+
+	if c.Config != nil && c.Config.HealthCheck != nil {
+		progress(c.Name, RollingWaiting)
+		if err := m.WaitHealthy(ctx, c.Name); err != nil {
+			progress(c.Name, RollingFailed)
+			return fmt.Errorf("replica '%s' did not become healthy: %w", c.Name, err)
+		}
+	}
+
+	progress(c.Name, RollingReady)
+	return nil
+}
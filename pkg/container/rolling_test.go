@@ -0,0 +1,121 @@
+package container_test
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestRollingRestart(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+
+	for i := 1; i <= 4; i++ {
+		name := container.ReplicaName("web", i)
+		err := manager.Create(name, &common.Container{
+			Image: "ubuntu:20.04",
+			Labels: map[string]string{
+				container.LabelService: "web",
+				container.LabelReplica: strconv.Itoa(i),
+			},
+			HealthCheck: &common.HealthCheck{Command: []string{"check"}, Interval: "1ms", Retries: 1},
+		})
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertNoError(t, mockCmd.AddContainer(name, "STOPPED"))
+		testing_internal.AssertNoError(t, manager.Start(name))
+	}
+
+	// The health check of the failing replica always fails
+	failing := "web_3"
+	checks, restore := mock.RecordCalls(&container.ExecCommand, "lxc-attach")
+	defer restore()
+	checks.Stub("lxc-attach", func(args []string) *exec.Cmd {
+		if args[1] == failing {
+			return exec.Command("false")
+		}
+		return exec.Command("true")
+	})
+
+	var mu sync.Mutex
+	var events []string
+	progress := func(replica, status string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, replica+" "+status)
+	}
+	indexOf := func(event string) int {
+		for i, e := range events {
+			if e == event {
+				return i
+			}
+		}
+		return -1
+	}
+
+	t.Run("stops_at_failed_batch", func(t *testing.T) {
+		err := manager.RollingRestart(context.Background(), "web", container.RollingRestartOptions{
+			Parallelism: 2,
+			Progress:    progress,
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "web_3")
+
+		for _, name := range []string{"web_1", "web_2"} {
+			if indexOf(name+" "+container.RollingReady) > indexOf("web_3 "+container.RollingRestarting) {
+				t.Errorf("%s wasn't ready before the next batch started: %s", name, strings.Join(events, ", "))
+			}
+		}
+		testing_internal.AssertEqual(t, true, indexOf("web_3 "+container.RollingFailed) >= 0)
+		// web_4 shares the failed batch, so it still restarts
+		testing_internal.AssertEqual(t, true, indexOf("web_4 "+container.RollingReady) >= 0)
+	})
+
+	t.Run("restarts_unavailable_first", func(t *testing.T) {
+		// web_3 is now unhealthy, so it is restarted on its own first, and
+		// its failure leaves the healthy replicas untouched
+		events = nil
+		err := manager.RollingRestart(context.Background(), "web", container.RollingRestartOptions{
+			Parallelism:    2,
+			MaxUnavailable: 1,
+			Progress:       progress,
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "web_3")
+		testing_internal.AssertEqual(t, "web_3 restarting, web_3 waiting for health check, web_3 failed", strings.Join(events, ", "))
+	})
+
+	t.Run("counts_unavailable_once", func(t *testing.T) {
+		// web_3 recovers once restarted, then room opens up for the others
+		failing = ""
+		events = nil
+		err := manager.RollingRestart(context.Background(), "web", container.RollingRestartOptions{
+			Parallelism:    2,
+			MaxUnavailable: 1,
+			Progress:       progress,
+		})
+		testing_internal.AssertNoError(t, err)
+		order := []string{"web_3", "web_1", "web_2", "web_4"}
+		for i := 1; i < len(order); i++ {
+			if indexOf(order[i-1]+" "+container.RollingReady) > indexOf(order[i]+" "+container.RollingRestarting) {
+				t.Errorf("%s restarted before %s was ready: %s", order[i], order[i-1], strings.Join(events, ", "))
+			}
+		}
+	})
+
+	t.Run("unknown_service", func(t *testing.T) {
+		err := manager.RollingRestart(context.Background(), "api", container.RollingRestartOptions{})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "no replicas")
+	})
+}
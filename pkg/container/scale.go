@@ -0,0 +1,186 @@
+package container
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
+
+// Labels recording which service a replica belongs to
+const (
+	LabelService = "lxc-compose.service"
+	LabelReplica = "lxc-compose.replica"
+)
+
+// ReplicaName returns the container name of a service replica, numbered from 1
+func ReplicaName(service string, index int) string {
+	return fmt.Sprintf("%s_%d", service, index)
+}
+
+// ReplicaIndex returns the replica number recorded in a container's labels,
+// or 0 if it isn't a service replica
+func ReplicaIndex(c Container) int {
+	if c.Config == nil {
+		return 0
+	}
+	index, err := strconv.Atoi(c.Config.Labels[LabelReplica])
+	if err != nil {
+		return 0
+	}
+	return index
+}
+
+// ServiceOf returns the service a container was created for, or "" if it
+// isn't a service replica
+func ServiceOf(c Container) string {
+	if c.Config == nil {
+		return ""
+	}
+	return c.Config.Labels[LabelService]
+}
+
+// Replicas returns the replicas of a service, ordered by replica number
+func (m *LXCManager) Replicas(service string) ([]Container, error) {
+	containers, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var replicas []Container
+	for _, c := range containers {
+		if ServiceOf(c) == service && ReplicaIndex(c) > 0 {
+			replicas = append(replicas, c)
+		}
+	}
+	sort.Slice(replicas, func(i, j int) bool {
+		return ReplicaIndex(replicas[i]) < ReplicaIndex(replicas[j])
+	})
+	return replicas, nil
+}
+
+// Scale runs exactly replicas containers for a service, named service_1 to
+// service_N. Missing replicas are created from cfg and started, stopped ones
+// are started, and surplus replicas are stopped and removed, highest first.
+func (m *LXCManager) Scale(service string, cfg *common.Container, replicas int) error {
+	if replicas < 0 {
+		return fmt.Errorf("invalid replica count %d for service '%s'", replicas, service)
+	}
+	if replicas > 1 {
+		if err := checkScalable(cfg); err != nil {
+			return fmt.Errorf("cannot scale service '%s' to %d replicas: %w", service, replicas, err)
+		}
+	}
+
+	existing, err := m.Replicas(service)
+	if err != nil {
+		return fmt.Errorf("failed to list replicas: %w", err)
+	}
+	current := make(map[int]Container, len(existing))
+	for _, c := range existing {
+		current[ReplicaIndex(c)] = c
+	}
+
+	for i := 1; i <= replicas; i++ {
+		name := ReplicaName(service, i)
+		c, ok := current[i]
+		if !ok {
+			logging.Info("Creating replica", "service", service, "container", name)
+			if err := m.Create(name, replicaConfig(service, i, cfg)); err != nil {
+				return fmt.Errorf("failed to create replica '%s': %w", name, err)
+			}
+		} else if c.State != "STOPPED" {
+			continue
+		}
+		if err := m.Start(name); err != nil {
+			return fmt.Errorf("failed to start replica '%s': %w", name, err)
+		}
+	}
+
+	for i := len(existing) - 1; i >= 0; i-- {
+		c := existing[i]
+		if ReplicaIndex(c) <= replicas {
+			continue
+		}
+		logging.Info("Removing replica", "service", service, "container", c.Name)
+		if c.State != "STOPPED" {
+			if err := m.Stop(c.Name); err != nil {
+				return fmt.Errorf("failed to stop replica '%s': %w", c.Name, err)
+			}
+		}
+		if err := m.Remove(c.Name); err != nil {
+			return fmt.Errorf("failed to remove replica '%s': %w", c.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// checkScalable rejects settings that can't be shared by several replicas
+func checkScalable(cfg *common.Container) error {
+	if len(cfg.Ports) > 0 {
+		return fmt.Errorf("host ports can only be bound by one replica")
+	}
+	if cfg.Network == nil {
+		return nil
+	}
+	if len(cfg.Network.PortForwards) > 0 {
+		return fmt.Errorf("host ports can only be bound by one replica")
+	}
+	if cfg.Network.IP != "" && !cfg.Network.DHCP {
+		return fmt.Errorf("static IP %s can only be used by one replica", cfg.Network.IP)
+	}
+	for _, iface := range cfg.Network.Interfaces {
+		if iface.IP != "" && !iface.DHCP {
+			return fmt.Errorf("static IP %s can only be used by one replica", iface.IP)
+		}
+	}
+	return nil
+}
+
+// replicaConfig clones a service config for one replica, labelling it and
+// giving it its own hostname. Explicit MACs are dropped so each replica gets
+// a stable address derived from its own name.
+func replicaConfig(service string, index int, cfg *common.Container) *common.Container {
+	replica := *cfg
+
+	replica.Labels = make(map[string]string, len(cfg.Labels)+2)
+	for k, v := range cfg.Labels {
+		replica.Labels[k] = v
+	}
+	replica.Labels[LabelService] = service
+	replica.Labels[LabelReplica] = strconv.Itoa(index)
+
+	if cfg.Network == nil {
+		return &replica
+	}
+
+	network := *cfg.Network
+	network.MAC = ""
+	network.Interfaces = make([]common.NetworkInterface, len(cfg.Network.Interfaces))
+	copy(network.Interfaces, cfg.Network.Interfaces)
+
+	suffix := "-" + strconv.Itoa(index)
+	named := false
+	if network.Hostname != "" {
+		network.Hostname += suffix
+		named = true
+	}
+	for i := range network.Interfaces {
+		network.Interfaces[i].MAC = ""
+		if network.Interfaces[i].Hostname != "" {
+			network.Interfaces[i].Hostname += suffix
+			named = true
+		}
+	}
+	if !named {
+		// Underscores aren't valid in hostnames
+		network.Hostname = strings.ReplaceAll(service, "_", "-") + suffix
+	}
+
+	replica.Network = &network
+	return &replica
+}
@@ -0,0 +1,84 @@
+package container_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestScale(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	manager, err := container.NewLXCManager(t.TempDir())
+	testing_internal.AssertNoError(t, err)
+
+	// Existing replicas are created directly since the mock only tracks
+	// containers registered after creation
+	for i := 1; i <= 3; i++ {
+		name := container.ReplicaName("web", i)
+		err := manager.Create(name, &common.Container{
+			Image: "ubuntu:20.04",
+			Labels: map[string]string{
+				container.LabelService: "web",
+				container.LabelReplica: strconv.Itoa(i),
+			},
+		})
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertNoError(t, mockCmd.AddContainer(name, "STOPPED"))
+	}
+	testing_internal.AssertNoError(t, manager.Create("db", &common.Container{Image: "postgres"}))
+
+	t.Run("lists_replicas_in_order", func(t *testing.T) {
+		replicas, err := manager.Replicas("web")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 3, len(replicas))
+		for i, c := range replicas {
+			testing_internal.AssertEqual(t, container.ReplicaName("web", i+1), c.Name)
+			testing_internal.AssertEqual(t, "web", container.ServiceOf(c))
+		}
+	})
+
+	t.Run("scale_down_starts_and_removes", func(t *testing.T) {
+		err := manager.Scale("web", &common.Container{Image: "ubuntu:20.04"}, 2)
+		testing_internal.AssertNoError(t, err)
+
+		replicas, err := manager.Replicas("web")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 2, len(replicas))
+		for _, c := range replicas {
+			testing_internal.AssertEqual(t, "RUNNING", c.State)
+		}
+		testing_internal.AssertEqual(t, false, manager.ContainerExists(container.ReplicaName("web", 3)))
+		testing_internal.AssertEqual(t, true, manager.ContainerExists("db"))
+	})
+
+	t.Run("rejects_shared_host_resources", func(t *testing.T) {
+		err := manager.Scale("web", &common.Container{
+			Image: "ubuntu:20.04",
+			Ports: []common.PortForward{{Protocol: "tcp", Host: 8080, Guest: 80}},
+		}, 3)
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "host ports")
+
+		err = manager.Scale("web", &common.Container{
+			Image:   "ubuntu:20.04",
+			Network: &common.NetworkConfig{Type: "veth", Bridge: "lxcbr0", IP: "10.0.3.10/24"},
+		}, 3)
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "static IP")
+	})
+
+	t.Run("scale_to_zero", func(t *testing.T) {
+		err := manager.Scale("web", &common.Container{Image: "ubuntu:20.04"}, 0)
+		testing_internal.AssertNoError(t, err)
+
+		replicas, err := manager.Replicas("web")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(replicas))
+	})
+}
@@ -0,0 +1,243 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
+)
+
+// snapshotBackend is implemented by storage backends that can snapshot a
+// container's rootfs and roll it back
+type snapshotBackend interface {
+	// Snapshot records the current rootfs of container name as snap
+	Snapshot(name, snap string) error
+	// Rollback replaces the rootfs of container name with snap
+	Rollback(name, snap string) error
+	// Snapshots lists the snapshots of container name, oldest first
+	Snapshots(name string) ([]string, error)
+}
+
+// SnapshotStorage snapshots a container's rootfs at the storage layer: a zfs
+// snapshot, a read-only btrfs subvolume, or a tarball for the dir backend. A
+// running container is frozen while the snapshot is taken.
+func (m *LXCManager) SnapshotStorage(name, snapName string) error {
+	if err := validation.ValidateSnapshotName(snapName); err != nil {
+		return err
+	}
+	container, backend, err := m.snapshotBackend(name)
+	if err != nil {
+		return err
+	}
+
+	err = m.whileFrozen(name, container.State, "snapshotting", func() error {
+		return backend.Snapshot(name, snapName)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to snapshot container '%s': %w", name, err)
+	}
+
+	logging.Info("Created storage snapshot", "name", name, "snapshot", snapName)
+	return nil
+}
+
+// RollbackStorage replaces a stopped container's rootfs with a snapshot taken
+// by SnapshotStorage. With zfs, snapshots newer than snapName are destroyed.
+func (m *LXCManager) RollbackStorage(name, snapName string) error {
+	if err := validation.ValidateSnapshotName(snapName); err != nil {
+		return err
+	}
+	container, backend, err := m.snapshotBackend(name)
+	if err != nil {
+		return err
+	}
+	if container.State != "STOPPED" {
+		return fmt.Errorf("container '%s' must be stopped before rolling back (current state: %s)", name, container.State)
+	}
+
+	if err := backend.Rollback(name, snapName); err != nil {
+		return fmt.Errorf("failed to roll back container '%s' to snapshot %s: %w", name, snapName, err)
+	}
+
+	logging.Info("Rolled back to storage snapshot", "name", name, "snapshot", snapName)
+	return nil
+}
+
+// ListStorageSnapshots lists the storage snapshots of a container, oldest first
+func (m *LXCManager) ListStorageSnapshots(name string) ([]string, error) {
+	_, backend, err := m.snapshotBackend(name)
+	if err != nil {
+		return nil, err
+	}
+	return backend.Snapshots(name)
+}
+
+// snapshotBackend returns a container and the storage backend its rootfs was
+// provisioned with, if that backend supports snapshots
+func (m *LXCManager) snapshotBackend(name string) (*Container, snapshotBackend, error) {
+	container, err := m.lookup(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get container: %w", err)
+	}
+
+	var storage *common.StorageConfig
+	if container.Config != nil {
+		storage = container.Config.Storage.ToCommonStorageConfig()
+	}
+	backend, err := newStorageBackend(m.configPath, storage)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid storage configuration: %w", err)
+	}
+
+	snapshots, ok := backend.(snapshotBackend)
+	if !ok {
+		return nil, nil, fmt.Errorf("storage backend %s does not support snapshots", container.Config.Storage.Backend)
+	}
+	return container, snapshots, nil
+}
+
+// snapshotDir is where the dir and btrfs backends keep a container's snapshots
+func snapshotDir(root, name string) string {
+	return filepath.Join(root, name, "snapshots")
+}
+
+// listSnapshotDir lists the entries of a container's snapshot directory,
+// oldest first, with suffix trimmed from their names
+func listSnapshotDir(root, name, suffix string) ([]string, error) {
+	entries, err := os.ReadDir(snapshotDir(root, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	type snapshot struct {
+		name    string
+		created int64
+	}
+	var snapshots []snapshot
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), suffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list snapshots: %w", err)
+		}
+		snapshots = append(snapshots, snapshot{strings.TrimSuffix(entry.Name(), suffix), info.ModTime().UnixNano()})
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].created < snapshots[j].created })
+
+	names := make([]string, len(snapshots))
+	for i, s := range snapshots {
+		names[i] = s.name
+	}
+	return names, nil
+}
+
+// snapshotPath is the tarball a dir snapshot is kept in, as the dir backend
+// has no copy-on-write to snapshot with
+func (b dirBackend) snapshotPath(name, snap string) string {
+	return filepath.Join(snapshotDir(b.root, name), snap+".tar")
+}
+
+func (b dirBackend) Snapshot(name, snap string) error {
+	path := b.snapshotPath(name, snap)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("snapshot %s already exists", snap)
+	}
+
+	data, err := archiveRootfs(rootfsPath(b.root, name))
+	if err != nil {
+		return fmt.Errorf("failed to archive rootfs: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func (b dirBackend) Rollback(name, snap string) error {
+	data, err := os.ReadFile(b.snapshotPath(name, snap))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("snapshot %s does not exist", snap)
+	}
+	if err != nil {
+		return err
+	}
+
+	rootfs := rootfsPath(b.root, name)
+	entries, err := os.ReadDir(rootfs)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(rootfs, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return extractLayer(data, rootfs)
+}
+
+func (b dirBackend) Snapshots(name string) ([]string, error) {
+	return listSnapshotDir(b.root, name, ".tar")
+}
+
+func (b zfsBackend) Snapshot(name, snap string) error {
+	return runStorageCommand("zfs", "snapshot", b.dataset(name)+"@"+snap)
+}
+
+func (b zfsBackend) Rollback(name, snap string) error {
+	return runStorageCommand("zfs", "rollback", "-r", b.dataset(name)+"@"+snap)
+}
+
+func (b zfsBackend) Snapshots(name string) ([]string, error) {
+	args := []string{"list", "-H", "-t", "snapshot", "-o", "name", "-s", "creation", "-d", "1", b.dataset(name)}
+	output, err := ExecCommand("zfs", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if _, snap, ok := strings.Cut(strings.TrimSpace(line), "@"); ok {
+			names = append(names, snap)
+		}
+	}
+	return names, nil
+}
+
+func (b btrfsBackend) Snapshot(name, snap string) error {
+	path := filepath.Join(snapshotDir(b.root, name), snap)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("snapshot %s already exists", snap)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return runStorageCommand("btrfs", "subvolume", "snapshot", "-r", rootfsPath(b.root, name), path)
+}
+
+func (b btrfsBackend) Rollback(name, snap string) error {
+	path := filepath.Join(snapshotDir(b.root, name), snap)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("snapshot %s does not exist", snap)
+	}
+
+	rootfs := rootfsPath(b.root, name)
+	if err := runStorageCommand("btrfs", "subvolume", "delete", rootfs); err != nil {
+		return err
+	}
+	// A writable snapshot of the read-only one becomes the new rootfs
+	return runStorageCommand("btrfs", "subvolume", "snapshot", path, rootfs)
+}
+
+func (b btrfsBackend) Snapshots(name string) ([]string, error) {
+	return listSnapshotDir(b.root, name, "")
+}
@@ -0,0 +1,151 @@
+package container_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestStorageSnapshots(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	dir := t.TempDir()
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+
+	lxcStates := map[string]string{}
+	calls, restore := mock.RecordCalls(&container.ExecCommand, "zfs")
+	defer restore()
+	calls.Stub("zfs", func(args []string) *exec.Cmd {
+		if args[0] == "list" && args[1] == "-H" && args[2] == "-t" {
+			return exec.Command("printf", "tank/db@first\ntank/db@second\n")
+		}
+		return exec.Command("true")
+	})
+	calls.Stub("lxc-info", func(args []string) *exec.Cmd {
+		if state, ok := lxcStates[args[1]]; ok {
+			return exec.Command("echo", "State: "+state)
+		}
+		return nil
+	})
+
+	create := func(t *testing.T, name string, storage *common.StorageConfig) {
+		t.Helper()
+		testing_internal.AssertNoError(t, manager.Create(name, &common.Container{Image: "ubuntu:20.04", Storage: storage}))
+		testing_internal.AssertNoError(t, mockCmd.AddContainer(name, "STOPPED"))
+	}
+
+	t.Run("dir", func(t *testing.T) {
+		create(t, "web", nil)
+		rootfs := filepath.Join(dir, "web", "rootfs")
+		testing_internal.AssertNoError(t, os.MkdirAll(filepath.Join(rootfs, "etc"), 0755))
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(rootfs, "etc", "app.conf"), []byte("v1"), 0644))
+		testing_internal.AssertNoError(t, os.Symlink("etc/app.conf", filepath.Join(rootfs, "conf")))
+
+		testing_internal.AssertNoError(t, manager.SnapshotStorage("web", "v1"))
+		err := manager.SnapshotStorage("web", "v1")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "already exists")
+
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(rootfs, "etc", "app.conf"), []byte("v2"), 0644))
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(rootfs, "new"), []byte("x"), 0644))
+		testing_internal.AssertNoError(t, manager.SnapshotStorage("web", "v2"))
+
+		snapshots, err := manager.ListStorageSnapshots("web")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "v1,v2", strings.Join(snapshots, ","))
+
+		testing_internal.AssertNoError(t, manager.RollbackStorage("web", "v1"))
+		data, err := os.ReadFile(filepath.Join(rootfs, "conf"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "v1", string(data))
+		if _, err := os.Stat(filepath.Join(rootfs, "new")); !os.IsNotExist(err) {
+			t.Errorf("expected file created after the snapshot to be gone, got %v", err)
+		}
+
+		err = manager.RollbackStorage("web", "missing")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "snapshot missing does not exist")
+	})
+
+	t.Run("dir_keeps_modes_and_owners", func(t *testing.T) {
+		create(t, "suid", nil)
+		rootfs := filepath.Join(dir, "suid", "rootfs")
+		passwd := filepath.Join(rootfs, "usr", "bin", "passwd")
+		tmp := filepath.Join(rootfs, "tmp")
+		testing_internal.AssertNoError(t, os.MkdirAll(filepath.Dir(passwd), 0755))
+		testing_internal.AssertNoError(t, os.WriteFile(passwd, []byte("suid"), 0755))
+		testing_internal.AssertNoError(t, os.Chmod(passwd, 0755|os.ModeSetuid))
+		testing_internal.AssertNoError(t, os.Mkdir(tmp, 0755))
+		testing_internal.AssertNoError(t, os.Chmod(tmp, 0777|os.ModeSticky))
+		root := os.Geteuid() == 0
+		if root {
+			testing_internal.AssertNoError(t, os.Lchown(passwd, 1000, 1000))
+			// chown clears the setuid bit
+			testing_internal.AssertNoError(t, os.Chmod(passwd, 0755|os.ModeSetuid))
+		}
+
+		testing_internal.AssertNoError(t, manager.SnapshotStorage("suid", "v1"))
+		testing_internal.AssertNoError(t, os.Chmod(passwd, 0644))
+		testing_internal.AssertNoError(t, os.Chmod(tmp, 0755))
+		testing_internal.AssertNoError(t, manager.RollbackStorage("suid", "v1"))
+
+		info, err := os.Stat(passwd)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0755|os.ModeSetuid, info.Mode())
+		info, err = os.Stat(tmp)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0777|os.ModeSticky|os.ModeDir, info.Mode())
+		if root {
+			info, err = os.Stat(passwd)
+			testing_internal.AssertNoError(t, err)
+			st := info.Sys().(*syscall.Stat_t)
+			testing_internal.AssertEqual(t, "1000:1000", fmt.Sprintf("%d:%d", st.Uid, st.Gid))
+		}
+	})
+
+	t.Run("running", func(t *testing.T) {
+		lxcStates["web"] = "RUNNING"
+		defer delete(lxcStates, "web")
+
+		err := manager.RollbackStorage("web", "v1")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "must be stopped")
+
+		// Snapshots of running containers are taken frozen
+		testing_internal.AssertNoError(t, manager.SnapshotStorage("web", "live"))
+	})
+
+	t.Run("zfs", func(t *testing.T) {
+		create(t, "db", &common.StorageConfig{Root: "1G", Backend: "zfs", Pool: "tank"})
+		calls.Reset()
+
+		testing_internal.AssertNoError(t, manager.SnapshotStorage("db", "first"))
+		testing_internal.AssertNoError(t, manager.RollbackStorage("db", "first"))
+		snapshots, err := manager.ListStorageSnapshots("db")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "first,second", strings.Join(snapshots, ","))
+
+		testing_internal.AssertEqual(t, strings.Join([]string{
+			"zfs snapshot tank/db@first",
+			"zfs rollback -r tank/db@first",
+			"zfs list -H -t snapshot -o name -s creation -d 1 tank/db",
+		}, "\n"), strings.Join(calls.Commands(), "\n"))
+	})
+
+	t.Run("invalid_name", func(t *testing.T) {
+		err := manager.SnapshotStorage("web", "../escape")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "invalid snapshot name")
+	})
+}
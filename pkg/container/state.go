@@ -8,28 +8,49 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/errors"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/recovery"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
 )
 
 // State represents the persistent state of a container
 type State struct {
+	SchemaVersion int               `json:"schema_version"`
 	Name          string            `json:"name"`
 	CreatedAt     time.Time         `json:"created_at"`
 	LastStartedAt *time.Time        `json:"last_started_at,omitempty"`
 	LastStoppedAt *time.Time        `json:"last_stopped_at,omitempty"`
+	LastFrozenAt  *time.Time        `json:"last_frozen_at,omitempty"`
+	LastResumedAt *time.Time        `json:"last_resumed_at,omitempty"`
 	Config        *config.Container `json:"config"`
 	Status        string            `json:"status"`
+	Health        string            `json:"health,omitempty"`
+	// StoppedByUser is set when the container was last stopped on request
+	// rather than by exiting or crashing, and cleared when it starts again
+	StoppedByUser bool `json:"stopped_by_user,omitempty"`
+	// HealthNotified is the health status hooks last ran for, so each
+	// transition is only notified once
+	HealthNotified string `json:"health_notified,omitempty"`
 }
 
+// Health states reported by container health checks
+const (
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
 // StateManager handles container state persistence
 type StateManager struct {
 	statePath string
 	states    map[string]*State
 	mu        sync.RWMutex
+	// readOnly keeps state changes in memory instead of writing them to disk
+	readOnly bool
 }
 
 // NewStateManager creates a new state manager
@@ -37,12 +58,26 @@ func NewStateManager(statePath string) (*StateManager, error) {
 	logging.Debug("Initializing state manager", "path", statePath)
 
 	if err := os.MkdirAll(statePath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create state directory: %w", err)
+		return nil, stateWriteError(err, statePath, "failed to create state directory")
 	}
 
+	return newStateManager(statePath, false)
+}
+
+// NewReadOnlyStateManager creates a state manager that loads the states in
+// statePath but never writes to it. Changes, such as statuses observed from
+// LXC or states that would be migrated, are only kept in memory, so it suits
+// inspection on a read-only mount. A missing directory is treated as empty.
+func NewReadOnlyStateManager(statePath string) (*StateManager, error) {
+	logging.Debug("Initializing read-only state manager", "path", statePath)
+	return newStateManager(statePath, true)
+}
+
+func newStateManager(statePath string, readOnly bool) (*StateManager, error) {
 	sm := &StateManager{
 		statePath: statePath,
 		states:    make(map[string]*State),
+		readOnly:  readOnly,
 	}
 
 	if err := sm.loadStates(); err != nil {
@@ -52,6 +87,30 @@ func NewStateManager(statePath string) (*StateManager, error) {
 	return sm, nil
 }
 
+// stateWriteError wraps a failed write to the state directory. Permission
+// and read-only filesystem failures become an ErrStorage error naming the
+// directory and how to get past it; anything else is wrapped as is.
+func stateWriteError(err error, statePath, msg string) error {
+	if !isNotWritable(err) {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	return errors.Wrap(err, errors.ErrStorage, fmt.Sprintf(
+		"%s: state directory %s is not writable; fix its permissions, point --config-dir at a writable directory, or use --read-only-state to inspect containers without saving state",
+		msg, statePath,
+	)).WithDetails(map[string]interface{}{"path": statePath})
+}
+
+// isNotWritable reports whether err is a permission or read-only filesystem failure
+func isNotWritable(err error) bool {
+	if os.IsPermission(err) {
+		return true
+	}
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
+	}
+	return err == syscall.EROFS
+}
+
 // SaveContainerState saves the state of a container with retries
 func (sm *StateManager) SaveContainerState(name string, cfg *config.Container, status string) error {
 	logging.Debug("Saving container state",
@@ -74,10 +133,29 @@ func (sm *StateManager) SaveContainerState(name string, cfg *config.Container, s
 
 		if existing, ok := sm.states[name]; ok {
 			state.CreatedAt = existing.CreatedAt
-			if status == "RUNNING" && (existing.Status == "STOPPED" || existing.Status == "FROZEN") {
+			state.LastStartedAt = existing.LastStartedAt
+			state.LastStoppedAt = existing.LastStoppedAt
+			state.LastFrozenAt = existing.LastFrozenAt
+			state.LastResumedAt = existing.LastResumedAt
+			// Health only applies while the container is up
+			if status == "RUNNING" || status == "FROZEN" {
+				state.Health = existing.Health
+				state.HealthNotified = existing.HealthNotified
+			} else {
+				state.StoppedByUser = existing.StoppedByUser
+			}
+			if status == "RUNNING" && existing.Status == "STOPPED" {
 				now := time.Now()
 				state.LastStartedAt = &now
 				logging.Debug("Container started", "name", name, "time", now)
+			} else if status == "RUNNING" && existing.Status == "FROZEN" {
+				now := time.Now()
+				state.LastResumedAt = &now
+				logging.Debug("Container resumed", "name", name, "time", now)
+			} else if status == "FROZEN" && existing.Status == "RUNNING" {
+				now := time.Now()
+				state.LastFrozenAt = &now
+				logging.Debug("Container frozen", "name", name, "time", now)
 			} else if status == "STOPPED" && existing.Status == "RUNNING" {
 				now := time.Now()
 				state.LastStoppedAt = &now
@@ -115,6 +193,92 @@ func (sm *StateManager) GetContainerState(name string) (*State, error) {
 	return state, nil
 }
 
+// SetHealth records the latest health check result for a container
+func (sm *StateManager) SetHealth(name, health string) error {
+	switch health {
+	case HealthStarting, HealthHealthy, HealthUnhealthy:
+	default:
+		return fmt.Errorf("invalid health state: %s", health)
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	state, ok := sm.states[name]
+	if !ok {
+		return fmt.Errorf("container %s does not exist", name)
+	}
+
+	updated := *state
+	updated.Health = health
+	if err := sm.saveState(name, &updated); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	sm.states[name] = &updated
+
+	return nil
+}
+
+// NotifyHealth records health as the status hooks last ran for and reports
+// whether it differs from the one before, i.e. whether hooks should run
+func (sm *StateManager) NotifyHealth(name, health string) (bool, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	state, ok := sm.states[name]
+	if !ok {
+		return false, fmt.Errorf("container %s does not exist", name)
+	}
+	if state.HealthNotified == health {
+		return false, nil
+	}
+
+	updated := *state
+	updated.HealthNotified = health
+	if err := sm.saveState(name, &updated); err != nil {
+		return false, fmt.Errorf("failed to save state: %w", err)
+	}
+	sm.states[name] = &updated
+
+	return true, nil
+}
+
+// MarkStoppedByUser records that the last stop of a container was requested
+// by the user, so restart policies that honor it leave the container stopped
+func (sm *StateManager) MarkStoppedByUser(name string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	state, ok := sm.states[name]
+	if !ok {
+		return fmt.Errorf("container %s does not exist", name)
+	}
+
+	updated := *state
+	updated.StoppedByUser = true
+	if err := sm.saveState(name, &updated); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	sm.states[name] = &updated
+
+	return nil
+}
+
+// observeStatus updates the cached status of a container with one read from
+// LXC, without persisting it
+func (sm *StateManager) observeStatus(name, status string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	state, ok := sm.states[name]
+	if !ok || state.Status == status {
+		return
+	}
+	updated := *state
+	updated.Status = status
+	sm.states[name] = &updated
+}
+
 // RemoveContainerState removes the state of a container
 func (sm *StateManager) RemoveContainerState(name string) error {
 	sm.mu.Lock()
@@ -126,9 +290,13 @@ func (sm *StateManager) RemoveContainerState(name string) error {
 
 	delete(sm.states, name)
 
+	if sm.readOnly {
+		return nil
+	}
+
 	stateFile := sm.getStatePath(name)
 	if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove state file: %w", err)
+		return stateWriteError(err, sm.statePath, "failed to remove state file")
 	}
 
 	return nil
@@ -168,23 +336,40 @@ func (sm *StateManager) loadState(name string) (*State, error) {
 		return nil, fmt.Errorf("failed to read state file: %w", err)
 	}
 
-	var state State
-	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	state, migrated, err := decodeState(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-save so the file is only migrated once
+	if migrated {
+		logging.Info("Migrated container state",
+			"name", name,
+			"schema_version", state.SchemaVersion,
+		)
+		if err := sm.saveState(name, state); err != nil {
+			return nil, err
+		}
 	}
 
-	return &state, nil
+	return state, nil
 }
 
 // saveState saves a single container state to disk
 func (sm *StateManager) saveState(name string, state *State) error {
+	state.SchemaVersion = CurrentStateSchemaVersion
 	data, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
+	if sm.readOnly {
+		logging.Debug("Not writing state in read-only mode", "name", name)
+		return nil
+	}
+
 	if err := os.WriteFile(sm.getStatePath(name), data, 0600); err != nil {
-		return fmt.Errorf("failed to write state file: %w", err)
+		return stateWriteError(err, sm.statePath, "failed to write state file")
 	}
 
 	return nil
@@ -232,16 +417,21 @@ func (sm *StateManager) SaveState(state *State) error {
 		return fmt.Errorf("invalid state: name is required")
 	}
 
-	// Create state directory if it doesn't exist
-	if err := os.MkdirAll(sm.statePath, 0700); err != nil {
-		return fmt.Errorf("failed to create state directory: %w", err)
-	}
-
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 	sm.states[state.Name] = state
 
+	if sm.readOnly {
+		return nil
+	}
+
+	// Create state directory if it doesn't exist
+	if err := os.MkdirAll(sm.statePath, 0700); err != nil {
+		return stateWriteError(err, sm.statePath, "failed to create state directory")
+	}
+
 	// Marshal state to JSON
+	state.SchemaVersion = CurrentStateSchemaVersion
 	data, err := json.Marshal(state)
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
@@ -250,7 +440,7 @@ func (sm *StateManager) SaveState(state *State) error {
 	// Write state file with restricted permissions
 	stateFile := sm.getStatePath(state.Name)
 	if err := os.WriteFile(stateFile, data, 0600); err != nil {
-		return fmt.Errorf("failed to write state file: %w", err)
+		return stateWriteError(err, sm.statePath, "failed to write state file")
 	}
 
 	return nil
@@ -0,0 +1,76 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentStateSchemaVersion is the State schema written by this version.
+// Bump it and register a migration whenever State or config.Container
+// changes in a way older files would mis-parse.
+const CurrentStateSchemaVersion = 1
+
+// stateMigration upgrades a raw state document from one schema version to
+// the next. It works on the decoded JSON so renamed or restructured fields
+// can be moved before the document is parsed into State.
+type stateMigration func(doc map[string]interface{}) error
+
+// stateMigrations maps a schema version to the migration that upgrades it to
+// the following version
+var stateMigrations = map[int]stateMigration{
+	0: migrateStateV0,
+}
+
+// migrateStateV0 upgrades files written before schema versioning. Their
+// layout matches version 1, so only the version stamp is added.
+func migrateStateV0(_ map[string]interface{}) error {
+	return nil
+}
+
+// decodeState parses a state file, running any migrations needed to bring it
+// to CurrentStateSchemaVersion. It reports whether the document was migrated.
+func decodeState(data []byte) (*State, bool, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+
+	version := 0
+	if v, ok := doc["schema_version"]; ok {
+		f, ok := v.(float64)
+		if !ok || f != float64(int(f)) {
+			return nil, false, fmt.Errorf("invalid state schema version: %v", v)
+		}
+		version = int(f)
+	}
+
+	if version > CurrentStateSchemaVersion {
+		return nil, false, fmt.Errorf("state schema version %d is newer than supported version %d", version, CurrentStateSchemaVersion)
+	}
+
+	migrated := version < CurrentStateSchemaVersion
+	for ; version < CurrentStateSchemaVersion; version++ {
+		migrate, ok := stateMigrations[version]
+		if !ok {
+			return nil, false, fmt.Errorf("no migration registered for state schema version %d", version)
+		}
+		if err := migrate(doc); err != nil {
+			return nil, false, fmt.Errorf("failed to migrate state from schema version %d: %w", version, err)
+		}
+		doc["schema_version"] = version + 1
+	}
+
+	if migrated {
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, false, fmt.Errorf("failed to marshal migrated state: %w", err)
+		}
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+
+	return &state, migrated, nil
+}
@@ -1,12 +1,16 @@
 package container_test
 
 import (
+	"encoding/json"
+	stderrors "errors"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/errors"
 	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
 )
 
@@ -47,6 +51,61 @@ func TestStateManager(t *testing.T) {
 		testing_internal.AssertEqual(t, "RUNNING", state.Status)
 	})
 
+	t.Run("health_state", func(t *testing.T) {
+		err := manager.SetHealth(containerName, container.HealthUnhealthy)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.SetHealth(containerName, "broken")
+		testing_internal.AssertError(t, err)
+
+		// Health survives state saves while running
+		err = manager.SaveContainerState(containerName, containerConfig, "RUNNING")
+		testing_internal.AssertNoError(t, err)
+		state, err := manager.GetContainerState(containerName)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, container.HealthUnhealthy, state.Health)
+
+		// Persisted to disk
+		loaded, err := manager.LoadStateFromDisk(containerName)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, container.HealthUnhealthy, loaded.Health)
+
+		// Cleared once the container stops
+		err = manager.SaveContainerState(containerName, containerConfig, "STOPPED")
+		testing_internal.AssertNoError(t, err)
+		state, err = manager.GetContainerState(containerName)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "", state.Health)
+
+		err = manager.SaveContainerState(containerName, containerConfig, "RUNNING")
+		testing_internal.AssertNoError(t, err)
+	})
+
+	t.Run("freeze_timestamps", func(t *testing.T) {
+		state, err := manager.GetContainerState(containerName)
+		testing_internal.AssertNoError(t, err)
+		started := state.LastStartedAt
+
+		err = manager.SaveContainerState(containerName, containerConfig, "FROZEN")
+		testing_internal.AssertNoError(t, err)
+		state, err = manager.GetContainerState(containerName)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertNotNil(t, state.LastFrozenAt)
+		if state.LastResumedAt != nil {
+			t.Errorf("expected no resume time before resuming, got %v", state.LastResumedAt)
+		}
+		frozen := *state.LastFrozenAt
+
+		// Resuming isn't a start
+		err = manager.SaveContainerState(containerName, containerConfig, "RUNNING")
+		testing_internal.AssertNoError(t, err)
+		state, err = manager.GetContainerState(containerName)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertNotNil(t, state.LastResumedAt)
+		testing_internal.AssertEqual(t, frozen, *state.LastFrozenAt)
+		testing_internal.AssertEqual(t, started, state.LastStartedAt)
+	})
+
 	t.Run("remove_state", func(t *testing.T) {
 		// Remove state
 		err := manager.RemoveContainerState(containerName)
@@ -84,3 +143,111 @@ func TestStateManager(t *testing.T) {
 		testing_internal.AssertEqual(t, os.FileMode(0600), info.Mode().Perm())
 	})
 }
+
+func TestStateMigration(t *testing.T) {
+	t.Run("v0_without_version", func(t *testing.T) {
+		statePath := t.TempDir()
+		v0 := `{
+  "name": "legacy",
+  "created_at": "2024-01-02T03:04:05Z",
+  "last_started_at": "2024-01-03T03:04:05Z",
+  "config": {
+    "image": "ubuntu:20.04",
+    "network": {"interfaces": [{"type": "veth", "bridge": "br0", "ip": "10.0.3.10/24"}]},
+    "environment": {"FOO": "bar"}
+  },
+  "status": "RUNNING",
+  "health": "healthy"
+}`
+		stateFile := filepath.Join(statePath, "legacy.json")
+		testing_internal.AssertNoError(t, os.WriteFile(stateFile, []byte(v0), 0600))
+
+		manager, err := container.NewStateManager(statePath)
+		testing_internal.AssertNoError(t, err)
+
+		state, err := manager.GetContainerState("legacy")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, container.CurrentStateSchemaVersion, state.SchemaVersion)
+		testing_internal.AssertEqual(t, "RUNNING", state.Status)
+		testing_internal.AssertEqual(t, container.HealthHealthy, state.Health)
+		testing_internal.AssertEqual(t, "2024-01-02T03:04:05Z", state.CreatedAt.UTC().Format(time.RFC3339))
+		testing_internal.AssertNotNil(t, state.LastStartedAt)
+		testing_internal.AssertEqual(t, "ubuntu:20.04", state.Config.Image)
+		testing_internal.AssertEqual(t, "10.0.3.10/24", state.Config.Network.Interfaces[0].IP)
+		testing_internal.AssertEqual(t, "bar", state.Config.Environment["FOO"])
+
+		// The migrated file is re-saved with the current version
+		data, err := os.ReadFile(stateFile)
+		testing_internal.AssertNoError(t, err)
+		var onDisk map[string]interface{}
+		testing_internal.AssertNoError(t, json.Unmarshal(data, &onDisk))
+		testing_internal.AssertEqual(t, float64(container.CurrentStateSchemaVersion), onDisk["schema_version"])
+		testing_internal.AssertEqual(t, "healthy", onDisk["health"])
+	})
+
+	t.Run("newer_version_skipped", func(t *testing.T) {
+		statePath := t.TempDir()
+		future := `{"schema_version": 999, "name": "future", "status": "STOPPED"}`
+		testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(statePath, "future.json"), []byte(future), 0600))
+
+		manager, err := container.NewStateManager(statePath)
+		testing_internal.AssertNoError(t, err)
+
+		_, err = manager.GetContainerState("future")
+		testing_internal.AssertError(t, err)
+
+		_, err = manager.LoadStateFromDisk("future")
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "newer than supported")
+	})
+}
+
+func TestReadOnlyState(t *testing.T) {
+	t.Run("keeps_changes_in_memory", func(t *testing.T) {
+		statePath := t.TempDir()
+		stored := `{"schema_version": 1, "name": "web", "status": "STOPPED", "config": {"image": "ubuntu:20.04"}}`
+		stateFile := filepath.Join(statePath, "web.json")
+		testing_internal.AssertNoError(t, os.WriteFile(stateFile, []byte(stored), 0600))
+
+		manager, err := container.NewReadOnlyStateManager(statePath)
+		testing_internal.AssertNoError(t, err)
+
+		err = manager.SaveContainerState("web", &config.Container{Image: "ubuntu:22.04"}, "RUNNING")
+		testing_internal.AssertNoError(t, err)
+		state, err := manager.GetContainerState("web")
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "RUNNING", state.Status)
+
+		testing_internal.AssertNoError(t, manager.RemoveContainerState("web"))
+		data, err := os.ReadFile(stateFile)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, stored, string(data))
+	})
+
+	t.Run("missing_directory", func(t *testing.T) {
+		statePath := filepath.Join(t.TempDir(), "state")
+
+		manager, err := container.NewReadOnlyStateManager(statePath)
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(manager.GetStates()))
+
+		_, err = os.Stat(statePath)
+		testing_internal.AssertEqual(t, true, os.IsNotExist(err))
+	})
+
+	t.Run("unwritable_directory", func(t *testing.T) {
+		dir := t.TempDir()
+		makeUnwritable(t, dir)
+
+		statePath := filepath.Join(dir, "state")
+		_, err := container.NewStateManager(statePath)
+		testing_internal.AssertError(t, err)
+
+		var storageErr *errors.Error
+		if !stderrors.As(err, &storageErr) || storageErr.Type != errors.ErrStorage {
+			t.Fatalf("expected a storage error, got %v", err)
+		}
+		testing_internal.AssertContains(t, err.Error(), statePath)
+		testing_internal.AssertContains(t, err.Error(), "--read-only-state")
+	})
+}
@@ -26,9 +26,27 @@ type NetworkStats struct {
 	Timestamp time.Time
 }
 
+// CPUStats represents CPU usage statistics
+type CPUStats struct {
+	UsageSeconds float64 // Cumulative CPU time consumed
+	Shares       int64
+	Timestamp    time.Time
+}
+
+// MemoryStats represents memory usage statistics
+type MemoryStats struct {
+	UsageBytes int64
+	LimitBytes int64
+	Timestamp  time.Time
+}
+
+// cgroupRoot is the base path of the LXC cgroup hierarchy.
+// This allows us to replace it during testing.
+var cgroupRoot = "/sys/fs/cgroup/lxc"
+
 // GetNetworkStats retrieves network statistics for a container
 func (m *LXCManager) GetNetworkStats(name string) ([]NetworkStats, error) {
-	netPath := filepath.Join("/sys/fs/cgroup/lxc", name, "devices")
+	netPath := filepath.Join(cgroupRoot, name, "devices")
 	if _, err := os.Stat(netPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("container %s is not running", name)
 	}
@@ -112,3 +130,100 @@ func (m *LXCManager) GetNetworkStats(name string) ([]NetworkStats, error) {
 
 	return stats, nil
 }
+
+// GetCPUStats retrieves CPU statistics for a container
+func (m *LXCManager) GetCPUStats(name string) (*CPUStats, error) {
+	cgroupPath := filepath.Join(cgroupRoot, name)
+	if _, err := os.Stat(cgroupPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("container %s is not running", name)
+	}
+
+	stats := &CPUStats{Timestamp: time.Now()}
+
+	// cgroup v2 reports usage in microseconds, v1 in nanoseconds
+	if usage, err := readCgroupKeyedValue(filepath.Join(cgroupPath, "cpu.stat"), "usage_usec"); err == nil {
+		stats.UsageSeconds = float64(usage) / 1e6
+	} else if usage, err := readCgroupValue(filepath.Join(cgroupPath, "cpuacct.usage")); err == nil {
+		stats.UsageSeconds = float64(usage) / 1e9
+	} else {
+		return nil, fmt.Errorf("failed to read CPU usage: %w", err)
+	}
+
+	if shares, err := readCgroupValue(filepath.Join(cgroupPath, "cpu.shares")); err == nil {
+		stats.Shares = shares
+	} else if weight, err := readCgroupValue(filepath.Join(cgroupPath, "cpu.weight")); err == nil {
+		stats.Shares = weight
+	}
+
+	logging.Debug("Collected CPU stats",
+		"container", name,
+		"usage_seconds", stats.UsageSeconds,
+		"shares", stats.Shares,
+	)
+
+	return stats, nil
+}
+
+// GetMemoryStats retrieves memory statistics for a container
+func (m *LXCManager) GetMemoryStats(name string) (*MemoryStats, error) {
+	cgroupPath := filepath.Join(cgroupRoot, name)
+	if _, err := os.Stat(cgroupPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("container %s is not running", name)
+	}
+
+	stats := &MemoryStats{Timestamp: time.Now()}
+
+	if usage, err := readCgroupValue(filepath.Join(cgroupPath, "memory.current")); err == nil {
+		stats.UsageBytes = usage
+	} else if usage, err := readCgroupValue(filepath.Join(cgroupPath, "memory.usage_in_bytes")); err == nil {
+		stats.UsageBytes = usage
+	} else {
+		return nil, fmt.Errorf("failed to read memory usage: %w", err)
+	}
+
+	if limit, err := readCgroupValue(filepath.Join(cgroupPath, "memory.max")); err == nil {
+		stats.LimitBytes = limit
+	} else if limit, err := readCgroupValue(filepath.Join(cgroupPath, "memory.limit_in_bytes")); err == nil {
+		stats.LimitBytes = limit
+	}
+
+	logging.Debug("Collected memory stats",
+		"container", name,
+		"usage_bytes", stats.UsageBytes,
+		"limit_bytes", stats.LimitBytes,
+	)
+
+	return stats, nil
+}
+
+// readCgroupValue reads a single integer value from a cgroup file.
+// An unlimited value ("max") is reported as 0.
+func readCgroupValue(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// readCgroupKeyedValue reads the value for key from a flat keyed cgroup file
+// such as cpu.stat
+func readCgroupKeyedValue(path, key string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("key %s not found in %s", key, path)
+}
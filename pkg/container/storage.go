@@ -0,0 +1,219 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
+)
+
+// StorageBackend provisions the volume a container's rootfs lives on
+type StorageBackend interface {
+	// Provision creates the rootfs volume of container name, limited to size
+	// bytes when the backend supports it and size is positive, and returns
+	// the directory it is mounted on, see volumeMounter
+	Provision(name string, size int64) (string, error)
+	// Destroy removes the rootfs volume of container name
+	Destroy(name string) error
+}
+
+// volumeMounter is implemented by backends whose volume LXC mounts itself on
+// start. Mount makes the volume available on the host until the returned
+// function is called.
+type volumeMounter interface {
+	Mount(name string) (func(), error)
+}
+
+// newStorageBackend returns the backend selected by cfg for containers kept
+// under root. A nil cfg or an empty backend selects dir.
+func newStorageBackend(root string, cfg *common.StorageConfig) (StorageBackend, error) {
+	if cfg == nil {
+		return dirBackend{root: root}, nil
+	}
+	if err := validation.ValidateStorageBackend(cfg.Backend, cfg.Pool); err != nil {
+		return nil, err
+	}
+
+	switch cfg.Backend {
+	case "zfs":
+		return zfsBackend{root: root, pool: cfg.Pool}, nil
+	case "btrfs":
+		return btrfsBackend{root: root}, nil
+	case "lvm":
+		return lvmBackend{root: root, vg: cfg.Pool}, nil
+	default:
+		return dirBackend{root: root}, nil
+	}
+}
+
+// storageSize returns the root size of cfg in bytes, or 0 without one
+func storageSize(cfg *common.StorageConfig) (int64, error) {
+	if cfg == nil || cfg.Root == "" {
+		return 0, nil
+	}
+	return validation.ValidateStorageSize(cfg.Root)
+}
+
+// runStorageCommand runs a storage tool, including its output in the error
+func runStorageCommand(args ...string) error {
+	if output, err := ExecCommand(args[0], args[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to run '%s': %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// rootfsPath is where a container's rootfs is mounted, whatever the backend
+func rootfsPath(root, name string) string {
+	return filepath.Join(root, name, "rootfs")
+}
+
+// dirBackend keeps the rootfs in a plain directory
+type dirBackend struct {
+	root string
+}
+
+func (b dirBackend) Provision(name string, _ int64) (string, error) {
+	path := rootfsPath(b.root, name)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return "", fmt.Errorf("failed to create rootfs directory: %w", err)
+	}
+	return path, nil
+}
+
+func (b dirBackend) Destroy(name string) error {
+	return os.RemoveAll(rootfsPath(b.root, name))
+}
+
+// zfsBackend keeps the rootfs in a dataset named after the container under
+// pool, limited with a quota
+type zfsBackend struct {
+	root string
+	pool string
+}
+
+func (b zfsBackend) dataset(name string) string {
+	return b.pool + "/" + name
+}
+
+func (b zfsBackend) Provision(name string, size int64) (string, error) {
+	if err := runStorageCommand("zfs", "list", "-H", "-o", "name", b.pool); err != nil {
+		return "", fmt.Errorf("zfs pool %s does not exist: %w", b.pool, err)
+	}
+
+	path := rootfsPath(b.root, name)
+	args := []string{"zfs", "create", "-o", "mountpoint=" + path}
+	if size > 0 {
+		args = append(args, "-o", fmt.Sprintf("quota=%d", size))
+	}
+	if err := runStorageCommand(append(args, b.dataset(name))...); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (b zfsBackend) Destroy(name string) error {
+	return runStorageCommand("zfs", "destroy", "-r", b.dataset(name))
+}
+
+// btrfsBackend keeps the rootfs in a subvolume. Sizes are applied as a qgroup
+// limit, which needs quotas enabled on the filesystem.
+type btrfsBackend struct {
+	root string
+}
+
+func (b btrfsBackend) Provision(name string, size int64) (string, error) {
+	path := rootfsPath(b.root, name)
+	if err := runStorageCommand("btrfs", "subvolume", "create", path); err != nil {
+		return "", err
+	}
+	if size > 0 {
+		if err := runStorageCommand("btrfs", "qgroup", "limit", fmt.Sprintf("%d", size), path); err != nil {
+			logging.Warn("Failed to limit btrfs subvolume size, are quotas enabled?", "container", name, "error", err)
+		}
+	}
+	return path, nil
+}
+
+func (b btrfsBackend) Destroy(name string) error {
+	// Read-only snapshot subvolumes can't be removed like directories
+	snapshots, err := b.Snapshots(name)
+	if err != nil {
+		return err
+	}
+	for _, snap := range snapshots {
+		if err := runStorageCommand("btrfs", "subvolume", "delete", filepath.Join(snapshotDir(b.root, name), snap)); err != nil {
+			return err
+		}
+	}
+	return runStorageCommand("btrfs", "subvolume", "delete", rootfsPath(b.root, name))
+}
+
+// lvmBackend keeps the rootfs on an ext4 logical volume named after the
+// container in volume group vg. LXC mounts it on start from lxc.rootfs.path,
+// on the host it is only mounted while Create fills it.
+type lvmBackend struct {
+	root string
+	vg   string
+}
+
+func (b lvmBackend) device(name string) string {
+	return lvmDevice(b.vg, name)
+}
+
+// lvmDevice is the device of the logical volume holding a container's rootfs
+func lvmDevice(vg, name string) string {
+	return "/dev/" + vg + "/" + name
+}
+
+func (b lvmBackend) Provision(name string, size int64) (string, error) {
+	if size <= 0 {
+		return "", fmt.Errorf("lvm backend requires a root size")
+	}
+	if err := runStorageCommand("vgs", b.vg); err != nil {
+		return "", fmt.Errorf("lvm volume group %s does not exist: %w", b.vg, err)
+	}
+
+	if err := runStorageCommand("lvcreate", "-y", "-L", fmt.Sprintf("%db", size), "-n", name, b.vg); err != nil {
+		return "", err
+	}
+
+	path := rootfsPath(b.root, name)
+	if err := runStorageCommand("mkfs.ext4", "-q", b.device(name)); err != nil {
+		b.removeVolume(name)
+		return "", err
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		b.removeVolume(name)
+		return "", fmt.Errorf("failed to create rootfs directory: %w", err)
+	}
+	return path, nil
+}
+
+// Mount mounts the volume on the rootfs directory until the returned function
+// is called
+func (b lvmBackend) Mount(name string) (func(), error) {
+	path := rootfsPath(b.root, name)
+	if err := runStorageCommand("mount", b.device(name), path); err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := runStorageCommand("umount", path); err != nil {
+			logging.Warn("Failed to unmount rootfs volume", "container", name, "error", err)
+		}
+	}, nil
+}
+
+func (b lvmBackend) Destroy(name string) error {
+	return runStorageCommand("lvremove", "-f", b.vg+"/"+name)
+}
+
+// removeVolume removes a logical volume Provision failed to set up
+func (b lvmBackend) removeVolume(name string) {
+	if err := runStorageCommand("lvremove", "-f", b.vg+"/"+name); err != nil {
+		logging.Warn("Failed to remove logical volume", "container", name, "error", err)
+	}
+}
@@ -0,0 +1,157 @@
+package container_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+)
+
+func TestStorageBackends(t *testing.T) {
+	mockCmd, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	dir := t.TempDir()
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+
+	// Storage tools are recorded, pools other than tank and vg0 don't exist
+	storageTools := []string{"zfs", "btrfs", "vgs", "lvcreate", "mkfs.ext4", "mount", "umount", "lvremove"}
+	calls, restore := mock.RecordCalls(&container.ExecCommand, storageTools...)
+	defer restore()
+	for _, tool := range storageTools {
+		tool := tool
+		calls.Stub(tool, func(args []string) *exec.Cmd {
+			cmd := strings.Join(append([]string{tool}, args...), " ")
+			if cmd == "zfs list -H -o name missing" || cmd == "vgs missing" {
+				return exec.Command("false")
+			}
+			return exec.Command("true")
+		})
+	}
+
+	lifecycle := func(t *testing.T, name string, storage *common.StorageConfig) string {
+		t.Helper()
+		calls.Reset()
+		testing_internal.AssertNoError(t, manager.Create(name, &common.Container{Image: "ubuntu:20.04", Storage: storage}))
+		testing_internal.AssertNoError(t, mockCmd.AddContainer(name, "STOPPED"))
+		testing_internal.AssertNoError(t, manager.Remove(name))
+		return strings.Join(calls.Commands(), "\n")
+	}
+
+	t.Run("dir", func(t *testing.T) {
+		calls.Reset()
+		testing_internal.AssertNoError(t, manager.Create("plain", &common.Container{Image: "ubuntu:20.04"}))
+		_, err := os.Stat(filepath.Join(dir, "plain", "rootfs"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, 0, len(calls.Calls()))
+	})
+
+	t.Run("zfs", func(t *testing.T) {
+		rootfs := filepath.Join(dir, "db", "rootfs")
+		got := lifecycle(t, "db", &common.StorageConfig{Root: "1G", Backend: "zfs", Pool: "tank"})
+		testing_internal.AssertEqual(t, strings.Join([]string{
+			"zfs list -H -o name tank",
+			"zfs create -o mountpoint=" + rootfs + " -o quota=1073741824 tank/db",
+			"zfs destroy -r tank/db",
+		}, "\n"), got)
+	})
+
+	t.Run("btrfs", func(t *testing.T) {
+		rootfs := filepath.Join(dir, "cache", "rootfs")
+		got := lifecycle(t, "cache", &common.StorageConfig{Root: "1M", Backend: "btrfs"})
+		testing_internal.AssertEqual(t, strings.Join([]string{
+			"btrfs subvolume create " + rootfs,
+			"btrfs qgroup limit 1048576 " + rootfs,
+			"btrfs subvolume delete " + rootfs,
+		}, "\n"), got)
+	})
+
+	t.Run("lvm", func(t *testing.T) {
+		rootfs := filepath.Join(dir, "web", "rootfs")
+		got := lifecycle(t, "web", &common.StorageConfig{Root: "1G", Backend: "lvm", Pool: "vg0"})
+		testing_internal.AssertEqual(t, strings.Join([]string{
+			"vgs vg0",
+			"lvcreate -y -L 1073741824b -n web vg0",
+			"mkfs.ext4 -q /dev/vg0/web",
+			"mount /dev/vg0/web " + rootfs,
+			"umount " + rootfs,
+			"lvremove -f vg0/web",
+		}, "\n"), got)
+	})
+
+	t.Run("lvm_rootfs_path", func(t *testing.T) {
+		testing_internal.AssertNoError(t, manager.Create("app", &common.Container{
+			Image:   "ubuntu:20.04",
+			Storage: &common.StorageConfig{Root: "1G", Backend: "lvm", Pool: "vg0"},
+		}))
+
+		data, err := os.ReadFile(filepath.Join(dir, "app", "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertConfigKey(t, string(data), "lxc.rootfs.path", "lvm:/dev/vg0/app")
+	})
+
+	t.Run("dir_rootfs_path", func(t *testing.T) {
+		data, err := os.ReadFile(filepath.Join(dir, "plain", "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertConfigKeyAbsent(t, string(data), "lxc.rootfs.path")
+	})
+
+	t.Run("missing_pool", func(t *testing.T) {
+		for _, storage := range []*common.StorageConfig{
+			{Root: "1G", Backend: "zfs", Pool: "missing"},
+			{Root: "1G", Backend: "lvm", Pool: "missing"},
+		} {
+			err := manager.Create("nopool", &common.Container{Image: "ubuntu:20.04", Storage: storage})
+			testing_internal.AssertError(t, err)
+			testing_internal.AssertContains(t, err.Error(), "missing does not exist")
+			if _, err := os.Stat(filepath.Join(dir, "nopool")); !os.IsNotExist(err) {
+				t.Errorf("expected container directory to be removed, got %v", err)
+			}
+		}
+	})
+
+	t.Run("pool_required", func(t *testing.T) {
+		err := manager.Create("nopool", &common.Container{Image: "ubuntu:20.04", Storage: &common.StorageConfig{Backend: "lvm"}})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "storage pool is required for lvm backend")
+	})
+
+	t.Run("staged_rootfs_requires_dir", func(t *testing.T) {
+		staged := filepath.Join(dir, "staged", "rootfs", "etc", "os-release")
+		testing_internal.AssertNoError(t, os.MkdirAll(filepath.Dir(staged), 0755))
+		testing_internal.AssertNoError(t, os.WriteFile(staged, []byte("ID=debian\n"), 0644))
+
+		calls.Reset()
+		err := manager.Create("staged", &common.Container{
+			Image:   "ubuntu:20.04",
+			Storage: &common.StorageConfig{Root: "1G", Backend: "zfs", Pool: "tank"},
+		})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "can only be used with the dir storage backend")
+		testing_internal.AssertEqual(t, 0, len(calls.Calls()))
+		_, err = os.Stat(staged)
+		testing_internal.AssertNoError(t, err)
+	})
+
+	t.Run("destroyed_on_failure", func(t *testing.T) {
+		calls.Reset()
+		err := manager.Create("broken", &common.Container{
+			Image:   "ubuntu:20.04",
+			Storage: &common.StorageConfig{Root: "1G", Backend: "zfs", Pool: "tank"},
+			Network: &common.NetworkConfig{
+				Interfaces: []common.NetworkInterface{{Type: "veth", Bridge: "lxcbr0", DHCP: true}},
+			},
+			Ports: []common.PortForward{{Protocol: "tcp", Host: 8080, Guest: 80}},
+		})
+		testing_internal.AssertError(t, err)
+		commands := calls.Commands()
+		testing_internal.AssertEqual(t, "zfs destroy -r tank/broken", commands[len(commands)-1])
+	})
+}
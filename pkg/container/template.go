@@ -23,7 +23,7 @@ type Template struct {
 // CreateTemplate creates a new template from an existing container
 func (m *LXCManager) CreateTemplate(containerName string, templateName string, description string) error {
 	// Get the container configuration
-	container, err := m.Get(containerName)
+	container, err := m.lookup(containerName)
 	if err != nil {
 		return fmt.Errorf("failed to get container: %w", err)
 	}
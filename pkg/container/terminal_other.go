@@ -0,0 +1,15 @@
+//go:build !linux
+
+package container
+
+import "fmt"
+
+// isTerminal reports whether fd refers to a terminal
+func isTerminal(_ int) bool {
+	return false
+}
+
+// makeRaw is only supported on Linux
+func makeRaw(_ int) (func() error, error) {
+	return nil, fmt.Errorf("raw terminal mode is not supported on this platform")
+}
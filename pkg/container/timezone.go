@@ -0,0 +1,71 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
+
+// hostLocaltime is the host file WithHostTimezone bind-mounts
+const hostLocaltime = "/etc/localtime"
+
+// writeTimezone sets the container's timezone the Debian way: /etc/timezone
+// names it and /etc/localtime links to its zoneinfo file. Like writeHostname,
+// it does nothing until the image has been extracted into the rootfs.
+func (m *LXCManager) writeTimezone(name, tz string) error {
+	if tz == "" {
+		return nil
+	}
+
+	rootfs := filepath.Join(m.configPath, name, "rootfs")
+	if info, err := os.Stat(filepath.Join(rootfs, "etc")); err != nil || !info.IsDir() {
+		logging.Debug("Skipping timezone files, rootfs not extracted", "container", name)
+		return nil
+	}
+
+	timezonePath, err := resolveInRootfs(rootfs, "/etc/timezone")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(timezonePath, []byte(tz+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write /etc/timezone: %w", err)
+	}
+
+	// The link is resolved inside the container, where the image's tzdata lives
+	localtimePath, err := layerTarget(rootfs, "/etc/localtime")
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(localtimePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace /etc/localtime: %w", err)
+	}
+	if err := os.Symlink("/usr/share/zoneinfo/"+tz, localtimePath); err != nil {
+		return fmt.Errorf("failed to link /etc/localtime: %w", err)
+	}
+	return nil
+}
+
+// WithHostTimezone returns a copy of cfg that bind-mounts the host's
+// /etc/localtime read-only, so the container follows the host's timezone.
+// Containers that set a timezone of their own keep it.
+func WithHostTimezone(cfg common.Container) common.Container {
+	if cfg.Timezone != "" {
+		return cfg
+	}
+
+	storage := common.StorageConfig{}
+	if cfg.Storage != nil {
+		storage = *cfg.Storage
+	}
+	storage.Mounts = append(append([]common.Mount(nil), storage.Mounts...), common.Mount{
+		Source:  hostLocaltime,
+		Target:  "etc/localtime",
+		Type:    "bind",
+		Options: []string{"bind", "ro", "create=file"},
+	})
+	cfg.Storage = &storage
+	return cfg
+}
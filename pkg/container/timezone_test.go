@@ -0,0 +1,64 @@
+package container_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/validation"
+)
+
+func TestTimezone(t *testing.T) {
+	_, cleanup := mock.SetupMockCommand(&container.ExecCommand)
+	defer cleanup()
+
+	zoneinfo := t.TempDir()
+	testing_internal.AssertNoError(t, os.MkdirAll(filepath.Join(zoneinfo, "Europe"), 0755))
+	testing_internal.AssertNoError(t, os.WriteFile(filepath.Join(zoneinfo, "Europe", "Berlin"), []byte("TZif"), 0644))
+	defer func(dir string) { validation.ZoneinfoDir = dir }(validation.ZoneinfoDir)
+	validation.ZoneinfoDir = zoneinfo
+
+	dir := t.TempDir()
+	manager, err := container.NewLXCManager(dir)
+	testing_internal.AssertNoError(t, err)
+
+	t.Run("writes_files", func(t *testing.T) {
+		etc := filepath.Join(dir, "web", "rootfs", "etc")
+		testing_internal.AssertNoError(t, os.MkdirAll(etc, 0755))
+		testing_internal.AssertNoError(t, os.Symlink("/usr/share/zoneinfo/Etc/UTC", filepath.Join(etc, "localtime")))
+
+		testing_internal.AssertNoError(t, manager.Create("web", &common.Container{Image: "ubuntu:20.04", Timezone: "Europe/Berlin"}))
+
+		data, err := os.ReadFile(filepath.Join(etc, "timezone"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "Europe/Berlin\n", string(data))
+		link, err := os.Readlink(filepath.Join(etc, "localtime"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertEqual(t, "/usr/share/zoneinfo/Europe/Berlin", link)
+	})
+
+	t.Run("unknown_zone", func(t *testing.T) {
+		err := manager.Create("db", &common.Container{Image: "ubuntu:20.04", Timezone: "Europe/Atlantis"})
+		testing_internal.AssertError(t, err)
+		testing_internal.AssertContains(t, err.Error(), "unknown timezone")
+	})
+
+	t.Run("host_timezone", func(t *testing.T) {
+		cfg := container.WithHostTimezone(common.Container{Image: "ubuntu:20.04"})
+		testing_internal.AssertNoError(t, manager.ApplyConfig("cache", &cfg))
+
+		data, err := os.ReadFile(filepath.Join(dir, "cache", "config"))
+		testing_internal.AssertNoError(t, err)
+		testing_internal.AssertContains(t, string(data), "/etc/localtime etc/localtime bind bind,ro,create=file 0 0")
+
+		// An explicit timezone wins over the host's
+		own := container.WithHostTimezone(common.Container{Image: "ubuntu:20.04", Timezone: "Europe/Berlin"})
+		if own.Storage != nil {
+			t.Errorf("expected no mounts for a service with a timezone, got %+v", own.Storage)
+		}
+	})
+}
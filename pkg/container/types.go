@@ -1,6 +1,9 @@
 package container
 
 import (
+	"time"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/config"
 )
 
@@ -8,5 +11,15 @@ import (
 type Container struct {
 	Name   string            `json:"name"`
 	State  string            `json:"state"`
+	Health string            `json:"health,omitempty"`
 	Config *config.Container `json:"config"`
+	// FrozenAt is when a FROZEN container was frozen, nil in other states
+	FrozenAt *time.Time `json:"frozen_at,omitempty"`
+	// IPs are the non-loopback addresses of a RUNNING container, as reported
+	// by the lxc-info read that refreshed its state. Empty without a refresh.
+	IPs []string `json:"ips,omitempty"`
+	// Bandwidth holds the rate limits of a RUNNING container's interfaces,
+	// keyed by interface name. Only Get reads them, interfaces without limits
+	// are left out.
+	Bandwidth map[string]*common.BandwidthLimit `json:"bandwidth,omitempty"`
 }
@@ -0,0 +1,29 @@
+package container_test
+
+import (
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// makeUnwritable makes dir unwritable for the rest of the test. Root ignores
+// directory permissions, so for root dir is bind mounted read-only on itself.
+func makeUnwritable(t *testing.T, dir string) {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		if err := os.Chmod(dir, 0500); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Chmod(dir, 0700) })
+		return
+	}
+
+	if err := unix.Mount(dir, dir, "", unix.MS_BIND, ""); err != nil {
+		t.Skipf("cannot bind mount %s: %v", dir, err)
+	}
+	t.Cleanup(func() { unix.Unmount(dir, 0) })
+	if err := unix.Mount("", dir, "", unix.MS_REMOUNT|unix.MS_BIND|unix.MS_RDONLY, ""); err != nil {
+		t.Skipf("cannot remount %s read-only: %v", dir, err)
+	}
+}
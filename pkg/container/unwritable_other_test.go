@@ -0,0 +1,20 @@
+//go:build !linux
+
+package container_test
+
+import (
+	"os"
+	"testing"
+)
+
+// makeUnwritable makes dir unwritable for the rest of the test
+func makeUnwritable(t *testing.T, dir string) {
+	t.Helper()
+	if os.Geteuid() == 0 {
+		t.Skip("root ignores directory permissions")
+	}
+	if err := os.Chmod(dir, 0500); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0700) })
+}
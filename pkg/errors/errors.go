@@ -25,6 +25,7 @@ const (
 	// System errors
 	ErrSystem   ErrorType = "System"
 	ErrInternal ErrorType = "Internal"
+	ErrTimeout  ErrorType = "Timeout"
 )
 
 // Error represents a structured error
@@ -124,6 +124,9 @@ type Command interface {
 
 // execLXCCommand handles LXC command execution and state transitions
 func (m *CommandState) execLXCCommand(name string, args ...string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.debug {
 		fmt.Printf("DEBUG: Mock command called: %s %s\n", name, strings.Join(args, " "))
 	}
@@ -196,6 +199,15 @@ func (m *CommandState) execLXCCommand(name string, args ...string) ([]byte, erro
 	return nil, nil
 }
 
+// StripLXCPath drops the -P <lxcpath> the container manager puts in front of
+// the arguments of lxc-* commands
+func StripLXCPath(args []string) []string {
+	if len(args) >= 2 && args[0] == "-P" {
+		return args[2:]
+	}
+	return args
+}
+
 // SetupMockCommand sets up a mock command executor
 func SetupMockCommand(execCommand *func(string, ...string) *exec.Cmd) (Command, func()) {
 	mockState := NewCommandState()
@@ -207,7 +219,7 @@ func SetupMockCommand(execCommand *func(string, ...string) *exec.Cmd) (Command,
 		cmd.Args = append([]string{command}, args...)
 
 		// Handle mock output based on command
-		output, err := mockState.execLXCCommand(command, args...)
+		output, err := mockState.execLXCCommand(command, StripLXCPath(args)...)
 		if err != nil {
 			// For error cases, return a command that will fail
 			failCmd := exec.Command("/bin/false")
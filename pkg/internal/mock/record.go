@@ -0,0 +1,121 @@
+package mock
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Call is a command run through an ExecCommand wrapped by RecordCalls
+type Call struct {
+	Name string
+	// Args are the arguments without the -P <lxcpath> the container manager
+	// puts in front of them, LXCPath is the path it gave
+	Args    []string
+	LXCPath string
+}
+
+// String returns the call's arguments joined by spaces
+func (c Call) String() string {
+	return strings.Join(c.Args, " ")
+}
+
+// Recorder records the commands run through an ExecCommand wrapped by
+// RecordCalls, and can replace some of them with stubs
+type Recorder struct {
+	mu    sync.Mutex
+	names map[string]bool
+	calls []Call
+	stubs map[string]func(args []string) *exec.Cmd
+}
+
+// RecordCalls wraps *execCommand to record each run of the named commands,
+// or of every command when no names are given. Commands still run through
+// the wrapped ExecCommand unless stubbed. Call the returned function to
+// restore *execCommand.
+func RecordCalls(execCommand *func(string, ...string) *exec.Cmd, names ...string) (*Recorder, func()) {
+	r := &Recorder{
+		names: make(map[string]bool),
+		stubs: make(map[string]func(args []string) *exec.Cmd),
+	}
+	for _, name := range names {
+		r.names[name] = true
+	}
+
+	original := *execCommand
+	*execCommand = func(name string, args ...string) *exec.Cmd {
+		call := Call{Name: name, Args: StripLXCPath(args)}
+		if len(call.Args) < len(args) {
+			call.LXCPath = args[1]
+		}
+
+		r.mu.Lock()
+		if len(r.names) == 0 || r.names[name] {
+			r.calls = append(r.calls, call)
+		}
+		stub := r.stubs[name]
+		r.mu.Unlock()
+
+		if stub != nil {
+			if cmd := stub(call.Args); cmd != nil {
+				return cmd
+			}
+		}
+		return original(name, args...)
+	}
+
+	return r, func() { *execCommand = original }
+}
+
+// Stub runs the command fn returns in place of name. fn gets the arguments
+// without -P <lxcpath> and can return nil to run the wrapped ExecCommand.
+// A nil fn removes the stub.
+func (r *Recorder) Stub(name string, fn func(args []string) *exec.Cmd) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stubs[name] = fn
+}
+
+// Calls returns the recorded calls in the order they were made
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Args returns the arguments of each recorded call, joined by spaces
+func (r *Recorder) Args() []string {
+	calls := r.Calls()
+	args := make([]string, len(calls))
+	for i, call := range calls {
+		args[i] = call.String()
+	}
+	return args
+}
+
+// Commands returns each recorded call as its name followed by its arguments
+func (r *Recorder) Commands() []string {
+	calls := r.Calls()
+	commands := make([]string, len(calls))
+	for i, call := range calls {
+		commands[i] = strings.Join(append([]string{call.Name}, call.Args...), " ")
+	}
+	return commands
+}
+
+// Names returns the command name of each recorded call
+func (r *Recorder) Names() []string {
+	calls := r.Calls()
+	names := make([]string, len(calls))
+	for i, call := range calls {
+		names[i] = call.Name
+	}
+	return names
+}
+
+// Reset forgets the calls recorded so far
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
@@ -96,3 +96,46 @@ func WriteFile(t *testing.T, dir, name, content string) string {
 func Contains(s, substr string) bool {
 	return s != "" && substr != "" && strings.Contains(s, substr)
 }
+
+// configValues parses the "key = value" lines of an LXC config, keeping every
+// value of keys set more than once
+func configValues(content string) map[string][]string {
+	values := make(map[string][]string)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		values[key] = append(values[key], strings.TrimSpace(value))
+	}
+	return values
+}
+
+// AssertConfigKey fails the test unless the LXC config content sets key to
+// value. Keys set more than once, like hooks, match if any of their values do.
+func AssertConfigKey(t *testing.T, content, key, value string) {
+	t.Helper()
+	values, ok := configValues(content)[key]
+	if !ok {
+		t.Fatalf("expected %s = %s, but %s is not set", key, value, key)
+	}
+	for _, v := range values {
+		if v == value {
+			return
+		}
+	}
+	t.Fatalf("expected %s = %s, got %s", key, value, strings.Join(values, ", "))
+}
+
+// AssertConfigKeyAbsent fails the test if the LXC config content sets key
+func AssertConfigKeyAbsent(t *testing.T, content, key string) {
+	t.Helper()
+	if values, ok := configValues(content)[key]; ok {
+		t.Fatalf("expected %s to not be set, got %s", key, strings.Join(values, ", "))
+	}
+}
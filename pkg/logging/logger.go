@@ -14,12 +14,25 @@ var (
 	Level = zap.NewAtomicLevel()
 )
 
+// TraceLevel is below debug, for output too detailed for debugging, such as
+// the full output of every command run
+const TraceLevel = zapcore.DebugLevel - 1
+
+// Output formats accepted by Config.Format
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
 // Config holds logging configuration
 type Config struct {
 	// Level is the minimum enabled logging level
 	Level string
 	// Development puts the logger in development mode
 	Development bool
+	// Format is FormatJSON or FormatConsole. Empty picks console in
+	// development mode and JSON otherwise.
+	Format string
 	// DisableCaller stops annotating logs with the calling function's file name and line number
 	DisableCaller bool
 }
@@ -28,6 +41,8 @@ type Config struct {
 func Init(cfg Config) error {
 	// Set log level
 	switch cfg.Level {
+	case "trace":
+		Level.SetLevel(TraceLevel)
 	case "debug":
 		Level.SetLevel(zapcore.DebugLevel)
 	case "info":
@@ -40,6 +55,17 @@ func Init(cfg Config) error {
 		return fmt.Errorf("invalid log level: %s", cfg.Level)
 	}
 
+	format := cfg.Format
+	if format == "" {
+		format = FormatJSON
+		if cfg.Development {
+			format = FormatConsole
+		}
+	}
+	if format != FormatJSON && format != FormatConsole {
+		return fmt.Errorf("invalid log format: %s (must be json or console)", format)
+	}
+
 	// Configure encoder
 	encoderConfig := zap.NewProductionEncoderConfig()
 	if cfg.Development {
@@ -47,22 +73,22 @@ func Init(cfg Config) error {
 	}
 	encoderConfig.TimeKey = "timestamp"
 	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	if format == FormatConsole {
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+	encoderConfig.EncodeLevel = traceLevelEncoder(encoderConfig.EncodeLevel, format == FormatConsole)
 
 	// Create logger configuration
 	config := zap.Config{
 		Level:            Level,
 		Development:      cfg.Development,
 		DisableCaller:    cfg.DisableCaller,
-		Encoding:         "json",
+		Encoding:         format,
 		EncoderConfig:    encoderConfig,
 		OutputPaths:      []string{"stdout"},
 		ErrorOutputPaths: []string{"stderr"},
 	}
 
-	if cfg.Development {
-		config.Encoding = "console"
-	}
-
 	// Build the logger
 	logger, err := config.Build()
 	if err != nil {
@@ -73,6 +99,26 @@ func Init(cfg Config) error {
 	return nil
 }
 
+// traceLevelEncoder names TraceLevel, which zap only knows as a number, and
+// leaves the other levels to encode
+func traceLevelEncoder(encode zapcore.LevelEncoder, capital bool) zapcore.LevelEncoder {
+	return func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		switch {
+		case l != TraceLevel:
+			encode(l, enc)
+		case capital:
+			enc.AppendString("TRACE")
+		default:
+			enc.AppendString("trace")
+		}
+	}
+}
+
+// Trace logs a trace message
+func Trace(msg string, keysAndValues ...interface{}) {
+	log.Logw(TraceLevel, msg, keysAndValues...)
+}
+
 // Debug logs a debug message
 func Debug(msg string, keysAndValues ...interface{}) {
 	log.Debugw(msg, keysAndValues...)
@@ -0,0 +1,107 @@
+// Package metrics exposes container statistics in the Prometheus text format
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+)
+
+// containerStates lists the states reported by the lxc_container_state gauge
+var containerStates = []string{"RUNNING", "STOPPED", "FROZEN"}
+
+// StatsSource provides the container information sampled by the exporter.
+// LXCManager satisfies this interface.
+type StatsSource interface {
+	List() ([]container.Container, error)
+	GetCPUStats(name string) (*container.CPUStats, error)
+	GetMemoryStats(name string) (*container.MemoryStats, error)
+}
+
+// Exporter samples container statistics and serves them over HTTP
+type Exporter struct {
+	source StatsSource
+}
+
+// NewExporter creates a new metrics exporter for the given source
+func NewExporter(source StatsSource) *Exporter {
+	return &Exporter{source: source}
+}
+
+// ServeHTTP implements http.Handler
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	var buf bytes.Buffer
+	if err := e.Collect(&buf); err != nil {
+		logging.Error("Failed to collect metrics", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		logging.Error("Failed to write metrics response", "error", err)
+	}
+}
+
+// Collect samples all containers and writes the metrics to buf
+func (e *Exporter) Collect(buf *bytes.Buffer) error {
+	containers, err := e.source.List()
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].Name < containers[j].Name
+	})
+
+	writeHeader(buf, "lxc_container_state", "gauge", "Current state of the container (1 for the active state)")
+	for _, c := range containers {
+		for _, state := range containerStates {
+			value := 0
+			if strings.EqualFold(c.State, state) {
+				value = 1
+			}
+			fmt.Fprintf(buf, "lxc_container_state{name=%q,state=%q} %d\n", c.Name, state, value)
+		}
+	}
+
+	// Resource usage is only available for running or frozen containers
+	var active []container.Container
+	for _, c := range containers {
+		if c.State == "RUNNING" || c.State == "FROZEN" {
+			active = append(active, c)
+		}
+	}
+
+	writeHeader(buf, "lxc_container_cpu_usage", "counter", "Cumulative CPU time consumed in seconds")
+	for _, c := range active {
+		stats, err := e.source.GetCPUStats(c.Name)
+		if err != nil {
+			logging.Warn("Failed to get CPU stats", "container", c.Name, "error", err)
+			continue
+		}
+		fmt.Fprintf(buf, "lxc_container_cpu_usage{name=%q} %g\n", c.Name, stats.UsageSeconds)
+	}
+
+	writeHeader(buf, "lxc_container_memory_bytes", "gauge", "Current memory usage in bytes")
+	for _, c := range active {
+		stats, err := e.source.GetMemoryStats(c.Name)
+		if err != nil {
+			logging.Warn("Failed to get memory stats", "container", c.Name, "error", err)
+			continue
+		}
+		fmt.Fprintf(buf, "lxc_container_memory_bytes{name=%q} %d\n", c.Name, stats.UsageBytes)
+	}
+
+	return nil
+}
+
+func writeHeader(buf *bytes.Buffer, name, metricType, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, metricType)
+}
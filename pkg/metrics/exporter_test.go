@@ -0,0 +1,93 @@
+package metrics_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/container"
+	testing_internal "github.com/larkinwc/proxmox-lxc-compose/pkg/internal/testing"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/metrics"
+)
+
+func init() {
+	// Initialize logger for tests
+	if err := logging.Init(logging.Config{
+		Level:       "debug",
+		Development: true,
+	}); err != nil {
+		panic("Failed to initialize logger for tests: " + err.Error())
+	}
+}
+
+type fakeSource struct {
+	containers []container.Container
+	cpu        map[string]*container.CPUStats
+	memory     map[string]*container.MemoryStats
+}
+
+func (f *fakeSource) List() ([]container.Container, error) {
+	return f.containers, nil
+}
+
+func (f *fakeSource) GetCPUStats(name string) (*container.CPUStats, error) {
+	if stats, ok := f.cpu[name]; ok {
+		return stats, nil
+	}
+	return nil, fmt.Errorf("container %s is not running", name)
+}
+
+func (f *fakeSource) GetMemoryStats(name string) (*container.MemoryStats, error) {
+	if stats, ok := f.memory[name]; ok {
+		return stats, nil
+	}
+	return nil, fmt.Errorf("container %s is not running", name)
+}
+
+func TestExporter(t *testing.T) {
+	source := &fakeSource{
+		containers: []container.Container{
+			{Name: "web", State: "RUNNING"},
+			{Name: "db", State: "STOPPED"},
+		},
+		cpu: map[string]*container.CPUStats{
+			"web": {UsageSeconds: 12.5},
+		},
+		memory: map[string]*container.MemoryStats{
+			"web": {UsageBytes: 104857600},
+		},
+	}
+
+	server := httptest.NewServer(metrics.NewExporter(source))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	testing_internal.AssertNoError(t, err)
+	defer resp.Body.Close()
+
+	testing_internal.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	testing_internal.AssertNoError(t, err)
+	content := string(body)
+
+	t.Run("state_gauge", func(t *testing.T) {
+		testing_internal.AssertContains(t, content, "# TYPE lxc_container_state gauge")
+		testing_internal.AssertContains(t, content, `lxc_container_state{name="web",state="RUNNING"} 1`)
+		testing_internal.AssertContains(t, content, `lxc_container_state{name="web",state="STOPPED"} 0`)
+		testing_internal.AssertContains(t, content, `lxc_container_state{name="db",state="STOPPED"} 1`)
+	})
+
+	t.Run("resource_usage", func(t *testing.T) {
+		testing_internal.AssertContains(t, content, `lxc_container_cpu_usage{name="web"} 12.5`)
+		testing_internal.AssertContains(t, content, `lxc_container_memory_bytes{name="web"} 104857600`)
+	})
+
+	t.Run("stopped_containers_not_sampled", func(t *testing.T) {
+		testing_internal.AssertNotContains(t, content, `lxc_container_cpu_usage{name="db"}`)
+		testing_internal.AssertNotContains(t, content, `lxc_container_memory_bytes{name="db"}`)
+	})
+}
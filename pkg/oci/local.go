@@ -1,6 +1,8 @@
 package oci
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -8,11 +10,14 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
 )
 
 type ImageMetadata struct {
 	ImageReference
-	StoredAt int64 `json:"stored_at"`
+	StoredAt int64  `json:"stored_at"`
+	Source   string `json:"source,omitempty"` // Container the image was committed from
 }
 
 type cachedImage struct {
@@ -33,11 +38,13 @@ func NewLocalImageStore(rootDir string) (*LocalImageStore, error) {
 	if err := os.MkdirAll(rootDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create image store directory: %w", err)
 	}
-	return &LocalImageStore{
+	store := &LocalImageStore{
 		rootDir: rootDir,
 		cache:   make(map[string]*cachedImage),
 		ttl:     86400, // 24 hours default TTL
-	}, nil
+	}
+	store.migrateLegacyLayout()
+	return store, nil
 }
 
 // Get retrieves an image from local storage
@@ -65,7 +72,7 @@ func (s *LocalImageStore) Get(ref ImageReference) ([]byte, error) {
 	}
 
 	// Read from disk
-	path := s.getImagePath(ref)
+	path := s.findImagePath(ref)
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -85,8 +92,27 @@ func (s *LocalImageStore) Get(ref ImageReference) ([]byte, error) {
 	return data, nil
 }
 
+// Has reports whether an image is in local storage
+func (s *LocalImageStore) Has(ref ImageReference) bool {
+	if s == nil || validateReference(ref) != nil {
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, err := os.Stat(s.findImagePath(ref))
+	return err == nil
+}
+
 // Store stores an image in local storage
 func (s *LocalImageStore) Store(ref ImageReference, data []byte) error {
+	return s.StoreFrom(ref, data, "")
+}
+
+// StoreFrom stores an image like Store, recording the container it was
+// committed from
+func (s *LocalImageStore) StoreFrom(ref ImageReference, data []byte, source string) error {
 	if s == nil {
 		return fmt.Errorf("store is nil")
 	}
@@ -105,11 +131,13 @@ func (s *LocalImageStore) Store(ref ImageReference, data []byte) error {
 	if err := os.WriteFile(path, data, 0644); err != nil {
 		return fmt.Errorf("failed to write image file: %w", err)
 	}
+	_ = os.Remove(s.legacyImagePath(ref)) // Superseded by the file just written
 
 	// Update metadata
 	metadata := ImageMetadata{
 		ImageReference: ref,
 		StoredAt:       time.Now().Unix(),
+		Source:         source,
 	}
 	if err := s.updateMetadata(metadata); err != nil {
 		_ = os.Remove(path) // Clean up image file if metadata update fails
@@ -150,6 +178,39 @@ func (s *LocalImageStore) List() ([]ImageReference, error) {
 	return refs, nil
 }
 
+// ListInfo returns all stored images along with their size and storage time
+func (s *LocalImageStore) ListInfo() ([]ImageInfo, error) {
+	if s == nil {
+		return nil, fmt.Errorf("store is nil")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	metadataList, err := s.readMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	var infos []ImageInfo
+	for _, metadata := range metadataList {
+		if s.ttl > 0 && time.Now().Unix()-metadata.StoredAt >= s.ttl {
+			continue
+		}
+
+		info := ImageInfo{
+			ImageReference: metadata.ImageReference,
+			StoredAt:       time.Unix(metadata.StoredAt, 0),
+			Source:         metadata.Source,
+		}
+		if fi, err := os.Stat(s.findImagePath(metadata.ImageReference)); err == nil {
+			info.Size = fi.Size()
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
 func (s *LocalImageStore) readMetadata() ([]ImageMetadata, error) {
 	metadataPath := filepath.Join(s.rootDir, "metadata.json")
 	data, err := os.ReadFile(metadataPath)
@@ -232,9 +293,8 @@ func (s *LocalImageStore) Remove(ref ImageReference) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	path := s.getImagePath(ref)
-	if err := os.Remove(path); err != nil {
-		if !os.IsNotExist(err) {
+	for _, path := range []string{s.getImagePath(ref), s.legacyImagePath(ref)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 			return fmt.Errorf("failed to remove image file: %w", err)
 		}
 	}
@@ -287,12 +347,75 @@ func (s *LocalImageStore) CleanExpiredImages() error {
 	return lastErr
 }
 
-// getImagePath returns the full path for an image
+// getImagePath returns the full path for an image. Images are grouped in
+// registry/repository directories for browsing, but named by a hash of the
+// full reference, so distinct references never share a file.
 func (s *LocalImageStore) getImagePath(ref ImageReference) string {
+	sum := sha256.Sum256([]byte(ref.String()))
+	elems := []string{s.rootDir, safePathElem(ref.Registry)}
+	for _, elem := range strings.Split(ref.Repository, "/") {
+		elems = append(elems, safePathElem(elem))
+	}
+	elems = append(elems, hex.EncodeToString(sum[:])+".tar")
+	return filepath.Join(elems...)
+}
+
+// legacyImagePath returns where images were stored before the hashed layout,
+// which could collide, e.g. for a/b:c and a_b:c
+func (s *LocalImageStore) legacyImagePath(ref ImageReference) string {
 	filename := strings.ReplaceAll(ref.String(), "/", "_") + ".tar"
 	return filepath.Join(s.rootDir, filename)
 }
 
+// findImagePath returns the path an image is stored at, falling back to the
+// legacy layout for images not migrated yet
+func (s *LocalImageStore) findImagePath(ref ImageReference) string {
+	path := s.getImagePath(ref)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(s.legacyImagePath(ref)); err == nil {
+			return s.legacyImagePath(ref)
+		}
+	}
+	return path
+}
+
+// migrateLegacyLayout moves images stored under the legacy flat layout to
+// the hashed layout. Failures are only logged; findImagePath keeps reading
+// unmigrated images, and the next store retries them.
+func (s *LocalImageStore) migrateLegacyLayout() {
+	metadataList, err := s.readMetadata()
+	if err != nil {
+		return
+	}
+	for _, metadata := range metadataList {
+		ref := metadata.ImageReference
+		legacy, path := s.legacyImagePath(ref), s.getImagePath(ref)
+		if _, err := os.Stat(legacy); err != nil {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			logging.Warn("Failed to migrate cached image", "image", ref.String(), "error", err)
+			continue
+		}
+		if err := os.Rename(legacy, path); err != nil {
+			logging.Warn("Failed to migrate cached image", "image", ref.String(), "error", err)
+			continue
+		}
+		logging.Debug("Migrated cached image", "image", ref.String(), "path", path)
+	}
+}
+
+// safePathElem makes a reference component usable as a directory name
+func safePathElem(elem string) string {
+	if elem == "" || elem == "." || elem == ".." {
+		return "_"
+	}
+	return strings.ReplaceAll(elem, ":", "_")
+}
+
 func validateReference(ref ImageReference) error {
 	if ref.Registry == "" || ref.Repository == "" || ref.Tag == "" {
 		return fmt.Errorf("invalid image reference: all fields must be non-empty")
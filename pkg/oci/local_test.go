@@ -59,6 +59,21 @@ func TestLocalImageStore(t *testing.T) {
 			t.Errorf("expected registry %s, got %s", testRef.Registry, images[0].Registry)
 		}
 
+		// Test listing image details
+		infos, err := store.ListInfo()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != 1 {
+			t.Fatalf("expected 1 image, got %d", len(infos))
+		}
+		if infos[0].Size != int64(len(testData)) {
+			t.Errorf("expected size %d, got %d", len(testData), infos[0].Size)
+		}
+		if infos[0].StoredAt.IsZero() {
+			t.Error("expected stored time to be set")
+		}
+
 		// Test removing an image
 		if err := store.Remove(testRef); err != nil {
 			t.Fatal(err)
@@ -178,3 +193,136 @@ func TestImageCaching(t *testing.T) {
 		t.Errorf("expected 0 images after TTL expiry, got %d", len(images))
 	}
 }
+
+func TestLocalImageStoreLayout(t *testing.T) {
+	err := logging.Init(logging.Config{
+		Level:       "debug",
+		Development: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slashRef := ImageReference{Registry: "localhost:5000", Repository: "a/b", Tag: "c"}
+	underscoreRef := ImageReference{Registry: "localhost:5000", Repository: "a_b", Tag: "c"}
+
+	t.Run("no_collisions", func(t *testing.T) {
+		store, err := NewLocalImageStore(filepath.Join(t.TempDir(), "images"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.SetCacheTTL(0) // Read from disk
+
+		if err := store.Store(slashRef, []byte("slash")); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Store(underscoreRef, []byte("underscore")); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := store.Get(slashRef)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "slash" {
+			t.Errorf("expected slash image data, got %q", data)
+		}
+		if dir := filepath.Dir(store.getImagePath(slashRef)); dir != filepath.Join(store.rootDir, "localhost_5000", "a", "b") {
+			t.Errorf("unexpected image directory %s", dir)
+		}
+	})
+
+	t.Run("migrates_legacy_files", func(t *testing.T) {
+		rootDir := filepath.Join(t.TempDir(), "images")
+		legacy := &LocalImageStore{rootDir: rootDir}
+		if err := os.MkdirAll(rootDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(legacy.legacyImagePath(slashRef), []byte("legacy"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := legacy.updateMetadata(ImageMetadata{ImageReference: slashRef, StoredAt: time.Now().Unix()}); err != nil {
+			t.Fatal(err)
+		}
+
+		store, err := NewLocalImageStore(rootDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(legacy.legacyImagePath(slashRef)); !os.IsNotExist(err) {
+			t.Error("expected legacy image file to be moved")
+		}
+		data, err := store.Get(slashRef)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "legacy" {
+			t.Errorf("expected migrated image data, got %q", data)
+		}
+	})
+
+	t.Run("reads_unmigrated_files", func(t *testing.T) {
+		store, err := NewLocalImageStore(filepath.Join(t.TempDir(), "images"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(store.legacyImagePath(slashRef), []byte("legacy"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.updateMetadata(ImageMetadata{ImageReference: slashRef, StoredAt: time.Now().Unix()}); err != nil {
+			t.Fatal(err)
+		}
+
+		refs, err := store.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(refs) != 1 || refs[0] != slashRef {
+			t.Errorf("expected [%s], got %v", slashRef, refs)
+		}
+		infos, err := store.ListInfo()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != 1 || infos[0].Size != int64(len("legacy")) {
+			t.Errorf("expected legacy image size %d, got %+v", len("legacy"), infos)
+		}
+		if _, err := store.Get(slashRef); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestLocalImageStoreSource(t *testing.T) {
+	store, err := NewLocalImageStore(filepath.Join(t.TempDir(), "images"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pulled := ImageReference{Registry: "docker.io", Repository: "library/alpine", Tag: "latest"}
+	committed := ImageReference{Registry: "docker.io", Repository: "library/web", Tag: "snapshot"}
+	if store.Has(committed) {
+		t.Fatal("expected empty store")
+	}
+	if err := store.Store(pulled, []byte("pulled")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreFrom(committed, []byte("committed"), "web"); err != nil {
+		t.Fatal(err)
+	}
+	if !store.Has(committed) {
+		t.Error("expected committed image to be stored")
+	}
+
+	infos, err := store.ListInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := map[string]string{}
+	for _, info := range infos {
+		sources[info.String()] = info.Source
+	}
+	if sources[pulled.String()] != "" || sources[committed.String()] != "web" {
+		t.Errorf("unexpected image sources %v", sources)
+	}
+}
@@ -46,10 +46,10 @@ func ParseImageReference(ref string) (ImageReference, error) {
 	// Handle the main part (everything before @digest if present)
 	mainPart := parts[0]
 
-	// Split tag if present
-	parts = strings.SplitN(mainPart, ":", 2)
-	if len(parts) == 2 {
-		tag = parts[1]
+	// Split tag if present, a colon before the last slash is a registry port
+	name := mainPart
+	if i := strings.LastIndex(mainPart, ":"); i > strings.LastIndex(mainPart, "/") {
+		name, tag = mainPart[:i], mainPart[i+1:]
 		if !tagRegex.MatchString(tag) {
 			return ImageReference{}, fmt.Errorf("invalid tag format: %s", tag)
 		}
@@ -58,7 +58,7 @@ func ParseImageReference(ref string) (ImageReference, error) {
 	}
 
 	// Handle registry and repository
-	parts = strings.Split(parts[0], "/")
+	parts = strings.Split(name, "/")
 	switch len(parts) {
 	case 1:
 		registry = "registry.hub.docker.com"
@@ -36,6 +36,24 @@ func TestParseImageReference(t *testing.T) {
 				Tag:        "1.0",
 			},
 		},
+		{
+			name:  "registry with port",
+			input: "localhost:5000/app:1.0",
+			want: ImageReference{
+				Registry:   "localhost:5000",
+				Repository: "app",
+				Tag:        "1.0",
+			},
+		},
+		{
+			name:  "registry with port without tag",
+			input: "registry:5000/org/app",
+			want: ImageReference{
+				Registry:   "registry:5000",
+				Repository: "org/app",
+				Tag:        "latest",
+			},
+		},
 		{
 			name:  "reference with digest",
 			input: "ubuntu@sha256:1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef",
@@ -51,6 +69,16 @@ func TestParseImageReference(t *testing.T) {
 			input:   "ubuntu:invalid@tag",
 			wantErr: true,
 		},
+		{
+			name:    "empty tag",
+			input:   "ubuntu::",
+			wantErr: true,
+		},
+		{
+			name:    "trailing slash",
+			input:   "org/app/",
+			wantErr: true,
+		},
 		{
 			name:    "invalid digest",
 			input:   "ubuntu@invalid-digest",
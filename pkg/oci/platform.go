@@ -0,0 +1,74 @@
+package oci
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+var platformPartRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Platform identifies the os/architecture variant of an image in a multi-arch manifest list
+type Platform struct {
+	OS           string
+	Architecture string
+	Variant      string
+}
+
+// String returns the platform in os/arch[/variant] form
+func (p Platform) String() string {
+	if p.OS == "" && p.Architecture == "" {
+		return ""
+	}
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// IsZero reports whether no platform has been set
+func (p Platform) IsZero() bool {
+	return p == Platform{}
+}
+
+// DefaultPlatform returns the platform of the host
+func DefaultPlatform() Platform {
+	p := Platform{
+		OS:           runtime.GOOS,
+		Architecture: runtime.GOARCH,
+	}
+	// Registries publish 32-bit arm images with an explicit variant
+	if p.Architecture == "arm" {
+		p.Variant = "v7"
+	}
+	return p
+}
+
+// ParsePlatform parses a platform string in os/arch[/variant] form
+func ParsePlatform(platform string) (Platform, error) {
+	if platform == "" {
+		return Platform{}, fmt.Errorf("empty platform")
+	}
+
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Platform{}, fmt.Errorf("invalid platform format: %s (expected os/arch[/variant])", platform)
+	}
+
+	for _, part := range parts {
+		if !platformPartRegex.MatchString(part) {
+			return Platform{}, fmt.Errorf("invalid platform format: %s (expected os/arch[/variant])", platform)
+		}
+	}
+
+	p := Platform{
+		OS:           parts[0],
+		Architecture: parts[1],
+	}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, nil
+}
@@ -0,0 +1,74 @@
+package oci
+
+import "testing"
+
+func TestParsePlatform(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Platform
+		wantErr bool
+	}{
+		{
+			name:  "os and arch",
+			input: "linux/amd64",
+			want:  Platform{OS: "linux", Architecture: "amd64"},
+		},
+		{
+			name:  "with variant",
+			input: "linux/arm/v7",
+			want:  Platform{OS: "linux", Architecture: "arm", Variant: "v7"},
+		},
+		{
+			name:    "empty",
+			input:   "",
+			wantErr: true,
+		},
+		{
+			name:    "missing arch",
+			input:   "linux",
+			wantErr: true,
+		},
+		{
+			name:    "too many parts",
+			input:   "linux/arm/v7/extra",
+			wantErr: true,
+		},
+		{
+			name:    "empty part",
+			input:   "linux//v7",
+			wantErr: true,
+		},
+		{
+			name:    "uppercase",
+			input:   "Linux/AMD64",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePlatform(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePlatform() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParsePlatform() = %v, want %v", got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.input {
+				t.Errorf("Platform.String() = %v, want %v", got.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestDefaultPlatform(t *testing.T) {
+	p := DefaultPlatform()
+	if p.OS == "" || p.Architecture == "" {
+		t.Errorf("DefaultPlatform() = %v, want os and architecture set", p)
+	}
+	if _, err := ParsePlatform(p.String()); err != nil {
+		t.Errorf("DefaultPlatform() produced invalid platform: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package oci
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LayerProgress reports the state of one image layer during a pull. Current
+// and Total are only set when docker reports byte counts, which it does when
+// attached to a terminal.
+type LayerProgress struct {
+	Layer   string
+	Status  string
+	Current int64
+	Total   int64
+}
+
+// PullProgress is called for every layer status docker reports during a pull
+type PullProgress func(LayerProgress)
+
+// Layer statuses reported by docker pull
+const (
+	LayerPullingFSLayer   = "Pulling fs layer"
+	LayerWaiting          = "Waiting"
+	LayerDownloading      = "Downloading"
+	LayerDownloadComplete = "Download complete"
+	LayerExtracting       = "Extracting"
+	LayerPullComplete     = "Pull complete"
+	LayerAlreadyExists    = "Already exists"
+)
+
+// parsePullLine parses a docker pull status line such as
+// "a1b2c3d4e5f6: Downloading [==>    ]  1.2MB/3.4MB"
+func parsePullLine(line string) (LayerProgress, bool) {
+	layer, rest, ok := strings.Cut(strings.TrimSpace(line), ": ")
+	if !ok || !isLayerID(layer) {
+		return LayerProgress{}, false
+	}
+
+	p := LayerProgress{Layer: layer, Status: strings.TrimSpace(rest)}
+	if i := strings.Index(rest, "["); i >= 0 {
+		p.Status = strings.TrimSpace(rest[:i])
+		if j := strings.Index(rest, "]"); j > i {
+			p.Current, p.Total = parseByteCounts(strings.TrimSpace(rest[j+1:]))
+		}
+	}
+	return p, true
+}
+
+// isLayerID reports whether s looks like a short layer digest
+func isLayerID(s string) bool {
+	if len(s) < 12 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseByteCounts parses a "1.2MB/3.4MB" progress counter
+func parseByteCounts(s string) (int64, int64) {
+	current, total, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0
+	}
+	c, ok1 := parseDockerSize(current)
+	t, ok2 := parseDockerSize(total)
+	if !ok1 || !ok2 {
+		return 0, 0
+	}
+	return c, t
+}
+
+// parseDockerSize parses the decimal sizes docker prints, e.g. "512B" or "1.5MB"
+func parseDockerSize(s string) (int64, bool) {
+	units := []struct {
+		suffix string
+		scale  float64
+	}{
+		{"GB", 1e9}, {"MB", 1e6}, {"kB", 1e3}, {"B", 1},
+	}
+	for _, u := range units {
+		if value, ok := strings.CutSuffix(s, u.suffix); ok {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil || f < 0 {
+				return 0, false
+			}
+			return int64(f * u.scale), true
+		}
+	}
+	return 0, false
+}
+
+// ansiEscape matches the cursor movements docker emits on terminals
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// pullProgressWriter splits docker pull output into lines, treating the
+// carriage returns docker uses to redraw progress bars as line breaks
+type pullProgressWriter struct {
+	buf      bytes.Buffer
+	progress PullProgress
+}
+
+func (w *pullProgressWriter) Write(b []byte) (int, error) {
+	w.buf.Write(b)
+	for {
+		data := w.buf.Bytes()
+		i := bytes.IndexAny(data, "\r\n")
+		if i < 0 {
+			break
+		}
+		line := string(data[:i])
+		w.buf.Next(i + 1)
+		w.emit(line)
+	}
+	return len(b), nil
+}
+
+// Flush reports a final line that wasn't newline-terminated
+func (w *pullProgressWriter) Flush() {
+	if w.buf.Len() > 0 {
+		w.emit(w.buf.String())
+		w.buf.Reset()
+	}
+}
+
+func (w *pullProgressWriter) emit(line string) {
+	if p, ok := parsePullLine(ansiEscape.ReplaceAllString(line, "")); ok {
+		w.progress(p)
+	}
+}
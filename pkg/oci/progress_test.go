@@ -0,0 +1,83 @@
+package oci
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParsePullLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want LayerProgress
+		ok   bool
+	}{
+		{
+			name: "status only",
+			line: "a1b2c3d4e5f6: Pulling fs layer",
+			want: LayerProgress{Layer: "a1b2c3d4e5f6", Status: LayerPullingFSLayer},
+			ok:   true,
+		},
+		{
+			name: "download with byte counts",
+			line: "a1b2c3d4e5f6: Downloading [=====>      ]  1.5MB/3MB",
+			want: LayerProgress{Layer: "a1b2c3d4e5f6", Status: LayerDownloading, Current: 1500000, Total: 3000000},
+			ok:   true,
+		},
+		{
+			name: "small sizes",
+			line: "a1b2c3d4e5f6: Extracting [==>     ]  512B/1.2kB",
+			want: LayerProgress{Layer: "a1b2c3d4e5f6", Status: LayerExtracting, Current: 512, Total: 1200},
+			ok:   true,
+		},
+		{name: "tag line", line: "latest: Pulling from library/alpine"},
+		{name: "digest line", line: "Digest: sha256:abcdef"},
+		{name: "empty", line: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parsePullLine(tt.line)
+			if ok != tt.ok {
+				t.Fatalf("parsePullLine(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			}
+			if ok && got != tt.want {
+				t.Errorf("parsePullLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPullWithProgress(t *testing.T) {
+	manager, mockCmd, _, cleanup := setupRegistryTest(t)
+	defer cleanup()
+
+	output := "latest: Pulling from library/alpine\n" +
+		"a1b2c3d4e5f6: Pulling fs layer\r" +
+		"\x1b[1Aa1b2c3d4e5f6: Downloading [=>   ]  1MB/2MB\r" +
+		"a1b2c3d4e5f6: Pull complete\n" +
+		"Status: Downloaded newer image for alpine:latest"
+	mockCmd.AddMockCommand("docker pull --platform "+DefaultPlatform().String()+" docker.io/library/alpine:latest", []byte(output))
+
+	var got []LayerProgress
+	ref := ImageReference{Registry: "docker.io", Repository: "library/alpine", Tag: "latest"}
+	if err := manager.PullWithProgress(context.Background(), ref, func(p LayerProgress) {
+		got = append(got, p)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []LayerProgress{
+		{Layer: "a1b2c3d4e5f6", Status: LayerPullingFSLayer},
+		{Layer: "a1b2c3d4e5f6", Status: LayerDownloading, Current: 1000000, Total: 2000000},
+		{Layer: "a1b2c3d4e5f6", Status: LayerPullComplete},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d progress updates, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("update %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
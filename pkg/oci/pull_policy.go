@@ -0,0 +1,201 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/errors"
+)
+
+// PullPolicy controls when an image is pulled before a container is created
+type PullPolicy string
+
+// Supported pull policies
+const (
+	PullAlways  PullPolicy = "always"  // Pull before every create
+	PullMissing PullPolicy = "missing" // Pull only if not in the local store
+	PullNever   PullPolicy = "never"   // Only use the local store
+)
+
+// ParsePullPolicy parses a pull policy name, defaulting to PullMissing
+func ParsePullPolicy(policy string) (PullPolicy, error) {
+	switch p := PullPolicy(strings.ToLower(policy)); p {
+	case "":
+		return PullMissing, nil
+	case PullAlways, PullMissing, PullNever:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unsupported pull policy %q (supported: always, missing, never)", policy)
+	}
+}
+
+// Ensure makes an image available in the local store according to policy
+// and returns it
+func (m *RegistryManager) Ensure(ctx context.Context, ref ImageReference, policy PullPolicy) ([]byte, error) {
+	if err := m.ensurePulled(ctx, ref, policy); err != nil {
+		return nil, err
+	}
+	return m.stored(ref)
+}
+
+// ensurePulled pulls an image into the local store if policy calls for it
+func (m *RegistryManager) ensurePulled(ctx context.Context, ref ImageReference, policy PullPolicy) error {
+	switch policy {
+	case PullAlways:
+		return m.Pull(ctx, ref)
+	case PullMissing, "":
+		if !m.store.Has(ref) {
+			return m.Pull(ctx, ref)
+		}
+		return nil
+	case PullNever:
+		if !m.store.Has(ref) {
+			return errors.New(errors.ErrImage,
+				fmt.Sprintf("image %s is not in the local store and the pull policy is never", formatDockerRef(ref)))
+		}
+		return nil
+	default:
+		return errors.New(errors.ErrValidation, fmt.Sprintf("unsupported pull policy %q", policy))
+	}
+}
+
+// stored returns an image from the local store
+func (m *RegistryManager) stored(ref ImageReference) ([]byte, error) {
+	data, err := m.store.Get(ref)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrStorage, "failed to retrieve image from cache")
+	}
+	return data, nil
+}
+
+// ImageFetcher looks up images by name for container creation, pulling them
+// as its policy requires, and stores images committed from containers. Each
+// reference is pulled at most once per pass, however many containers use it.
+// A pass lasts until Forget is called, and a failed pull isn't remembered, so
+// the next lookup of that reference tries again.
+type ImageFetcher struct {
+	registry *RegistryManager
+	policy   PullPolicy
+
+	mu    sync.Mutex
+	pulls map[string]*imagePull
+}
+
+// imagePull is a pull of one reference, shared by everyone waiting for it
+type imagePull struct {
+	done chan struct{}
+	err  error
+}
+
+// NewImageFetcher creates an image fetcher backed by registry
+func NewImageFetcher(registry *RegistryManager, policy PullPolicy) *ImageFetcher {
+	return &ImageFetcher{registry: registry, policy: policy, pulls: make(map[string]*imagePull)}
+}
+
+// Image returns the docker save archive of the named image
+func (f *ImageFetcher) Image(ctx context.Context, image string) ([]byte, error) {
+	ref, err := ParseImageReference(image)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrValidation, "invalid image reference")
+	}
+	if err := f.ensurePulled(ctx, ref); err != nil {
+		return nil, err
+	}
+	return f.registry.stored(ref)
+}
+
+// Prefetch pulls the given images as the fetcher's policy requires, up to
+// parallel at once, so later Image calls find them in the local store.
+// Images are pulled once per reference, and a failed pull is reported to
+// everyone waiting for it.
+func (f *ImageFetcher) Prefetch(ctx context.Context, images []string, parallel int) error {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	seen := make(map[string]bool)
+	var refs []ImageReference
+	for _, image := range images {
+		ref, err := ParseImageReference(image)
+		if err != nil {
+			return errors.Wrap(err, errors.ErrValidation, fmt.Sprintf("invalid image reference %q", image))
+		}
+		if key := formatDockerRef(ref); !seen[key] {
+			seen[key] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	errs := make([]error, len(refs))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, ref := range refs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, ref ImageReference) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = f.ensurePulled(ctx, ref)
+		}(i, ref)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensurePulled pulls ref according to the fetcher's policy, or waits for the
+// pull already started for it
+func (f *ImageFetcher) ensurePulled(ctx context.Context, ref ImageReference) error {
+	key := formatDockerRef(ref)
+
+	f.mu.Lock()
+	pull, ok := f.pulls[key]
+	if !ok {
+		pull = &imagePull{done: make(chan struct{})}
+		f.pulls[key] = pull
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		pull.err = f.registry.ensurePulled(ctx, ref, f.policy)
+		if pull.err != nil {
+			f.mu.Lock()
+			if f.pulls[key] == pull {
+				delete(f.pulls, key)
+			}
+			f.mu.Unlock()
+		}
+		close(pull.done)
+		return pull.err
+	}
+
+	select {
+	case <-pull.done:
+		return pull.err
+	case <-ctx.Done():
+		return contextError(ctx, "waiting for image pull")
+	}
+}
+
+// Forget ends the current pass, so each reference is pulled again as the
+// fetcher's policy requires the next time it is used
+func (f *ImageFetcher) Forget() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pulls = make(map[string]*imagePull)
+}
+
+// Commit stores the docker save archive of a container committed as ref
+func (f *ImageFetcher) Commit(ref ImageReference, archive []byte, source string) error {
+	if err := f.registry.store.StoreFrom(ref, archive, source); err != nil {
+		return errors.Wrap(err, errors.ErrStorage, "failed to store image in cache")
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package oci
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/errors"
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/internal/mock"
+)
+
+func TestParsePullPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		want    PullPolicy
+		wantErr bool
+	}{
+		{policy: "", want: PullMissing},
+		{policy: "always", want: PullAlways},
+		{policy: "Missing", want: PullMissing},
+		{policy: "never", want: PullNever},
+		{policy: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePullPolicy(tt.policy)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePullPolicy(%q) error = %v, wantErr %v", tt.policy, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePullPolicy(%q) = %q, want %q", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	manager, mockCmd, _, cleanup := setupRegistryTest(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	ref := ImageReference{
+		Registry:   "docker.io",
+		Repository: "library/alpine",
+		Tag:        "latest",
+	}
+
+	t.Run("never_without_image", func(t *testing.T) {
+		_, err := manager.Ensure(ctx, ref, PullNever)
+		if !errors.IsType(err, errors.ErrImage) {
+			t.Fatalf("expected image error, got %v", err)
+		}
+	})
+
+	t.Run("missing_pulls_once", func(t *testing.T) {
+		data, err := manager.Ensure(ctx, ref, PullMissing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "mock image data" {
+			t.Errorf("unexpected image data %q", data)
+		}
+
+		// A newer image in the registry is ignored while one is stored
+		mockCmd.AddMockCommand("docker save docker.io/library/alpine:latest", []byte("updated image data"))
+		data, err = manager.Ensure(ctx, ref, PullMissing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "mock image data" {
+			t.Errorf("expected stored image, got %q", data)
+		}
+	})
+
+	t.Run("never_with_image", func(t *testing.T) {
+		if _, err := manager.Ensure(ctx, ref, PullNever); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("always_pulls", func(t *testing.T) {
+		data, err := manager.Ensure(ctx, ref, PullAlways)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "updated image data" {
+			t.Errorf("expected pulled image, got %q", data)
+		}
+	})
+}
+
+func TestImageFetcherPrefetch(t *testing.T) {
+	manager, mockCmd, _, cleanup := setupRegistryTest(t)
+	defer cleanup()
+	mockCmd.AddMockCommand("docker save registry.hub.docker.com/library/alpine:latest", []byte("mock image data"))
+
+	// Count pulls, which may run concurrently
+	calls, restore := mock.RecordCalls(&execCommand, "docker")
+	defer restore()
+	pulls := func() int {
+		n := 0
+		for _, call := range calls.Calls() {
+			if call.Args[0] == "pull" {
+				n++
+			}
+		}
+		return n
+	}
+
+	ctx := context.Background()
+
+	t.Run("pulls_each_reference_once", func(t *testing.T) {
+		fetcher := NewImageFetcher(manager, PullAlways)
+
+		images := []string{"alpine", "library/alpine"}
+		for i := 0; i < 8; i++ {
+			images = append(images, "alpine:latest")
+		}
+		if err := fetcher.Prefetch(ctx, images, 4); err != nil {
+			t.Fatal(err)
+		}
+		if pulls() != 1 {
+			t.Fatalf("expected 1 pull, got %d", pulls())
+		}
+
+		// Containers created afterwards use the prefetched image
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				data, err := fetcher.Image(ctx, "alpine")
+				if err != nil {
+					t.Error(err)
+				} else if string(data) != "mock image data" {
+					t.Errorf("unexpected image data %q", data)
+				}
+			}()
+		}
+		wg.Wait()
+		if pulls() != 1 {
+			t.Errorf("expected no further pulls, got %d", pulls())
+		}
+	})
+
+	t.Run("failure_reported_once_per_reference", func(t *testing.T) {
+		fetcher := NewImageFetcher(manager, PullNever)
+
+		err := fetcher.Prefetch(ctx, []string{"alpine", "busybox", "busybox"}, 2)
+		if !errors.IsType(err, errors.ErrImage) || !strings.Contains(err.Error(), "busybox") {
+			t.Fatalf("expected missing busybox error, got %v", err)
+		}
+		if _, err := fetcher.Image(ctx, "busybox"); !errors.IsType(err, errors.ErrImage) {
+			t.Errorf("expected missing busybox error, got %v", err)
+		}
+	})
+
+	t.Run("failed_pull_is_retried", func(t *testing.T) {
+		fetcher := NewImageFetcher(manager, PullAlways)
+
+		// Pulls fail until the registry comes back
+		unavailable := true
+		countExec := execCommand
+		execCommand = func(name string, args ...string) *exec.Cmd {
+			if name == "docker" && args[0] == "pull" && unavailable {
+				return exec.Command("false")
+			}
+			return countExec(name, args...)
+		}
+		defer func() { execCommand = countExec }()
+
+		if err := fetcher.Prefetch(ctx, []string{"alpine"}, 1); err == nil {
+			t.Fatal("expected pull error")
+		}
+		unavailable = false
+		if _, err := fetcher.Image(ctx, "alpine"); err != nil {
+			t.Errorf("expected the pull to be retried, got %v", err)
+		}
+	})
+
+	t.Run("forget_pulls_again", func(t *testing.T) {
+		fetcher := NewImageFetcher(manager, PullAlways)
+		calls.Reset()
+
+		for i := 0; i < 2; i++ {
+			if _, err := fetcher.Image(ctx, "alpine"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		fetcher.Forget()
+		if _, err := fetcher.Image(ctx, "alpine"); err != nil {
+			t.Fatal(err)
+		}
+		if pulls() != 2 {
+			t.Errorf("expected a pull per pass, got %d", pulls())
+		}
+	})
+
+	t.Run("invalid_reference", func(t *testing.T) {
+		fetcher := NewImageFetcher(manager, PullMissing)
+		if err := fetcher.Prefetch(ctx, []string{"Alpine::"}, 2); !errors.IsType(err, errors.ErrValidation) {
+			t.Errorf("expected validation error, got %v", err)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os/exec"
 	"time"
 
@@ -41,15 +42,30 @@ func NewRegistryManager(storageDir string) (*RegistryManager, error) {
 }
 
 func (m *RegistryManager) Pull(ctx context.Context, ref ImageReference) error {
+	return m.PullWithProgress(ctx, ref, nil)
+}
+
+// PullWithProgress pulls an image like Pull, calling progress with each layer
+// status docker reports. A nil progress behaves like Pull.
+func (m *RegistryManager) PullWithProgress(ctx context.Context, ref ImageReference, progress PullProgress) error {
 	return recovery.RetryWithBackoff(ctx, recovery.DefaultRetryConfig, func() error {
+		platform := ref.Platform
+		if platform.IsZero() {
+			platform = DefaultPlatform()
+		}
+
 		logging.Info("Pulling image",
 			"registry", ref.Registry,
 			"repository", ref.Repository,
-			"tag", ref.Tag)
-
-		// Use docker to pull the image
-		pullCmd := execCommand("docker", "pull", formatDockerRef(ref))
-		if out, err := pullCmd.CombinedOutput(); err != nil {
+			"tag", ref.Tag,
+			"platform", platform.String())
+
+		// Use docker to pull the image, letting it resolve the manifest list for the platform
+		pullCmd := execCommand("docker", "pull", "--platform", platform.String(), formatDockerRef(ref))
+		if out, err := runPull(ctx, pullCmd, progress); err != nil {
+			if ctxErr := contextError(ctx, "pulling image"); ctxErr != nil {
+				return ctxErr
+			}
 			return errors.Wrap(err, errors.ErrRegistry, "failed to pull image").
 				WithDetails(map[string]interface{}{
 					"output": string(out),
@@ -60,8 +76,11 @@ func (m *RegistryManager) Pull(ctx context.Context, ref ImageReference) error {
 		// Save the image to a tar
 		logging.Debug("Saving image to tar")
 		saveCmd := execCommand("docker", "save", formatDockerRef(ref))
-		data, err := saveCmd.Output()
+		data, err := commandOutput(ctx, saveCmd)
 		if err != nil {
+			if ctxErr := contextError(ctx, "saving image"); ctxErr != nil {
+				return ctxErr
+			}
 			return errors.Wrap(err, errors.ErrRegistry, "failed to save image")
 		}
 
@@ -77,6 +96,70 @@ func (m *RegistryManager) Pull(ctx context.Context, ref ImageReference) error {
 	})
 }
 
+// runPull runs docker pull, streaming its output to progress when set, and
+// returns the combined output
+func runPull(ctx context.Context, cmd *exec.Cmd, progress PullProgress) ([]byte, error) {
+	if progress == nil {
+		return combinedOutput(ctx, cmd)
+	}
+
+	var out bytes.Buffer
+	w := &pullProgressWriter{progress: progress}
+	cmd.Stdout = io.MultiWriter(&out, w)
+	cmd.Stderr = &out
+	err := runCommand(ctx, cmd)
+	w.Flush()
+	return out.Bytes(), err
+}
+
+// runCommand runs cmd, killing it if ctx is done before it exits
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// commandOutput is cmd.Output, honouring ctx like runCommand
+func commandOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := runCommand(ctx, cmd)
+	return out.Bytes(), err
+}
+
+// combinedOutput is cmd.CombinedOutput, honouring ctx like runCommand
+func combinedOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := runCommand(ctx, cmd)
+	return out.Bytes(), err
+}
+
+// contextError returns a Timeout error if ctx's deadline passed during the
+// given operation, or ctx.Err() if it was cancelled. Neither is retried.
+func contextError(ctx context.Context, operation string) error {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return errors.Wrap(ctx.Err(), errors.ErrTimeout, "timed out "+operation)
+	default:
+		return ctx.Err()
+	}
+}
+
 func (m *RegistryManager) Push(ctx context.Context, ref ImageReference) error {
 	return recovery.RetryWithBackoff(ctx, recovery.DefaultRetryConfig, func() error {
 		logging.Info("Pushing image",
@@ -95,7 +178,10 @@ func (m *RegistryManager) Push(ctx context.Context, ref ImageReference) error {
 		logging.Debug("Loading image into docker")
 		loadCmd := execCommand("docker", "load")
 		loadCmd.Stdin = bytes.NewReader(data)
-		if out, err := loadCmd.CombinedOutput(); err != nil {
+		if out, err := combinedOutput(ctx, loadCmd); err != nil {
+			if ctxErr := contextError(ctx, "loading image"); ctxErr != nil {
+				return ctxErr
+			}
 			return errors.Wrap(err, errors.ErrRegistry, "failed to load image").
 				WithDetails(map[string]interface{}{
 					"output": string(out),
@@ -106,7 +192,10 @@ func (m *RegistryManager) Push(ctx context.Context, ref ImageReference) error {
 		// Push to registry
 		logging.Debug("Pushing image to registry")
 		pushCmd := execCommand("docker", "push", formatDockerRef(ref))
-		if out, err := pushCmd.CombinedOutput(); err != nil {
+		if out, err := combinedOutput(ctx, pushCmd); err != nil {
+			if ctxErr := contextError(ctx, "pushing image"); ctxErr != nil {
+				return ctxErr
+			}
 			return errors.Wrap(err, errors.ErrRegistry, "failed to push image").
 				WithDetails(map[string]interface{}{
 					"output": string(out),
@@ -193,6 +282,19 @@ func (m *RegistryManager) List(_ context.Context) ([]ImageReference, error) {
 	return images, nil
 }
 
+// ListInfo returns locally stored images along with their size and storage time
+func (m *RegistryManager) ListInfo(_ context.Context) ([]ImageInfo, error) {
+	logging.Info("Listing images")
+
+	images, err := m.store.ListInfo()
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrStorage, "failed to list images")
+	}
+
+	logging.Info("Successfully listed images")
+	return images, nil
+}
+
 func (m *RegistryManager) Delete(_ context.Context, ref ImageReference) error {
 	logging.Info("Deleting image",
 		"registry", ref.Registry,
@@ -3,10 +3,12 @@ package oci
 import (
 	"context"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/errors"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/logging"
 	"github.com/larkinwc/proxmox-lxc-compose/pkg/testutil"
 )
@@ -34,7 +36,7 @@ func setupRegistryTest(t *testing.T) (*RegistryManager, *testutil.MockCommandExe
 
 	// Set up basic mock commands
 	mockImageData := []byte("mock image data")
-	mockCmd.AddMockCommand("docker pull docker.io/library/alpine:latest", nil)
+	mockCmd.AddMockCommand("docker pull --platform "+DefaultPlatform().String()+" docker.io/library/alpine:latest", nil)
 	mockCmd.AddMockCommand("docker save docker.io/library/alpine:latest", mockImageData)
 	mockCmd.AddMockCommand("docker load", nil)
 	mockCmd.AddMockCommand("docker push docker.io/library/alpine:latest", nil)
@@ -150,12 +152,21 @@ func TestRegistryErrors(t *testing.T) {
 	}
 
 	t.Run("pull_errors", func(t *testing.T) {
-		mockCmd.AddErrorCommand("docker pull docker.io/library/alpine:latest", "failed to pull")
+		mockCmd.AddErrorCommand("docker pull --platform "+DefaultPlatform().String()+" docker.io/library/alpine:latest", "failed to pull")
 		if err := manager.Pull(ctx, testRef); err == nil {
 			t.Error("expected error on pull failure")
 		}
 	})
 
+	t.Run("pull_platform", func(t *testing.T) {
+		platformRef := testRef
+		platformRef.Platform = Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}
+		mockCmd.AddErrorCommand("docker pull --platform linux/arm64/v8 docker.io/library/alpine:latest", "failed to pull")
+		if err := manager.Pull(ctx, platformRef); err == nil {
+			t.Error("expected explicit platform to be passed to docker pull")
+		}
+	})
+
 	t.Run("push_errors", func(t *testing.T) {
 		mockCmd.AddErrorCommand("docker push docker.io/library/alpine:latest", "failed to push")
 		if err := manager.Push(ctx, testRef); err == nil {
@@ -173,3 +184,54 @@ func TestRegistryErrors(t *testing.T) {
 		}
 	})
 }
+
+func TestRegistryTimeout(t *testing.T) {
+	manager, _, _, cleanup := setupRegistryTest(t)
+	defer cleanup()
+
+	// Every docker command hangs, as it would against an unresponsive registry
+	execCommand = func(string, ...string) *exec.Cmd {
+		return exec.Command("sleep", "10")
+	}
+
+	testRef := ImageReference{
+		Registry:   "docker.io",
+		Repository: "library/alpine",
+		Tag:        "latest",
+	}
+
+	t.Run("pull", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := manager.Pull(ctx, testRef)
+		if !errors.IsType(err, errors.ErrTimeout) {
+			t.Fatalf("expected timeout error, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("pull was not cancelled promptly, took %s", elapsed)
+		}
+	})
+
+	t.Run("push", func(t *testing.T) {
+		if err := manager.store.Store(testRef, []byte("mock image data")); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		if err := manager.Push(ctx, testRef); !errors.IsType(err, errors.ErrTimeout) {
+			t.Fatalf("expected timeout error, got %v", err)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+
+		if err := manager.Pull(ctx, testRef); err != context.Canceled {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+}
@@ -1,6 +1,9 @@
 package oci
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // ImageReference represents a reference to an OCI image
 type ImageReference struct {
@@ -8,6 +11,8 @@ type ImageReference struct {
 	Repository string
 	Tag        string
 	Digest     string
+	// Platform selects an image from a multi-arch manifest list, defaults to the host platform
+	Platform Platform
 }
 
 // String returns a string representation of the image reference
@@ -19,6 +24,14 @@ func (r ImageReference) String() string {
 	return ref
 }
 
+// ImageInfo describes a locally stored image
+type ImageInfo struct {
+	ImageReference
+	Size     int64
+	StoredAt time.Time
+	Source   string `json:",omitempty"` // Container the image was committed from
+}
+
 // ImageManager handles OCI image operations
 type ImageManager interface {
 	// Pull pulls an image from a registry
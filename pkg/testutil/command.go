@@ -9,11 +9,14 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
-// MockCommandState tracks the state of mock commands
+// MockCommandState tracks the state of mock commands. It is safe for
+// concurrent use through its methods and the mocked command.
 type MockCommandState struct {
+	mu              sync.RWMutex
 	Name            string
 	Args            []string
 	ContainerStates map[string]string
@@ -35,6 +38,9 @@ type containerState struct {
 
 // CommandWasCalled checks if a command was called with the given name and arguments
 func (m *MockCommandState) CommandWasCalled(name string, args ...string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	for _, cmd := range m.commandHistory {
 		if cmd.name != name {
 			continue
@@ -70,6 +76,9 @@ func SetupMockCommand(execCommand *func(string, ...string) *exec.Cmd) (*MockComm
 	oldExec := *execCommand
 
 	*execCommand = func(name string, args ...string) *exec.Cmd {
+		mock.mu.Lock()
+		defer mock.mu.Unlock()
+
 		if mock.debug {
 			fmt.Printf("DEBUG: Mock command called: %s %s\n", name, strings.Join(args, " "))
 			fmt.Printf("DEBUG: Current container states: %v\n", mock.ContainerStates)
@@ -131,8 +140,7 @@ func SetupMockCommand(execCommand *func(string, ...string) *exec.Cmd) (*MockComm
 				}
 				return exec.Command("sh", "-c", fmt.Sprintf("echo 'Container is not in a valid state for pausing (current state: %s)' >&2; exit 1", state))
 			}
-			mock.ContainerStates[containerName] = "FROZEN"
-			if err := mock.SetContainerState(containerName, "FROZEN"); err != nil {
+			if err := mock.setContainerState(containerName, "FROZEN"); err != nil {
 				if mock.debug {
 					fmt.Printf("DEBUG: Failed to update state: %v\n", err)
 				}
@@ -146,8 +154,7 @@ func SetupMockCommand(execCommand *func(string, ...string) *exec.Cmd) (*MockComm
 				}
 				return exec.Command("sh", "-c", fmt.Sprintf("echo 'Container is not in a valid state for resuming (current state: %s)' >&2; exit 1", state))
 			}
-			mock.ContainerStates[containerName] = "RUNNING"
-			if err := mock.SetContainerState(containerName, "RUNNING"); err != nil {
+			if err := mock.setContainerState(containerName, "RUNNING"); err != nil {
 				if mock.debug {
 					fmt.Printf("DEBUG: Failed to update state: %v\n", err)
 				}
@@ -161,8 +168,7 @@ func SetupMockCommand(execCommand *func(string, ...string) *exec.Cmd) (*MockComm
 				}
 				return exec.Command("sh", "-c", fmt.Sprintf("echo 'Container is not in a valid state for starting (current state: %s)' >&2; exit 1", state))
 			}
-			mock.ContainerStates[containerName] = "RUNNING"
-			if err := mock.SetContainerState(containerName, "RUNNING"); err != nil {
+			if err := mock.setContainerState(containerName, "RUNNING"); err != nil {
 				if mock.debug {
 					fmt.Printf("DEBUG: Failed to update state: %v\n", err)
 				}
@@ -176,8 +182,7 @@ func SetupMockCommand(execCommand *func(string, ...string) *exec.Cmd) (*MockComm
 				}
 				return exec.Command("sh", "-c", fmt.Sprintf("echo 'Container is not in a valid state for stopping (current state: %s)' >&2; exit 1", state))
 			}
-			mock.ContainerStates[containerName] = "STOPPED"
-			if err := mock.SetContainerState(containerName, "STOPPED"); err != nil {
+			if err := mock.setContainerState(containerName, "STOPPED"); err != nil {
 				if mock.debug {
 					fmt.Printf("DEBUG: Failed to update state: %v\n", err)
 				}
@@ -190,6 +195,9 @@ func SetupMockCommand(execCommand *func(string, ...string) *exec.Cmd) (*MockComm
 	}
 
 	return mock, func() {
+		mock.mu.Lock()
+		defer mock.mu.Unlock()
+
 		if mock.debug {
 			fmt.Printf("DEBUG: Cleaning up %d temporary files\n", len(mock.tmpFiles))
 		}
@@ -206,6 +214,14 @@ func SetupMockCommand(execCommand *func(string, ...string) *exec.Cmd) (*MockComm
 
 // SetContainerState allows tests to set the state of a container
 func (m *MockCommandState) SetContainerState(containerName, state string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.setContainerState(containerName, state)
+}
+
+// setContainerState updates the state of a container, m.mu must be held
+func (m *MockCommandState) setContainerState(containerName, state string) error {
 	if m.debug {
 		fmt.Printf("DEBUG: SetContainerState called for %s with state %s\n", containerName, state)
 		fmt.Printf("DEBUG: Current container states before update: %v\n", m.ContainerStates)
@@ -282,6 +298,9 @@ func (m *MockCommandState) SetContainerState(containerName, state string) error
 
 // AddContainer adds a container to the mock state
 func (m *MockCommandState) AddContainer(containerName, state string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if m.debug {
 		fmt.Printf("DEBUG: Adding container %s with state %s\n", containerName, state)
 	}
@@ -345,17 +364,26 @@ func (m *MockCommandState) AddContainer(containerName, state string) {
 
 // SetDebug enables or disables debug logging
 func (m *MockCommandState) SetDebug(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.debug = enabled
 }
 
 // GetContainerState returns the state of a container
 func (m *MockCommandState) GetContainerState(containerName string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	state, exists := m.ContainerStates[containerName]
 	return state, exists
 }
 
 // RemoveContainer removes a container from the mock state
 func (m *MockCommandState) RemoveContainer(containerName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	delete(m.ContainerStates, containerName)
 }
 
@@ -365,6 +393,9 @@ func (m *MockCommandState) ContainerExists(containerName string) bool {
 		return false
 	}
 
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	_, exists := m.ContainerStates[containerName]
 	return exists
 }
@@ -0,0 +1,48 @@
+package testutil
+
+import (
+	"fmt"
+	"os/exec"
+	"sync"
+	"testing"
+)
+
+func TestMockCommandStateConcurrent(t *testing.T) {
+	t.Setenv("CONTAINER_CONFIG_PATH", t.TempDir())
+
+	execCommand := exec.Command
+	mock, cleanup := SetupMockCommand(&execCommand)
+	defer cleanup()
+	mock.SetDebug(false)
+
+	// Run under -race: each goroutine drives its own container through the
+	// mocked command while others read and write the shared state
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("c%d", i)
+		mock.AddContainer(name, "STOPPED")
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, cmd := range []string{"lxc-start", "lxc-freeze", "lxc-unfreeze", "lxc-stop"} {
+				if err := execCommand(cmd, "-n", name).Run(); err != nil {
+					t.Errorf("%s %s: %v", cmd, name, err)
+				}
+				mock.ContainerExists(name)
+				mock.CommandWasCalled(cmd, "-n", name)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("c%d", i)
+		if state, _ := mock.GetContainerState(name); state != "STOPPED" {
+			t.Errorf("expected %s to be STOPPED, got %s", name, state)
+		}
+		if !mock.CommandWasCalled("lxc-stop", "-n", name) {
+			t.Errorf("expected lxc-stop to be recorded for %s", name)
+		}
+	}
+}
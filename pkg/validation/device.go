@@ -165,25 +165,25 @@ func ValidateDeviceConfig(device *common.DeviceConfig) error {
 	}
 
 	if err := ValidateDeviceName(device.Name); err != nil {
-		return err
+		return WithPath("name", err)
 	}
 
 	if err := ValidateDeviceType(device.Type); err != nil {
-		return err
+		return WithPath("type", err)
 	}
 
 	if err := ValidateDevicePath(device.Source, true); err != nil {
-		return err
+		return WithPath("source", err)
 	}
 
 	if device.Destination != "" {
 		if err := ValidateDevicePath(device.Destination, false); err != nil {
-			return err
+			return WithPath("destination", err)
 		}
 	}
 
 	if err := ValidateDeviceOptions(device.Type, device.Options); err != nil {
-		return err
+		return WithPath("options", err)
 	}
 
 	return nil
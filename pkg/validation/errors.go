@@ -0,0 +1,91 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+)
+
+// ValidationError describes an invalid value and where it lives in the config,
+// using yaml field names (e.g. services.web.network.interfaces[0].type)
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors aggregates every validation failure found in a config
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Add records err under path. Paths already carried by err are kept relative
+// to path, and plain errors become a ValidationError located at path.
+func (e *ValidationErrors) Add(path string, err error) {
+	if err == nil {
+		return
+	}
+
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		for _, ve := range errs {
+			*e = append(*e, &ValidationError{Path: JoinPath(path, ve.Path), Message: ve.Message})
+		}
+		return
+	}
+
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		*e = append(*e, &ValidationError{Path: JoinPath(path, ve.Path), Message: ve.Message})
+		return
+	}
+
+	*e = append(*e, &ValidationError{Path: path, Message: err.Error()})
+}
+
+// ErrorOrNil returns nil when no errors were recorded, so callers don't
+// return a non-nil error interface holding an empty slice
+func (e ValidationErrors) ErrorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// WithPath prefixes the location of err with path
+func WithPath(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	errs.Add(path, err)
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errs
+}
+
+// JoinPath appends field to prefix, leaving index segments like "[0]" unseparated
+func JoinPath(prefix, field string) string {
+	switch {
+	case prefix == "":
+		return field
+	case field == "":
+		return prefix
+	case strings.HasPrefix(field, "["):
+		return prefix + field
+	default:
+		return prefix + "." + field
+	}
+}
@@ -0,0 +1,74 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestValidationErrorPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantPath string
+	}{
+		{
+			name: "interface field",
+			err: ValidateNetworkConfig(&NetworkConfig{
+				Interfaces: []NetworkInterface{{Type: "veth"}, {Type: "invalid"}},
+			}),
+			wantPath: "interfaces[1].type",
+		},
+		{
+			name: "port forward field",
+			err: ValidateNetworkConfig(&NetworkConfig{
+				Interfaces:   []NetworkInterface{{Type: "veth"}},
+				PortForwards: []PortForward{{Protocol: "tcp", Host: 0, Guest: 80}},
+			}),
+			wantPath: "port_forwards[0].host",
+		},
+		{
+			name:     "capability",
+			err:      ValidateSecurityProfile(&SecurityProfile{Capabilities: []string{"NET_ADMIN", "BOGUS"}}),
+			wantPath: "capabilities[1]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs ValidationErrors
+			errs.Add("services.web", tt.err)
+			if len(errs) != 1 {
+				t.Fatalf("expected 1 error, got %d: %v", len(errs), errs)
+			}
+			if want := "services.web." + tt.wantPath; errs[0].Path != want {
+				t.Errorf("path = %q, want %q", errs[0].Path, want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorsAdd(t *testing.T) {
+	var errs ValidationErrors
+	errs.Add("image", nil)
+	if errs.ErrorOrNil() != nil {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+
+	errs.Add("image", fmt.Errorf("image is required"))
+	errs.Add("network", ValidationErrors{
+		{Path: "interfaces[0].mtu", Message: "invalid MTU"},
+		{Path: "dns_servers", Message: "invalid DNS server"},
+	})
+
+	err := errs.ErrorOrNil()
+	assertTestError(t, err, true, "network.interfaces[0].mtu: invalid MTU")
+
+	var got ValidationErrors
+	if !errors.As(err, &got) || len(got) != 3 {
+		t.Fatalf("expected 3 aggregated errors, got %v", err)
+	}
+	if got[0].Error() != "image: image is required" {
+		t.Errorf("unexpected first error %q", got[0].Error())
+	}
+}
@@ -0,0 +1,81 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+)
+
+// ValidateHealthCheck validates a health check probe and its timing
+func ValidateHealthCheck(hc *common.HealthCheck) error {
+	if hc == nil {
+		return nil
+	}
+
+	switch strings.ToLower(hc.Type) {
+	case "", common.HealthCheckCommand:
+		if len(hc.Command) == 0 {
+			return WithPath("command", fmt.Errorf("command is required for command health checks"))
+		}
+	case common.HealthCheckTCP, common.HealthCheckHTTP:
+		if err := ValidatePortNumber(hc.Port); err != nil {
+			return WithPath("port", err)
+		}
+		if strings.EqualFold(hc.Type, common.HealthCheckHTTP) && hc.Path != "" && !strings.HasPrefix(hc.Path, "/") {
+			return WithPath("path", fmt.Errorf("path must start with /"))
+		}
+	default:
+		return WithPath("type", fmt.Errorf("unsupported health check type %q (supported: command, tcp, http)", hc.Type))
+	}
+
+	durations := []struct{ field, value string }{
+		{"interval", hc.Interval},
+		{"timeout", hc.Timeout},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		value, err := time.ParseDuration(d.value)
+		if err != nil {
+			return WithPath(d.field, fmt.Errorf("invalid duration %q", d.value))
+		}
+		if value <= 0 {
+			return WithPath(d.field, fmt.Errorf("%s must be positive", d.field))
+		}
+	}
+
+	if hc.Retries < 0 {
+		return WithPath("retries", fmt.Errorf("retries must be non-negative"))
+	}
+	if err := ValidateHealthHook(hc.OnHealthy); err != nil {
+		return WithPath("on_healthy", err)
+	}
+	if err := ValidateHealthHook(hc.OnUnhealthy); err != nil {
+		return WithPath("on_unhealthy", err)
+	}
+	return nil
+}
+
+// ValidateHealthHook validates a health status hook, which must either run a
+// command or call an http(s) webhook
+func ValidateHealthHook(hook *common.HealthHook) error {
+	if hook == nil {
+		return nil
+	}
+	if len(hook.Command) > 0 == (hook.URL != "") {
+		return fmt.Errorf("hook must set exactly one of command and url")
+	}
+	if hook.URL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return WithPath("url", fmt.Errorf("invalid webhook URL %q: must be an http or https URL", hook.URL))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/larkinwc/proxmox-lxc-compose/pkg/common"
+)
+
+func TestValidateHealthCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		hc          *common.HealthCheck
+		wantErr     bool
+		errContains string
+	}{
+		{name: "nil", hc: nil},
+		{name: "command", hc: &common.HealthCheck{Command: []string{"pg_isready"}, Interval: "5s", Retries: 3}},
+		{name: "tcp", hc: &common.HealthCheck{Type: "tcp", Port: 5432}},
+		{name: "http", hc: &common.HealthCheck{Type: "http", Port: 8080, Path: "/healthz", Timeout: "2s"}},
+		{name: "command missing", hc: &common.HealthCheck{Type: "command"}, wantErr: true, errContains: "command is required"},
+		{name: "tcp without port", hc: &common.HealthCheck{Type: "tcp"}, wantErr: true, errContains: "port must be between"},
+		{name: "http relative path", hc: &common.HealthCheck{Type: "http", Port: 80, Path: "healthz"}, wantErr: true, errContains: "must start with /"},
+		{name: "unknown type", hc: &common.HealthCheck{Type: "grpc", Port: 80}, wantErr: true, errContains: "unsupported health check type"},
+		{name: "bad interval", hc: &common.HealthCheck{Type: "tcp", Port: 80, Interval: "soon"}, wantErr: true, errContains: "interval"},
+		{name: "negative timeout", hc: &common.HealthCheck{Type: "tcp", Port: 80, Timeout: "-1s"}, wantErr: true, errContains: "timeout must be positive"},
+		{name: "negative retries", hc: &common.HealthCheck{Type: "tcp", Port: 80, Retries: -1}, wantErr: true, errContains: "retries"},
+		{name: "hooks", hc: &common.HealthCheck{Type: "tcp", Port: 80,
+			OnHealthy:   &common.HealthHook{Command: []string{"/usr/local/bin/notify", "up"}},
+			OnUnhealthy: &common.HealthHook{URL: "https://hooks.example.com/alert"}}},
+		{name: "hook without action", hc: &common.HealthCheck{Type: "tcp", Port: 80, OnHealthy: &common.HealthHook{}}, wantErr: true, errContains: "on_healthy"},
+		{name: "hook with both actions", hc: &common.HealthCheck{Type: "tcp", Port: 80,
+			OnUnhealthy: &common.HealthHook{Command: []string{"notify"}, URL: "http://example.com"}}, wantErr: true, errContains: "exactly one of command and url"},
+		{name: "hook with bad url", hc: &common.HealthCheck{Type: "tcp", Port: 80,
+			OnUnhealthy: &common.HealthHook{URL: "ftp://example.com"}}, wantErr: true, errContains: "invalid webhook URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateHealthCheck(tt.hc), tt.wantErr, tt.errContains)
+		})
+	}
+}
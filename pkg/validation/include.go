@@ -0,0 +1,30 @@
+package validation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ValidateIncludeConfig validates an LXC config file to include, which must be
+// an absolute path to an existing file
+func ValidateIncludeConfig(path string) error {
+	if path == "" {
+		return fmt.Errorf("include config path is required")
+	}
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("include config %s must be an absolute path", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("include config %s does not exist", path)
+		}
+		return fmt.Errorf("failed to check include config %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("include config %s is a directory", path)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateIncludeConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "common.conf")
+	if err := os.WriteFile(file, []byte("lxc.arch = linux64\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		wantErr     bool
+		errContains string
+	}{
+		{name: "existing file", path: file},
+		{name: "empty", path: "", wantErr: true, errContains: "path is required"},
+		{name: "relative", path: "common.conf", wantErr: true, errContains: "must be an absolute path"},
+		{name: "missing", path: filepath.Join(dir, "missing.conf"), wantErr: true, errContains: "does not exist"},
+		{name: "directory", path: dir, wantErr: true, errContains: "is a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateIncludeConfig(tt.path), tt.wantErr, tt.errContains)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateInit validates an init system choice. Booting a full init system
+// replaces the entrypoint/command, so combining them is rejected.
+func ValidateInit(init string, hasCommand bool) error {
+	switch strings.ToLower(init) {
+	case "", "none":
+		return nil
+	case "systemd", "sysvinit":
+		if hasCommand {
+			return fmt.Errorf("command and entrypoint cannot be used with init %q", init)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported init %q (supported: none, systemd, sysvinit)", init)
+	}
+}
+
+// ValidateTTYs validates the number of ttys given to a container
+func ValidateTTYs(ttys int) error {
+	if ttys < 0 {
+		return fmt.Errorf("ttys must not be negative, got %d", ttys)
+	}
+	return nil
+}
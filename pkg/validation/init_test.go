@@ -0,0 +1,45 @@
+package validation
+
+import "testing"
+
+func TestValidateInit(t *testing.T) {
+	tests := []struct {
+		name        string
+		init        string
+		hasCommand  bool
+		wantErr     bool
+		errContains string
+	}{
+		{name: "default", init: ""},
+		{name: "none with command", init: "none", hasCommand: true},
+		{name: "systemd", init: "systemd"},
+		{name: "sysvinit uppercase", init: "SysVinit"},
+		{name: "systemd with command", init: "systemd", hasCommand: true, wantErr: true, errContains: "cannot be used"},
+		{name: "unknown", init: "openrc", wantErr: true, errContains: "unsupported init"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateInit(tt.init, tt.hasCommand), tt.wantErr, tt.errContains)
+		})
+	}
+}
+
+func TestValidateTTYs(t *testing.T) {
+	tests := []struct {
+		name        string
+		ttys        int
+		wantErr     bool
+		errContains string
+	}{
+		{name: "none", ttys: 0},
+		{name: "some", ttys: 4},
+		{name: "negative", ttys: -1, wantErr: true, errContains: "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateTTYs(tt.ttys), tt.wantErr, tt.errContains)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseNamespaceMode parses a pid or ipc mode. host keeps the host's
+// namespace, container:<name> joins that container's namespace, and an empty
+// mode gives the container a namespace of its own.
+func ParseNamespaceMode(kind, mode string) (host bool, target string, err error) {
+	switch {
+	case mode == "":
+		return false, "", nil
+	case mode == "host":
+		return true, "", nil
+	}
+	target, ok := strings.CutPrefix(mode, "container:")
+	if !ok {
+		return false, "", fmt.Errorf("unsupported %s mode %q (supported: host, container:<name>)", kind, mode)
+	}
+	if target == "" {
+		return false, "", fmt.Errorf("%s mode %q names no container", kind, mode)
+	}
+	return false, target, nil
+}
+
+// ValidateNamespaceMode validates a pid or ipc mode, see ParseNamespaceMode
+func ValidateNamespaceMode(kind, mode string) error {
+	_, _, err := ParseNamespaceMode(kind, mode)
+	return err
+}
+
+// SharedNamespace is a namespace a container joins from another container
+type SharedNamespace struct {
+	// Kind is the namespace: net, pid or ipc
+	Kind string
+	// Container is the container whose namespace is joined
+	Container string
+}
+
+// Name is the namespace as named in messages, network rather than net
+func (ns SharedNamespace) Name() string {
+	if ns.Kind == "net" {
+		return "network"
+	}
+	return ns.Kind
+}
+
+// SharedNamespaces returns the namespaces that a network_mode, pid and ipc
+// mode join from other containers. Invalid modes are left out.
+func SharedNamespaces(networkMode, pidMode, ipcMode string) []SharedNamespace {
+	var shared []SharedNamespace
+	if target, err := ParseNetworkMode(networkMode); err == nil && target != "" {
+		shared = append(shared, SharedNamespace{Kind: "net", Container: target})
+	}
+	if _, target, err := ParseNamespaceMode("pid", pidMode); err == nil && target != "" {
+		shared = append(shared, SharedNamespace{Kind: "pid", Container: target})
+	}
+	if _, target, err := ParseNamespaceMode("ipc", ipcMode); err == nil && target != "" {
+		shared = append(shared, SharedNamespace{Kind: "ipc", Container: target})
+	}
+	return shared
+}
@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNamespaceMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		wantHost    bool
+		wantTarget  string
+		wantErr     bool
+		errContains string
+	}{
+		{name: "empty"},
+		{name: "host", mode: "host", wantHost: true},
+		{name: "container", mode: "container:app", wantTarget: "app"},
+		{name: "missing container", mode: "container:", wantErr: true, errContains: "names no container"},
+		{name: "unknown mode", mode: "private", wantErr: true, errContains: "unsupported pid mode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, target, err := ParseNamespaceMode("pid", tt.mode)
+			assertTestError(t, err, tt.wantErr, tt.errContains)
+			if host != tt.wantHost || target != tt.wantTarget {
+				t.Errorf("ParseNamespaceMode(%q) = %v, %q, want %v, %q", tt.mode, host, target, tt.wantHost, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestSharedNamespaces(t *testing.T) {
+	got := SharedNamespaces("container:vpn", "host", "container:app")
+	want := []SharedNamespace{{Kind: "net", Container: "vpn"}, {Kind: "ipc", Container: "app"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SharedNamespaces() = %v, want %v", got, want)
+	}
+
+	if got := SharedNamespaces("", "bogus", ""); len(got) != 0 {
+		t.Errorf("SharedNamespaces() = %v, want none", got)
+	}
+}
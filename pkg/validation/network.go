@@ -184,13 +184,61 @@ func ValidateProtocol(protocol string) error {
 // ValidatePortForward validates a port forwarding configuration
 func ValidatePortForward(pf *PortForward) error {
 	if err := ValidateProtocol(pf.Protocol); err != nil {
-		return err
+		return WithPath("protocol", err)
 	}
 	if err := ValidatePortNumber(pf.Host); err != nil {
-		return fmt.Errorf("invalid host port: %w", err)
+		return WithPath("host", fmt.Errorf("invalid host port: %w", err))
 	}
 	if err := ValidatePortNumber(pf.Guest); err != nil {
-		return fmt.Errorf("invalid guest port: %w", err)
+		return WithPath("guest", fmt.Errorf("invalid guest port: %w", err))
+	}
+	return nil
+}
+
+// dnsOptionLimits are the resolv.conf options taking a value, with the largest
+// value the resolver honors, see resolv.conf(5)
+var dnsOptionLimits = map[string]int{
+	"ndots":    15,
+	"timeout":  30,
+	"attempts": 5,
+}
+
+// dnsFlagOptions are the resolv.conf options without a value
+var dnsFlagOptions = map[string]bool{
+	"debug":                 true,
+	"rotate":                true,
+	"no-check-names":        true,
+	"inet6":                 true,
+	"edns0":                 true,
+	"single-request":        true,
+	"single-request-reopen": true,
+	"no-tld-query":          true,
+	"use-vc":                true,
+	"no-reload":             true,
+	"trust-ad":              true,
+}
+
+// ValidateDNSOptions validates resolv.conf options such as ndots:2 or rotate
+func ValidateDNSOptions(options []string) error {
+	for _, option := range options {
+		name, value, hasValue := strings.Cut(option, ":")
+		if limit, ok := dnsOptionLimits[name]; ok {
+			if !hasValue {
+				return fmt.Errorf("DNS option %s requires a value, e.g. %s:1", name, name)
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 || n > limit {
+				return fmt.Errorf("invalid DNS option %q: value must be between 0 and %d", option, limit)
+			}
+			continue
+		}
+		if dnsFlagOptions[name] {
+			if hasValue {
+				return fmt.Errorf("DNS option %s doesn't take a value", name)
+			}
+			continue
+		}
+		return fmt.Errorf("unknown DNS option: %q", option)
 	}
 	return nil
 }
@@ -226,56 +274,97 @@ func ValidateSearchDomains(domains []string) error {
 	return nil
 }
 
-// ValidateNetworkInterface validates a network interface configuration
+// ValidateNetworkInterface validates a network interface configuration.
+// Errors carry the offending field as a ValidationError path.
 func ValidateNetworkInterface(iface *NetworkInterface) error {
 	if err := ValidateNetworkType(iface.Type); err != nil {
-		return err
+		return WithPath("type", err)
 	}
 
 	if iface.Type == "bridge" && iface.Bridge == "" {
-		return fmt.Errorf("bridge name is required for bridge network type")
+		return WithPath("bridge", fmt.Errorf("bridge name is required for bridge network type"))
 	}
 
 	if err := ValidateNetworkInterfaceName(iface.Interface); err != nil {
-		return err
+		return WithPath("interface", err)
 	}
 
 	// Validate DHCP and static IP settings
-	if iface.DHCP {
-		if iface.IP != "" {
-			return fmt.Errorf("cannot specify static IP when DHCP is enabled")
-		}
-		if iface.Gateway != "" {
-			return fmt.Errorf("cannot specify gateway when DHCP is enabled")
-		}
-	} else if iface.IP != "" {
+	if err := ValidateDHCPStatics(iface.DHCP, iface.IP, iface.Gateway); err != nil {
+		return err
+	}
+	if !iface.DHCP && iface.IP != "" {
 		if err := ValidateIPAddress(iface.IP); err != nil {
-			return fmt.Errorf("invalid IP address: %w", err)
+			return WithPath("ip", fmt.Errorf("invalid IP address: %w", err))
 		}
 
 		if iface.Gateway != "" {
 			if err := ValidateIPAddress(iface.Gateway); err != nil {
-				return fmt.Errorf("invalid gateway: %w", err)
+				return WithPath("gateway", fmt.Errorf("invalid gateway: %w", err))
 			}
 		}
 	}
 
 	if err := ValidateDNSServers(iface.DNS); err != nil {
-		return err
+		return WithPath("dns", err)
 	}
 
 	if err := ValidateHostname(iface.Hostname); err != nil {
-		return err
+		return WithPath("hostname", err)
 	}
 
 	if err := ValidateMTU(iface.MTU); err != nil {
-		return err
+		return WithPath("mtu", err)
 	}
 
 	if err := ValidateMAC(iface.MAC); err != nil {
-		return err
+		return WithPath("mac", err)
+	}
+
+	return nil
+}
+
+// ValidateDHCPStatics rejects a static IP or gateway alongside DHCP, which
+// would otherwise be written as contradictory LXC network keys
+func ValidateDHCPStatics(dhcp bool, ip, gateway string) error {
+	if !dhcp {
+		return nil
+	}
+	if ip != "" {
+		return WithPath("ip", fmt.Errorf("cannot specify static IP when DHCP is enabled"))
+	}
+	if gateway != "" {
+		return WithPath("gateway", fmt.Errorf("cannot specify gateway when DHCP is enabled"))
+	}
+	return nil
+}
+
+// ParseNetworkMode returns the container whose network namespace a
+// network_mode of container:<name> joins, or "" when mode is empty
+func ParseNetworkMode(mode string) (string, error) {
+	if mode == "" {
+		return "", nil
+	}
+	target, ok := strings.CutPrefix(mode, "container:")
+	if !ok {
+		return "", fmt.Errorf("unsupported network mode %q (supported: container:<name>)", mode)
 	}
+	if target == "" {
+		return "", fmt.Errorf("network mode %q names no container", mode)
+	}
+	return target, nil
+}
 
+// ValidateNetworkMode validates a network_mode. A container sharing another's
+// network namespace has no network of its own to configure.
+func ValidateNetworkMode(mode string, hasNetwork bool) error {
+	target, err := ParseNetworkMode(mode)
+	if err != nil {
+		return err
+	}
+	if target != "" && hasNetwork {
+		return fmt.Errorf("network mode %q cannot be combined with a network configuration", mode)
+	}
 	return nil
 }
 
@@ -314,8 +403,11 @@ func ValidateVPNConfig(cfg *common.VPNConfig) error {
 	return nil
 }
 
-// ValidateNetworkConfig validates the complete network configuration
+// ValidateNetworkConfig validates the complete network configuration,
+// returning every problem found as ValidationErrors
 func ValidateNetworkConfig(cfg *NetworkConfig) error {
+	var errs ValidationErrors
+
 	// Support legacy configuration
 	if cfg.Type != "" {
 		// Convert legacy config to new format
@@ -331,37 +423,30 @@ func ValidateNetworkConfig(cfg *NetworkConfig) error {
 			MTU:       cfg.MTU,
 			MAC:       cfg.MAC,
 		}
-		if err := ValidateNetworkInterface(&legacyIface); err != nil {
-			return err
-		}
+		errs.Add("", ValidateNetworkInterface(&legacyIface))
+	} else {
+		// Top-level fields still reach the generated config without a type
+		errs.Add("", ValidateDHCPStatics(cfg.DHCP, cfg.IP, cfg.Gateway))
 	}
 
 	// Validate interfaces
 	if len(cfg.Interfaces) == 0 && cfg.Type == "" {
-		return fmt.Errorf("at least one network interface must be configured")
+		errs.Add("interfaces", fmt.Errorf("at least one network interface must be configured"))
 	}
 
 	for i, iface := range cfg.Interfaces {
-		if err := ValidateNetworkInterface(&iface); err != nil {
-			return fmt.Errorf("interface %d: %w", i, err)
-		}
+		errs.Add(fmt.Sprintf("interfaces[%d]", i), ValidateNetworkInterface(&iface))
 	}
 
 	// Validate port forwards
 	for i, pf := range cfg.PortForwards {
-		if err := ValidatePortForward(&pf); err != nil {
-			return fmt.Errorf("port forward %d: %w", i, err)
-		}
+		errs.Add(fmt.Sprintf("port_forwards[%d]", i), ValidatePortForward(&pf))
 	}
 
 	// Validate DNS configuration
-	if err := ValidateDNSServers(cfg.DNSServers); err != nil {
-		return fmt.Errorf("DNS servers: %w", err)
-	}
+	errs.Add("dns_servers", ValidateDNSServers(cfg.DNSServers))
+	errs.Add("search_domains", ValidateSearchDomains(cfg.SearchDomains))
+	errs.Add("dns_options", ValidateDNSOptions(cfg.DNSOptions))
 
-	if err := ValidateSearchDomains(cfg.SearchDomains); err != nil {
-		return fmt.Errorf("search domains: %w", err)
-	}
-
-	return nil
+	return errs.ErrorOrNil()
 }
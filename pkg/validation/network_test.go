@@ -171,6 +171,53 @@ func TestValidateDNSServers(t *testing.T) {
 	}
 }
 
+func TestValidateDNSOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     []string
+		wantErr     bool
+		errContains string
+	}{
+		{name: "empty list", options: nil},
+		{name: "valid options", options: []string{"ndots:2", "timeout:1", "attempts:5", "rotate", "edns0", "trust-ad"}},
+		{name: "ndots zero", options: []string{"ndots:0"}},
+		{name: "ndots too large", options: []string{"ndots:16"}, wantErr: true, errContains: "between 0 and 15"},
+		{name: "timeout not a number", options: []string{"timeout:one"}, wantErr: true, errContains: "invalid DNS option"},
+		{name: "missing value", options: []string{"ndots"}, wantErr: true, errContains: "requires a value"},
+		{name: "flag with value", options: []string{"rotate:1"}, wantErr: true, errContains: "doesn't take a value"},
+		{name: "unknown option", options: []string{"ndots:2", "fast"}, wantErr: true, errContains: "unknown DNS option"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateDNSOptions(tt.options), tt.wantErr, tt.errContains)
+		})
+	}
+}
+
+func TestValidateNetworkMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		hasNetwork  bool
+		wantErr     bool
+		errContains string
+	}{
+		{name: "empty"},
+		{name: "empty with network", hasNetwork: true},
+		{name: "container", mode: "container:db"},
+		{name: "missing container", mode: "container:", wantErr: true, errContains: "names no container"},
+		{name: "host mode", mode: "host", wantErr: true, errContains: "unsupported network mode"},
+		{name: "with network", mode: "container:db", hasNetwork: true, wantErr: true, errContains: "cannot be combined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateNetworkMode(tt.mode, tt.hasNetwork), tt.wantErr, tt.errContains)
+		})
+	}
+}
+
 func TestValidateNetworkInterface(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -325,6 +372,29 @@ func TestValidateNetworkConfig(t *testing.T) {
 			wantErr:     true,
 			errContains: "unsupported network type",
 		},
+		{
+			name: "legacy DHCP with gateway",
+			cfg: &NetworkConfig{
+				Type:    "bridge",
+				Bridge:  "br0",
+				DHCP:    true,
+				Gateway: "192.168.1.1",
+			},
+			wantErr:     true,
+			errContains: "cannot specify gateway when DHCP is enabled",
+		},
+		{
+			name: "untyped top-level DHCP with static IP",
+			cfg: &NetworkConfig{
+				DHCP: true,
+				IP:   "192.168.1.100/24",
+				Interfaces: []NetworkInterface{
+					{Type: "bridge", Bridge: "br0", DHCP: true},
+				},
+			},
+			wantErr:     true,
+			errContains: "cannot specify static IP when DHCP is enabled",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,145 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ValidateSwappiness validates a memory.swappiness value
+func ValidateSwappiness(swappiness int) error {
+	if swappiness < 0 || swappiness > 100 {
+		return fmt.Errorf("swappiness must be between 0 and 100, got %d", swappiness)
+	}
+	return nil
+}
+
+// ulimitNames are the resource limits LXC accepts as lxc.prlimit.<name>
+var ulimitNames = map[string]bool{
+	"as": true, "core": true, "cpu": true, "data": true, "fsize": true,
+	"locks": true, "memlock": true, "msgqueue": true, "nice": true, "nofile": true,
+	"nproc": true, "rss": true, "rtprio": true, "rttime": true, "sigpending": true,
+	"stack": true,
+}
+
+// ValidateUlimit validates a process resource limit name and its soft and
+// hard values
+func ValidateUlimit(name string, soft, hard int64) error {
+	if !ulimitNames[name] {
+		return fmt.Errorf("unsupported ulimit %q", name)
+	}
+	if soft < 0 || hard < 0 {
+		return fmt.Errorf("ulimit %s must be non-negative", name)
+	}
+	if soft > hard {
+		return fmt.Errorf("ulimit %s soft limit %d exceeds hard limit %d", name, soft, hard)
+	}
+	return nil
+}
+
+// ValidateCPUSet validates a cpuset list such as "0-3,6", as used by
+// cpuset.cpus and cpuset.mems. An empty string means no pinning.
+func ValidateCPUSet(set string) error {
+	if set == "" {
+		return nil
+	}
+
+	for _, part := range strings.Split(set, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := parseCPUIndex(lo)
+		if err != nil {
+			return fmt.Errorf("invalid cpuset %q: %w", set, err)
+		}
+		if !isRange {
+			continue
+		}
+		end, err := parseCPUIndex(hi)
+		if err != nil {
+			return fmt.Errorf("invalid cpuset %q: %w", set, err)
+		}
+		if end < start {
+			return fmt.Errorf("invalid cpuset %q: range %s is reversed", set, part)
+		}
+	}
+
+	return nil
+}
+
+// parseCPUIndex parses a single non-negative cpuset index
+func parseCPUIndex(s string) (int, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty entry")
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || strings.HasPrefix(s, "+") {
+		return 0, fmt.Errorf("%q is not a non-negative integer", s)
+	}
+	return n, nil
+}
+
+// ValidateCPUs validates a docker-compose CPU count such as "1.5" and returns
+// it as a number
+func ValidateCPUs(cpus string) (float64, error) {
+	n, err := strconv.ParseFloat(strings.TrimSpace(cpus), 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("invalid cpus %q: must be a number", cpus)
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("cpus must be positive, got %s", cpus)
+	}
+	return n, nil
+}
+
+// ValidateCPULimits validates CPU cores, cpu.shares and the CFS quota and
+// period, where zero means unset. A quota only limits CPU time relative to a
+// period, so it requires one.
+func ValidateCPULimits(cores int, shares, quota, period int64) error {
+	if cores < 0 {
+		return fmt.Errorf("cores must be at least 1, got %d", cores)
+	}
+	if shares < 0 {
+		return fmt.Errorf("cpu shares must be positive, got %d", shares)
+	}
+	if quota < 0 {
+		return fmt.Errorf("cpu quota must be positive, got %d", quota)
+	}
+	if period < 0 {
+		return fmt.Errorf("cpu period must be positive, got %d", period)
+	}
+	if quota > 0 && period == 0 {
+		return fmt.Errorf("cpu quota requires a cpu period")
+	}
+	return nil
+}
+
+// ValidateMemoryLimits validates the memory limit, memory+swap limit and soft
+// limit, where empty means unset. Since swap counts memory too it can't be
+// below the limit, and the soft limit can't exceed the limit.
+func ValidateMemoryLimits(limit, swap, reserve string) error {
+	sizes := make(map[string]int64)
+	for _, v := range []struct{ name, value string }{
+		{"memory limit", limit},
+		{"memory swap", swap},
+		{"memory reserve", reserve},
+	} {
+		if v.value == "" {
+			continue
+		}
+		size, err := ValidateStorageSize(v.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", v.name, err)
+		}
+		sizes[v.name] = size
+	}
+
+	if l, ok := sizes["memory limit"]; ok {
+		if s, ok := sizes["memory swap"]; ok && s < l {
+			return fmt.Errorf("memory swap %s must not be below the memory limit %s", swap, limit)
+		}
+		if r, ok := sizes["memory reserve"]; ok && r > l {
+			return fmt.Errorf("memory reserve %s must not exceed the memory limit %s", reserve, limit)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package validation
+
+import "testing"
+
+func TestValidateSwappiness(t *testing.T) {
+	tests := []struct {
+		name        string
+		swappiness  int
+		wantErr     bool
+		errContains string
+	}{
+		{name: "minimum", swappiness: 0},
+		{name: "typical", swappiness: 60},
+		{name: "maximum", swappiness: 100},
+		{name: "negative", swappiness: -1, wantErr: true, errContains: "between 0 and 100"},
+		{name: "too large", swappiness: 101, wantErr: true, errContains: "between 0 and 100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateSwappiness(tt.swappiness), tt.wantErr, tt.errContains)
+		})
+	}
+}
+
+func TestValidateCPUSet(t *testing.T) {
+	tests := []struct {
+		name        string
+		set         string
+		wantErr     bool
+		errContains string
+	}{
+		{name: "empty", set: ""},
+		{name: "single cpu", set: "0"},
+		{name: "range", set: "0-3"},
+		{name: "range and list", set: "0-3,6"},
+		{name: "list", set: "1,3,5"},
+		{name: "degenerate range", set: "2-2"},
+		{name: "negative", set: "-1", wantErr: true, errContains: "empty entry"},
+		{name: "trailing comma", set: "0,", wantErr: true, errContains: "empty entry"},
+		{name: "open range", set: "0-", wantErr: true, errContains: "empty entry"},
+		{name: "reversed range", set: "3-1", wantErr: true, errContains: "reversed"},
+		{name: "letters", set: "a-b", wantErr: true, errContains: "not a non-negative integer"},
+		{name: "double range", set: "0-2-4", wantErr: true, errContains: "not a non-negative integer"},
+		{name: "whitespace", set: "0, 1", wantErr: true, errContains: "not a non-negative integer"},
+		{name: "plus sign", set: "+1", wantErr: true, errContains: "not a non-negative integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateCPUSet(tt.set), tt.wantErr, tt.errContains)
+		})
+	}
+}
+
+func TestValidateUlimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       string
+		soft, hard  int64
+		wantErr     bool
+		errContains string
+	}{
+		{name: "nofile", limit: "nofile", soft: 1024, hard: 65536},
+		{name: "nproc equal", limit: "nproc", soft: 4096, hard: 4096},
+		{name: "unknown", limit: "files", soft: 1, hard: 1, wantErr: true, errContains: "unsupported ulimit"},
+		{name: "soft above hard", limit: "nofile", soft: 2048, hard: 1024, wantErr: true, errContains: "exceeds hard limit"},
+		{name: "negative", limit: "core", soft: -1, hard: 0, wantErr: true, errContains: "non-negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateUlimit(tt.limit, tt.soft, tt.hard), tt.wantErr, tt.errContains)
+		})
+	}
+}
+
+func TestValidateCPUs(t *testing.T) {
+	tests := []struct {
+		name        string
+		cpus        string
+		want        float64
+		wantErr     bool
+		errContains string
+	}{
+		{name: "whole", cpus: "2", want: 2},
+		{name: "fraction", cpus: "1.5", want: 1.5},
+		{name: "small fraction", cpus: "0.25", want: 0.25},
+		{name: "zero", cpus: "0", wantErr: true, errContains: "must be positive"},
+		{name: "negative", cpus: "-1", wantErr: true, errContains: "must be positive"},
+		{name: "not a number", cpus: "two", wantErr: true, errContains: "must be a number"},
+		{name: "infinite", cpus: "Inf", wantErr: true, errContains: "must be a number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateCPUs(tt.cpus)
+			assertTestError(t, err, tt.wantErr, tt.errContains)
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateCPULimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		cores       int
+		shares      int64
+		quota       int64
+		period      int64
+		wantErr     bool
+		errContains string
+	}{
+		{name: "unset"},
+		{name: "all set", cores: 2, shares: 1024, quota: 150000, period: 100000},
+		{name: "period only", period: 100000},
+		{name: "negative cores", cores: -1, wantErr: true, errContains: "cores must be at least 1"},
+		{name: "negative shares", shares: -5, wantErr: true, errContains: "shares must be positive"},
+		{name: "negative quota", quota: -1, period: 100000, wantErr: true, errContains: "quota must be positive"},
+		{name: "negative period", period: -100, wantErr: true, errContains: "period must be positive"},
+		{name: "quota without period", quota: 50000, wantErr: true, errContains: "requires a cpu period"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateCPULimits(tt.cores, tt.shares, tt.quota, tt.period), tt.wantErr, tt.errContains)
+		})
+	}
+}
+
+func TestValidateMemoryLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       string
+		swap        string
+		reserve     string
+		wantErr     bool
+		errContains string
+	}{
+		{name: "unset"},
+		{name: "all set", limit: "1G", swap: "2G", reserve: "512M"},
+		{name: "swap equal to limit", limit: "1G", swap: "1024M"},
+		{name: "swap without limit", swap: "1G"},
+		{name: "invalid limit", limit: "lots", wantErr: true, errContains: "invalid memory limit"},
+		{name: "invalid swap", limit: "1G", swap: "-1", wantErr: true, errContains: "invalid memory swap"},
+		{name: "invalid reserve", reserve: "1X", wantErr: true, errContains: "invalid memory reserve"},
+		{name: "swap below limit", limit: "1G", swap: "512M", wantErr: true, errContains: "must not be below the memory limit"},
+		{name: "reserve above limit", limit: "512M", reserve: "1G", wantErr: true, errContains: "must not exceed the memory limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateMemoryLimits(tt.limit, tt.swap, tt.reserve), tt.wantErr, tt.errContains)
+		})
+	}
+}
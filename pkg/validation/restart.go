@@ -0,0 +1,16 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateRestartPolicy validates a container restart policy
+func ValidateRestartPolicy(policy string) error {
+	switch strings.ToLower(policy) {
+	case "", "no", "always", "unless-stopped":
+		return nil
+	default:
+		return fmt.Errorf("unsupported restart policy %q (supported: no, always, unless-stopped)", policy)
+	}
+}
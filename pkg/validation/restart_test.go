@@ -0,0 +1,24 @@
+package validation
+
+import "testing"
+
+func TestValidateRestartPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		wantErr     bool
+		errContains string
+	}{
+		{name: "default", policy: ""},
+		{name: "no", policy: "no"},
+		{name: "always", policy: "always"},
+		{name: "unless-stopped uppercase", policy: "Unless-Stopped"},
+		{name: "on-failure", policy: "on-failure", wantErr: true, errContains: "unsupported restart policy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateRestartPolicy(tt.policy), tt.wantErr, tt.errContains)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// strictDroppedCaps are the capabilities strict isolation removes because
+// they allow escaping or reconfiguring the host
+var strictDroppedCaps = map[string]bool{
+	"SYS_ADMIN":       true,
+	"SYS_MODULE":      true,
+	"SYS_RAWIO":       true,
+	"SYS_PTRACE":      true,
+	"SYS_BOOT":        true,
+	"MAC_ADMIN":       true,
+	"MAC_OVERRIDE":    true,
+	"DAC_READ_SEARCH": true,
+}
+
+// ValidateCapabilityConflicts reports capability lists that contradict the
+// isolation settings: capabilities strict isolation drops, and keep-lists
+// under privileged mode, which keeps every capability anyway
+func ValidateCapabilityConflicts(isolation string, privileged bool, caps []string) error {
+	if len(caps) == 0 {
+		return nil
+	}
+
+	var errs ValidationErrors
+	isolation = strings.ToLower(isolation)
+
+	if privileged || isolation == "privileged" {
+		errs.Add("capabilities", fmt.Errorf("privileged containers keep all capabilities, so this keep-list has no effect; remove it or drop privileged mode"))
+		return errs.ErrorOrNil()
+	}
+
+	if isolation == "strict" {
+		for i, c := range caps {
+			name := strings.TrimPrefix(strings.ToUpper(c), "CAP_")
+			if strictDroppedCaps[name] {
+				errs.Add(fmt.Sprintf("capabilities[%d]", i), fmt.Errorf("%s is dropped by strict isolation; remove it or use isolation: default", name))
+			}
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// ValidatePrivilegedShortcut reports a top-level privileged flag that
+// contradicts the nested security block it is folded into
+func ValidatePrivilegedShortcut(privileged bool, isolation string, nestedPrivileged bool) error {
+	isolation = strings.ToLower(isolation)
+	if privileged {
+		if isolation != "" && isolation != "privileged" {
+			return fmt.Errorf("privileged: true conflicts with security.isolation: %s; remove one of them", isolation)
+		}
+		return nil
+	}
+	if nestedPrivileged {
+		return fmt.Errorf("privileged: false conflicts with security.privileged: true; remove one of them")
+	}
+	if isolation == "privileged" {
+		return fmt.Errorf("privileged: false conflicts with security.isolation: privileged; remove one of them")
+	}
+	return nil
+}
+
+// NormalizeCapabilities returns capability names the way lxc.cap.keep expects
+// them: lowercase without the CAP_ prefix, without duplicates and sorted
+func NormalizeCapabilities(caps []string) []string {
+	seen := make(map[string]bool, len(caps))
+	var names []string
+	for _, c := range caps {
+		name := strings.ToLower(strings.TrimSpace(c))
+		name = strings.TrimPrefix(name, "cap_")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
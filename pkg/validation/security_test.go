@@ -0,0 +1,121 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCapabilityConflicts(t *testing.T) {
+	tests := []struct {
+		name        string
+		isolation   string
+		privileged  bool
+		caps        []string
+		wantErr     bool
+		errContains string
+	}{
+		{name: "no capabilities", isolation: "strict"},
+		{name: "default with capabilities", isolation: "default", caps: []string{"SYS_ADMIN", "NET_ADMIN"}},
+		{name: "unset isolation with capabilities", caps: []string{"SYS_ADMIN"}},
+		{name: "strict with allowed capabilities", isolation: "strict", caps: []string{"NET_ADMIN", "SYS_TIME"}},
+		{
+			name:        "strict with dropped capability",
+			isolation:   "strict",
+			caps:        []string{"NET_ADMIN", "SYS_ADMIN"},
+			wantErr:     true,
+			errContains: "capabilities[1]: SYS_ADMIN is dropped by strict isolation",
+		},
+		{
+			name:        "strict with prefixed lowercase capability",
+			isolation:   "Strict",
+			caps:        []string{"cap_sys_module"},
+			wantErr:     true,
+			errContains: "SYS_MODULE is dropped by strict isolation",
+		},
+		{name: "privileged without capabilities", privileged: true},
+		{
+			name:        "privileged flag with keep-list",
+			isolation:   "default",
+			privileged:  true,
+			caps:        []string{"NET_ADMIN"},
+			wantErr:     true,
+			errContains: "capabilities: privileged containers keep all capabilities",
+		},
+		{
+			name:        "privileged isolation with keep-list",
+			isolation:   "privileged",
+			caps:        []string{"NET_ADMIN"},
+			wantErr:     true,
+			errContains: "keep-list has no effect",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateCapabilityConflicts(tt.isolation, tt.privileged, tt.caps), tt.wantErr, tt.errContains)
+		})
+	}
+}
+
+func TestValidatePrivilegedShortcut(t *testing.T) {
+	tests := []struct {
+		name             string
+		privileged       bool
+		isolation        string
+		nestedPrivileged bool
+		wantErr          bool
+		errContains      string
+	}{
+		{name: "privileged with empty security", privileged: true},
+		{name: "privileged matching nested block", privileged: true, isolation: "Privileged", nestedPrivileged: true},
+		{name: "privileged with other settings", privileged: true, isolation: ""},
+		{
+			name:        "privileged with strict isolation",
+			privileged:  true,
+			isolation:   "strict",
+			wantErr:     true,
+			errContains: "conflicts with security.isolation: strict",
+		},
+		{name: "unprivileged with default isolation", isolation: "default"},
+		{
+			name:             "unprivileged with nested privileged",
+			nestedPrivileged: true,
+			wantErr:          true,
+			errContains:      "conflicts with security.privileged: true",
+		},
+		{
+			name:        "unprivileged with privileged isolation",
+			isolation:   "privileged",
+			wantErr:     true,
+			errContains: "conflicts with security.isolation: privileged",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidatePrivilegedShortcut(tt.privileged, tt.isolation, tt.nestedPrivileged), tt.wantErr, tt.errContains)
+		})
+	}
+}
+
+func TestNormalizeCapabilities(t *testing.T) {
+	tests := []struct {
+		name string
+		caps []string
+		want string
+	}{
+		{name: "empty"},
+		{name: "lowercased", caps: []string{"NET_ADMIN"}, want: "net_admin"},
+		{name: "prefix removed", caps: []string{"CAP_SYS_TIME", "cap_chown"}, want: "chown sys_time"},
+		{name: "duplicates removed", caps: []string{"NET_ADMIN", "net_admin", "CAP_NET_ADMIN"}, want: "net_admin"},
+		{name: "sorted", caps: []string{"SYS_TIME", "CHOWN", "NET_RAW"}, want: "chown net_raw sys_time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(NormalizeCapabilities(tt.caps), " "); got != tt.want {
+				t.Errorf("NormalizeCapabilities(%v) = %q, want %q", tt.caps, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package validation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signalNumbers maps signal names (without the SIG prefix) to their Linux numbers
+var signalNumbers = map[string]int{
+	"HUP":    1,
+	"INT":    2,
+	"QUIT":   3,
+	"ILL":    4,
+	"TRAP":   5,
+	"ABRT":   6,
+	"BUS":    7,
+	"FPE":    8,
+	"KILL":   9,
+	"USR1":   10,
+	"SEGV":   11,
+	"USR2":   12,
+	"PIPE":   13,
+	"ALRM":   14,
+	"TERM":   15,
+	"STKFLT": 16,
+	"CHLD":   17,
+	"CONT":   18,
+	"STOP":   19,
+	"TSTP":   20,
+	"TTIN":   21,
+	"TTOU":   22,
+	"URG":    23,
+	"XCPU":   24,
+	"XFSZ":   25,
+	"VTALRM": 26,
+	"PROF":   27,
+	"WINCH":  28,
+	"IO":     29,
+	"PWR":    30,
+	"SYS":    31,
+}
+
+// ParseSignal converts a signal name (e.g. SIGTERM, TERM) or number to its Linux signal number
+func ParseSignal(signal string) (int, error) {
+	signal = strings.TrimSpace(signal)
+	if signal == "" {
+		return 0, fmt.Errorf("signal is required")
+	}
+
+	if n, err := strconv.Atoi(signal); err == nil {
+		if n < 1 || n > 64 {
+			return 0, fmt.Errorf("invalid signal number: %d (must be between 1 and 64)", n)
+		}
+		return n, nil
+	}
+
+	name := strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+	n, ok := signalNumbers[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown signal: %s", signal)
+	}
+	return n, nil
+}
+
+// ValidateSignal validates a signal name or number
+func ValidateSignal(signal string) error {
+	_, err := ParseSignal(signal)
+	return err
+}
+
+// ParseStopGracePeriod parses a stop_grace_period such as 10s or 1m30s
+func ParseStopGracePeriod(period string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil {
+		return 0, fmt.Errorf("invalid stop grace period %q: %w", period, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("stop grace period must not be negative: %s", period)
+	}
+	return d, nil
+}
@@ -0,0 +1,95 @@
+package validation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		name        string
+		signal      string
+		want        int
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name:    "full name",
+			signal:  "SIGTERM",
+			want:    15,
+			wantErr: false,
+		},
+		{
+			name:    "short name",
+			signal:  "QUIT",
+			want:    3,
+			wantErr: false,
+		},
+		{
+			name:    "lowercase name",
+			signal:  "sigusr1",
+			want:    10,
+			wantErr: false,
+		},
+		{
+			name:    "numeric signal",
+			signal:  "9",
+			want:    9,
+			wantErr: false,
+		},
+		{
+			name:        "empty signal",
+			signal:      "",
+			wantErr:     true,
+			errContains: "required",
+		},
+		{
+			name:        "unknown signal",
+			signal:      "SIGFOO",
+			wantErr:     true,
+			errContains: "unknown signal",
+		},
+		{
+			name:        "out of range number",
+			signal:      "99",
+			wantErr:     true,
+			errContains: "invalid signal number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSignal(tt.signal)
+			assertTestError(t, err, tt.wantErr, tt.errContains)
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseSignal(%q) = %d, want %d", tt.signal, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStopGracePeriod(t *testing.T) {
+	tests := []struct {
+		name        string
+		period      string
+		want        time.Duration
+		wantErr     bool
+		errContains string
+	}{
+		{name: "seconds", period: "10s", want: 10 * time.Second},
+		{name: "minutes and seconds", period: "1m30s", want: 90 * time.Second},
+		{name: "zero", period: "0s"},
+		{name: "missing unit", period: "10", wantErr: true, errContains: "invalid stop grace period"},
+		{name: "negative", period: "-5s", wantErr: true, errContains: "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStopGracePeriod(tt.period)
+			assertTestError(t, err, tt.wantErr, tt.errContains)
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseStopGracePeriod(%q) = %v, want %v", tt.period, got, tt.want)
+			}
+		})
+	}
+}
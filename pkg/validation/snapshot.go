@@ -0,0 +1,23 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var snapshotNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ValidateSnapshotName validates a storage snapshot name, which ends up in
+// zfs snapshot names and file names
+func ValidateSnapshotName(name string) error {
+	if name == "" {
+		return fmt.Errorf("snapshot name is required")
+	}
+	if !snapshotNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name (must start with letter/number and contain only letters, numbers, dots, hyphens, and underscores): %s", name)
+	}
+	if len(name) > 64 {
+		return fmt.Errorf("snapshot name too long (max 64 characters): %s", name)
+	}
+	return nil
+}
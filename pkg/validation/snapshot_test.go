@@ -0,0 +1,29 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSnapshotName(t *testing.T) {
+	tests := []struct {
+		name        string
+		snapshot    string
+		wantErr     bool
+		errContains string
+	}{
+		{name: "simple", snapshot: "before-upgrade"},
+		{name: "dotted", snapshot: "v1.2_3"},
+		{name: "empty", snapshot: "", wantErr: true, errContains: "snapshot name is required"},
+		{name: "leading dot", snapshot: ".hidden", wantErr: true, errContains: "invalid snapshot name"},
+		{name: "path", snapshot: "a/b", wantErr: true, errContains: "invalid snapshot name"},
+		{name: "zfs separator", snapshot: "a@b", wantErr: true, errContains: "invalid snapshot name"},
+		{name: "too long", snapshot: strings.Repeat("a", 65), wantErr: true, errContains: "too long"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateSnapshotName(tt.snapshot), tt.wantErr, tt.errContains)
+		})
+	}
+}
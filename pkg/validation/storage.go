@@ -2,6 +2,7 @@ package validation
 
 import (
 	"fmt"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
@@ -80,47 +81,90 @@ func FormatBytes(bytes int64) string {
 	return fmt.Sprintf("%.1f%s", value, units[unit])
 }
 
+// ValidateStorageBackend validates a rootfs storage backend, where empty means
+// dir. The zfs and lvm backends need a pool: a zfs pool or dataset, or an LVM
+// volume group.
+func ValidateStorageBackend(backend, pool string) error {
+	switch backend {
+	case "", "dir", "btrfs":
+		return nil
+	case "zfs", "lvm":
+		if pool == "" {
+			return WithPath("pool", fmt.Errorf("storage pool is required for %s backend", backend))
+		}
+		return nil
+	default:
+		return WithPath("backend", fmt.Errorf("invalid storage backend: %s", backend))
+	}
+}
+
+// ValidateStorageConfig validates storage settings and mounts, locating
+// failures with a ValidationError path
 func ValidateStorageConfig(config *common.StorageConfig) error {
 	if config == nil {
 		return fmt.Errorf("storage configuration is required")
 	}
 
 	if config.Root == "" {
-		return fmt.Errorf("root storage size is required")
+		return WithPath("root", fmt.Errorf("root storage size is required"))
 	}
 
 	if _, err := ValidateStorageSize(config.Root); err != nil {
-		return fmt.Errorf("invalid root storage size: %w", err)
+		return WithPath("root", fmt.Errorf("invalid root storage size: %w", err))
 	}
 
-	switch config.Backend {
-	case "dir", "zfs", "btrfs", "lvm":
-		// Valid backends
-	default:
-		return fmt.Errorf("invalid storage backend: %s", config.Backend)
+	if config.Backend == "" {
+		return WithPath("backend", fmt.Errorf("invalid storage backend: %s", config.Backend))
 	}
-
-	if config.Backend == "zfs" && config.Pool == "" {
-		return fmt.Errorf("storage pool is required for zfs backend")
+	if err := ValidateStorageBackend(config.Backend, config.Pool); err != nil {
+		return err
 	}
 
-	for _, mount := range config.Mounts {
+	for i, mount := range config.Mounts {
+		path := fmt.Sprintf("mounts[%d]", i)
 		if mount.Source == "" {
-			return fmt.Errorf("mount source is required")
+			return WithPath(JoinPath(path, "source"), fmt.Errorf("mount source is required"))
 		}
 		if mount.Target == "" {
-			return fmt.Errorf("mount target is required")
+			return WithPath(JoinPath(path, "target"), fmt.Errorf("mount target is required"))
 		}
 		if mount.Type == "" {
-			return fmt.Errorf("mount type is required")
+			return WithPath(JoinPath(path, "type"), fmt.Errorf("mount type is required"))
 		}
 		switch mount.Type {
 		case "bind", "volume":
 			// Valid mount types
 		default:
-			return fmt.Errorf("invalid mount type: %s", mount.Type)
+			return WithPath(JoinPath(path, "type"), fmt.Errorf("invalid mount type: %s", mount.Type))
+		}
+	}
+
+	for i, mount := range config.TmpfsMounts {
+		if err := ValidateTmpfsMount(mount); err != nil {
+			return WithPath(fmt.Sprintf("tmpfs[%d]", i), err)
 		}
 	}
 
 	return nil
 }
+
+// ValidateTmpfsMount validates a tmpfs mount's target, size and octal mode
+func ValidateTmpfsMount(mount common.TmpfsMount) error {
+	if mount.Target == "" {
+		return WithPath("target", fmt.Errorf("tmpfs target is required"))
+	}
+	if !path.IsAbs(mount.Target) {
+		return WithPath("target", fmt.Errorf("tmpfs target must be an absolute path: %s", mount.Target))
+	}
+	if mount.Size != "" {
+		if _, err := ValidateStorageSize(mount.Size); err != nil {
+			return WithPath("size", fmt.Errorf("invalid tmpfs size: %w", err))
+		}
+	}
+	if mount.Mode != "" {
+		if mode, err := strconv.ParseUint(mount.Mode, 8, 32); err != nil || mode > 07777 {
+			return WithPath("mode", fmt.Errorf("invalid tmpfs mode: %s (should be octal, e.g. 1777)", mount.Mode))
+		}
+	}
+	return nil
+}
@@ -252,6 +252,15 @@ func TestValidateStorageConfig(t *testing.T) {
 			wantErr:     true,
 			errContains: "storage pool is required for zfs backend",
 		},
+		{
+			name: "missing volume group for lvm",
+			config: &common.StorageConfig{
+				Root:    "10G",
+				Backend: "lvm",
+			},
+			wantErr:     true,
+			errContains: "storage pool is required for lvm backend",
+		},
 		{
 			name: "invalid mount",
 			config: &common.StorageConfig{
@@ -268,6 +277,45 @@ func TestValidateStorageConfig(t *testing.T) {
 			wantErr:     true,
 			errContains: "mount source is required",
 		},
+		{
+			name: "valid tmpfs mount",
+			config: &common.StorageConfig{
+				Root:        "10G",
+				Backend:     "dir",
+				TmpfsMounts: []common.TmpfsMount{{Target: "/run/cache", Size: "64M", Mode: "1777"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "relative tmpfs target",
+			config: &common.StorageConfig{
+				Root:        "10G",
+				Backend:     "dir",
+				TmpfsMounts: []common.TmpfsMount{{Target: "run/cache"}},
+			},
+			wantErr:     true,
+			errContains: "tmpfs[0].target",
+		},
+		{
+			name: "invalid tmpfs size",
+			config: &common.StorageConfig{
+				Root:        "10G",
+				Backend:     "dir",
+				TmpfsMounts: []common.TmpfsMount{{Target: "/run/cache", Size: "64X"}},
+			},
+			wantErr:     true,
+			errContains: "invalid tmpfs size",
+		},
+		{
+			name: "invalid tmpfs mode",
+			config: &common.StorageConfig{
+				Root:        "10G",
+				Backend:     "dir",
+				TmpfsMounts: []common.TmpfsMount{{Target: "/run/cache", Mode: "999"}},
+			},
+			wantErr:     true,
+			errContains: "invalid tmpfs mode",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,53 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// sysctlPattern matches dotted sysctl names such as net.ipv4.ip_forward
+var sysctlPattern = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-zA-Z0-9_-]+)+$`)
+
+// namespacedSysctls are the sysctls isolated by the IPC and UTS namespaces.
+// Prefixes end in a dot.
+var namespacedSysctls = []string{
+	"kernel.domainname",
+	"kernel.hostname",
+	"kernel.msgmax",
+	"kernel.msgmnb",
+	"kernel.msgmni",
+	"kernel.sem",
+	"kernel.shm_rmid_forced",
+	"kernel.shmall",
+	"kernel.shmmax",
+	"kernel.shmmni",
+	"fs.mqueue.",
+	"net.",
+}
+
+// ValidateSysctl validates a sysctl name and value
+func ValidateSysctl(key, value string) error {
+	if !sysctlPattern.MatchString(key) {
+		return fmt.Errorf("invalid sysctl name %q (expected a dotted name such as net.core.somaxconn)", key)
+	}
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("sysctl %s requires a value", key)
+	}
+	if strings.ContainsAny(value, "\n\r") {
+		return fmt.Errorf("sysctl %s value must be a single line", key)
+	}
+	return nil
+}
+
+// IsNamespacedSysctl reports whether a sysctl only affects the container's
+// own namespaces. Other sysctls are host-wide, and setting them either fails
+// in an unprivileged container or changes the host.
+func IsNamespacedSysctl(key string) bool {
+	for _, s := range namespacedSysctls {
+		if key == s || (strings.HasSuffix(s, ".") && strings.HasPrefix(key, s)) {
+			return true
+		}
+	}
+	return false
+}
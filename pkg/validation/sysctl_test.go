@@ -0,0 +1,45 @@
+package validation
+
+import "testing"
+
+func TestValidateSysctl(t *testing.T) {
+	tests := []struct {
+		name        string
+		key, value  string
+		wantErr     bool
+		errContains string
+	}{
+		{name: "net", key: "net.core.somaxconn", value: "1024"},
+		{name: "ipc", key: "kernel.shmmax", value: "68719476736"},
+		{name: "multiple values", key: "net.ipv4.ip_local_port_range", value: "1024 65000"},
+		{name: "no dot", key: "somaxconn", value: "1024", wantErr: true, errContains: "invalid sysctl name"},
+		{name: "slash separated", key: "net/core/somaxconn", value: "1024", wantErr: true, errContains: "invalid sysctl name"},
+		{name: "empty value", key: "net.core.somaxconn", value: " ", wantErr: true, errContains: "requires a value"},
+		{name: "multi-line value", key: "net.core.somaxconn", value: "1\n2", wantErr: true, errContains: "single line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTestError(t, ValidateSysctl(tt.key, tt.value), tt.wantErr, tt.errContains)
+		})
+	}
+}
+
+func TestIsNamespacedSysctl(t *testing.T) {
+	tests := map[string]bool{
+		"net.ipv4.ip_forward":  true,
+		"kernel.shmmax":        true,
+		"fs.mqueue.msg_max":    true,
+		"kernel.hostname":      true,
+		"kernel.shmmax_extra":  false,
+		"vm.swappiness":        false,
+		"kernel.pid_max":       false,
+		"fs.file-max":          false,
+		"network.not.a.prefix": false,
+	}
+	for key, want := range tests {
+		if got := IsNamespacedSysctl(key); got != want {
+			t.Errorf("IsNamespacedSysctl(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package validation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ZoneinfoDir is the host's tzdata directory timezones are checked against
+var ZoneinfoDir = "/usr/share/zoneinfo"
+
+var timezoneRegex = regexp.MustCompile(`^[A-Za-z0-9_+-]+(/[A-Za-z0-9_+-]+)*$`)
+
+// ValidateTimezone validates an IANA timezone name such as Europe/Berlin. The
+// name is checked against the host's tzdata when it is installed, otherwise
+// only its format is checked.
+func ValidateTimezone(tz string) error {
+	if tz == "" {
+		return nil
+	}
+	if !timezoneRegex.MatchString(tz) {
+		return fmt.Errorf("invalid timezone %q", tz)
+	}
+
+	if info, err := os.Stat(ZoneinfoDir); err != nil || !info.IsDir() {
+		return nil
+	}
+	info, err := os.Stat(filepath.Join(ZoneinfoDir, tz))
+	if err != nil || info.IsDir() {
+		return fmt.Errorf("unknown timezone %q", tz)
+	}
+	return nil
+}
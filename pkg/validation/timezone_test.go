@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateTimezone(t *testing.T) {
+	zoneinfo := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(zoneinfo, "Europe"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, zone := range []string{"UTC", "Europe/Berlin"} {
+		if err := os.WriteFile(filepath.Join(zoneinfo, zone), []byte("TZif"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func(dir string) { ZoneinfoDir = dir }(ZoneinfoDir)
+
+	tests := []struct {
+		name        string
+		zoneinfo    string
+		tz          string
+		wantErr     bool
+		errContains string
+	}{
+		{name: "unset", zoneinfo: zoneinfo, tz: ""},
+		{name: "utc", zoneinfo: zoneinfo, tz: "UTC"},
+		{name: "region", zoneinfo: zoneinfo, tz: "Europe/Berlin"},
+		{name: "unknown", zoneinfo: zoneinfo, tz: "Europe/Atlantis", wantErr: true, errContains: "unknown timezone"},
+		{name: "directory", zoneinfo: zoneinfo, tz: "Europe", wantErr: true, errContains: "unknown timezone"},
+		{name: "traversal", zoneinfo: zoneinfo, tz: "../../etc/passwd", wantErr: true, errContains: "invalid timezone"},
+		{name: "absolute", zoneinfo: zoneinfo, tz: "/UTC", wantErr: true, errContains: "invalid timezone"},
+		{name: "no tzdata", zoneinfo: filepath.Join(zoneinfo, "missing"), tz: "Asia/Tokyo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ZoneinfoDir = tt.zoneinfo
+			assertTestError(t, ValidateTimezone(tt.tz), tt.wantErr, tt.errContains)
+		})
+	}
+}
@@ -25,6 +25,7 @@ type NetworkConfig struct {
 	PortForwards  []PortForward      `json:"port_forwards,omitempty"`
 	DNSServers    []string           `json:"dns_servers,omitempty"`
 	SearchDomains []string           `json:"search_domains,omitempty"`
+	DNSOptions    []string           `json:"dns_options,omitempty"`
 	Isolated      bool               `json:"isolated,omitempty"`
 
 	// Legacy fields for backward compatibility
@@ -61,20 +62,20 @@ func ValidateSecurityProfile(cfg *SecurityProfile) error {
 		case "default", "strict", "privileged":
 			// Valid values
 		default:
-			return fmt.Errorf("invalid isolation level: %s", cfg.Isolation)
+			return WithPath("isolation", fmt.Errorf("invalid isolation level: %s", cfg.Isolation))
 		}
 	}
 
 	if cfg.Privileged && strings.ToLower(cfg.Isolation) == "strict" {
-		return fmt.Errorf("cannot use privileged mode with strict isolation")
+		return WithPath("privileged", fmt.Errorf("cannot use privileged mode with strict isolation"))
 	}
 
-	for _, cap := range cfg.Capabilities {
+	for i, cap := range cfg.Capabilities {
 		if !isValidCapability(cap) {
-			return fmt.Errorf("invalid capability: %s", cap)
+			return WithPath(fmt.Sprintf("capabilities[%d]", i), fmt.Errorf("invalid capability: %s", cap))
 		}
 	}
-	return nil
+	return ValidateCapabilityConflicts(cfg.Isolation, cfg.Privileged, cfg.Capabilities)
 }
 
 // isValidCapability checks if a Linux capability is valid